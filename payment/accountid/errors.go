@@ -0,0 +1,85 @@
+package accountid
+
+import (
+	"fmt"
+
+	"go.fraixed.es/errors"
+)
+
+type code uint8
+
+// The list of specific error codes that the accountid package can return.
+const (
+	ErrInvalidBIC code = iota + 1
+
+	ErrInvalidIBANChecksum
+	ErrInvalidIBANCountry
+	ErrInvalidIBANFormat
+	ErrInvalidIBANLength
+
+	ErrInvalidSortCode
+	ErrInvalidUKAccountNumber
+	ErrInvalidUKModulusCheck
+
+	ErrInvalidWeightsTable
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrInvalidBIC:
+		return "InvalidBIC"
+	case ErrInvalidIBANChecksum:
+		return "InvalidIBANChecksum"
+	case ErrInvalidIBANCountry:
+		return "InvalidIBANCountry"
+	case ErrInvalidIBANFormat:
+		return "InvalidIBANFormat"
+	case ErrInvalidIBANLength:
+		return "InvalidIBANLength"
+	case ErrInvalidSortCode:
+		return "InvalidSortCode"
+	case ErrInvalidUKAccountNumber:
+		return "InvalidUKAccountNumber"
+	case ErrInvalidUKModulusCheck:
+		return "InvalidUKModulusCheck"
+	case ErrInvalidWeightsTable:
+		return "InvalidWeightsTable"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrInvalidBIC:
+		return "The BIC doesn't have the ISO 9362 format"
+	case ErrInvalidIBANChecksum:
+		return "The check digits of the IBAN don't match with its mod-97 checksum"
+	case ErrInvalidIBANCountry:
+		return "The country of the IBAN doesn't use IBANs or it isn't a known one"
+	case ErrInvalidIBANFormat:
+		return "The IBAN doesn't have the ISO 13616 electronic format"
+	case ErrInvalidIBANLength:
+		return "The IBAN doesn't have the length of its country"
+	case ErrInvalidSortCode:
+		return "The UK sort code isn't composed by 6 digits"
+	case ErrInvalidUKAccountNumber:
+		return "The UK account number isn't composed by 8 digits"
+	case ErrInvalidUKModulusCheck:
+		return "The UK sort code and account number don't pass the modulus check"
+	case ErrInvalidWeightsTable:
+		return "The modulus weights table has an invalid row"
+	}
+
+	return ""
+}
+
+// mdArg creates a new metadata from a function argument which is related with
+// the error to create, likewise payment.ErrMDArg, which cannot be used because
+// the payment package depends on this package.
+func mdArg(name string, val interface{}) errors.MD {
+	return errors.MD{
+		K: fmt.Sprintf("arg:%s", name),
+		V: val,
+	}
+}
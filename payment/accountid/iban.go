@@ -0,0 +1,106 @@
+// Package accountid validates the identifiers of the accounts and the banks of
+// the parties of a payment: IBANs, BICs and UK sort codes and account numbers.
+//
+// The UK accounts are validated with the modulus checking of Vocalink, which
+// requires its table of weights by sort code. The package only bundles the rows
+// of the examples of the specification, hence the accounts of any other sort
+// code aren't checked, as the specification establishes for the sort codes
+// which aren't in the table, until the complete table is set. The table is
+// published by Vocalink as the valacdos.txt file and it's updated a few times
+// per year; the applications must download each new release and set it at
+// startup, without rebuilding them, as follows:
+//
+//	var f, err = os.Open("valacdos.txt")
+//	// handle err and close f
+//	w, err := accountid.LoadWeights(f)
+//	// handle err
+//	accountid.SetWeights(w)
+package accountid
+
+import (
+	"regexp"
+
+	"go.fraixed.es/errors"
+)
+
+//nolint:gochecknoglobals
+var (
+	ibanRegexp = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]+$`)
+	bicRegexp  = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+)
+
+// ibanLengths contains the length of the IBANs of each country which uses
+// them, according to the SWIFT IBAN registry.
+//
+//nolint:gochecknoglobals
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22, "BH": 22, "BI": 27,
+	"BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24, "DE": 22, "DJ": 27, "DK": 18, "DO": 28,
+	"EE": 20, "EG": 29, "ES": 24, "FI": 18, "FK": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23,
+	"GL": 18, "GR": 27, "GT": 28, "HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27,
+	"JO": 30, "KW": 30, "KZ": 20, "LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "LY": 25,
+	"MC": 27, "MD": 24, "ME": 22, "MK": 19, "MN": 20, "MR": 27, "MT": 31, "MU": 30, "NI": 28, "NL": 18,
+	"NO": 15, "OM": 23, "PK": 24, "PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "RU": 33,
+	"SA": 24, "SC": 31, "SD": 18, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "SO": 23, "ST": 25, "SV": 28,
+	"TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20, "YE": 30,
+}
+
+// ValidateIBAN validates that iban is an IBAN in electronic format (i.e. upper
+// case and without spaces), with the length of its country and whose check
+// digits match with its mod-97 checksum.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidIBANChecksum
+//
+// * ErrInvalidIBANCountry
+//
+// * ErrInvalidIBANFormat
+//
+// * ErrInvalidIBANLength
+func ValidateIBAN(iban string) error {
+	if !ibanRegexp.MatchString(iban) {
+		return errors.New(ErrInvalidIBANFormat, mdArg("iban", iban))
+	}
+
+	var l, ok = ibanLengths[iban[:2]]
+	if !ok {
+		return errors.New(ErrInvalidIBANCountry, mdArg("iban", iban))
+	}
+
+	if len(iban) != l {
+		return errors.New(ErrInvalidIBANLength, mdArg("iban", iban))
+	}
+
+	// The country code and the check digits are moved to the end and each letter
+	// is replaced by 2 digits (A = 10, ..., Z = 35); the remainder is calculated
+	// digit by digit for not overflowing.
+	var rem int
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' {
+			rem = (rem*100 + int(c-'A') + 10) % 97
+		} else {
+			rem = (rem*10 + int(c-'0')) % 97
+		}
+	}
+
+	if rem != 1 {
+		return errors.New(ErrInvalidIBANChecksum, mdArg("iban", iban))
+	}
+
+	return nil
+}
+
+// ValidateBIC validates that bic is a BIC (i.e. SWIFT code) of 8 or 11
+// characters in upper case.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidBIC
+func ValidateBIC(bic string) error {
+	if !bicRegexp.MatchString(bic) {
+		return errors.New(ErrInvalidBIC, mdArg("bic", bic))
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package accountid_test
+
+import (
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment/accountid"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.fraixed.es/errors"
+)
+
+func TestValidateIBAN(t *testing.T) {
+	var tcases = []struct {
+		iban string
+		code errors.Code
+	}{
+		{iban: "GB29NWBK60161331926819"},
+		{iban: "DE89370400440532013000"},
+		{iban: "NO9386011117947"},
+		{iban: "gb29nwbk60161331926819", code: accountid.ErrInvalidIBANFormat},
+		{iban: "GB29 NWBK 6016 1331 9268 19", code: accountid.ErrInvalidIBANFormat},
+		{iban: "US29NWBK60161331926819", code: accountid.ErrInvalidIBANCountry},
+		{iban: "GB29NWBK6016133192681", code: accountid.ErrInvalidIBANLength},
+		{iban: "GB28NWBK60161331926819", code: accountid.ErrInvalidIBANChecksum},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.iban, func(t *testing.T) {
+			t.Parallel()
+
+			var err = accountid.ValidateIBAN(tc.iban)
+			if tc.code == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			testutil.AssertError(t, err, tc.code, errors.MD{K: "arg:iban", V: tc.iban})
+		})
+	}
+}
+
+func TestValidateBIC(t *testing.T) {
+	for _, bic := range []string{"NWBKGB2L", "DEUTDEFF500", "BOFAUS3N"} {
+		assert.NoError(t, accountid.ValidateBIC(bic), bic)
+	}
+
+	for _, bic := range []string{"NWBKGB2", "nwbkgb2l", "NWBK GB 2L", "1WBKGB2L", "DEUTDEFF50"} {
+		var err = accountid.ValidateBIC(bic)
+		testutil.AssertError(t, err, accountid.ErrInvalidBIC, errors.MD{K: "arg:bic", V: bic})
+	}
+}
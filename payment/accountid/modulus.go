@@ -0,0 +1,297 @@
+package accountid
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.fraixed.es/errors"
+)
+
+//nolint:gochecknoglobals
+var (
+	sortCodeRegexp     = regexp.MustCompile(`^[0-9]{6}$`)
+	ukAccountNumRegexp = regexp.MustCompile(`^[0-9]{8}$`)
+	// defaultWeights is the table used by the package functions; it holds a
+	// *Weights.
+	defaultWeights atomic.Value
+)
+
+func init() { //nolint:gochecknoinits
+	var w, err = LoadWeights(strings.NewReader(bundledWeights))
+	if err != nil {
+		panic(err)
+	}
+
+	SetWeights(w)
+}
+
+// The modulus checking methods of the Vocalink's specification.
+const (
+	methodDblAl = "DBLAL"
+	methodMod10 = "MOD10"
+	methodMod11 = "MOD11"
+)
+
+// weightsRow is a row of the Vocalink's weights table which applies to the sort
+// codes in the range [start, end].
+type weightsRow struct {
+	start     int
+	end       int
+	method    string
+	weights   [14]int
+	exception int
+}
+
+// supportedExceptions are the exceptions of the Vocalink's specification which
+// are implemented. The sort codes which have a row with any other exception
+// aren't checked.
+//
+//nolint:gochecknoglobals
+var supportedExceptions = map[int]bool{0: true, 1: true, 3: true, 4: true, 6: true, 7: true, 8: true}
+
+// Weights is a table of the Vocalink's modulus checking weights.
+type Weights struct {
+	rows []weightsRow
+}
+
+// LoadWeights loads a weights table with the format of the Vocalink's
+// valacdos.txt file, which contains a row per line with the following fields
+// separated by spaces: the start sort code, the end sort code, the method
+// (MOD10, MOD11 or DBLAL), the 14 weights and an optional exception number.
+// Empty lines are ignored.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidWeightsTable
+func LoadWeights(r io.Reader) (*Weights, error) {
+	var (
+		w  Weights
+		sc = bufio.NewScanner(r)
+		ln int
+	)
+
+	for sc.Scan() {
+		ln++
+
+		var fs = strings.Fields(sc.Text())
+		if len(fs) == 0 {
+			continue
+		}
+
+		var row, ok = parseWeightsRow(fs)
+		if !ok {
+			return nil, errors.New(ErrInvalidWeightsTable, mdArg("line", ln))
+		}
+
+		w.rows = append(w.rows, row)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, ErrInvalidWeightsTable, mdArg("line", ln))
+	}
+
+	sort.SliceStable(w.rows, func(i, j int) bool { return w.rows[i].start < w.rows[j].start })
+	return &w, nil
+}
+
+func parseWeightsRow(fs []string) (weightsRow, bool) {
+	var row weightsRow
+	if len(fs) != 17 && len(fs) != 18 {
+		return row, false
+	}
+
+	if !sortCodeRegexp.MatchString(fs[0]) || !sortCodeRegexp.MatchString(fs[1]) {
+		return row, false
+	}
+
+	row.start, _ = strconv.Atoi(fs[0])
+	row.end, _ = strconv.Atoi(fs[1])
+
+	row.method = fs[2]
+	if row.method != methodDblAl && row.method != methodMod10 && row.method != methodMod11 {
+		return row, false
+	}
+
+	for i := range row.weights {
+		var w, err = strconv.Atoi(fs[i+3])
+		if err != nil {
+			return row, false
+		}
+
+		row.weights[i] = w
+	}
+
+	if len(fs) == 18 {
+		var ex, err = strconv.Atoi(fs[17])
+		if err != nil {
+			return row, false
+		}
+
+		row.exception = ex
+	}
+
+	return row, true
+}
+
+// SetWeights sets the weights table used by ValidateUKAccount.
+//
+// By default, the package uses a bundled table, which only contains the rows of
+// the examples of the Vocalink's specification; the complete table is published
+// by Vocalink and it should be loaded with LoadWeights and set by this function.
+func SetWeights(w *Weights) {
+	defaultWeights.Store(w)
+}
+
+// ValidateSortCode validates that sc is a UK sort code without separators
+// (i.e. 6 digits).
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidSortCode
+func ValidateSortCode(sc string) error {
+	if !sortCodeRegexp.MatchString(sc) {
+		return errors.New(ErrInvalidSortCode, mdArg("sc", sc))
+	}
+
+	return nil
+}
+
+// ValidateUKAccount validates the UK sort code sc and the account number num
+// with the weights table set by SetWeights.
+//
+// See Weights.ValidateUKAccount.
+func ValidateUKAccount(sc string, num string) error {
+	return defaultWeights.Load().(*Weights).ValidateUKAccount(sc, num)
+}
+
+// ValidateUKAccount validates that sc is a UK sort code, num a UK account number
+// of 8 digits and that both pass the Vocalink's modulus check of w.
+//
+// The accounts whose sort code isn't in w are valid because they cannot be
+// checked, as well as the ones whose rows have an exception which isn't
+// implemented (2, 5 and from 9 to 14).
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidSortCode
+//
+// * ErrInvalidUKAccountNumber
+//
+// * ErrInvalidUKModulusCheck
+func (w *Weights) ValidateUKAccount(sc string, num string) error {
+	if err := ValidateSortCode(sc); err != nil {
+		return err
+	}
+
+	if !ukAccountNumRegexp.MatchString(num) {
+		return errors.New(ErrInvalidUKAccountNumber, mdArg("num", num))
+	}
+
+	var rows = w.lookup(sc)
+	for _, r := range rows {
+		if !supportedExceptions[r.exception] {
+			return nil
+		}
+	}
+
+	// The digits are named in the specification as u, v, w, x, y, z for the sort
+	// code and a, b, c, d, e, f, g, h for the account number.
+	var ds = digits(sc + num)
+	for _, r := range rows {
+		switch {
+		case r.exception == 3 && (ds[8] == 6 || ds[8] == 9):
+			continue
+		case r.exception == 6 && ds[6] >= 4 && ds[6] <= 8 && ds[12] == ds[13]:
+			// Foreign currency accounts, which cannot be checked
+			return nil
+		}
+
+		if !r.check(ds) {
+			return errors.New(ErrInvalidUKModulusCheck, mdArg("sc", sc), mdArg("num", num))
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the rows which apply to the sort code sc.
+func (w *Weights) lookup(sc string) []weightsRow {
+	var (
+		n, _ = strconv.Atoi(sc)
+		rows []weightsRow
+	)
+
+	for _, r := range w.rows {
+		if r.start > n {
+			break
+		}
+
+		if n <= r.end {
+			rows = append(rows, r)
+		}
+	}
+
+	return rows
+}
+
+// check returns true if the digits of the sort code and the account number ds
+// pass the modulus check of r.
+func (r weightsRow) check(ds [14]int) bool {
+	var ws = r.weights
+	switch r.exception {
+	case 7:
+		if ds[12] == 9 {
+			for i := 0; i < 8; i++ {
+				ws[i] = 0
+			}
+		}
+	case 8:
+		var sds = digits("090126")
+		copy(ds[:6], sds[:6])
+	}
+
+	var sum int
+	for i, d := range ds {
+		var p = d * ws[i]
+		if r.method == methodDblAl {
+			p = p/10 + p%10
+		}
+
+		sum += p
+	}
+
+	switch r.method {
+	case methodDblAl:
+		if r.exception == 1 {
+			sum += 27
+		}
+
+		return sum%10 == 0
+	case methodMod10:
+		return sum%10 == 0
+	case methodMod11:
+		if r.exception == 4 {
+			return sum%11 == ds[12]*10+ds[13]
+		}
+
+		return sum%11 == 0
+	}
+
+	return false
+}
+
+// digits returns the digits of s, which must only contain digits, into an array
+// of 14 elements.
+func digits(s string) [14]int {
+	var ds [14]int
+	for i := 0; i < len(s) && i < len(ds); i++ {
+		ds[i] = int(s[i] - '0')
+	}
+
+	return ds
+}
@@ -0,0 +1,83 @@
+package accountid_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment/accountid"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestValidateUKAccount(t *testing.T) {
+	var tcases = []struct {
+		desc string
+		sc   string
+		num  string
+		code errors.Code
+	}{
+		{desc: "valid: MOD10", sc: "089999", num: "66374958"},
+		{desc: "valid: MOD11", sc: "107999", num: "88837491"},
+		{desc: "valid: DBLAL", sc: "202959", num: "63748472"},
+		{desc: "valid: not in the table", sc: "403000", num: "31926819"},
+		{desc: "invalid: MOD10", sc: "089999", num: "66374959", code: accountid.ErrInvalidUKModulusCheck},
+		{desc: "invalid: MOD11", sc: "107999", num: "88837492", code: accountid.ErrInvalidUKModulusCheck},
+		{desc: "invalid: DBLAL", sc: "202959", num: "63748473", code: accountid.ErrInvalidUKModulusCheck},
+		{desc: "invalid: sort code", sc: "08-99-99", num: "66374958", code: accountid.ErrInvalidSortCode},
+		{desc: "invalid: account number", sc: "089999", num: "6637495", code: accountid.ErrInvalidUKAccountNumber},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var err = accountid.ValidateUKAccount(tc.sc, tc.num)
+			if tc.code == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			testutil.AssertError(t, err, tc.code)
+		})
+	}
+}
+
+func TestWeights_ValidateUKAccount(t *testing.T) {
+	var w, err = accountid.LoadWeights(strings.NewReader(`
+100000 109999 MOD11 0 0 0 0 0 0 8 7 6 5 4 3 2 1
+100000 109999 DBLAL 2 1 2 1 2 1 2 1 2 1 2 1 2 1 3
+200000 209999 DBLAL 2 1 2 1 2 1 2 1 2 1 2 1 2 1 1
+300000 309999 MOD11 0 0 0 0 0 0 8 7 6 5 4 3 2 1 5
+`))
+	require.NoError(t, err)
+
+	t.Run("both rows must pass", func(t *testing.T) {
+		// 107999-88837491 passes the MOD11 but not the DBLAL
+		var err = w.ValidateUKAccount("107999", "88837491")
+		testutil.AssertError(t, err, accountid.ErrInvalidUKModulusCheck,
+			errors.MD{K: "arg:sc", V: "107999"}, errors.MD{K: "arg:num", V: "88837491"},
+		)
+	})
+
+	t.Run("exception 3 skips the DBLAL when c is 6 or 9", func(t *testing.T) {
+		// 100000-00600008 passes the MOD11 but not the DBLAL
+		assert.NoError(t, w.ValidateUKAccount("100000", "00600008"))
+	})
+
+	t.Run("exception 1 adds 27 to the DBLAL", func(t *testing.T) {
+		assert.NoError(t, w.ValidateUKAccount("200000", "00000009"))
+		testutil.AssertError(t, w.ValidateUKAccount("202959", "63748472"), accountid.ErrInvalidUKModulusCheck)
+	})
+
+	t.Run("not supported exceptions aren't checked", func(t *testing.T) {
+		assert.NoError(t, w.ValidateUKAccount("300000", "00000001"))
+	})
+
+	t.Run("invalid table", func(t *testing.T) {
+		var _, err = accountid.LoadWeights(strings.NewReader("100000 109999 MOD12 0 0 0 0 0 0 8 7 6 5 4 3 2 1\n"))
+		testutil.AssertError(t, err, accountid.ErrInvalidWeightsTable, errors.MD{K: "arg:line", V: 1})
+	})
+}
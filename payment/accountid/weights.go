@@ -0,0 +1,10 @@
+package accountid
+
+// bundledWeights is the weights table used by default. It only contains the
+// rows of the examples of the Vocalink's modulus checking specification, see
+// the package documentation for setting the complete table.
+const bundledWeights = `
+089999 089999 MOD10 0 0 0 0 0 0 7 1 3 7 1 3 7 1
+107999 107999 MOD11 0 0 0 0 0 0 8 7 6 5 4 3 2 1
+202959 202959 DBLAL 2 1 2 1 2 1 2 1 2 1 2 1 2 1
+`
@@ -0,0 +1,175 @@
+package payment
+
+import "go.fraixed.es/errors"
+
+// AggregateKey is the type which represents the fields of the payments which
+// are allowed for grouping them when aggregating their amounts.
+type AggregateKey uint8
+
+// The list of valid AggregateKey values.
+const (
+	aggregateKeyNone AggregateKey = iota
+	AggregateKeyCurrency
+	AggregateKeyOrgID
+	AggregateKeyPaymentScheme
+	AggregateKeyProcessingDate
+	aggregateKeyEnd
+)
+
+// String returns the path of the field of the JSON representation of a payment.
+func (k AggregateKey) String() string {
+	switch k {
+	case AggregateKeyCurrency:
+		return "attributes.currency"
+	case AggregateKeyOrgID:
+		return "organisation_id"
+	case AggregateKeyPaymentScheme:
+		return "attributes.payment_scheme"
+	case AggregateKeyProcessingDate:
+		return "attributes.processing_date"
+	}
+
+	return ""
+}
+
+// Valid returns true if k is a valid AggregateKey value, otherwise false.
+func (k AggregateKey) Valid() bool {
+	return k > aggregateKeyNone && k < aggregateKeyEnd
+}
+
+// ParseAggregateKey returns the AggregateKey whose path is p (see
+// AggregateKey.String) and true, otherwise false.
+func ParseAggregateKey(p string) (AggregateKey, bool) {
+	for k := aggregateKeyNone + 1; k < aggregateKeyEnd; k++ {
+		if k.String() == p {
+			return k, true
+		}
+	}
+
+	return aggregateKeyNone, false
+}
+
+// AggregateFn is the type which represents the functions which aggregate the
+// amounts of the payments.
+type AggregateFn uint8
+
+// The list of valid AggregateFn values.
+const (
+	aggregateFnNone AggregateFn = iota
+	AggregateFnCount
+	AggregateFnSum
+	AggregateFnMin
+	AggregateFnMax
+	AggregateFnAvg
+	aggregateFnEnd
+)
+
+// String returns the name of the function.
+func (f AggregateFn) String() string {
+	switch f {
+	case AggregateFnCount:
+		return "count"
+	case AggregateFnSum:
+		return "sum"
+	case AggregateFnMin:
+		return "min"
+	case AggregateFnMax:
+		return "max"
+	case AggregateFnAvg:
+		return "avg"
+	}
+
+	return ""
+}
+
+// Valid returns true if f is a valid AggregateFn value, otherwise false.
+func (f AggregateFn) Valid() bool {
+	return f > aggregateFnNone && f < aggregateFnEnd
+}
+
+// ParseAggregateFn returns the AggregateFn whose name is n (see
+// AggregateFn.String) and true, otherwise false.
+func ParseAggregateFn(n string) (AggregateFn, bool) {
+	for f := aggregateFnNone + 1; f < aggregateFnEnd; f++ {
+		if f.String() == n {
+			return f, true
+		}
+	}
+
+	return aggregateFnNone, false
+}
+
+// Aggregation specifies how to aggregate the amounts of a list of payments.
+//
+// The payments are grouped by the values of the fields of GroupBy, or all of
+// them are in the same group when it's empty, and the functions of Fns are
+// applied to the amounts of each group.
+type Aggregation struct {
+	GroupBy []AggregateKey
+	Fns     []AggregateFn
+}
+
+// Validate validates that a has at least one function and that all its keys
+// and functions are valid and only present once.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidArgAggregation
+func (a Aggregation) Validate() error {
+	if len(a.Fns) == 0 {
+		return errors.New(ErrInvalidArgAggregation, ErrMDField("Fns", a.Fns))
+	}
+
+	var keys = map[AggregateKey]bool{}
+	for i, k := range a.GroupBy {
+		if !k.Valid() || keys[k] {
+			return errors.New(ErrInvalidArgAggregation, ErrMDField("GroupBy", k), ErrMDVar("index", i))
+		}
+
+		keys[k] = true
+	}
+
+	var fns = map[AggregateFn]bool{}
+	for i, f := range a.Fns {
+		if !f.Valid() || fns[f] {
+			return errors.New(ErrInvalidArgAggregation, ErrMDField("Fns", f), ErrMDVar("index", i))
+		}
+
+		fns[f] = true
+	}
+
+	return nil
+}
+
+// Has returns true if a contains the function f, otherwise false.
+func (a Aggregation) Has(f AggregateFn) bool {
+	for _, af := range a.Fns {
+		if af == f {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Aggregate is the result of aggregating the amounts of a group of payments.
+//
+// The amounts are decimal numbers, without trailing zeros in their decimal
+// places, which are exact except Avg, which is rounded to 3 decimal places,
+// the maximum minor units of the currencies. They are empty when their
+// function isn't in the Aggregation.
+//
+// The amounts are aggregated regardless of their currency, so the Aggregation
+// should group by currency when the payments can have different ones.
+type Aggregate struct {
+	// Group contains the values of the fields of the group, in the same order
+	// than Aggregation.GroupBy; a value is empty when the payments don't have
+	// such field.
+	Group []string
+	// Count is the number of payments of the group, which is always set.
+	Count uint64
+	Sum   string
+	Min   string
+	Max   string
+	Avg   string
+}
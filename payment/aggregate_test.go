@@ -0,0 +1,66 @@
+package payment_test
+
+import (
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAggregateKey(t *testing.T) {
+	for _, k := range []payment.AggregateKey{
+		payment.AggregateKeyCurrency, payment.AggregateKeyOrgID,
+		payment.AggregateKeyPaymentScheme, payment.AggregateKeyProcessingDate,
+	} {
+		var pk, ok = payment.ParseAggregateKey(k.String())
+		assert.True(t, ok)
+		assert.Equal(t, k, pk)
+		assert.True(t, pk.Valid())
+	}
+
+	var k, ok = payment.ParseAggregateKey("currency")
+	assert.False(t, ok)
+	assert.False(t, k.Valid())
+}
+
+func TestParseAggregateFn(t *testing.T) {
+	for _, f := range []payment.AggregateFn{
+		payment.AggregateFnCount, payment.AggregateFnSum, payment.AggregateFnMin,
+		payment.AggregateFnMax, payment.AggregateFnAvg,
+	} {
+		var pf, ok = payment.ParseAggregateFn(f.String())
+		assert.True(t, ok)
+		assert.Equal(t, f, pf)
+		assert.True(t, pf.Valid())
+	}
+
+	var f, ok = payment.ParseAggregateFn("median")
+	assert.False(t, ok)
+	assert.False(t, f.Valid())
+}
+
+func TestAggregation_Validate(t *testing.T) {
+	var (
+		currency = payment.AggregateKeyCurrency
+		sum      = payment.AggregateFnSum
+	)
+
+	require.NoError(t, payment.Aggregation{Fns: []payment.AggregateFn{sum}}.Validate())
+	require.NoError(t, payment.Aggregation{
+		GroupBy: []payment.AggregateKey{currency, payment.AggregateKeyOrgID},
+		Fns:     []payment.AggregateFn{payment.AggregateFnCount, sum},
+	}.Validate())
+
+	for _, a := range []payment.Aggregation{
+		{},
+		{GroupBy: []payment.AggregateKey{currency}},
+		{GroupBy: []payment.AggregateKey{0}, Fns: []payment.AggregateFn{sum}},
+		{GroupBy: []payment.AggregateKey{currency, currency}, Fns: []payment.AggregateFn{sum}},
+		{Fns: []payment.AggregateFn{sum, 0}},
+		{Fns: []payment.AggregateFn{sum, payment.AggregateFnAvg, sum}},
+	} {
+		testutil.AssertError(t, a.Validate(), payment.ErrInvalidArgAggregation)
+	}
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// APIKeyPrefix is the prefix of all the API keys, which allows to distinguish
+// them from the bearer tokens.
+const APIKeyPrefix = "pk_"
+
+// apiKeySecretLen is the number of random bytes of the secret of the API keys.
+const apiKeySecretLen = 32
+
+// APIKey contains the information of an issued API key. The key itself isn't
+// part of it because it's only known when it's issued.
+type APIKey struct {
+	ID     uuid.UUID
+	OrgID  uuid.UUID
+	Scopes []Scope
+	// CreatedAt is the time when the key was issued.
+	CreatedAt time.Time
+	// RevokedAt is the time when the key was revoked; it's zero if it hasn't
+	// been revoked.
+	RevokedAt time.Time
+}
+
+// Revoked returns true if the key has been revoked.
+func (k APIKey) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// Principal returns the principal authenticated by the key.
+func (k APIKey) Principal() Principal {
+	return Principal{OrgID: k.OrgID, Scopes: k.Scopes, KeyID: k.ID}
+}
+
+// KeyStore is the interface which any specific implementation for persisting
+// the API keys must satisfy. The implementations only persist the hash of the
+// keys' secrets (see GenerateKey).
+//
+// All the methods can return, a part of their specific ones which are
+// documented on them, the general error codes documented in payment.Service.
+type KeyStore interface {
+	// IssueKey issues a new API key for the principal p returning its
+	// information and the key, which must be given to the client because it
+	// cannot be retrieved anymore.
+	//
+	// This method can return any of the errors returned by p.Validate.
+	IssueKey(ctx context.Context, p Principal) (APIKey, string, error)
+
+	// RevokeKey revokes the API key which has associated the passed ID. Revoking
+	// a revoked key doesn't change the time when it was revoked.
+	//
+	// The following error codes can be returned:
+	//
+	// * payment.ErrNotFound
+	RevokeKey(ctx context.Context, id uuid.UUID) error
+
+	// RotateKey revokes the API key which has associated the passed ID and
+	// issues a new one for the same principal, atomically, returning the same
+	// values than IssueKey.
+	//
+	// The following error codes can be returned:
+	//
+	// * payment.ErrNotFound - When the key doesn't exist or it's revoked.
+	RotateKey(ctx context.Context, id uuid.UUID) (APIKey, string, error)
+
+	// GetKey retrieves the information of the API key which has associated the
+	// passed ID.
+	//
+	// The following error codes can be returned:
+	//
+	// * payment.ErrNotFound
+	GetKey(ctx context.Context, id uuid.UUID) (APIKey, error)
+
+	// FindKeys retrieves the information of all the API keys issued for the
+	// organisation orgID, including the revoked ones.
+	FindKeys(ctx context.Context, orgID uuid.UUID) ([]APIKey, error)
+
+	// VerifyKey returns the principal authenticated by key.
+	//
+	// The following error codes can be returned:
+	//
+	// * ErrInvalidAPIKey
+	VerifyKey(ctx context.Context, key string) (Principal, error)
+}
+
+// GenerateKey generates a new API key whose ID is id and returns it and the
+// hash of its secret, which is what the KeyStore implementations must persist.
+//
+// The keys have the format "<APIKeyPrefix><id hex>_<secret>", being the secret
+// random bytes encoded with base64 for URLs without padding.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedSysError
+func GenerateKey(id uuid.UUID) (string, []byte, error) {
+	var secret = make([]byte, apiKeySecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	var es = base64.RawURLEncoding.EncodeToString(secret)
+	return APIKeyPrefix + hex.EncodeToString(id.Bytes()) + "_" + es, hashSecret(es), nil
+}
+
+// ParseKey returns the ID of the API key key and the hash of its secret, for
+// comparing it with the persisted one with a constant time comparison (e.g.
+// hmac.Equal).
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidAPIKey - When key doesn't have the format of the keys.
+func ParseKey(key string) (uuid.UUID, []byte, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return uuid.Nil, nil, errors.New(ErrInvalidAPIKey)
+	}
+
+	var parts = strings.SplitN(strings.TrimPrefix(key, APIKeyPrefix), "_", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return uuid.Nil, nil, errors.New(ErrInvalidAPIKey)
+	}
+
+	var idb, err = hex.DecodeString(parts[0])
+	if err != nil {
+		return uuid.Nil, nil, errors.Wrap(err, ErrInvalidAPIKey)
+	}
+
+	id, err := uuid.FromBytes(idb)
+	if err != nil {
+		return uuid.Nil, nil, errors.Wrap(err, ErrInvalidAPIKey)
+	}
+
+	return id, hashSecret(parts[1]), nil
+}
+
+func hashSecret(secret string) []byte {
+	var h = sha256.Sum256([]byte(secret))
+	return h[:]
+}
@@ -0,0 +1,56 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKey(t *testing.T) {
+	var (
+		id             = testutil.NewUUID(t)
+		key, hash, err = auth.GenerateKey(id)
+	)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, auth.APIKeyPrefix))
+
+	pid, phash, err := auth.ParseKey(key)
+	require.NoError(t, err)
+	assert.Equal(t, id, pid)
+	assert.Equal(t, hash, phash)
+
+	okey, ohash, err := auth.GenerateKey(id)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, okey)
+	assert.NotEqual(t, hash, ohash)
+}
+
+func TestParseKey(t *testing.T) {
+	var id = testutil.NewUUID(t)
+	var tcases = []struct {
+		desc string
+		key  string
+	}{
+		{desc: "without prefix", key: strings.Replace(id.String(), "-", "", -1) + "_secret"},
+		{desc: "without secret", key: auth.APIKeyPrefix + strings.Replace(id.String(), "-", "", -1)},
+		{desc: "empty secret", key: auth.APIKeyPrefix + strings.Replace(id.String(), "-", "", -1) + "_"},
+		{desc: "invalid ID", key: auth.APIKeyPrefix + "zz_secret"},
+		{desc: "short ID", key: auth.APIKeyPrefix + "abcd_secret"},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var pid, _, err = auth.ParseKey(tc.key)
+			testutil.AssertError(t, err, auth.ErrInvalidAPIKey)
+			assert.Equal(t, uuid.Nil, pid)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package auth authenticates and authorizes the clients of the API.
+//
+// The clients are authenticated by API keys, which are stored hashed (see
+// KeyStore), or by bearer tokens signed with HMAC-SHA256, which are verified
+// locally (see TokenSigner); both identify an organisation and the scopes
+// granted to it.
+package auth
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// Scope is a permission granted to the clients.
+type Scope string
+
+// The list of valid Scope values.
+const (
+	// ScopeAdmin allows to manage the API keys of any organisation.
+	ScopeAdmin Scope = "admin"
+	// ScopePaymentsRead allows to retrieve the payments of the organisation.
+	ScopePaymentsRead Scope = "payments:read"
+	// ScopePaymentsWrite allows to create, update and delete the payments of
+	// the organisation.
+	ScopePaymentsWrite Scope = "payments:write"
+)
+
+// Valid returns true if s is a valid Scope value, otherwise false.
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeAdmin, ScopePaymentsRead, ScopePaymentsWrite:
+		return true
+	}
+
+	return false
+}
+
+// Principal is the authenticated client.
+type Principal struct {
+	OrgID  uuid.UUID
+	Scopes []Scope
+	// KeyID is the ID of the API key which authenticated the client or uuid.Nil
+	// if it was authenticated by a bearer token.
+	KeyID uuid.UUID
+}
+
+// Validate validates that the principal has an organisation and its scopes are
+// valid.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidOrgID
+//
+// * ErrInvalidScope
+func (p Principal) Validate() error {
+	if p.OrgID == uuid.Nil {
+		return errors.New(ErrInvalidOrgID, payment.ErrMDField("OrgID", p.OrgID))
+	}
+
+	for _, s := range p.Scopes {
+		if !s.Valid() {
+			return errors.New(ErrInvalidScope, payment.ErrMDField("Scopes", p.Scopes))
+		}
+	}
+
+	return nil
+}
+
+// HasScope returns true if s has been granted to the principal.
+func (p Principal) HasScope(s Scope) bool {
+	for _, ps := range p.Scopes {
+		if ps == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+type principalCtxKey struct{}
+
+// WithPrincipal returns a copy of ctx which carries the principal p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, p)
+}
+
+// PrincipalFrom returns the principal carried by ctx. It returns false if ctx
+// doesn't carry any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	var p, ok = ctx.Value(principalCtxKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,56 @@
+package auth
+
+type code uint8
+
+// The list of specific error codes that the auth package can return.
+const (
+	ErrExpiredToken code = iota + 1
+	ErrInvalidAPIKey
+	ErrInvalidOrgID
+	ErrInvalidScope
+	ErrInvalidSigningSecret
+	ErrInvalidToken
+	ErrMissingCredentials
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrExpiredToken:
+		return "ExpiredToken"
+	case ErrInvalidAPIKey:
+		return "InvalidAPIKey"
+	case ErrInvalidOrgID:
+		return "InvalidOrgID"
+	case ErrInvalidScope:
+		return "InvalidScope"
+	case ErrInvalidSigningSecret:
+		return "InvalidSigningSecret"
+	case ErrInvalidToken:
+		return "InvalidToken"
+	case ErrMissingCredentials:
+		return "MissingCredentials"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrExpiredToken:
+		return "The bearer token has expired"
+	case ErrInvalidAPIKey:
+		return "The API key is malformed, it doesn't exist or it has been revoked"
+	case ErrInvalidOrgID:
+		return "The organisation ID is empty"
+	case ErrInvalidScope:
+		return "The scope isn't one of the known scopes"
+	case ErrInvalidSigningSecret:
+		return "The secret for signing the bearer tokens is too short"
+	case ErrInvalidToken:
+		return "The bearer token is malformed or its signature isn't valid"
+	case ErrMissingCredentials:
+		return "The request doesn't have credentials"
+	}
+
+	return ""
+}
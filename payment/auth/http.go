@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.fraixed.es/errors"
+)
+
+// Authenticator authenticates the HTTP requests by the credentials of their
+// Authorization header, which must use the Bearer scheme with an API key or a
+// signed bearer token.
+type Authenticator struct {
+	keys   KeyStore
+	tokens TokenSigner
+	now    func() time.Time
+}
+
+// NewAuthenticator creates an Authenticator which verifies the API keys with
+// ks and the bearer tokens with ts.
+func NewAuthenticator(ks KeyStore, ts TokenSigner) Authenticator {
+	return Authenticator{keys: ks, tokens: ts, now: time.Now}
+}
+
+// Authenticate returns the principal authenticated by the credentials of r.
+// The credentials which start with APIKeyPrefix are API keys, the rest are
+// bearer tokens.
+//
+// The following error codes can be returned:
+//
+// * ErrMissingCredentials - When r doesn't have the Authorization header or
+// it doesn't use the Bearer scheme.
+//
+// * Any of the errors returned by KeyStore.VerifyKey and TokenSigner.Verify.
+func (a Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	var authz = r.Header.Get("Authorization")
+	var parts = strings.SplitN(authz, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return Principal{}, errors.New(ErrMissingCredentials)
+	}
+
+	var cred = strings.TrimSpace(parts[1])
+	if strings.HasPrefix(cred, APIKeyPrefix) {
+		return a.keys.VerifyKey(r.Context(), cred)
+	}
+
+	return a.tokens.Verify(cred, a.now())
+}
@@ -0,0 +1,86 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	var ts, err = auth.NewTokenSigner([]byte(strings.Repeat("s", auth.MinSigningSecretLen)))
+	require.NoError(t, err)
+
+	var (
+		kp = auth.Principal{OrgID: testutil.NewUUID(t), Scopes: []auth.Scope{auth.ScopePaymentsWrite}}
+		tp = auth.Principal{OrgID: testutil.NewUUID(t), Scopes: []auth.Scope{auth.ScopePaymentsRead}}
+		ks = keyStoreStub{
+			verifyKey: func(_ context.Context, key string) (auth.Principal, error) {
+				if key != auth.APIKeyPrefix+"the-key" {
+					return auth.Principal{}, errors.New(auth.ErrInvalidAPIKey)
+				}
+
+				return kp, nil
+			},
+		}
+		authn = auth.NewAuthenticator(ks, ts)
+	)
+
+	token, err := ts.Sign(tp, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	var tcases = []struct {
+		desc      string
+		authz     string
+		principal auth.Principal
+		err       errors.Code
+	}{
+		{desc: "API key", authz: "Bearer " + auth.APIKeyPrefix + "the-key", principal: kp},
+		{desc: "bearer token", authz: "Bearer " + token, principal: tp},
+		{desc: "case insensitive scheme", authz: "bearer " + token, principal: tp},
+		{desc: "error: invalid API key", authz: "Bearer " + auth.APIKeyPrefix + "other", err: auth.ErrInvalidAPIKey},
+		{desc: "error: invalid token", authz: "Bearer " + token + "x", err: auth.ErrInvalidToken},
+		{desc: "error: no header", err: auth.ErrMissingCredentials},
+		{desc: "error: basic scheme", authz: "Basic dXNlcjpwYXNz", err: auth.ErrMissingCredentials},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var r = httptest.NewRequest(http.MethodGet, "/payments", nil)
+			if tc.authz != "" {
+				r.Header.Set("Authorization", tc.authz)
+			}
+
+			var p, err = authn.Authenticate(r)
+			if tc.err != nil {
+				testutil.AssertError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.principal, p)
+		})
+	}
+}
+
+// keyStoreStub is an auth.KeyStore whose methods call the function of the
+// field with the same name. They panic if the field isn't set.
+type keyStoreStub struct {
+	auth.KeyStore
+	verifyKey func(context.Context, string) (auth.Principal, error)
+}
+
+func (s keyStoreStub) VerifyKey(ctx context.Context, key string) (auth.Principal, error) {
+	return s.verifyKey(ctx, key)
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// MinSigningSecretLen is the minimum length of the secret used for signing the
+// bearer tokens.
+const MinSigningSecretLen = 32
+
+// tokenVersion is the prefix of the tokens which identifies their format.
+const tokenVersion = "v1"
+
+// tokenClaims is the payload of the bearer tokens.
+type tokenClaims struct {
+	OrgID     uuid.UUID `json:"org"`
+	Scopes    []Scope   `json:"scopes"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+// TokenSigner signs and verifies bearer tokens with an HMAC-SHA256 secret.
+//
+// The tokens have the format "v1.<claims>.<signature>", being the claims the
+// JSON of the principal's organisation and scopes and the expiration Unix
+// time, and the signature the HMAC-SHA256 of "v1.<claims>", both encoded with
+// base64 for URLs without padding.
+type TokenSigner struct {
+	secret []byte
+}
+
+// NewTokenSigner creates a TokenSigner which uses secret for signing the
+// tokens.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidSigningSecret - When secret is shorter than MinSigningSecretLen.
+func NewTokenSigner(secret []byte) (TokenSigner, error) {
+	var s = make([]byte, len(secret))
+	copy(s, secret)
+
+	var ts = TokenSigner{secret: s}
+	if err := ts.checkSecret(); err != nil {
+		return TokenSigner{}, err
+	}
+
+	return ts, nil
+}
+
+// Sign returns a token for the principal p which expires at exp.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidSigningSecret - When ts isn't created by NewTokenSigner, hence its
+// secret is shorter than MinSigningSecretLen.
+//
+// * payment.ErrUnexpectedSysError
+//
+// * Any of the errors returned by p.Validate
+func (ts TokenSigner) Sign(p Principal, exp time.Time) (string, error) {
+	if err := ts.checkSecret(); err != nil {
+		return "", err
+	}
+
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	var b, err = json.Marshal(tokenClaims{OrgID: p.OrgID, Scopes: p.Scopes, ExpiresAt: exp.Unix()})
+	if err != nil {
+		return "", errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	var signed = tokenVersion + "." + base64.RawURLEncoding.EncodeToString(b)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(ts.mac(signed)), nil
+}
+
+// Verify returns the principal of token if its signature is valid and it
+// hasn't expired at now.
+//
+// The following error codes can be returned:
+//
+// * ErrExpiredToken
+//
+// * ErrInvalidSigningSecret - When ts isn't created by NewTokenSigner, hence its
+// secret is shorter than MinSigningSecretLen.
+//
+// * ErrInvalidToken
+func (ts TokenSigner) Verify(token string, now time.Time) (Principal, error) {
+	if err := ts.checkSecret(); err != nil {
+		return Principal{}, err
+	}
+
+	var i = strings.LastIndex(token, ".")
+	if i < 0 || !strings.HasPrefix(token, tokenVersion+".") {
+		return Principal{}, errors.New(ErrInvalidToken)
+	}
+
+	var sig, err = base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil {
+		return Principal{}, errors.Wrap(err, ErrInvalidToken)
+	}
+
+	if !hmac.Equal(sig, ts.mac(token[:i])) {
+		return Principal{}, errors.New(ErrInvalidToken)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token[len(tokenVersion)+1 : i])
+	if err != nil {
+		return Principal{}, errors.Wrap(err, ErrInvalidToken)
+	}
+
+	var c tokenClaims
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Principal{}, errors.Wrap(err, ErrInvalidToken)
+	}
+
+	if !now.Before(time.Unix(c.ExpiresAt, 0)) {
+		return Principal{}, errors.New(ErrExpiredToken, payment.ErrMDVar("exp", time.Unix(c.ExpiresAt, 0)))
+	}
+
+	var p = Principal{OrgID: c.OrgID, Scopes: c.Scopes}
+	if err := p.Validate(); err != nil {
+		var ec, _ = errors.GetCode(err)
+		return Principal{}, errors.Wrap(err, ErrInvalidToken, payment.ErrMDVar("claims_error", ec.String()))
+	}
+
+	return p, nil
+}
+
+// checkSecret returns an ErrInvalidSigningSecret error if the secret of ts is
+// shorter than MinSigningSecretLen, which is the case of its zero value.
+func (ts TokenSigner) checkSecret() error {
+	if len(ts.secret) < MinSigningSecretLen {
+		return errors.New(ErrInvalidSigningSecret, payment.ErrMDFact("min_length", MinSigningSecretLen))
+	}
+
+	return nil
+}
+
+func (ts TokenSigner) mac(signed string) []byte {
+	var h = hmac.New(sha256.New, ts.secret)
+	_, _ = h.Write([]byte(signed))
+	return h.Sum(nil)
+}
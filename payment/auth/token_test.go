@@ -0,0 +1,122 @@
+package auth_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTokenSigner(t *testing.T) {
+	var _, err = auth.NewTokenSigner([]byte("short"))
+	testutil.AssertError(t, err, auth.ErrInvalidSigningSecret, payment.ErrMDFact("min_length", auth.MinSigningSecretLen))
+
+	_, err = auth.NewTokenSigner([]byte(strings.Repeat("s", auth.MinSigningSecretLen)))
+	assert.NoError(t, err)
+}
+
+func TestTokenSigner(t *testing.T) {
+	var ts, err = auth.NewTokenSigner([]byte(strings.Repeat("s", auth.MinSigningSecretLen)))
+	require.NoError(t, err)
+
+	var (
+		now = time.Now()
+		p   = auth.Principal{OrgID: testutil.NewUUID(t), Scopes: []auth.Scope{auth.ScopePaymentsRead}}
+	)
+
+	token, err := ts.Sign(p, now.Add(time.Hour))
+	require.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		var vp, err = ts.Verify(token, now)
+		require.NoError(t, err)
+		assert.Equal(t, p, vp)
+	})
+
+	t.Run("error: expired", func(t *testing.T) {
+		t.Parallel()
+
+		var _, err = ts.Verify(token, now.Add(2*time.Hour))
+		testutil.AssertError(t, err, auth.ErrExpiredToken, payment.ErrMDVar("exp", time.Unix(now.Add(time.Hour).Unix(), 0)))
+	})
+
+	t.Run("error: signed with another secret", func(t *testing.T) {
+		t.Parallel()
+
+		var ots, err = auth.NewTokenSigner([]byte(strings.Repeat("o", auth.MinSigningSecretLen)))
+		require.NoError(t, err)
+
+		_, err = ots.Verify(token, now)
+		testutil.AssertError(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("error: tampered claims", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			parts = strings.Split(token, ".")
+			op    = auth.Principal{OrgID: testutil.NewUUID(t), Scopes: p.Scopes}
+		)
+
+		var ot, err = ts.Sign(op, now.Add(time.Hour))
+		require.NoError(t, err)
+
+		_, err = ts.Verify(parts[0]+"."+strings.Split(ot, ".")[1]+"."+parts[2], now)
+		testutil.AssertError(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("error: malformed", func(t *testing.T) {
+		t.Parallel()
+
+		var _, err = ts.Verify("not-a-token", now)
+		testutil.AssertError(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("error: sign invalid principal", func(t *testing.T) {
+		t.Parallel()
+
+		var _, err = ts.Sign(auth.Principal{}, now)
+		testutil.AssertError(t, err, auth.ErrInvalidOrgID, payment.ErrMDField("OrgID", auth.Principal{}.OrgID))
+	})
+}
+
+func TestTokenSigner_zeroValue(t *testing.T) {
+	var (
+		ts  auth.TokenSigner
+		now = time.Now()
+		p   = auth.Principal{OrgID: testutil.NewUUID(t), Scopes: []auth.Scope{auth.ScopePaymentsRead}}
+	)
+
+	t.Run("error: sign", func(t *testing.T) {
+		t.Parallel()
+
+		var _, err = ts.Sign(p, now.Add(time.Hour))
+		testutil.AssertError(t, err, auth.ErrInvalidSigningSecret, payment.ErrMDFact("min_length", auth.MinSigningSecretLen))
+	})
+
+	t.Run("error: verify token signed with an empty secret", func(t *testing.T) {
+		t.Parallel()
+
+		var claims = base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(
+			`{"org":%q,"scopes":[%q],"exp":%d}`, p.OrgID, auth.ScopePaymentsRead, now.Add(time.Hour).Unix(),
+		)))
+
+		var h = hmac.New(sha256.New, nil)
+		_, _ = h.Write([]byte("v1." + claims))
+		var token = "v1." + claims + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+
+		var _, err = ts.Verify(token, now)
+		testutil.AssertError(t, err, auth.ErrInvalidSigningSecret, payment.ErrMDFact("min_length", auth.MinSigningSecretLen))
+	})
+}
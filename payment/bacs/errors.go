@@ -0,0 +1,51 @@
+package bacs
+
+type code uint8
+
+// The list of specific error codes that the bacs package can return.
+const (
+	ErrInvalidAmount code = iota + 1
+	ErrInvalidCurrency
+	ErrInvalidHeader
+	ErrInvalidParty
+	ErrInvalidPaymentScheme
+	ErrInvalidPaymentType
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrInvalidAmount:
+		return "InvalidAmount"
+	case ErrInvalidCurrency:
+		return "InvalidCurrency"
+	case ErrInvalidHeader:
+		return "InvalidHeader"
+	case ErrInvalidParty:
+		return "InvalidParty"
+	case ErrInvalidPaymentScheme:
+		return "InvalidPaymentScheme"
+	case ErrInvalidPaymentType:
+		return "InvalidPaymentType"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrInvalidAmount:
+		return "The amount of the payment doesn't fit in the amount field of a Standard 18 record"
+	case ErrInvalidCurrency:
+		return "The currency of the payment isn't GBP"
+	case ErrInvalidHeader:
+		return "The header values of the Standard 18 file are invalid"
+	case ErrInvalidParty:
+		return "The party of the payment doesn't have a UK sort code and an 8 digits account number"
+	case ErrInvalidPaymentScheme:
+		return "The payment scheme of the payment isn't BACS"
+	case ErrInvalidPaymentType:
+		return "The payment type cannot be represented in a Standard 18 file"
+	}
+
+	return ""
+}
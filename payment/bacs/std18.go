@@ -0,0 +1,380 @@
+// Package bacs generates the Standard 18 files used for submitting the UK Bacs
+// payments.
+package bacs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/accountid"
+	"go.fraixed.es/errors"
+)
+
+// The list of the transaction codes of the detail records. The contra record
+// of the credits has the debit code and the one of the debits the credit code.
+const (
+	txCodeCredit = "99"
+	txCodeDebit  = "17"
+)
+
+// maxPence is the maximum amount, in pence, of the 11 digits amount field.
+const maxPence = 99999999999
+
+//nolint:gochecknoglobals
+var (
+	sunRegexp           = regexp.MustCompile(`^[0-9]{6}$`)
+	serialNumberRegexp  = regexp.MustCompile(`^[A-Z0-9]{1,6}$`)
+	accountNumberRegexp = regexp.MustCompile(`^[0-9]{8}$`)
+	// invalidCharsRegexp matches the characters which aren't in the Bacs
+	// character set.
+	invalidCharsRegexp = regexp.MustCompile(`[^A-Z0-9.&/\- ]`)
+)
+
+// Header contains the values of the labels of a Standard 18 file.
+type Header struct {
+	// ServiceUserNumber is the 6 digits number of the Bacs service user which
+	// submits the file.
+	ServiceUserNumber string
+	// SerialNumber is the volume serial number of the file, up to 6 uppercase
+	// alphanumeric characters.
+	SerialNumber string
+	// CreatedAt is the creation date of the file.
+	CreatedAt time.Time
+	// ProcessingDate is the date when the payments of the file are processed.
+	ProcessingDate time.Time
+}
+
+// validate validates the header values.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidHeader
+func (h Header) validate() error {
+	if !sunRegexp.MatchString(h.ServiceUserNumber) {
+		return errors.New(ErrInvalidHeader, payment.ErrMDField("ServiceUserNumber", h.ServiceUserNumber))
+	}
+
+	if !serialNumberRegexp.MatchString(h.SerialNumber) {
+		return errors.New(ErrInvalidHeader, payment.ErrMDField("SerialNumber", h.SerialNumber))
+	}
+
+	if h.CreatedAt.IsZero() {
+		return errors.New(ErrInvalidHeader, payment.ErrMDField("CreatedAt", h.CreatedAt))
+	}
+
+	if h.ProcessingDate.IsZero() {
+		return errors.New(ErrInvalidHeader, payment.ErrMDField("ProcessingDate", h.ProcessingDate))
+	}
+
+	return nil
+}
+
+// Generate writes to w the Standard 18 file with the header h of the payments
+// which fulfill f, retrieved from svc and sorted by ID.
+//
+// This function can return the errors returned by svc.Find and EncodeStd18.
+func Generate(ctx context.Context, svc payment.Service, w io.Writer, h Header, f payment.Filter) error {
+	var (
+		st       = payment.Sort{{Field: payment.SortFieldID, Dir: payment.SortAscending}}
+		pms, err = svc.Find(ctx, f, payment.SelectAll(), st, payment.Chunk{})
+	)
+	if err != nil {
+		return err
+	}
+
+	return EncodeStd18(w, h, pms...)
+}
+
+// EncodeStd18 writes to w the Standard 18 file with the header h of the Bacs
+// payments pms.
+//
+// The file has the VOL1, HDR1, HDR2 and UHL1 header labels, the detail records
+// grouped by originating account, each group followed by the contra records of
+// its credits and debits, and the EOF1, EOF2 and UTL1 trailer labels, being
+// UTL1 the totals of the detail and contra records.
+//
+// The originating account of the credit payments is the debtor party and the
+// destination account the beneficiary party, and the other way around for the
+// debit payments (direct debits). The groups are in the order of the first
+// payment of their originating account in pms.
+//
+// The detail records have the amount in pence, the payment reference as
+// service user's reference and the account name, or the name when it's empty,
+// of the originating and destination parties as service user's name and
+// destination account name; the free text fields are converted to uppercase,
+// with the characters outside of the Bacs character set replaced by spaces and
+// truncated to 18 characters.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidAmount
+//
+// * ErrInvalidCurrency
+//
+// * ErrInvalidHeader
+//
+// * ErrInvalidParty
+//
+// * ErrInvalidPaymentScheme
+//
+// * ErrInvalidPaymentType
+//
+// * payment.ErrUnexpectedOSError
+func EncodeStd18(w io.Writer, h Header, pms ...payment.Pymt) error {
+	if err := h.validate(); err != nil {
+		return err
+	}
+
+	var (
+		groups []*group
+		gidx   = map[string]*group{}
+	)
+
+	for _, p := range pms {
+		var r, err = newDetailRecord(p)
+		if err != nil {
+			return err
+		}
+
+		var key = r.origSortCode + r.origAccount
+		var g, ok = gidx[key]
+		if !ok {
+			g = &group{origSortCode: r.origSortCode, origAccount: r.origAccount, origName: r.userName}
+			gidx[key] = g
+			groups = append(groups, g)
+		}
+
+		g.details = append(g.details, r)
+	}
+
+	var (
+		b   strings.Builder
+		tot totals
+	)
+
+	writeHeaderLabels(&b, h)
+	for _, g := range groups {
+		for _, r := range g.details {
+			b.WriteString(r.String() + "\n")
+			tot.add(r)
+		}
+
+		for _, r := range g.contras() {
+			b.WriteString(r.String() + "\n")
+			tot.add(r)
+		}
+	}
+	writeTrailerLabels(&b, h, tot)
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return errors.Wrap(err, payment.ErrUnexpectedOSError)
+	}
+
+	return nil
+}
+
+// record is a detail or contra record of a Standard 18 file.
+type record struct {
+	destSortCode string
+	destAccount  string
+	txCode       string
+	origSortCode string
+	origAccount  string
+	// amount is in pence.
+	amount   int64
+	userName string
+	userRef  string
+	destName string
+}
+
+// newDetailRecord returns the detail record of the payment p.
+//
+// See EncodeStd18 for the list of error codes which can be returned, except
+// ErrInvalidHeader and payment.ErrUnexpectedOSError.
+func newDetailRecord(p payment.Pymt) (record, error) {
+	var a = p.Attributes
+	if a.PaymentScheme != "BACS" {
+		return record{}, errors.New(
+			ErrInvalidPaymentScheme, payment.ErrMDVar("id", p.ID), payment.ErrMDField("PaymentScheme", a.PaymentScheme),
+		)
+	}
+
+	if a.Currency != "GBP" {
+		return record{}, errors.New(
+			ErrInvalidCurrency, payment.ErrMDVar("id", p.ID), payment.ErrMDField("Currency", a.Currency),
+		)
+	}
+
+	var pence = int64(math.Round(a.Amount * 100))
+	if pence <= 0 || pence > maxPence {
+		return record{}, errors.New(ErrInvalidAmount, payment.ErrMDVar("id", p.ID), payment.ErrMDField("Amount", a.Amount))
+	}
+
+	var (
+		r          = record{amount: pence, userRef: field(a.Reference, 18)}
+		orig, dest payment.Party
+	)
+
+	switch a.PaymentType {
+	case "Credit":
+		r.txCode = txCodeCredit
+		orig, dest = a.DebtorParty, a.BeneficiaryParty
+	case "Debit":
+		r.txCode = txCodeDebit
+		orig, dest = a.BeneficiaryParty, a.DebtorParty
+	default:
+		return record{}, errors.New(
+			ErrInvalidPaymentType, payment.ErrMDVar("id", p.ID), payment.ErrMDField("PaymentType", a.PaymentType),
+		)
+	}
+
+	for _, pt := range []struct {
+		field string
+		party payment.Party
+	}{{"DebtorParty", a.DebtorParty}, {"BeneficiaryParty", a.BeneficiaryParty}} {
+		if pt.party.BankIDCode != "GBDSC" || accountid.ValidateSortCode(pt.party.BankID) != nil ||
+			!accountNumberRegexp.MatchString(pt.party.AccountNumber) {
+			return record{}, errors.New(ErrInvalidParty, payment.ErrMDVar("id", p.ID), payment.ErrMDField(pt.field, pt.party))
+		}
+	}
+
+	r.origSortCode, r.origAccount, r.userName = orig.BankID, orig.AccountNumber, field(partyName(orig), 18)
+	r.destSortCode, r.destAccount, r.destName = dest.BankID, dest.AccountNumber, field(partyName(dest), 18)
+	return r, nil
+}
+
+// String returns the 100 characters of the record.
+func (r record) String() string {
+	return fmt.Sprintf("%s%s0%s%s%s    %011d%s%s%s",
+		r.destSortCode, r.destAccount, r.txCode, r.origSortCode, r.origAccount, r.amount,
+		r.userName, r.userRef, r.destName,
+	)
+}
+
+// group is the group of detail records of an originating account.
+type group struct {
+	origSortCode string
+	origAccount  string
+	origName     string
+	details      []record
+}
+
+// contras returns the contra records of the credits and the debits of the
+// group, only of the ones which have detail records.
+func (g group) contras() []record {
+	var credits, debits int64
+	var ncredits, ndebits int
+	for _, r := range g.details {
+		if r.txCode == txCodeCredit {
+			credits += r.amount
+			ncredits++
+		} else {
+			debits += r.amount
+			ndebits++
+		}
+	}
+
+	var contra = record{
+		destSortCode: g.origSortCode,
+		destAccount:  g.origAccount,
+		origSortCode: g.origSortCode,
+		origAccount:  g.origAccount,
+		userName:     g.origName,
+		userRef:      field("CONTRA", 18),
+		destName:     g.origName,
+	}
+
+	var rs []record
+	if ncredits > 0 {
+		contra.txCode, contra.amount = txCodeDebit, credits
+		rs = append(rs, contra)
+	}
+
+	if ndebits > 0 {
+		contra.txCode, contra.amount = txCodeCredit, debits
+		rs = append(rs, contra)
+	}
+
+	return rs
+}
+
+// totals are the totals of the UTL1 label.
+type totals struct {
+	debitValue  int64
+	creditValue int64
+	debitCount  int
+	creditCount int
+}
+
+func (t *totals) add(r record) {
+	if r.txCode == txCodeCredit {
+		t.creditValue += r.amount
+		t.creditCount++
+		return
+	}
+
+	t.debitValue += r.amount
+	t.debitCount++
+}
+
+func writeHeaderLabels(b *strings.Builder, h Header) {
+	fmt.Fprintf(b, "VOL1%s0%s    %s    %s1\n", field(h.SerialNumber, 6), spaces(26), h.ServiceUserNumber, spaces(28))
+	fmt.Fprintf(b, "HDR1%s%s\n", fileLabel(h), "000000"+spaces(20))
+	b.WriteString(recordFormatLabel("HDR2") + "\n")
+	fmt.Fprintf(b, "UHL1%s999999    00000000%s001%s\n", julianDate(h.ProcessingDate), "1 DAILY  ", spaces(40))
+}
+
+func writeTrailerLabels(b *strings.Builder, h Header, t totals) {
+	fmt.Fprintf(b, "EOF1%s%s\n", fileLabel(h), "000000"+spaces(20))
+	b.WriteString(recordFormatLabel("EOF2") + "\n")
+	fmt.Fprintf(b, "UTL1%013d%013d%07d%07d%s\n", t.debitValue, t.creditValue, t.debitCount, t.creditCount, spaces(36))
+}
+
+// fileLabel returns the common part of the HDR1 and EOF1 labels from the file
+// identifier to the accessibility indicator.
+func fileLabel(h Header) string {
+	return fmt.Sprintf("A%sS  1%s%s000100010001  %s%s ",
+		h.ServiceUserNumber, h.ServiceUserNumber, field(h.SerialNumber, 6),
+		julianDate(h.CreatedAt), julianDate(h.ProcessingDate),
+	)
+}
+
+// recordFormatLabel returns the HDR2 or EOF2 label, depending of name.
+func recordFormatLabel(name string) string {
+	return name + "F0200000100" + spaces(35) + "00" + spaces(28)
+}
+
+// julianDate returns t with the format " YYDDD", being DDD the day of the year.
+func julianDate(t time.Time) string {
+	return fmt.Sprintf(" %02d%03d", t.Year()%100, t.YearDay())
+}
+
+// field returns s in uppercase, with the characters outside of the Bacs
+// character set replaced by spaces and truncated or padded with spaces to n
+// characters.
+func field(s string, n int) string {
+	s = invalidCharsRegexp.ReplaceAllString(strings.ToUpper(s), " ")
+	if len(s) > n {
+		return s[:n]
+	}
+
+	return s + spaces(n-len(s))
+}
+
+func partyName(p payment.Party) string {
+	if p.AccountName != "" {
+		return p.AccountName
+	}
+
+	return p.Name
+}
+
+func spaces(n int) string {
+	return strings.Repeat(" ", n)
+}
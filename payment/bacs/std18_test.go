@@ -0,0 +1,176 @@
+package bacs_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/bacs"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestGenerate(t *testing.T) {
+	var (
+		h = bacs.Header{
+			ServiceUserNumber: "123456",
+			SerialNumber:      "000001",
+			CreatedAt:         time.Date(2019, 2, 27, 10, 0, 0, 0, time.UTC),
+			ProcessingDate:    time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC),
+		}
+		acme = payment.Party{
+			AccountName: "Acme Ltd", AccountNumber: "66374958", BankID: "089999", BankIDCode: "GBDSC", Name: "Acme",
+		}
+		jane = payment.Party{AccountNumber: "88837491", BankID: "107999", BankIDCode: "GBDSC", Name: "Jane Doe"}
+		john = payment.Party{AccountNumber: "63748472", BankID: "202959", BankIDCode: "GBDSC", Name: "John Smith"}
+		pms  = []payment.Pymt{
+			newPymt(t, "Credit", 10.5, "Invoice 1", acme, jane),
+			newPymt(t, "Credit", 20, "Invoice 2 with a long reference €", acme, john),
+			newPymt(t, "Debit", 5, "Subscription", acme, jane),
+		}
+		ff  = payment.Filter{}
+		svc = &testutil.SvcStub{
+			FindFn: func(
+				_ context.Context, f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk,
+			) ([]payment.Pymt, error) {
+				assert.Equal(t, ff, f)
+				assert.Equal(t, payment.SelectAll(), sl)
+				assert.Equal(t, payment.Sort{{Field: payment.SortFieldID, Dir: payment.SortAscending}}, st)
+				assert.Equal(t, payment.Chunk{}, c)
+				return pms, nil
+			},
+		}
+	)
+
+	var buf bytes.Buffer
+	var err = bacs.Generate(context.Background(), svc, &buf, h, ff)
+	require.NoError(t, err)
+
+	var lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 12)
+
+	for i, l := range lines {
+		if i >= 4 && i < 9 {
+			assert.Len(t, l, 100, "line %d", i)
+		} else {
+			assert.Len(t, l, 80, "line %d", i)
+		}
+	}
+
+	assert.Equal(t, "VOL1000001"+"0", lines[0][:11])
+	assert.Equal(t, "    123456    ", lines[0][37:51])
+	assert.Equal(t, "HDR1A123456S  1123456000001000100010001   19058 19060 000000", lines[1][:60])
+	assert.Equal(t, "HDR2F0200000100", lines[2][:15])
+	assert.Equal(t, "UHL1 19060999999    000000001 DAILY  001", lines[3][:40])
+
+	var rec = func(dest string, code string, orig string, pence int, name, ref, destName string) string {
+		return fmt.Sprintf("%s0%s%s    %011d%-18s%-18s%-18s", dest, code, orig, pence, name, ref, destName)
+	}
+
+	assert.Equal(t, rec("10799988837491", "99", "08999966374958", 1050, "ACME LTD", "INVOICE 1", "JANE DOE"), lines[4])
+	assert.Equal(t,
+		rec("20295963748472", "99", "08999966374958", 2000, "ACME LTD", "INVOICE 2 WITH A L", "JOHN SMITH"), lines[5],
+	)
+	assert.Equal(t, rec("08999966374958", "17", "08999966374958", 3050, "ACME LTD", "CONTRA", "ACME LTD"), lines[6])
+	assert.Equal(t, rec("08999966374958", "17", "10799988837491", 500, "JANE DOE", "SUBSCRIPTION", "ACME LTD"), lines[7])
+	assert.Equal(t, rec("10799988837491", "99", "10799988837491", 500, "JANE DOE", "CONTRA", "JANE DOE"), lines[8])
+
+	assert.Equal(t, "EOF1"+lines[1][4:], lines[9])
+	assert.Equal(t, "EOF2"+lines[2][4:], lines[10])
+	assert.Equal(t, "UTL1"+"0000000003550"+"0000000003550"+"0000002"+"0000003", lines[11][:44])
+}
+
+func TestEncodeStd18_error(t *testing.T) {
+	var (
+		h = bacs.Header{
+			ServiceUserNumber: "123456",
+			SerialNumber:      "1",
+			CreatedAt:         time.Now(),
+			ProcessingDate:    time.Now(),
+		}
+		debtor      = payment.Party{AccountNumber: "66374958", BankID: "089999", BankIDCode: "GBDSC", Name: "Acme"}
+		beneficiary = payment.Party{AccountNumber: "88837491", BankID: "107999", BankIDCode: "GBDSC", Name: "Jane"}
+	)
+
+	var tcases = []struct {
+		desc   string
+		modify func(*bacs.Header, *payment.Pymt)
+		code   errors.Code
+		mds    []errors.MD
+	}{
+		{
+			desc:   "service user number",
+			modify: func(h *bacs.Header, _ *payment.Pymt) { h.ServiceUserNumber = "12345" },
+			code:   bacs.ErrInvalidHeader,
+			mds:    []errors.MD{payment.ErrMDField("ServiceUserNumber", "12345")},
+		},
+		{
+			desc:   "payment scheme",
+			modify: func(_ *bacs.Header, p *payment.Pymt) { p.Attributes.PaymentScheme = "FPS" },
+			code:   bacs.ErrInvalidPaymentScheme,
+			mds:    []errors.MD{payment.ErrMDField("PaymentScheme", "FPS")},
+		},
+		{
+			desc:   "currency",
+			modify: func(_ *bacs.Header, p *payment.Pymt) { p.Attributes.Currency = "EUR" },
+			code:   bacs.ErrInvalidCurrency,
+			mds:    []errors.MD{payment.ErrMDField("Currency", "EUR")},
+		},
+		{
+			desc:   "amount",
+			modify: func(_ *bacs.Header, p *payment.Pymt) { p.Attributes.Amount = 1000000000 },
+			code:   bacs.ErrInvalidAmount,
+			mds:    []errors.MD{payment.ErrMDField("Amount", float64(1000000000))},
+		},
+		{
+			desc: "party",
+			modify: func(_ *bacs.Header, p *payment.Pymt) {
+				p.Attributes.BeneficiaryParty.AccountNumber = "GB29NWBK60161331926819"
+			},
+			code: bacs.ErrInvalidParty,
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				h = h
+				p = newPymt(t, "Credit", 1, "Ref", debtor, beneficiary)
+			)
+
+			tc.modify(&h, &p)
+			var err = bacs.EncodeStd18(&bytes.Buffer{}, h, p)
+			testutil.AssertError(t, err, tc.code, tc.mds...)
+		})
+	}
+}
+
+func newPymt(t *testing.T, typ string, amount float64, ref string, debtor, beneficiary payment.Party) payment.Pymt {
+	var p = payment.Pymt{
+		ID: testutil.NewUUID(t),
+		PymtUpsert: payment.PymtUpsert{
+			Type:  "Payment",
+			OrgID: testutil.NewUUID(t),
+			Attributes: payment.Attrs{
+				Amount:           amount,
+				Currency:         "GBP",
+				PaymentScheme:    "BACS",
+				PaymentType:      typ,
+				Reference:        ref,
+				BeneficiaryParty: beneficiary,
+				DebtorParty:      debtor,
+			},
+		},
+	}
+
+	return p
+}
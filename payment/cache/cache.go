@@ -0,0 +1,438 @@
+// Package cache provides a payment service decorator which caches the payments
+// retrieved from the decorated service, so the hot payments aren't retrieved
+// from the store on each call.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/filter"
+)
+
+// Config contains the parameters of the service returned by New.
+type Config struct {
+	// MaxEntries is the maximum number of cached results, when it's reached the
+	// least recently used one is evicted. Default 1000.
+	MaxEntries int
+	// TTL is the time during which the payments retrieved by Get are cached. The
+	// zero value means that they don't expire and they are only invalidated by
+	// the version comparison and the calls to the service.
+	TTL time.Duration
+	// FindTTL is the time during which the results of Find are cached. It should
+	// be short because the results aren't invalidated by the payments created or
+	// modified without using the service. The zero value means that they aren't
+	// cached.
+	FindTTL time.Duration
+	// Now returns the current time. Default time.Now.
+	Now func() time.Time
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = 1000
+	}
+
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	return c
+}
+
+// Stats are the statistics of the cache since the service was created.
+type Stats struct {
+	GetHits    uint64
+	GetMisses  uint64
+	FindHits   uint64
+	FindMisses uint64
+	// Evictions is the number of entries evicted for not exceeding MaxEntries.
+	Evictions uint64
+	// Invalidations is the number of entries removed because they were stale or
+	// expired.
+	Invalidations uint64
+	// Entries is the current number of entries.
+	Entries int
+}
+
+// Service is a payment.Service which caches the results of Get and, optionally,
+// Find of the decorated service. The results of Aggregate aren't cached.
+//
+// Service also satisfies the payment.BatchCreator and payment.ChangeFeed
+// interfaces, delegating them to the decorated service (see
+// payment.CreateBatch and payment.Changes).
+//
+// The results are cached by the organisation scope carried by the context (see
+// payment.WithOrgScope and tenancy.WithOrgID), hence the service can be
+// decorated by the one returned by tenancy.New or decorate it.
+//
+// The cached payments of a payment ID are invalidated when:
+//
+// * Delete, Patch, Transition or Update are called with such ID.
+//
+// * Get or Find retrieve such payment with a greater version.
+//
+// The cached results of Find are invalidated when any of the methods which
+// modify payments is called.
+//
+// The payments modified without using the service are retrieved from the cache
+// until they are invalidated or expire (see Config), so the service should
+// only be used when it's the only one which modifies the payments or such
+// staleness is acceptable.
+type Service struct {
+	svc payment.Service
+	cfg Config
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[key]*list.Element
+	// ids indexes the entries by the IDs of the payments that they contain.
+	ids map[uuid.UUID]map[*list.Element]struct{}
+	// finds indexes the entries of Find results.
+	finds map[*list.Element]struct{}
+	// gen is incremented on each invalidation caused by a call, so the results
+	// retrieved before it aren't cached.
+	gen   uint64
+	stats Stats
+}
+
+type key struct {
+	orgID uuid.UUID
+	id    uuid.UUID
+	sl    payment.Selection
+	find  string
+}
+
+type entry struct {
+	key     key
+	pymts   []payment.Pymt
+	expires time.Time
+}
+
+// New returns a Service which delegates the operations to svc and caches their
+// results according to cfg.
+//
+// The methods return the same values than svc, except that the payments are
+// always retrieved from svc with their version, for comparing them with the
+// cached ones.
+func New(svc payment.Service, cfg Config) *Service {
+	return &Service{
+		svc:     svc,
+		cfg:     cfg.withDefaults(),
+		lru:     list.New(),
+		entries: map[key]*list.Element{},
+		ids:     map[uuid.UUID]map[*list.Element]struct{}{},
+		finds:   map[*list.Element]struct{}{},
+	}
+}
+
+// Stats returns the current statistics of the cache.
+func (s *Service) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var st = s.stats
+	st.Entries = s.lru.Len()
+	return st
+}
+
+// Aggregate satisfies the payment.Service interface. Its results aren't
+// cached.
+func (s *Service) Aggregate(
+	ctx context.Context, f payment.Filter, a payment.Aggregation,
+) ([]payment.Aggregate, error) {
+	return s.svc.Aggregate(ctx, f, a)
+}
+
+// Changes satisfies the payment.ChangeFeed interface. The events aren't cached.
+func (s *Service) Changes(ctx context.Context, fromSeq uint64) (<-chan payment.Event, <-chan error) {
+	return payment.Changes(ctx, s.svc, fromSeq)
+}
+
+// Create satisfies the payment.Service interface.
+func (s *Service) Create(ctx context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
+	var id, err = s.svc.Create(ctx, p)
+	s.invalidate(uuid.Nil)
+	return id, err
+}
+
+// CreateBatch satisfies the payment.BatchCreator interface.
+func (s *Service) CreateBatch(ctx context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+	var ids, err = payment.CreateBatch(ctx, s.svc, ps)
+	s.invalidate(uuid.Nil)
+	return ids, err
+}
+
+// Delete satisfies the payment.Service interface.
+func (s *Service) Delete(ctx context.Context, id uuid.UUID, version uint32) error {
+	var err = s.svc.Delete(ctx, id, version)
+	s.invalidate(id)
+	return err
+}
+
+// Find satisfies the payment.Service interface.
+func (s *Service) Find(
+	ctx context.Context, f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk,
+) ([]payment.Pymt, error) {
+	if s.cfg.FindTTL <= 0 {
+		return s.svc.Find(ctx, f, sl, st, c)
+	}
+
+	var (
+		vsl = withVersion(sl)
+		k   = key{orgID: orgID(ctx), find: findKey(f, vsl, st, c)}
+	)
+
+	var pymts, gen, ok = s.lookup(k)
+	if ok {
+		return clone(pymts, sl), nil
+	}
+
+	pymts, err := s.svc.Find(ctx, f, vsl, st, c)
+	if err != nil {
+		return pymts, err
+	}
+
+	s.store(k, gen, pymts, s.cfg.FindTTL)
+	return clone(pymts, sl), nil
+}
+
+// Get satisfies the payment.Service interface.
+func (s *Service) Get(ctx context.Context, id uuid.UUID, sl payment.Selection) (payment.Pymt, error) {
+	var (
+		vsl = withVersion(sl)
+		k   = key{orgID: orgID(ctx), id: id, sl: vsl}
+	)
+
+	var pymts, gen, ok = s.lookup(k)
+	if ok {
+		return clone(pymts, sl)[0], nil
+	}
+
+	var p, err = s.svc.Get(ctx, id, vsl)
+	if err != nil {
+		return p, err
+	}
+
+	pymts = []payment.Pymt{p}
+	s.store(k, gen, pymts, s.cfg.TTL)
+	return clone(pymts, sl)[0], nil
+}
+
+// History satisfies the payment.Service interface. The status changes aren't
+// cached.
+func (s *Service) History(ctx context.Context, id uuid.UUID) ([]payment.StatusChange, error) {
+	return s.svc.History(ctx, id)
+}
+
+// Patch satisfies the payment.Service interface.
+func (s *Service) Patch(ctx context.Context, id uuid.UUID, version uint32, p payment.Patch) error {
+	var err = s.svc.Patch(ctx, id, version, p)
+	s.invalidate(id)
+	return err
+}
+
+// Transition satisfies the payment.Service interface.
+func (s *Service) Transition(
+	ctx context.Context, id uuid.UUID, version uint32, to payment.Status, reason string,
+) error {
+	var err = s.svc.Transition(ctx, id, version, to, reason)
+	s.invalidate(id)
+	return err
+}
+
+// Update satisfies the payment.Service interface.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, version uint32, p payment.PymtUpsert) error {
+	var err = s.svc.Update(ctx, id, version, p)
+	s.invalidate(id)
+	return err
+}
+
+// lookup returns the payments of the entry k and true if it's cached and it
+// hasn't expired, otherwise false and the current generation, which must be
+// passed to store.
+func (s *Service) lookup(k key) ([]payment.Pymt, uint64, bool) {
+	var (
+		now  = s.cfg.Now()
+		hits = &s.stats.GetHits
+		miss = &s.stats.GetMisses
+	)
+
+	if k.find != "" {
+		hits, miss = &s.stats.FindHits, &s.stats.FindMisses
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var el, ok = s.entries[k]
+	if ok {
+		var e = el.Value.(*entry)
+		if e.expires.IsZero() || now.Before(e.expires) {
+			*hits++
+			s.lru.MoveToFront(el)
+			return e.pymts, 0, true
+		}
+
+		s.remove(el)
+		s.stats.Invalidations++
+	}
+
+	*miss++
+	return nil, s.gen, false
+}
+
+// store caches pymts in the entry k, which expires after ttl if it isn't 0,
+// unless that any invalidation has happened since the generation gen. The
+// cached entries which contain any of the payments with a lower version are
+// invalidated.
+func (s *Service) store(k key, gen uint64, pymts []payment.Pymt, ttl time.Duration) {
+	var e = &entry{key: k, pymts: pymts}
+	if ttl > 0 {
+		e.expires = s.cfg.Now().Add(ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if gen != s.gen {
+		return
+	}
+
+	for _, p := range pymts {
+		for el := range s.ids[p.ID] {
+			if v, ok := el.Value.(*entry).version(p.ID); ok && v < p.Version {
+				s.remove(el)
+				s.stats.Invalidations++
+			}
+		}
+	}
+
+	if el, ok := s.entries[k]; ok {
+		s.remove(el)
+	}
+
+	var el = s.lru.PushFront(e)
+	s.entries[k] = el
+	if k.find != "" {
+		s.finds[el] = struct{}{}
+	}
+
+	for _, p := range pymts {
+		if s.ids[p.ID] == nil {
+			s.ids[p.ID] = map[*list.Element]struct{}{}
+		}
+
+		s.ids[p.ID][el] = struct{}{}
+	}
+
+	for s.lru.Len() > s.cfg.MaxEntries {
+		s.remove(s.lru.Back())
+		s.stats.Evictions++
+	}
+}
+
+// invalidate removes the cached entries which contain the payment id, if it
+// isn't uuid.Nil, and the results of Find.
+func (s *Service) invalidate(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gen++
+	for el := range s.ids[id] {
+		s.remove(el)
+		s.stats.Invalidations++
+	}
+
+	for el := range s.finds {
+		s.remove(el)
+		s.stats.Invalidations++
+	}
+}
+
+// remove removes el from the cache and its indexes. s.mu must be locked.
+func (s *Service) remove(el *list.Element) {
+	var e = el.Value.(*entry)
+	s.lru.Remove(el)
+	delete(s.entries, e.key)
+	delete(s.finds, el)
+	for _, p := range e.pymts {
+		delete(s.ids[p.ID], el)
+		if len(s.ids[p.ID]) == 0 {
+			delete(s.ids, p.ID)
+		}
+	}
+}
+
+// version returns the version of the payment id contained in e and true or
+// false if e doesn't contain it.
+func (e *entry) version(id uuid.UUID) (uint32, bool) {
+	for _, p := range e.pymts {
+		if p.ID == id {
+			return p.Version, true
+		}
+	}
+
+	return 0, false
+}
+
+// orgID returns the organisation scope carried by ctx or uuid.Nil if it doesn't
+// carry any.
+func orgID(ctx context.Context) uuid.UUID {
+	var id, _ = payment.OrgScope(ctx)
+	return id
+}
+
+func withVersion(sl payment.Selection) payment.Selection {
+	sl.Version = true
+	return sl
+}
+
+// clone returns a copy of pymts, which doesn't share any memory with them, so
+// the callers cannot modify the cached payments. The version of the payments is
+// cleared if sl doesn't select it.
+func clone(pymts []payment.Pymt, sl payment.Selection) []payment.Pymt {
+	if pymts == nil {
+		return nil
+	}
+
+	var cpymts = make([]payment.Pymt, len(pymts))
+	for i, p := range pymts {
+		if !sl.Version {
+			p.Version = 0
+		}
+
+		var ci = &p.Attributes.ChargesInformation
+		if ci.SenderCharges != nil {
+			ci.SenderCharges = append([]payment.Charge(nil), ci.SenderCharges...)
+		}
+
+		cpymts[i] = p
+	}
+
+	return cpymts
+}
+
+// findKey returns the canonical encoding of the parameters of Find.
+func findKey(f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk) string {
+	return fmt.Sprintf("%s|%+v|%+v|%+v", filter.String(f, leafField, cmpString, logicalString), sl, st, c)
+}
+
+func leafField(fl payment.FilterLeaf) string {
+	return fmt.Sprintf("%T", fl)
+}
+
+// cmpString returns the number of c followed by the type and the quoted string
+// representation of v, so values of different types are never equal.
+func cmpString(c payment.FilterCmp, v interface{}) (string, bool) {
+	return fmt.Sprintf("%d %T %q", c, v, fmt.Sprint(v)), true
+}
+
+func logicalString(l payment.FilterLogical) string {
+	return fmt.Sprintf("%d", l)
+}
@@ -0,0 +1,288 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/cache"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/tenancy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestService_Get(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		pid   = testutil.NewUUID(t)
+		store = newStoreStub(t, pid)
+		svc   = cache.New(store.svc(), cache.Config{})
+	)
+
+	var sl = payment.Selection{Type: true, Attributes: true}
+	var p, err = svc.Get(ctx, pid, sl)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), p.Version, "version isn't selected")
+	assert.Equal(t, store.pymts[pid].Attributes, p.Attributes)
+	assert.Equal(t,
+		[]payment.Selection{{Version: true, Type: true, Attributes: true}}, store.gets, "version is always retrieved",
+	)
+
+	// Mutating the returned payment doesn't modify the cached one
+	p.Attributes.ChargesInformation.SenderCharges[0].Amount = 1000
+
+	sl.Version = true
+	p, err = svc.Get(ctx, pid, sl)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), p.Version)
+	assert.Equal(t, store.pymts[pid].Attributes, p.Attributes)
+	assert.Len(t, store.gets, 1, "selections which only differ on the version share the entry")
+
+	_, err = svc.Get(ctx, pid, payment.Selection{Status: true})
+	require.NoError(t, err)
+	assert.Len(t, store.gets, 2)
+
+	var orgID = testutil.NewUUID(t)
+	_, err = svc.Get(payment.WithOrgScope(ctx, orgID), pid, payment.SelectAll())
+	require.NoError(t, err)
+	assert.Len(t, store.gets, 3, "the entries are scoped to the organisation of the context")
+
+	_, err = svc.Get(tenancy.WithOrgID(ctx, orgID), pid, payment.SelectAll())
+	require.NoError(t, err)
+	assert.Len(t, store.gets, 3, "tenancy.WithOrgID sets the organisation scope")
+
+	_, err = svc.Get(tenancy.WithOrgID(ctx, testutil.NewUUID(t)), pid, payment.SelectAll())
+	require.NoError(t, err)
+	assert.Len(t, store.gets, 4, "the entries of other organisations aren't shared")
+
+	_, err = svc.Get(ctx, testutil.NewUUID(t), payment.SelectAll())
+	testutil.AssertError(t, err, payment.ErrNotFound)
+	assert.Equal(t, cache.Stats{GetHits: 2, GetMisses: 5, Entries: 4}, svc.Stats())
+
+	t.Run("invalidation", func(t *testing.T) {
+		for _, inv := range []func() error{
+			func() error { return svc.Update(ctx, pid, 1, payment.PymtUpsert{}) },
+			func() error { return svc.Patch(ctx, pid, 2, payment.Patch{}) },
+			func() error { return svc.Transition(ctx, pid, 3, payment.StatusSubmitted, "") },
+			func() error { return svc.Delete(ctx, pid, 4) },
+		} {
+			var _, err = svc.Get(ctx, pid, payment.SelectAll())
+			require.NoError(t, err)
+			require.NotZero(t, svc.Stats().Entries)
+
+			require.NoError(t, inv())
+			assert.Zero(t, svc.Stats().Entries)
+		}
+	})
+}
+
+func TestService_Get_versionComparison(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		pid   = testutil.NewUUID(t)
+		store = newStoreStub(t, pid)
+		svc   = cache.New(store.svc(), cache.Config{})
+	)
+
+	var _, err = svc.Get(ctx, pid, payment.Selection{Status: true})
+	require.NoError(t, err)
+
+	// The payment is modified without using the cache service
+	var p = store.pymts[pid]
+	p.Version++
+	p.Status = payment.StatusSubmitted
+	store.pymts[pid] = p
+
+	p, err = svc.Get(ctx, pid, payment.Selection{Status: true})
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPending, p.Status)
+
+	_, err = svc.Get(ctx, pid, payment.SelectAll())
+	require.NoError(t, err)
+	p, err = svc.Get(ctx, pid, payment.Selection{Status: true})
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusSubmitted, p.Status, "stale entry is invalidated by the newer version")
+	assert.Equal(t, uint64(1), svc.Stats().Invalidations)
+}
+
+func TestService_Get_maxEntries(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		pids  = []uuid.UUID{testutil.NewUUID(t), testutil.NewUUID(t), testutil.NewUUID(t)}
+		store = newStoreStub(t, pids...)
+		svc   = cache.New(store.svc(), cache.Config{MaxEntries: 2})
+	)
+
+	for _, id := range []uuid.UUID{pids[0], pids[1], pids[0], pids[2], pids[0], pids[1]} {
+		var _, err = svc.Get(ctx, id, payment.SelectAll())
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, cache.Stats{GetHits: 2, GetMisses: 4, Evictions: 2, Entries: 2}, svc.Stats())
+}
+
+func TestService_Find(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		now   = time.Date(2026, 10, 19, 14, 0, 0, 0, time.UTC)
+		pid   = testutil.NewUUID(t)
+		store = newStoreStub(t, pid)
+		sl    = payment.Selection{Status: true}
+		st    = payment.Sort{{Field: payment.SortFieldID, Dir: payment.SortAscending}}
+		c     = payment.Chunk{Limit: 10}
+	)
+
+	var fst, err = payment.NewFilterByStatus(payment.FilterCmpEqual, payment.StatusPending)
+	require.NoError(t, err)
+	famt, err := payment.NewFilterByAmount(payment.FilterCmpGreaterThan, 10)
+	require.NoError(t, err)
+
+	t.Run("disabled", func(t *testing.T) {
+		var svc = cache.New(store.svc(), cache.Config{})
+		for i := 0; i < 2; i++ {
+			var _, err = svc.Find(ctx, fst, sl, st, c)
+			require.NoError(t, err)
+		}
+
+		assert.Len(t, store.finds, 2)
+		assert.Equal(t, cache.Stats{}, svc.Stats())
+	})
+
+	store.finds = nil
+	var svc = cache.New(store.svc(), cache.Config{
+		FindTTL: time.Second,
+		Now:     func() time.Time { return now },
+	})
+
+	pymts, err := svc.Find(ctx, fst, sl, st, c)
+	require.NoError(t, err)
+	require.Len(t, pymts, 1)
+	assert.Equal(t, uint32(0), pymts[0].Version)
+	assert.True(t, store.finds[0].Version, "version is always retrieved")
+
+	for _, args := range []struct {
+		f  payment.Filter
+		st payment.Sort
+		c  payment.Chunk
+	}{
+		{f: fst, st: st, c: c},
+		{f: famt, st: st, c: c},
+		{f: fst, st: payment.Sort{}, c: c},
+		{f: fst, st: st, c: payment.Chunk{Limit: 10, Offset: 10}},
+	} {
+		var _, err = svc.Find(ctx, args.f, sl, args.st, args.c)
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, store.finds, 4)
+	assert.Equal(t, cache.Stats{FindHits: 1, FindMisses: 4, Entries: 4}, svc.Stats())
+
+	now = now.Add(time.Second)
+	_, err = svc.Find(ctx, fst, sl, st, c)
+	require.NoError(t, err)
+	assert.Len(t, store.finds, 5, "the results expire")
+
+	_, err = svc.Create(ctx, payment.PymtUpsert{})
+	require.NoError(t, err)
+	_, err = svc.Find(ctx, fst, sl, st, c)
+	require.NoError(t, err)
+	assert.Len(t, store.finds, 6, "the results are invalidated by the calls which modify payments")
+
+	_, err = svc.CreateBatch(ctx, []payment.PymtUpsert{{}})
+	require.NoError(t, err)
+	_, err = svc.Find(ctx, fst, sl, st, c)
+	require.NoError(t, err)
+	assert.Len(t, store.finds, 7, "the results are invalidated by the created batches")
+
+	t.Run("the found payments invalidate the older cached ones", func(t *testing.T) {
+		var _, err = svc.Get(ctx, pid, sl)
+		require.NoError(t, err)
+
+		var p = store.pymts[pid]
+		p.Version++
+		store.pymts[pid] = p
+
+		_, err = svc.Find(ctx, famt, payment.SelectAll(), st, c)
+		require.NoError(t, err)
+		_, err = svc.Get(ctx, pid, sl)
+		require.NoError(t, err)
+		assert.Len(t, store.gets, 2)
+	})
+}
+
+// storeStub is a payment store, with the payments of its IDs, which records
+// the selections passed to Get and Find.
+type storeStub struct {
+	pymts map[uuid.UUID]payment.Pymt
+	gets  []payment.Selection
+	finds []payment.Selection
+}
+
+func newStoreStub(t *testing.T, ids ...uuid.UUID) *storeStub {
+	var s = &storeStub{pymts: map[uuid.UUID]payment.Pymt{}}
+	for _, id := range ids {
+		var p = payment.Pymt{ID: id, Version: 1, Status: payment.StatusPending}
+		p.Type = "Payment"
+		p.Attributes = testutil.NewAttrs(t)
+		p.Attributes.ChargesInformation.SenderCharges = []payment.Charge{{Amount: 5, Currency: "GBP"}}
+		s.pymts[id] = p
+	}
+
+	return s
+}
+
+// svc returns a payment.Service which gets the payments from s, returning the
+// selected fields and the ID, and succeeds on the rest of the methods.
+func (s *storeStub) svc() *testutil.SvcStub {
+	var get = func(id uuid.UUID, sl payment.Selection) payment.Pymt {
+		var p = s.pymts[id]
+		if !sl.Version {
+			p.Version = 0
+		}
+
+		if !sl.Status {
+			p.Status = 0
+		}
+
+		p.Attributes.ChargesInformation.SenderCharges = append(
+			[]payment.Charge(nil), p.Attributes.ChargesInformation.SenderCharges...,
+		)
+		return p
+	}
+
+	return &testutil.SvcStub{
+		CreateFn: func(context.Context, payment.PymtUpsert) (uuid.UUID, error) {
+			return uuid.Nil, nil
+		},
+		CreateBatchFn: func(_ context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+			return make([]uuid.UUID, len(ps)), nil
+		},
+		DeleteFn: func(context.Context, uuid.UUID, uint32) error { return nil },
+		FindFn: func(
+			_ context.Context, _ payment.Filter, sl payment.Selection, _ payment.Sort, _ payment.Chunk,
+		) ([]payment.Pymt, error) {
+			s.finds = append(s.finds, sl)
+			var pymts []payment.Pymt
+			for id := range s.pymts {
+				pymts = append(pymts, get(id, sl))
+			}
+
+			return pymts, nil
+		},
+		GetFn: func(_ context.Context, id uuid.UUID, sl payment.Selection) (payment.Pymt, error) {
+			s.gets = append(s.gets, sl)
+			if _, ok := s.pymts[id]; !ok {
+				return payment.Pymt{}, errors.New(payment.ErrNotFound)
+			}
+
+			return get(id, sl), nil
+		},
+		PatchFn:      func(context.Context, uuid.UUID, uint32, payment.Patch) error { return nil },
+		TransitionFn: func(context.Context, uuid.UUID, uint32, payment.Status, string) error { return nil },
+		UpdateFn:     func(context.Context, uuid.UUID, uint32, payment.PymtUpsert) error { return nil },
+	}
+}
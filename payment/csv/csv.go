@@ -0,0 +1,517 @@
+// Package csv exports and imports payments as CSV, flattening their fields in
+// columns so they can be managed with spreadsheets.
+//
+// The CSV has a header whose columns are named after the JSON representation
+// of the payments, being the nested fields joined by dots. The layout of the
+// columns, in order, is:
+//
+// * id, version, status, type, organisation_id
+//
+// * attributes.amount, attributes.currency, attributes.reference,
+// attributes.end_to_end_reference, attributes.numeric_reference,
+// attributes.payment_id, attributes.payment_purpose, attributes.payment_scheme,
+// attributes.payment_type, attributes.processing_date,
+// attributes.scheme_payment_sub_type, attributes.scheme_payment_type
+//
+// * attributes.<party>.account_name, attributes.<party>.account_number,
+// attributes.<party>.account_number_code, attributes.<party>.account_type,
+// attributes.<party>.address, attributes.<party>.bank_id,
+// attributes.<party>.bank_id_code, attributes.<party>.name; being <party>
+// beneficiary_party, debtor_party and sponsor_party
+//
+// * attributes.charges_information.bearer_code,
+// attributes.charges_information.sender_charges,
+// attributes.charges_information.receiver_charges_amount,
+// attributes.charges_information.receiver_charges_currency
+//
+// * attributes.fx.contract_reference, attributes.fx.exchange_rate,
+// attributes.fx.original_amount, attributes.fx.original_currency
+//
+// The sender charges are in a single column, each one is the currency and the
+// amount separated by a space and they are separated by semicolons (e.g.
+// "GBP 5;USD 10.25"). The status is its name (see payment.Status.String).
+//
+// The values which start with "=", "+", "-", "@", a tab or a carriage return,
+// which the spreadsheets interpret as formulas, are prefixed with an apostrophe
+// for not being executed when the CSV is opened (i.e. CSV injection); the
+// values which start with an apostrophe are also prefixed for being able to
+// read them back, because the first apostrophe of each value is removed when
+// reading.
+package csv
+
+import (
+	"context"
+	stdcsv "encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// column is a column of the CSV layout.
+type column struct {
+	name string
+	// selected reports if the column is part of the fields selected by sl.
+	selected func(sl payment.Selection) bool
+	get      func(p *payment.Pymt) string
+	// set sets the value v to p and reports if v has a valid format. It's nil
+	// for the columns which aren't part of payment.PymtUpsert, which are
+	// ignored when importing.
+	set func(p *payment.PymtUpsert, v string) bool
+}
+
+//nolint:gochecknoglobals
+var columns = newColumns()
+
+// escapedChars are the first characters of the values which are escaped in the
+// CSV, see escapeValue.
+const escapedChars = "=+-@\t\r'"
+
+// escapeValue returns v prefixed with an apostrophe if it starts with any of
+// escapedChars, otherwise v.
+func escapeValue(v string) string {
+	if v != "" && strings.IndexByte(escapedChars, v[0]) >= 0 {
+		return "'" + v
+	}
+
+	return v
+}
+
+// unescapeValue returns v without the apostrophe prefix added by escapeValue.
+func unescapeValue(v string) string {
+	return strings.TrimPrefix(v, "'")
+}
+
+func newColumns() []column {
+	var (
+		always  = func(payment.Selection) bool { return true }
+		version = func(sl payment.Selection) bool { return sl.Version }
+		status  = func(sl payment.Selection) bool { return sl.Status }
+		typ     = func(sl payment.Selection) bool { return sl.Type }
+		orgID   = func(sl payment.Selection) bool { return sl.OrgID }
+	)
+
+	var cs = []column{
+		{
+			name:     "id",
+			selected: always,
+			get:      func(p *payment.Pymt) string { return p.ID.String() },
+		},
+		{
+			name:     "version",
+			selected: version,
+			get:      func(p *payment.Pymt) string { return strconv.FormatUint(uint64(p.Version), 10) },
+		},
+		{
+			name:     "status",
+			selected: status,
+			get:      func(p *payment.Pymt) string { return p.Status.String() },
+		},
+		{
+			name:     "type",
+			selected: typ,
+			get:      func(p *payment.Pymt) string { return p.Type },
+			set: func(p *payment.PymtUpsert, v string) bool {
+				p.Type = v
+				return true
+			},
+		},
+		{
+			name:     "organisation_id",
+			selected: orgID,
+			get:      func(p *payment.Pymt) string { return p.OrgID.String() },
+			set: func(p *payment.PymtUpsert, v string) bool {
+				if v == "" {
+					return true
+				}
+
+				var id, err = uuid.FromString(v)
+				p.OrgID = id
+				return err == nil
+			},
+		},
+	}
+
+	var attrs = []column{
+		floatColumn("amount", func(a *payment.Attrs) *float64 { return &a.Amount }),
+		stringColumn("currency", func(a *payment.Attrs) *string { return &a.Currency }),
+		stringColumn("reference", func(a *payment.Attrs) *string { return &a.Reference }),
+		stringColumn("end_to_end_reference", func(a *payment.Attrs) *string { return &a.EndToEndReference }),
+		stringColumn("numeric_reference", func(a *payment.Attrs) *string { return &a.NumericReference }),
+		stringColumn("payment_id", func(a *payment.Attrs) *string { return &a.PaymentID }),
+		stringColumn("payment_purpose", func(a *payment.Attrs) *string { return &a.PaymentPurpose }),
+		stringColumn("payment_scheme", func(a *payment.Attrs) *string { return &a.PaymentScheme }),
+		stringColumn("payment_type", func(a *payment.Attrs) *string { return &a.PaymentType }),
+		stringColumn("processing_date", func(a *payment.Attrs) *string { return &a.ProcessingDate }),
+		stringColumn("scheme_payment_sub_type", func(a *payment.Attrs) *string { return &a.SchemePaymentSubType }),
+		stringColumn("scheme_payment_type", func(a *payment.Attrs) *string { return &a.SchemePaymentType }),
+	}
+
+	attrs = append(attrs, partyColumns("beneficiary_party", func(a *payment.Attrs) *payment.Party {
+		return &a.BeneficiaryParty
+	})...)
+	attrs = append(attrs, partyColumns("debtor_party", func(a *payment.Attrs) *payment.Party {
+		return &a.DebtorParty
+	})...)
+	attrs = append(attrs, partyColumns("sponsor_party", func(a *payment.Attrs) *payment.Party {
+		return &a.SponsorParty
+	})...)
+
+	attrs = append(attrs,
+		stringColumn("charges_information.bearer_code", func(a *payment.Attrs) *string {
+			return &a.ChargesInformation.BearerCode
+		}),
+		column{
+			name: "attributes.charges_information.sender_charges",
+			get: func(p *payment.Pymt) string {
+				return formatCharges(p.Attributes.ChargesInformation.SenderCharges)
+			},
+			set: func(p *payment.PymtUpsert, v string) bool {
+				var cs, ok = parseCharges(v)
+				p.Attributes.ChargesInformation.SenderCharges = cs
+				return ok
+			},
+		},
+		floatColumn("charges_information.receiver_charges_amount", func(a *payment.Attrs) *float64 {
+			return &a.ChargesInformation.ReceiverChargesAmount
+		}),
+		stringColumn("charges_information.receiver_charges_currency", func(a *payment.Attrs) *string {
+			return &a.ChargesInformation.ReceiverChargesCurrency
+		}),
+		stringColumn("fx.contract_reference", func(a *payment.Attrs) *string { return &a.Fx.ContractReference }),
+		stringColumn("fx.exchange_rate", func(a *payment.Attrs) *string { return &a.Fx.ExchangeRate }),
+		stringColumn("fx.original_amount", func(a *payment.Attrs) *string { return &a.Fx.OriginalAmount }),
+		stringColumn("fx.original_currency", func(a *payment.Attrs) *string { return &a.Fx.OriginalCurrency }),
+	)
+
+	for i := range attrs {
+		var path = strings.TrimPrefix(attrs[i].name, "attributes.")
+		attrs[i].selected = func(sl payment.Selection) bool { return sl.Attr(path) }
+	}
+
+	return append(cs, attrs...)
+}
+
+// stringColumn returns the column of the string attribute returned by field,
+// being name the attribute name without the "attributes." prefix.
+func stringColumn(name string, field func(*payment.Attrs) *string) column {
+	return column{
+		name: "attributes." + name,
+		get:  func(p *payment.Pymt) string { return *field(&p.Attributes) },
+		set: func(p *payment.PymtUpsert, v string) bool {
+			*field(&p.Attributes) = v
+			return true
+		},
+	}
+}
+
+// floatColumn returns the column of the float attribute returned by field,
+// being name the attribute name without the "attributes." prefix. An empty
+// value is zero.
+func floatColumn(name string, field func(*payment.Attrs) *float64) column {
+	return column{
+		name: "attributes." + name,
+		get:  func(p *payment.Pymt) string { return strconv.FormatFloat(*field(&p.Attributes), 'f', -1, 64) },
+		set: func(p *payment.PymtUpsert, v string) bool {
+			if v == "" {
+				*field(&p.Attributes) = 0
+				return true
+			}
+
+			var f, err = strconv.ParseFloat(v, 64)
+			*field(&p.Attributes) = f
+			return err == nil
+		},
+	}
+}
+
+// partyColumns returns the columns of the party attribute called name returned
+// by party.
+func partyColumns(name string, party func(*payment.Attrs) *payment.Party) []column {
+	var pstr = func(f func(*payment.Party) *string) func(*payment.Attrs) *string {
+		return func(a *payment.Attrs) *string { return f(party(a)) }
+	}
+
+	return []column{
+		stringColumn(name+".account_name", pstr(func(p *payment.Party) *string { return &p.AccountName })),
+		stringColumn(name+".account_number", pstr(func(p *payment.Party) *string { return &p.AccountNumber })),
+		stringColumn(name+".account_number_code", pstr(func(p *payment.Party) *string {
+			return &p.AccountNumberCode
+		})),
+		{
+			name: "attributes." + name + ".account_type",
+			get:  func(p *payment.Pymt) string { return strconv.Itoa(party(&p.Attributes).AccountType) },
+			set: func(p *payment.PymtUpsert, v string) bool {
+				if v == "" {
+					party(&p.Attributes).AccountType = 0
+					return true
+				}
+
+				var at, err = strconv.Atoi(v)
+				party(&p.Attributes).AccountType = at
+				return err == nil
+			},
+		},
+		stringColumn(name+".address", pstr(func(p *payment.Party) *string { return &p.Address })),
+		stringColumn(name+".bank_id", pstr(func(p *payment.Party) *string { return &p.BankID })),
+		stringColumn(name+".bank_id_code", pstr(func(p *payment.Party) *string { return &p.BankIDCode })),
+		stringColumn(name+".name", pstr(func(p *payment.Party) *string { return &p.Name })),
+	}
+}
+
+func formatCharges(cs []payment.Charge) string {
+	var fcs = make([]string, len(cs))
+	for i, c := range cs {
+		fcs[i] = c.Currency + " " + strconv.FormatFloat(c.Amount, 'f', -1, 64)
+	}
+
+	return strings.Join(fcs, ";")
+}
+
+func parseCharges(v string) ([]payment.Charge, bool) {
+	if v == "" {
+		return nil, true
+	}
+
+	var fcs = strings.Split(v, ";")
+	var cs = make([]payment.Charge, len(fcs))
+	for i, fc := range fcs {
+		var ca = strings.Fields(fc)
+		if len(ca) != 2 {
+			return nil, false
+		}
+
+		var a, err = strconv.ParseFloat(ca[1], 64)
+		if err != nil {
+			return nil, false
+		}
+
+		cs[i] = payment.Charge{Amount: a, Currency: ca[0]}
+	}
+
+	return cs, true
+}
+
+// Columns returns the names of the columns of the payments' fields selected by
+// sl, in the order of the layout. The id column is always selected.
+func Columns(sl payment.Selection) []string {
+	var names []string
+	for _, c := range columns {
+		if c.selected(sl) {
+			names = append(names, c.name)
+		}
+	}
+
+	return names
+}
+
+// Export writes to w a CSV with the header and a record for each payment of
+// pms, with the columns of the fields selected by sl (see Columns).
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedOSError
+func Export(w io.Writer, sl payment.Selection, pms ...payment.Pymt) error {
+	var cs []column
+	for _, c := range columns {
+		if c.selected(sl) {
+			cs = append(cs, c)
+		}
+	}
+
+	var cw = stdcsv.NewWriter(w)
+	if err := cw.Write(Columns(sl)); err != nil {
+		return errors.Wrap(err, payment.ErrUnexpectedOSError)
+	}
+
+	var rec = make([]string, len(cs))
+	for i := range pms {
+		for j, c := range cs {
+			rec[j] = escapeValue(c.get(&pms[i]))
+		}
+
+		if err := cw.Write(rec); err != nil {
+			return errors.Wrap(err, payment.ErrUnexpectedOSError, payment.ErrMDVar("index", i))
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return errors.Wrap(err, payment.ErrUnexpectedOSError)
+	}
+
+	return nil
+}
+
+// RowError is the error of a row of an imported CSV.
+type RowError struct {
+	// Row is the number of the row in the CSV, being 1 the header.
+	Row int
+	// Err is an error with ErrInvalidRecord code or with all the violations of
+	// the row (see payment.Violations.Err). The violations of the values which
+	// cannot be converted to the type of the payment field have the
+	// ErrInvalidValue code and the column name as field, the rest are the
+	// violations of payment.PymtUpsert.
+	Err error
+}
+
+func (re RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", re.Row, re.Err)
+}
+
+// Read reads from r a CSV with the layout of the package and returns the
+// payments of the rows which don't have errors, in the same order, and the
+// errors of the rest of the rows.
+//
+// The header can have any of the columns of the layout, in any order; the
+// fields of the columns which aren't present, have their zero value, except
+// type which is "Payment" and organisation_id which is orgID, the latter is
+// also used for the empty values of the organisation_id column. The rows whose
+// organisation_id isn't empty nor orgID have a violation with the
+// payment.ErrInvalidPaymentOrgID code, so a CSV cannot create payments of other
+// organisations. The id, version and status columns are ignored.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidHeader
+//
+// * ErrInvalidRecord - When the CSV is malformed.
+//
+// * payment.ErrUnexpectedOSError
+func Read(r io.Reader, orgID uuid.UUID) ([]payment.PymtUpsert, []RowError, error) {
+	var cr = stdcsv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	var header, err = cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil, errors.New(ErrInvalidHeader)
+		}
+
+		return nil, nil, wrapReadErr(err)
+	}
+
+	var (
+		hcs   = make([]*column, len(header))
+		found = map[string]bool{}
+	)
+
+	for i, name := range header {
+		if found[name] {
+			return nil, nil, errors.New(ErrInvalidHeader, payment.ErrMDVar("column", name))
+		}
+
+		found[name] = true
+		for j := range columns {
+			if columns[j].name == name {
+				hcs[i] = &columns[j]
+				break
+			}
+		}
+
+		if hcs[i] == nil {
+			return nil, nil, errors.New(ErrInvalidHeader, payment.ErrMDVar("column", name))
+		}
+	}
+
+	var (
+		pms  []payment.PymtUpsert
+		rerr []RowError
+	)
+
+	for row := 2; ; row++ {
+		var rec, err = cr.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, nil, wrapReadErr(err)
+		}
+
+		if len(rec) != len(hcs) {
+			rerr = append(rerr, RowError{
+				Row: row,
+				Err: errors.New(ErrInvalidRecord, payment.ErrMDVar("fields", len(rec))),
+			})
+			continue
+		}
+
+		var (
+			p  = payment.PymtUpsert{Type: "Payment", OrgID: orgID}
+			vs payment.Violations
+		)
+
+		for i, c := range hcs {
+			if c.set == nil {
+				continue
+			}
+
+			var v = unescapeValue(rec[i])
+			if !c.set(&p, v) {
+				vs = append(vs, payment.Violation{Field: c.name, Code: ErrInvalidValue, Value: v})
+			}
+		}
+
+		// The invalid organisation IDs are already reported as invalid values
+		if p.OrgID != orgID && p.OrgID != uuid.Nil {
+			vs = append(vs, payment.Violation{
+				Field: "organisation_id", Code: payment.ErrInvalidPaymentOrgID, Value: p.OrgID.String(),
+			})
+		}
+
+		if len(vs) == 0 {
+			vs = p.Violations()
+		}
+
+		if err := vs.Err(); err != nil {
+			rerr = append(rerr, RowError{Row: row, Err: err})
+			continue
+		}
+
+		pms = append(pms, p)
+	}
+
+	return pms, rerr, nil
+}
+
+// wrapReadErr wraps the error returned by the CSV reader with
+// ErrInvalidRecord code when it's due to a malformed CSV, otherwise with
+// payment.ErrUnexpectedOSError.
+func wrapReadErr(err error) error {
+	if perr, ok := err.(*stdcsv.ParseError); ok {
+		return errors.Wrap(err, ErrInvalidRecord, payment.ErrMDVar("line", perr.Line))
+	}
+
+	return errors.Wrap(err, payment.ErrUnexpectedOSError)
+}
+
+// Import reads the payments from r, as Read does, and creates them with bc in
+// a single transaction when none of the rows has errors, returning their IDs in
+// the same order than the rows. When any of the rows has errors, no payment is
+// created and the errors are returned.
+//
+// This function can return the errors returned by Read and bc.CreateBatch.
+func Import(
+	ctx context.Context, bc payment.BatchCreator, r io.Reader, orgID uuid.UUID,
+) ([]uuid.UUID, []RowError, error) {
+	var pms, rerr, err = Read(r, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(rerr) > 0 {
+		return nil, rerr, nil
+	}
+
+	ids, err := bc.CreateBatch(ctx, pms)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ids, nil, nil
+}
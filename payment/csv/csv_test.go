@@ -0,0 +1,282 @@
+package csv_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/csv"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestColumns(t *testing.T) {
+	assert.Equal(t, []string{"id"}, csv.Columns(payment.Selection{}))
+	assert.Equal(t, []string{"id", "status", "type"}, csv.Columns(payment.Selection{Status: true, Type: true}))
+
+	var cs = csv.Columns(payment.SelectAll())
+	assert.Len(t, cs, 49)
+	assert.Equal(t, []string{"id", "version", "status", "type", "organisation_id", "attributes.amount"}, cs[:6])
+	assert.Contains(t, cs, "attributes.sponsor_party.bank_id_code")
+	assert.Equal(t, "attributes.fx.original_currency", cs[len(cs)-1])
+
+	sl, err := payment.NewSelection("version", "attributes.fx", "attributes.debtor_party.name", "attributes.amount")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"id", "version", "attributes.amount", "attributes.debtor_party.name", "attributes.fx.contract_reference",
+		"attributes.fx.exchange_rate", "attributes.fx.original_amount", "attributes.fx.original_currency",
+	}, csv.Columns(sl))
+}
+
+func TestExport_Read(t *testing.T) {
+	var (
+		orgID = testutil.NewUUID(t)
+		pms   = make([]payment.Pymt, 3)
+	)
+
+	for i := range pms {
+		pms[i] = payment.Pymt{
+			ID:      testutil.NewUUID(t),
+			Version: uint32(i),
+			Status:  payment.StatusSubmitted,
+			PymtUpsert: payment.PymtUpsert{
+				Type:       "Payment",
+				OrgID:      orgID,
+				Attributes: testutil.NewAttrs(t),
+			},
+		}
+	}
+
+	pms[0].Attributes.Reference = "Invoice, \"quoted\"\nand multiline"
+	pms[1].Attributes.Reference = "=HYPERLINK(\"http://example.com\")"
+	pms[1].Attributes.EndToEndReference = "-2+3"
+	pms[1].Attributes.PaymentPurpose = "@SUM(A1)"
+	pms[1].Attributes.BeneficiaryParty.Address = "+44 1 Street"
+	pms[1].Attributes.DebtorParty.Address = "\tcmd"
+	pms[2].Attributes.Reference = "'=quoted"
+	pms[2].Attributes.PaymentPurpose = "\r=1"
+
+	var buf bytes.Buffer
+	var err = csv.Export(&buf, payment.SelectAll(), pms...)
+	require.NoError(t, err)
+
+	var lines = strings.SplitN(buf.String(), "\n", 2)
+	assert.Equal(t, strings.Join(csv.Columns(payment.SelectAll()), ","), lines[0])
+	for _, v := range []string{
+		`,"'=HYPERLINK(""http://example.com"")",`, ",'-2+3,", ",'@SUM(A1),", ",'+44 1 Street,", ",'\tcmd,",
+		",''=quoted,", "\"'\r=1\"",
+	} {
+		assert.Contains(t, lines[1], v)
+	}
+
+	dpms, rerr, err := csv.Read(&buf, orgID)
+	require.NoError(t, err)
+	assert.Empty(t, rerr)
+	require.Len(t, dpms, len(pms))
+	for i, p := range pms {
+		assert.Equal(t, p.PymtUpsert, dpms[i], "payment %d", i)
+	}
+
+	t.Run("selection", func(t *testing.T) {
+		var buf bytes.Buffer
+		var err = csv.Export(&buf, payment.Selection{Status: true}, pms[0])
+		require.NoError(t, err)
+		assert.Equal(t, "id,status\n"+pms[0].ID.String()+",Submitted\n", buf.String())
+	})
+}
+
+func TestRead(t *testing.T) {
+	var (
+		orgID = testutil.NewUUID(t)
+		a     = testutil.NewAttrs(t)
+		buf   bytes.Buffer
+	)
+
+	a.ChargesInformation.SenderCharges = []payment.Charge{{Amount: 5, Currency: "USD"}, {Amount: 10.25, Currency: "USD"}}
+
+	// Export a payment with only the attributes to create the CSV
+	var err = csv.Export(&buf, payment.Selection{Attributes: true}, payment.Pymt{
+		PymtUpsert: payment.PymtUpsert{Attributes: a},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), ",USD 5;USD 10.25,")
+
+	var rows = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, rows, 2)
+
+	var csvDoc = strings.Join([]string{
+		rows[0],
+		rows[1],
+		strings.Replace(rows[1], ",USD 5;USD 10.25,", ",GBP5,", 1),
+		strings.Replace(rows[1], ","+a.PaymentID+",", ",,", 1),
+		"a,b",
+		rows[1],
+	}, "\n")
+
+	pms, rerr, err := csv.Read(strings.NewReader(csvDoc), orgID)
+	require.NoError(t, err)
+
+	var ep = payment.PymtUpsert{Type: "Payment", OrgID: orgID, Attributes: a}
+	assert.Equal(t, []payment.PymtUpsert{ep, ep}, pms)
+
+	require.Len(t, rerr, 3)
+	assert.Equal(t, 3, rerr[0].Row)
+	testutil.AssertError(t, rerr[0].Err, csv.ErrInvalidValue, payment.ErrMDField(
+		"attributes.charges_information.sender_charges",
+		payment.Violation{Field: "attributes.charges_information.sender_charges", Code: csv.ErrInvalidValue, Value: "GBP5"},
+	))
+	assert.Equal(t, 4, rerr[1].Row)
+	testutil.AssertError(t, rerr[1].Err, payment.ErrInvalidPaymentAttrPaymentID, payment.ErrMDField(
+		"Attributes.PaymentID",
+		payment.Violation{Field: "Attributes.PaymentID", Code: payment.ErrInvalidPaymentAttrPaymentID, Value: ""},
+	))
+	assert.Equal(t, 5, rerr[2].Row)
+	testutil.AssertError(t, rerr[2].Err, csv.ErrInvalidRecord, payment.ErrMDVar("fields", 2))
+}
+
+func TestRead_orgID(t *testing.T) {
+	var (
+		orgID = testutil.NewUUID(t)
+		oid   = testutil.NewUUID(t)
+		a     = testutil.NewAttrs(t)
+		buf   bytes.Buffer
+	)
+
+	var err = csv.Export(&buf, payment.Selection{OrgID: true, Attributes: true}, payment.Pymt{
+		PymtUpsert: payment.PymtUpsert{OrgID: orgID, Attributes: a},
+	})
+	require.NoError(t, err)
+
+	var rows = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, rows, 2)
+
+	var csvDoc = strings.Join([]string{
+		rows[0],
+		rows[1],
+		strings.Replace(rows[1], ","+orgID.String()+",", ",,", 1),
+		strings.Replace(rows[1], ","+orgID.String()+",", ","+oid.String()+",", 1),
+	}, "\n")
+
+	pms, rerr, err := csv.Read(strings.NewReader(csvDoc), orgID)
+	require.NoError(t, err)
+
+	var ep = payment.PymtUpsert{Type: "Payment", OrgID: orgID, Attributes: a}
+	assert.Equal(t, []payment.PymtUpsert{ep, ep}, pms)
+
+	require.Len(t, rerr, 1)
+	assert.Equal(t, 4, rerr[0].Row)
+	testutil.AssertError(t, rerr[0].Err, payment.ErrInvalidPaymentOrgID, payment.ErrMDField(
+		"organisation_id",
+		payment.Violation{Field: "organisation_id", Code: payment.ErrInvalidPaymentOrgID, Value: oid.String()},
+	))
+
+	t.Run("import", func(t *testing.T) {
+		var (
+			bc             batchCreatorStub
+			ids, rerr, err = csv.Import(context.Background(), &bc, strings.NewReader(csvDoc), orgID)
+		)
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+		assert.Nil(t, bc.ps)
+		require.Len(t, rerr, 1)
+		assert.Equal(t, 4, rerr[0].Row)
+	})
+}
+
+func TestRead_error(t *testing.T) {
+	var orgID = testutil.NewUUID(t)
+
+	var tcases = []struct {
+		desc string
+		doc  string
+		code errors.Code
+		mds  []errors.MD
+	}{
+		{
+			desc: "empty",
+			doc:  "",
+			code: csv.ErrInvalidHeader,
+		},
+		{
+			desc: "unknown column",
+			doc:  "id,attributes.unknown\n",
+			code: csv.ErrInvalidHeader,
+			mds:  []errors.MD{payment.ErrMDVar("column", "attributes.unknown")},
+		},
+		{
+			desc: "duplicated column",
+			doc:  "type,type\n",
+			code: csv.ErrInvalidHeader,
+			mds:  []errors.MD{payment.ErrMDVar("column", "type")},
+		},
+		{
+			desc: "malformed",
+			doc:  "type\n\"Payment\n",
+			code: csv.ErrInvalidRecord,
+			mds:  []errors.MD{payment.ErrMDVar("line", 2)},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var _, _, err = csv.Read(strings.NewReader(tc.doc), orgID)
+			testutil.AssertError(t, err, tc.code, tc.mds...)
+		})
+	}
+}
+
+type batchCreatorStub struct {
+	ps []payment.PymtUpsert
+}
+
+func (s *batchCreatorStub) CreateBatch(_ context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+	s.ps = ps
+
+	var ids = make([]uuid.UUID, len(ps))
+	for i := range ids {
+		ids[i] = uuid.Must(uuid.NewV4())
+	}
+
+	return ids, nil
+}
+
+func TestImport(t *testing.T) {
+	var (
+		orgID = testutil.NewUUID(t)
+		p     = payment.Pymt{PymtUpsert: payment.PymtUpsert{Attributes: testutil.NewAttrs(t)}}
+		buf   bytes.Buffer
+	)
+
+	require.NoError(t, csv.Export(&buf, payment.Selection{Attributes: true}, p, p))
+	var doc = buf.String()
+
+	t.Run("ok", func(t *testing.T) {
+		var bc batchCreatorStub
+		var ids, rerr, err = csv.Import(context.Background(), &bc, strings.NewReader(doc), orgID)
+		require.NoError(t, err)
+		assert.Empty(t, rerr)
+		assert.Len(t, ids, 2)
+		require.Len(t, bc.ps, 2)
+		assert.Equal(t, orgID, bc.ps[1].OrgID)
+	})
+
+	t.Run("row errors", func(t *testing.T) {
+		var bc batchCreatorStub
+		var ids, rerr, err = csv.Import(
+			context.Background(), &bc, strings.NewReader(doc+"\"\"\n"), orgID,
+		)
+		require.NoError(t, err)
+		assert.Nil(t, ids)
+		require.Len(t, rerr, 1)
+		assert.Equal(t, 4, rerr[0].Row)
+		assert.Nil(t, bc.ps)
+	})
+}
@@ -0,0 +1,36 @@
+package csv
+
+type code uint8
+
+// The list of specific error codes that the csv package can return.
+const (
+	ErrInvalidHeader code = iota + 1
+	ErrInvalidRecord
+	ErrInvalidValue
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrInvalidHeader:
+		return "InvalidHeader"
+	case ErrInvalidRecord:
+		return "InvalidRecord"
+	case ErrInvalidValue:
+		return "InvalidValue"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrInvalidHeader:
+		return "The header of the CSV is missing or it has unknown or duplicated columns"
+	case ErrInvalidRecord:
+		return "The record of the CSV is malformed or it doesn't have a field per column"
+	case ErrInvalidValue:
+		return "The value of the column cannot be converted to the type of the payment field"
+	}
+
+	return ""
+}
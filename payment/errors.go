@@ -31,12 +31,47 @@ const (
 	ErrUnexpectedOSError
 	ErrUnexpectedStoreError
 	ErrUnexpectedSysError
+
+	// The codes are appended after the existing ones, rather than sorted with
+	// them, for not changing the value of the existing codes.
+
+	ErrInvalidArgIdempotencyKey
+	ErrInvalidArgIdempotencyKeyReused
+
+	ErrInvalidArgStatus
+	ErrInvalidArgStatusTransition
+	ErrNotEditable
+
+	ErrInvalidPaymentAttrAmount
+	ErrInvalidPaymentAttrCode
+	ErrInvalidPaymentAttrCurrency
+	ErrInvalidPaymentAttrDate
+	ErrInvalidPaymentAttrFormat
+	ErrInvalidPaymentAttrRequired
+
+	ErrInvalidPaymentAttrAccountNumber
+	ErrInvalidPaymentAttrBankID
+
+	ErrInvalidArgPatch
+	ErrInvalidArgPatchReadOnly
+	ErrInvalidArgPatchTestFailed
+
+	ErrInvalidArgSelection
+
+	ErrInvalidArgSort
+	ErrInvalidArgSortNotSupported
+
+	ErrInvalidArgAggregation
+
+	ErrNotSupported
 )
 
 func (c code) String() string {
 	switch c {
 	case ErrAbortedOperation:
 		return "AbortedOperation"
+	case ErrInvalidArgAggregation:
+		return "InvalidArgAggregation"
 	case ErrInvalidArgFilterCmpNotExists:
 		return "InvalidArgFilterCmpNotExists"
 	case ErrInvalidArgFilterCmpNotSupported:
@@ -49,6 +84,26 @@ func (c code) String() string {
 		return "InvalidArgFilterNodeEmpty"
 	case ErrInvalidArgFilterValue:
 		return "InvalidArgFilterValue"
+	case ErrInvalidArgIdempotencyKey:
+		return "InvalidArgIdempotencyKey"
+	case ErrInvalidArgIdempotencyKeyReused:
+		return "InvalidArgIdempotencyKeyReused"
+	case ErrInvalidArgPatch:
+		return "InvalidArgPatch"
+	case ErrInvalidArgPatchReadOnly:
+		return "InvalidArgPatchReadOnly"
+	case ErrInvalidArgPatchTestFailed:
+		return "InvalidArgPatchTestFailed"
+	case ErrInvalidArgSelection:
+		return "InvalidArgSelection"
+	case ErrInvalidArgSort:
+		return "InvalidArgSort"
+	case ErrInvalidArgSortNotSupported:
+		return "InvalidArgSortNotSupported"
+	case ErrInvalidArgStatus:
+		return "InvalidArgStatus"
+	case ErrInvalidArgStatusTransition:
+		return "InvalidArgStatusTransition"
 	case ErrInvalidArgVersionMismatch:
 		return "InvalidArgVersionMismatch"
 	case ErrInvalidPaymentID:
@@ -57,10 +112,30 @@ func (c code) String() string {
 		return "InvalidPaymentOgID"
 	case ErrInvalidPaymentType:
 		return "InvalidPaymentType"
+	case ErrInvalidPaymentAttrAccountNumber:
+		return "InvalidPaymentAttrAccountNumber"
+	case ErrInvalidPaymentAttrAmount:
+		return "InvalidPaymentAttrAmount"
+	case ErrInvalidPaymentAttrBankID:
+		return "InvalidPaymentAttrBankID"
+	case ErrInvalidPaymentAttrCode:
+		return "InvalidPaymentAttrCode"
+	case ErrInvalidPaymentAttrCurrency:
+		return "InvalidPaymentAttrCurrency"
+	case ErrInvalidPaymentAttrDate:
+		return "InvalidPaymentAttrDate"
+	case ErrInvalidPaymentAttrFormat:
+		return "InvalidPaymentAttrFormat"
 	case ErrInvalidPaymentAttrPaymentID:
 		return "InvalidPaymentAttrPaymentID"
+	case ErrInvalidPaymentAttrRequired:
+		return "InvalidPaymentAttrRequired"
+	case ErrNotEditable:
+		return "NotEditable"
 	case ErrNotFound:
 		return "NotFound"
+	case ErrNotSupported:
+		return "NotSupported"
 	case ErrUnexpectedOSError:
 		return "UnexpectedOSError"
 	case ErrUnexpectedStoreError:
@@ -76,6 +151,8 @@ func (c code) Message() string {
 	switch c {
 	case ErrAbortedOperation:
 		return "the operation has been aborted"
+	case ErrInvalidArgAggregation:
+		return "The aggregation doesn't have functions or contains an invalid or duplicated group key or function"
 	case ErrInvalidArgFilterCmpNotExists:
 		return "The filter comparison operator doesn't exist"
 	case ErrInvalidArgFilterCmpNotSupported:
@@ -88,6 +165,26 @@ func (c code) Message() string {
 		return "The filter node cannot be an empty"
 	case ErrInvalidArgFilterValue:
 		return "The filter value isn't a valid one"
+	case ErrInvalidArgIdempotencyKey:
+		return "The idempotency key is empty or too long"
+	case ErrInvalidArgIdempotencyKeyReused:
+		return "The idempotency key has already been used with a different payment"
+	case ErrInvalidArgPatch:
+		return "The patch isn't a valid JSON Merge Patch or JSON Patch document or it cannot be applied to the payment"
+	case ErrInvalidArgPatchReadOnly:
+		return "The patch modifies a field of the payment which cannot be modified"
+	case ErrInvalidArgPatchTestFailed:
+		return "A test operation of the JSON Patch has failed"
+	case ErrInvalidArgSelection:
+		return "The selection contains a field which doesn't exist"
+	case ErrInvalidArgSort:
+		return "The sort contains a key with an invalid field or direction or a duplicated field"
+	case ErrInvalidArgSortNotSupported:
+		return "The sort contains a field which isn't supported by the store"
+	case ErrInvalidArgStatus:
+		return "The status isn't a valid one"
+	case ErrInvalidArgStatusTransition:
+		return "The payment cannot change from its current status to the provided one"
 	case ErrInvalidArgVersionMismatch:
 		return "The provided version doesn't match with the current one"
 	case ErrInvalidPaymentID:
@@ -96,10 +193,31 @@ func (c code) Message() string {
 		return "Invalid payment because its organisation ID is not valid"
 	case ErrInvalidPaymentType:
 		return "Invalid payment because its type value is not valid"
+	case ErrInvalidPaymentAttrAccountNumber:
+		return "Invalid payment because an account number of its parties isn't valid for its account number code"
+	case ErrInvalidPaymentAttrAmount:
+		return "Invalid payment because an amount of its attributes isn't positive or has more decimals than its currency allows"
+	case ErrInvalidPaymentAttrBankID:
+		return "Invalid payment because a bank ID of its parties isn't valid for its bank ID code"
+	case ErrInvalidPaymentAttrCode:
+		return "Invalid payment because a code of its attributes isn't a known one"
+	case ErrInvalidPaymentAttrCurrency:
+		return "Invalid payment because a currency of its attributes isn't an ISO 4217 currency code or it isn't " +
+			"consistent with the other currencies of the payment"
+	case ErrInvalidPaymentAttrDate:
+		return "Invalid payment because a date of its attributes isn't an ISO 8601 date (YYYY-MM-DD)"
+	case ErrInvalidPaymentAttrFormat:
+		return "Invalid payment because a value of its attributes doesn't have the required format"
 	case ErrInvalidPaymentAttrPaymentID:
 		return "Invalid payment because the payment ID value of its attributes is not valid"
+	case ErrInvalidPaymentAttrRequired:
+		return "Invalid payment because a required value of its attributes is empty"
+	case ErrNotEditable:
+		return "The payment cannot be updated because it has already been submitted"
 	case ErrNotFound:
 		return "The entity was not found"
+	case ErrNotSupported:
+		return "The operation isn't supported by the payment service"
 	case ErrUnexpectedOSError:
 		return "an unexpected error has been returned when performing an operative system operation"
 	case ErrUnexpectedStoreError:
@@ -112,11 +230,11 @@ func (c code) Message() string {
 }
 
 // ErrMDArg creates a new metadata from an function argument which is related
-// with the error to create.
+// with the error to create. val is redacted, see Redact.
 func ErrMDArg(name string, val interface{}) errors.MD {
 	return errors.MD{
 		K: fmt.Sprintf("arg:%s", name),
-		V: val,
+		V: Redact(val),
 	}
 }
 
@@ -124,10 +242,11 @@ func ErrMDArg(name string, val interface{}) errors.MD {
 // relevant for the error to create.
 // When the variable is not exposed, the name should be meaningful to the
 // user/developer/ops when reading the verbose version of the error.
+// val is redacted, see Redact.
 func ErrMDVar(name string, val interface{}) errors.MD {
 	return errors.MD{
 		K: fmt.Sprintf("var:%s", name),
-		V: val,
+		V: Redact(val),
 	}
 }
 
@@ -136,19 +255,25 @@ func ErrMDVar(name string, val interface{}) errors.MD {
 // This metadata is intended to be used for internal function calls, so the
 // user isn't aware of those and when they return an error code which isn't
 // enough concrete to let inform the user what specifically happened.
+// args are redacted, see Redact.
 func ErrMDFnCall(fname string, args ...interface{}) errors.MD {
+	var rargs = make([]interface{}, len(args))
+	for i, a := range args {
+		rargs[i] = Redact(a)
+	}
+
 	return errors.MD{
 		K: fmt.Sprintf("func:%s", fname),
-		V: fmt.Sprintf("%+v", args),
+		V: fmt.Sprintf("%+v", rargs),
 	}
 }
 
 // ErrMDField creates a new metadata from a struct field whose name and value
-// are relevant for the error to create.
+// are relevant for the error to create. val is redacted, see Redact.
 func ErrMDField(name string, val interface{}) errors.MD {
 	return errors.MD{
 		K: fmt.Sprintf("field:%s", name),
-		V: val,
+		V: Redact(val),
 	}
 }
 
@@ -156,10 +281,11 @@ func ErrMDField(name string, val interface{}) errors.MD {
 // fact which provide context to the error for the user/developer/ops when
 // reading the verbose version of the error.
 //
-// name should be short but meaninful to understand the value.
+// name should be short but meaninful to understand the value and val is
+// redacted, see Redact.
 func ErrMDFact(name string, val interface{}) errors.MD {
 	return errors.MD{
 		K: fmt.Sprintf("fact:%s", name),
-		V: val,
+		V: Redact(val),
 	}
 }
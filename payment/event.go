@@ -0,0 +1,108 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"go.fraixed.es/errors"
+)
+
+// EventType is the type which represents the kind of change that an Event
+// informs about.
+type EventType uint8
+
+// The list of valid EventType values.
+const (
+	eventTypeNone EventType = iota
+	EventTypeCreated
+	EventTypeUpdated
+	EventTypeDeleted
+)
+
+// Valid returns true if t is a valid EventType value, otherwise false.
+func (t EventType) Valid() bool {
+	return t > eventTypeNone && t <= EventTypeDeleted
+}
+
+func (t EventType) String() string {
+	switch t {
+	case EventTypeCreated:
+		return "Created"
+	case EventTypeUpdated:
+		return "Updated"
+	case EventTypeDeleted:
+		return "Deleted"
+	}
+
+	return ""
+}
+
+// ParseEventType returns the EventType whose string representation is s. It
+// returns false if s doesn't match with any valid EventType.
+func ParseEventType(s string) (EventType, bool) {
+	for t := EventTypeCreated; t <= EventTypeDeleted; t++ {
+		if t.String() == s {
+			return t, true
+		}
+	}
+
+	return eventTypeNone, false
+}
+
+// Event contains the information of a change applied to a payment.
+type Event struct {
+	// Seq is the sequence number of the event. Sequence numbers are unique and
+	// they grow in the same order that the changes have been applied.
+	Seq     uint64
+	Type    EventType
+	PymtID  uuid.UUID
+	Version uint32
+	// Pymt is the payload of the event. It's the payment after the change has
+	// been applied except for EventTypeDeleted, which is the payment at the
+	// moment of being deleted.
+	Pymt Pymt
+}
+
+// ChangeFeed is the interface which any specific implementation of a payment
+// service which exposes the changes applied to the payments must satisfy.
+type ChangeFeed interface {
+	// Changes returns a channel which receives, in order, the events whose
+	// sequence number is equal or greater than fromSeq; once all the existing
+	// events are sent, it keeps sending the new ones while they happen.
+	//
+	// The events are delivered at-least-once, hence the consumers must keep
+	// track of the sequence number of the last processed event and call again
+	// this method with the next sequence number, in case that the delivery is
+	// interrupted, being aware that they may receive some events more than
+	// once.
+	//
+	// The events channel is closed when ctx is done or when an error happens,
+	// in the latter case the error is sent to the errors channel before it's
+	// closed. The errors channel is closed after the events channel.
+	//
+	// The errors which can be sent are the general ones documented in Service.
+	Changes(ctx context.Context, fromSeq uint64) (<-chan Event, <-chan error)
+}
+
+// Changes returns the channels returned by svc.Changes, as ChangeFeed
+// documents, when svc satisfies ChangeFeed. It's intended for the services
+// which decorate another service, so they satisfy ChangeFeed whatever the
+// decorated service is.
+//
+// When svc doesn't satisfy ChangeFeed, the events channel is closed and the
+// error code ErrNotSupported is sent to the errors channel.
+func Changes(ctx context.Context, svc Service, fromSeq uint64) (<-chan Event, <-chan error) {
+	if cf, ok := svc.(ChangeFeed); ok {
+		return cf.Changes(ctx, fromSeq)
+	}
+
+	var (
+		evtc = make(chan Event)
+		errc = make(chan error, 1)
+	)
+
+	close(evtc)
+	errc <- errors.New(ErrNotSupported, ErrMDFnCall("Changes"))
+	close(errc)
+	return evtc, errc
+}
@@ -0,0 +1,36 @@
+package payment_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEventType(t *testing.T) {
+	for _, et := range []payment.EventType{
+		payment.EventTypeCreated, payment.EventTypeUpdated, payment.EventTypeDeleted,
+	} {
+		var pet, ok = payment.ParseEventType(et.String())
+		assert.True(t, ok)
+		assert.Equal(t, et, pet)
+		assert.True(t, pet.Valid())
+	}
+
+	var et, ok = payment.ParseEventType("Unknown")
+	assert.False(t, ok)
+	assert.False(t, et.Valid())
+}
+
+func TestChanges(t *testing.T) {
+	// The service doesn't satisfy payment.ChangeFeed
+	var evtc, errc = payment.Changes(context.Background(), struct{ payment.Service }{&testutil.SvcStub{}}, 0)
+
+	var _, ok = <-evtc
+	assert.False(t, ok)
+	testutil.AssertError(t, <-errc, payment.ErrNotSupported)
+	_, ok = <-errc
+	assert.False(t, ok)
+}
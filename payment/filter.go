@@ -161,6 +161,48 @@ func (f FilterLeafID) IsSet() bool {
 	return f.cmp != filterCmpNone
 }
 
+// FilterLeafOrgID is the FilterLeaf for filtering payments by organisation ID.
+type FilterLeafOrgID struct {
+	val uuid.UUID
+	cmp FilterCmp
+}
+
+// NewFilterByOrgID creates a new Filter leaf node of a FilterLeafOrgID with the
+// specified cmp and val.
+//
+// The following error codes can be returned (declared in errs sub package):
+//
+// * InvalidArgFilterCmpNotExists
+//
+// * InvalidArgFilterCmpNotSupported - when cmp isn't FilterCmpEqual nor
+// FilterCmpNotEqual
+func NewFilterByOrgID(cmp FilterCmp, val uuid.UUID) (Filter, error) {
+	if err := validatepCmp(cmp); err != nil {
+		return Filter{}, err
+	}
+
+	switch cmp {
+	case FilterCmpEqual, FilterCmpNotEqual:
+	default:
+		return Filter{}, errors.New(ErrInvalidArgFilterCmpNotSupported, ErrMDArg("cmp", cmp))
+	}
+
+	return NewFilterFromLeaf(FilterLeafOrgID{
+		val: val,
+		cmp: cmp,
+	})
+}
+
+// Filter returns the operation and organisation ID value which has been set.
+func (f FilterLeafOrgID) Filter() (FilterCmp, interface{}) {
+	return f.cmp, f.val
+}
+
+// IsSet returns true when the filter is set, otherwise none.
+func (f FilterLeafOrgID) IsSet() bool {
+	return f.cmp != filterCmpNone
+}
+
 // FilterLeafType is the FilterLeaf for filtering payments by type.
 type FilterLeafType struct {
 	filterLeafString
@@ -200,6 +242,46 @@ func NewFilterByType(cmp FilterCmp, val string) (Filter, error) {
 	return NewFilterFromLeaf(FilterLeafType{f})
 }
 
+// FilterLeafStatus is the FilterLeaf for filtering payments by status. Its
+// value is the string representation of the status.
+type FilterLeafStatus struct {
+	filterLeafString
+}
+
+// NewFilterByStatus creates a new Filter leaf node of a FilterLeafStatus with
+// cmp and val.
+//
+// The following error codes can be returned (declared in errs sub package):
+//
+// * InvalidArgFilterCmpNotExists
+//
+// * InvalidArgFilterCmpNotSupported - when cmp isn't FilterCmpEqual nor
+// FilterCmpNotEqual
+//
+// * InvalidArgFilterValue - when val isn't a valid Status
+func NewFilterByStatus(cmp FilterCmp, val Status) (Filter, error) {
+	if err := validatepCmp(cmp); err != nil {
+		return Filter{}, err
+	}
+
+	switch cmp {
+	case FilterCmpEqual, FilterCmpNotEqual:
+	default:
+		return Filter{}, errors.New(ErrInvalidArgFilterCmpNotSupported, ErrMDArg("cmp", cmp))
+	}
+
+	if !val.Valid() {
+		return Filter{}, errors.New(ErrInvalidArgFilterValue, ErrMDArg("val", val))
+	}
+
+	var f, err = newFilterLeafString(cmp, val.String())
+	if err != nil {
+		return Filter{}, err
+	}
+
+	return NewFilterFromLeaf(FilterLeafStatus{f})
+}
+
 // FilterLeafAmount allows to filter payment by its amount filed.
 type FilterLeafAmount struct {
 	filterLeafFloat64
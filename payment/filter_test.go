@@ -318,6 +318,75 @@ func TestNewFilterByID(t *testing.T) {
 	}
 }
 
+func TestNewFilterByOrgID(t *testing.T) {
+	type params struct {
+		cmp payment.FilterCmp
+		val uuid.UUID
+	}
+
+	type tcase struct {
+		desc   string
+		args   params
+		assert func(*testing.T, tcase, payment.Filter, error)
+	}
+
+	var tcases = []tcase{
+		{
+			desc: "successful",
+			args: params{
+				cmp: func() payment.FilterCmp {
+					// nolint:gosec
+					if rand.Int()%2 == 0 {
+						return payment.FilterCmpEqual
+					}
+
+					return payment.FilterCmpNotEqual
+				}(),
+				val: uuid.Must(uuid.NewV4()),
+			},
+			assert: func(t *testing.T, tc tcase, f payment.Filter, err error) {
+				assert.NoError(t, err)
+				if assert.Equal(t, f.NodeType(), payment.FilterNodeTypeLeaf) {
+					var l = f.Leaf()
+					assert.True(t, l.IsSet())
+
+					var cmp, val = l.Filter()
+					assert.Equal(t, tc.args.cmp, cmp)
+					assert.Equal(t, tc.args.val, val)
+				}
+			},
+		},
+		{
+			desc: "error: unsupported cmp",
+			args: params{
+				cmp: payment.FilterCmp(rand.Intn(4) + 3),
+				val: uuid.Must(uuid.NewV4()),
+			},
+			assert: func(t *testing.T, tc tcase, _ payment.Filter, err error) {
+				testutil.AssertError(t, err, payment.ErrInvalidArgFilterCmpNotSupported, payment.ErrMDArg("cmp", tc.args.cmp))
+			},
+		},
+		{
+			desc: "error: cmp doesn't exist",
+			args: params{
+				cmp: payment.FilterCmp(rand.Intn(240) + 15),
+				val: uuid.Must(uuid.NewV4()),
+			},
+			assert: func(t *testing.T, tc tcase, _ payment.Filter, err error) {
+				testutil.AssertError(t, err, payment.ErrInvalidArgFilterCmpNotExists, payment.ErrMDArg("cmp", tc.args.cmp))
+			},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			var f, err = payment.NewFilterByOrgID(tc.args.cmp, tc.args.val)
+			tc.assert(t, tc, f, err)
+		})
+	}
+}
+
 func TestNewFilterByType(t *testing.T) {
 	type params struct {
 		cmp payment.FilterCmp
@@ -396,3 +465,72 @@ func TestNewFilterByType(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFilterByStatus(t *testing.T) {
+	type params struct {
+		cmp payment.FilterCmp
+		val payment.Status
+	}
+
+	type tcase struct {
+		desc   string
+		args   params
+		assert func(*testing.T, tcase, payment.Filter, error)
+	}
+
+	var tcases = []tcase{
+		{
+			desc: "successful",
+			args: params{
+				cmp: func() payment.FilterCmp {
+					// nolint:gosec
+					if rand.Int()%2 == 0 {
+						return payment.FilterCmpEqual
+					}
+
+					return payment.FilterCmpNotEqual
+				}(),
+				val: payment.StatusSubmitted,
+			},
+			assert: func(t *testing.T, tc tcase, f payment.Filter, err error) {
+				assert.NoError(t, err)
+				if assert.Equal(t, f.NodeType(), payment.FilterNodeTypeLeaf) {
+					var l = f.Leaf()
+					assert.True(t, l.IsSet())
+
+					var cmp, val = l.Filter()
+					assert.Equal(t, tc.args.cmp, cmp)
+					assert.Equal(t, tc.args.val.String(), val)
+				}
+			},
+		},
+		{
+			desc: "error: unsupported cmp",
+			args: params{
+				cmp: payment.FilterCmpMatch,
+				val: payment.StatusPending,
+			},
+			assert: func(t *testing.T, tc tcase, _ payment.Filter, err error) {
+				testutil.AssertError(t, err, payment.ErrInvalidArgFilterCmpNotSupported, payment.ErrMDArg("cmp", tc.args.cmp))
+			},
+		},
+		{
+			desc: "error: invalid value",
+			args: params{
+				cmp: payment.FilterCmpEqual,
+				val: payment.Status(rand.Intn(240) + 15),
+			},
+			assert: func(t *testing.T, tc tcase, _ payment.Filter, err error) {
+				testutil.AssertError(t, err, payment.ErrInvalidArgFilterValue, payment.ErrMDArg("val", tc.args.val))
+			},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			var f, err = payment.NewFilterByStatus(tc.args.cmp, tc.args.val)
+			tc.assert(t, tc, f, err)
+		})
+	}
+}
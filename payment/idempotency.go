@@ -0,0 +1,44 @@
+package payment
+
+import (
+	"context"
+
+	"go.fraixed.es/errors"
+)
+
+// IdempotencyKeyMaxLen is the maximum length of an idempotency key.
+const IdempotencyKeyMaxLen = 255
+
+type idempotencyKeyCtxKey struct{}
+
+// WithIdempotencyKey returns a copy of ctx which carries the idempotency key
+// key. Service.Create uses it for not creating the same payment more than once
+// when a client retries the operation (e.g. after a timeout).
+//
+// The key is scoped to the organisation of the payment, hence different
+// organisations can use the same keys.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+// IdempotencyKey returns the idempotency key carried by ctx. It returns false if
+// ctx doesn't carry any.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidArgIdempotencyKey - when the key carried by ctx is empty or its
+//   length is greater than IdempotencyKeyMaxLen.
+func IdempotencyKey(ctx context.Context) (string, bool, error) {
+	var key, ok = ctx.Value(idempotencyKeyCtxKey{}).(string)
+	if !ok {
+		return "", false, nil
+	}
+
+	if key == "" || len(key) > IdempotencyKeyMaxLen {
+		return "", true, errors.New(ErrInvalidArgIdempotencyKey,
+			ErrMDVar("idempotency_key", key), ErrMDFact("max_length", IdempotencyKeyMaxLen),
+		)
+	}
+
+	return key, true, nil
+}
@@ -0,0 +1,33 @@
+package payment_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	var ctx = context.Background()
+
+	var key, ok, err = payment.IdempotencyKey(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, key)
+
+	key, ok, err = payment.IdempotencyKey(payment.WithIdempotencyKey(ctx, "a-key"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a-key", key)
+
+	for _, k := range []string{"", strings.Repeat("k", payment.IdempotencyKeyMaxLen+1)} {
+		_, ok, err = payment.IdempotencyKey(payment.WithIdempotencyKey(ctx, k))
+		assert.True(t, ok)
+		testutil.AssertError(t, err, payment.ErrInvalidArgIdempotencyKey,
+			payment.ErrMDVar("idempotency_key", k), payment.ErrMDFact("max_length", payment.IdempotencyKeyMaxLen),
+		)
+	}
+}
@@ -0,0 +1,68 @@
+package testutil
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/stretchr/testify/require"
+)
+
+// NewAttrs creates payment attributes with random values which respect the
+// requirements of the business domain and abort the test if there is an error.
+func NewAttrs(t *testing.T) payment.Attrs {
+	var a payment.Attrs
+	require.NoError(t, faker.FakeData(&a))
+
+	a.Amount = NewAmount()
+	a.Currency = "GBP"
+	a.NumericReference = strconv.Itoa(rand.Intn(10000000))
+	a.PaymentScheme = "FPS"
+	a.PaymentType = "Credit"
+	a.ProcessingDate = time.Now().Format("2006-01-02")
+	a.SchemePaymentSubType = "InternetBanking"
+	a.SchemePaymentType = "ImmediatePayment"
+
+	a.BeneficiaryParty = newParty(a.BeneficiaryParty, "107999", "88837491")
+	a.DebtorParty = newParty(a.DebtorParty, "089999", "66374958")
+	a.SponsorParty = newParty(a.SponsorParty, "202959", "63748472")
+
+	a.ChargesInformation.BearerCode = "SHAR"
+	if len(a.ChargesInformation.SenderCharges) == 0 {
+		a.ChargesInformation.SenderCharges = make([]payment.Charge, 1)
+	}
+
+	for i := range a.ChargesInformation.SenderCharges {
+		a.ChargesInformation.SenderCharges[i].Amount = NewAmount()
+		a.ChargesInformation.SenderCharges[i].Currency = "USD"
+	}
+	a.ChargesInformation.ReceiverChargesAmount = NewAmount()
+	a.ChargesInformation.ReceiverChargesCurrency = "USD"
+
+	a.Fx.ExchangeRate = "1.25"
+	a.Fx.OriginalAmount = strconv.FormatFloat(a.Amount*1.25, 'f', 2, 64)
+	a.Fx.OriginalCurrency = "USD"
+
+	return a
+}
+
+// NewAmount returns a random positive amount with 2 decimal places.
+func NewAmount() float64 {
+	return float64(rand.Intn(10000000)+1) / 100
+}
+
+// newParty returns p with the values, whose requirements of the business
+// domain cannot be fulfilled by random values, set to valid ones, being sc and
+// num a UK sort code and account number which pass the modulus check.
+func newParty(p payment.Party, sc string, num string) payment.Party {
+	p.AccountNumber = num
+	p.AccountNumberCode = "BBAN"
+	p.AccountType = 0
+	p.BankID = sc
+	p.BankIDCode = "GBDSC"
+
+	return p
+}
@@ -0,0 +1,85 @@
+package testutil
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+)
+
+// SvcStub is a payment.Service, which also satisfies the payment.BatchCreator
+// and payment.ChangeFeed interfaces, whose methods call the function of the
+// field with the same name and the "Fn" suffix. They panic if the field isn't
+// set.
+type SvcStub struct {
+	AggregateFn   func(context.Context, payment.Filter, payment.Aggregation) ([]payment.Aggregate, error)
+	ChangesFn     func(context.Context, uint64) (<-chan payment.Event, <-chan error)
+	CreateFn      func(context.Context, payment.PymtUpsert) (uuid.UUID, error)
+	CreateBatchFn func(context.Context, []payment.PymtUpsert) ([]uuid.UUID, error)
+	DeleteFn      func(context.Context, uuid.UUID, uint32) error
+	FindFn        func(context.Context, payment.Filter, payment.Selection, payment.Sort, payment.Chunk) ([]payment.Pymt, error)
+	GetFn         func(context.Context, uuid.UUID, payment.Selection) (payment.Pymt, error)
+	HistoryFn     func(context.Context, uuid.UUID) ([]payment.StatusChange, error)
+	PatchFn       func(context.Context, uuid.UUID, uint32, payment.Patch) error
+	TransitionFn  func(context.Context, uuid.UUID, uint32, payment.Status, string) error
+	UpdateFn      func(context.Context, uuid.UUID, uint32, payment.PymtUpsert) error
+}
+
+// Aggregate satisfies the payment.Service interface.
+func (s *SvcStub) Aggregate(
+	ctx context.Context, f payment.Filter, a payment.Aggregation,
+) ([]payment.Aggregate, error) {
+	return s.AggregateFn(ctx, f, a)
+}
+
+// Changes satisfies the payment.ChangeFeed interface.
+func (s *SvcStub) Changes(ctx context.Context, fromSeq uint64) (<-chan payment.Event, <-chan error) {
+	return s.ChangesFn(ctx, fromSeq)
+}
+
+// Create satisfies the payment.Service interface.
+func (s *SvcStub) Create(ctx context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
+	return s.CreateFn(ctx, p)
+}
+
+// CreateBatch satisfies the payment.BatchCreator interface.
+func (s *SvcStub) CreateBatch(ctx context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+	return s.CreateBatchFn(ctx, ps)
+}
+
+// Delete satisfies the payment.Service interface.
+func (s *SvcStub) Delete(ctx context.Context, id uuid.UUID, v uint32) error {
+	return s.DeleteFn(ctx, id, v)
+}
+
+// Find satisfies the payment.Service interface.
+func (s *SvcStub) Find(
+	ctx context.Context, f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk,
+) ([]payment.Pymt, error) {
+	return s.FindFn(ctx, f, sl, st, c)
+}
+
+// Get satisfies the payment.Service interface.
+func (s *SvcStub) Get(ctx context.Context, id uuid.UUID, sl payment.Selection) (payment.Pymt, error) {
+	return s.GetFn(ctx, id, sl)
+}
+
+// History satisfies the payment.Service interface.
+func (s *SvcStub) History(ctx context.Context, id uuid.UUID) ([]payment.StatusChange, error) {
+	return s.HistoryFn(ctx, id)
+}
+
+// Patch satisfies the payment.Service interface.
+func (s *SvcStub) Patch(ctx context.Context, id uuid.UUID, v uint32, p payment.Patch) error {
+	return s.PatchFn(ctx, id, v, p)
+}
+
+// Transition satisfies the payment.Service interface.
+func (s *SvcStub) Transition(ctx context.Context, id uuid.UUID, v uint32, to payment.Status, r string) error {
+	return s.TransitionFn(ctx, id, v, to, r)
+}
+
+// Update satisfies the payment.Service interface.
+func (s *SvcStub) Update(ctx context.Context, id uuid.UUID, v uint32, p payment.PymtUpsert) error {
+	return s.UpdateFn(ctx, id, v, p)
+}
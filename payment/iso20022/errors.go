@@ -0,0 +1,46 @@
+package iso20022
+
+type code uint8
+
+// The list of specific error codes that the iso20022 package can return.
+const (
+	ErrInvalidAmount code = iota + 1
+	ErrInvalidControlSum
+	ErrInvalidDocument
+	ErrInvalidNumberOfTxs
+	ErrInvalidPaymentType
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrInvalidAmount:
+		return "InvalidAmount"
+	case ErrInvalidControlSum:
+		return "InvalidControlSum"
+	case ErrInvalidDocument:
+		return "InvalidDocument"
+	case ErrInvalidNumberOfTxs:
+		return "InvalidNumberOfTxs"
+	case ErrInvalidPaymentType:
+		return "InvalidPaymentType"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrInvalidAmount:
+		return "An amount of the document isn't a decimal number or its currency isn't an ISO 4217 currency code"
+	case ErrInvalidControlSum:
+		return "The control sum of the document doesn't match with the sum of the amounts of its transactions"
+	case ErrInvalidDocument:
+		return "The document isn't a well-formed XML document of the expected ISO 20022 message"
+	case ErrInvalidNumberOfTxs:
+		return "The number of transactions of the document doesn't match with the transactions that it contains"
+	case ErrInvalidPaymentType:
+		return "The payment type cannot be represented by the ISO 20022 message"
+	}
+
+	return ""
+}
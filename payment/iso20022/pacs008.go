@@ -0,0 +1,375 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// Pacs008Namespace is the XML namespace of the pacs.008 documents.
+const Pacs008Namespace = "urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08"
+
+// fxInstructionPrefix prefixes the instruction for the next agent which
+// contains the reference of the foreign exchange contract, which doesn't have a
+// specific element in the message.
+const fxInstructionPrefix = "/FXCTRCT/"
+
+type pacs008Document struct {
+	XMLName           xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08 Document"`
+	FIToFICstmrCdtTrf struct {
+		GrpHdr      pacs008GroupHeader `xml:"GrpHdr"`
+		CdtTrfTxInf []pacs008Tx        `xml:"CdtTrfTxInf"`
+	} `xml:"FIToFICstmrCdtTrf"`
+}
+
+type pacs008GroupHeader struct {
+	MsgID             string  `xml:"MsgId"`
+	CreDtTm           string  `xml:"CreDtTm"`
+	NbOfTxs           string  `xml:"NbOfTxs"`
+	CtrlSum           string  `xml:"CtrlSum,omitempty"`
+	TtlIntrBkSttlmAmt *amount `xml:"TtlIntrBkSttlmAmt,omitempty"`
+	SttlmInf          struct {
+		SttlmMtd string `xml:"SttlmMtd"`
+	} `xml:"SttlmInf"`
+}
+
+type pacs008Tx struct {
+	PmtID struct {
+		InstrID    string `xml:"InstrId,omitempty"`
+		EndToEndID string `xml:"EndToEndId"`
+		TxID       string `xml:"TxId,omitempty"`
+	} `xml:"PmtId"`
+	PmtTpInf       *paymentTypeInfo `xml:"PmtTpInf,omitempty"`
+	IntrBkSttlmAmt amount           `xml:"IntrBkSttlmAmt"`
+	IntrBkSttlmDt  string           `xml:"IntrBkSttlmDt,omitempty"`
+	InstdAmt       *amount          `xml:"InstdAmt,omitempty"`
+	XchgRate       string           `xml:"XchgRate,omitempty"`
+	ChrgBr         string           `xml:"ChrgBr,omitempty"`
+	ChrgsInf       []struct {
+		Amt amount `xml:"Amt"`
+		Agt agent  `xml:"Agt"`
+	} `xml:"ChrgsInf,omitempty"`
+	IntrmyAgt1     *agent       `xml:"IntrmyAgt1,omitempty"`
+	IntrmyAgt1Acct *cashAccount `xml:"IntrmyAgt1Acct,omitempty"`
+	Dbtr           partyID      `xml:"Dbtr"`
+	DbtrAcct       *cashAccount `xml:"DbtrAcct,omitempty"`
+	DbtrAgt        agent        `xml:"DbtrAgt"`
+	CdtrAgt        agent        `xml:"CdtrAgt"`
+	Cdtr           partyID      `xml:"Cdtr"`
+	CdtrAcct       *cashAccount `xml:"CdtrAcct,omitempty"`
+	InstrForNxtAgt []struct {
+		InstrInf string `xml:"InstrInf"`
+	} `xml:"InstrForNxtAgt,omitempty"`
+	Purp   *codeOrProprietary `xml:"Purp,omitempty"`
+	RmtInf *remittanceInfo    `xml:"RmtInf,omitempty"`
+}
+
+// EncodePacs008 writes to w the pacs.008 FIToFICustomerCreditTransfer document
+// (version 08) of the payments pms identified by gh.
+//
+// The group header contains the number of transactions, the control sum of
+// their interbank settlement amounts and, when all of them have the same
+// currency, the total interbank settlement amount. Each payment is a credit
+// transfer transaction whose transaction ID is the payment ID and whose
+// instruction ID is the payment ID of its attributes.
+//
+// The debtor and beneficiary parties are the debtor and the creditor with their
+// accounts and agents and the sponsor party is the first intermediary agent,
+// see the mapping of the party fields in newPartyElems. The sender charges are
+// the charges of the debtor agent and the receiver charges the charges of the
+// creditor agent. The foreign exchange original amount and rate are the
+// instructed amount and the exchange rate and its contract reference is an
+// instruction for the next agent prefixed by "/FXCTRCT/".
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidPaymentType - Some payment isn't a credit transfer.
+//
+// * payment.ErrUnexpectedOSError
+//
+// * payment.ErrUnexpectedSysError
+func EncodePacs008(w io.Writer, gh GroupHeader, pms ...payment.Pymt) error {
+	var (
+		doc  pacs008Document
+		ams  = make([]amount, len(pms))
+		ccys = map[string]bool{}
+	)
+
+	for i, p := range pms {
+		var tx, err = newPacs008Tx(p)
+		if err != nil {
+			return err
+		}
+
+		doc.FIToFICstmrCdtTrf.CdtTrfTxInf = append(doc.FIToFICstmrCdtTrf.CdtTrfTxInf, tx)
+		ams[i] = tx.IntrBkSttlmAmt
+		ccys[tx.IntrBkSttlmAmt.Ccy] = true
+	}
+
+	var cs, err = sum(ams)
+	if err != nil {
+		return errors.Wrap(err, payment.ErrUnexpectedSysError, payment.ErrMDFnCall("sum", ams))
+	}
+
+	var hdr = &doc.FIToFICstmrCdtTrf.GrpHdr
+	hdr.MsgID = gh.MsgID
+	hdr.CreDtTm = gh.CreatedAt.Format(isoDateTimeLayout)
+	hdr.NbOfTxs = strconv.Itoa(len(pms))
+	hdr.CtrlSum = cs
+	hdr.SttlmInf.SttlmMtd = "CLRG"
+
+	if len(ccys) == 1 {
+		hdr.TtlIntrBkSttlmAmt = &amount{Ccy: ams[0].Ccy, Value: cs}
+	}
+
+	return writeDocument(w, doc)
+}
+
+func newPacs008Tx(p payment.Pymt) (pacs008Tx, error) {
+	var (
+		tx pacs008Tx
+		a  = p.Attributes
+	)
+
+	if a.PaymentType != "Credit" {
+		return tx, errors.New(
+			ErrInvalidPaymentType, payment.ErrMDVar("id", p.ID), payment.ErrMDField("PaymentType", a.PaymentType),
+		)
+	}
+
+	tx.PmtID.InstrID = a.PaymentID
+	tx.PmtID.EndToEndID = a.EndToEndReference
+	if tx.PmtID.EndToEndID == "" {
+		tx.PmtID.EndToEndID = notProvided
+	}
+
+	if p.ID != uuid.Nil {
+		tx.PmtID.TxID = p.ID.String()
+	}
+
+	tx.PmtTpInf = newPaymentTypeInfo(a)
+
+	tx.IntrBkSttlmAmt = newAmount(a.Amount, a.Currency)
+	tx.IntrBkSttlmDt = a.ProcessingDate
+
+	if a.Fx.OriginalAmount != "" || a.Fx.OriginalCurrency != "" {
+		tx.InstdAmt = &amount{Ccy: a.Fx.OriginalCurrency, Value: a.Fx.OriginalAmount}
+	}
+
+	tx.XchgRate = a.Fx.ExchangeRate
+	if a.Fx.ContractReference != "" {
+		tx.InstrForNxtAgt = append(tx.InstrForNxtAgt, struct {
+			InstrInf string `xml:"InstrInf"`
+		}{InstrInf: fxInstructionPrefix + a.Fx.ContractReference})
+	}
+
+	tx.Dbtr, tx.DbtrAcct, tx.DbtrAgt = newPartyElems(a.DebtorParty)
+	tx.Cdtr, tx.CdtrAcct, tx.CdtrAgt = newPartyElems(a.BeneficiaryParty)
+	tx.IntrmyAgt1, tx.IntrmyAgt1Acct = newIntermediaryElems(a.SponsorParty)
+
+	var ci = a.ChargesInformation
+	tx.ChrgBr = ci.BearerCode
+	for _, sc := range ci.SenderCharges {
+		tx.ChrgsInf = append(tx.ChrgsInf, struct {
+			Amt amount `xml:"Amt"`
+			Agt agent  `xml:"Agt"`
+		}{Amt: newAmount(sc.Amount, sc.Currency), Agt: tx.DbtrAgt})
+	}
+
+	if ci.ReceiverChargesAmount != 0 || ci.ReceiverChargesCurrency != "" {
+		tx.ChrgsInf = append(tx.ChrgsInf, struct {
+			Amt amount `xml:"Amt"`
+			Agt agent  `xml:"Agt"`
+		}{Amt: newAmount(ci.ReceiverChargesAmount, ci.ReceiverChargesCurrency), Agt: tx.CdtrAgt})
+	}
+
+	tx.Purp = proprietary(a.PaymentPurpose)
+	tx.RmtInf = newRemittanceInfo(a.Reference, a.NumericReference)
+
+	return tx, nil
+}
+
+// DecodePacs008 reads from r a pacs.008 FIToFICustomerCreditTransfer document
+// (version 08) and returns its group header and a payment of the organisation
+// orgID for each credit transfer transaction.
+//
+// The number of transactions and the control sum of the group header are
+// verified. The mapping of the elements to the payment fields is the reverse of
+// the one described in EncodePacs008, except that the charges of the creditor
+// agent are only the receiver charges when it isn't the debtor agent and the
+// elements which don't map to any field are ignored. The payments aren't
+// validated.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidAmount
+//
+// * ErrInvalidControlSum
+//
+// * ErrInvalidDocument
+//
+// * ErrInvalidNumberOfTxs
+func DecodePacs008(r io.Reader, orgID uuid.UUID) (GroupHeader, []payment.PymtUpsert, error) {
+	var (
+		doc pacs008Document
+		gh  GroupHeader
+	)
+
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return gh, nil, errors.Wrap(err, ErrInvalidDocument)
+	}
+
+	var (
+		hdr = doc.FIToFICstmrCdtTrf.GrpHdr
+		txs = doc.FIToFICstmrCdtTrf.CdtTrfTxInf
+	)
+
+	gh.MsgID = hdr.MsgID
+	if hdr.CreDtTm != "" {
+		var t, err = parseISODateTime(hdr.CreDtTm)
+		if err != nil {
+			return gh, nil, err
+		}
+
+		gh.CreatedAt = t
+	}
+
+	var (
+		pms = make([]payment.PymtUpsert, len(txs))
+		ams = make([]amount, len(txs))
+	)
+
+	for i, tx := range txs {
+		var p, err = tx.pymt(orgID)
+		if err != nil {
+			var c, _ = errors.GetCode(err)
+			return gh, nil, errors.Wrap(err, c, payment.ErrMDFact("tx", i))
+		}
+
+		pms[i] = p
+		ams[i] = tx.IntrBkSttlmAmt
+	}
+
+	if err := verifyGroup(hdr.NbOfTxs, hdr.CtrlSum, ams); err != nil {
+		return gh, nil, err
+	}
+
+	return gh, pms, nil
+}
+
+func (tx pacs008Tx) pymt(orgID uuid.UUID) (payment.PymtUpsert, error) {
+	var (
+		p = payment.PymtUpsert{Type: "Payment", OrgID: orgID}
+		a = &p.Attributes
+	)
+
+	a.PaymentID = tx.PmtID.InstrID
+	if tx.PmtID.EndToEndID != notProvided {
+		a.EndToEndReference = tx.PmtID.EndToEndID
+	}
+
+	a.PaymentType = "Credit"
+	tx.PmtTpInf.setAttrs(a)
+
+	var amt, err = tx.IntrBkSttlmAmt.float()
+	if err != nil {
+		return p, err
+	}
+
+	a.Amount = amt
+	a.Currency = tx.IntrBkSttlmAmt.Ccy
+	a.ProcessingDate = tx.IntrBkSttlmDt
+
+	if tx.InstdAmt != nil {
+		a.Fx.OriginalAmount = tx.InstdAmt.Value
+		a.Fx.OriginalCurrency = tx.InstdAmt.Ccy
+	}
+
+	a.Fx.ExchangeRate = tx.XchgRate
+	for _, ins := range tx.InstrForNxtAgt {
+		if strings.HasPrefix(ins.InstrInf, fxInstructionPrefix) {
+			a.Fx.ContractReference = strings.TrimPrefix(ins.InstrInf, fxInstructionPrefix)
+		}
+	}
+
+	if a.DebtorParty, err = party(&tx.Dbtr, tx.DbtrAcct, tx.DbtrAgt); err != nil {
+		return p, err
+	}
+
+	if a.BeneficiaryParty, err = party(&tx.Cdtr, tx.CdtrAcct, tx.CdtrAgt); err != nil {
+		return p, err
+	}
+
+	if a.SponsorParty, err = intermediaryParty(tx.IntrmyAgt1, tx.IntrmyAgt1Acct); err != nil {
+		return p, err
+	}
+
+	var ci = &a.ChargesInformation
+	ci.BearerCode = tx.ChrgBr
+	for _, c := range tx.ChrgsInf {
+		var amt, err = c.Amt.float()
+		if err != nil {
+			return p, err
+		}
+
+		if sameAgent(c.Agt, tx.CdtrAgt) && !sameAgent(c.Agt, tx.DbtrAgt) {
+			ci.ReceiverChargesAmount = amt
+			ci.ReceiverChargesCurrency = c.Amt.Ccy
+			continue
+		}
+
+		ci.SenderCharges = append(ci.SenderCharges, payment.Charge{Amount: amt, Currency: c.Amt.Ccy})
+	}
+
+	a.PaymentPurpose = tx.Purp.value()
+	a.Reference, a.NumericReference = tx.RmtInf.references()
+
+	return p, nil
+}
+
+// parseISODateTime parses the ISO 20022 date and time s.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidDocument
+func parseISODateTime(s string) (time.Time, error) {
+	var t, err = time.Parse(isoDateTimeLayout, s)
+	if err != nil {
+		// The time zone is optional
+		if t, err = time.Parse("2006-01-02T15:04:05", s); err != nil {
+			return t, errors.Wrap(err, ErrInvalidDocument, payment.ErrMDVar("date_time", s))
+		}
+	}
+
+	return t, nil
+}
+
+// writeDocument writes to w the XML declaration and the XML encoding of doc.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedOSError
+//
+// * payment.ErrUnexpectedSysError
+func writeDocument(w io.Writer, doc interface{}) error {
+	var b, err = xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.Wrap(err, payment.ErrUnexpectedOSError)
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return errors.Wrap(err, payment.ErrUnexpectedOSError)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package iso20022_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/iso20022"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestEncodePacs008_DecodePacs008(t *testing.T) {
+	var (
+		orgID = testutil.NewUUID(t)
+		gh    = iso20022.GroupHeader{MsgID: "MSG-1", CreatedAt: time.Now().UTC().Truncate(time.Second)}
+		pms   = make([]payment.Pymt, 3)
+	)
+
+	for i := range pms {
+		pms[i] = payment.Pymt{
+			ID: testutil.NewUUID(t),
+			PymtUpsert: payment.PymtUpsert{
+				Type:       "Payment",
+				OrgID:      orgID,
+				Attributes: testutil.NewAttrs(t),
+			},
+		}
+	}
+
+	// Payment without sponsor, charges, foreign exchange and with IBAN and BIC
+	var a = &pms[2].Attributes
+	a.SponsorParty = payment.Party{}
+	a.ChargesInformation.SenderCharges = nil
+	a.ChargesInformation.ReceiverChargesAmount = 0
+	a.ChargesInformation.ReceiverChargesCurrency = ""
+	a.Fx.ContractReference, a.Fx.ExchangeRate, a.Fx.OriginalAmount, a.Fx.OriginalCurrency = "", "", "", ""
+	a.DebtorParty.AccountNumber, a.DebtorParty.AccountNumberCode = "GB29NWBK60161331926819", "IBAN"
+	a.DebtorParty.BankID, a.DebtorParty.BankIDCode = "NWBKGB2L", "SWBIC"
+	a.EndToEndReference = ""
+
+	var buf bytes.Buffer
+	var err = iso20022.EncodePacs008(&buf, gh, pms...)
+	require.NoError(t, err)
+
+	var doc = buf.String()
+	assert.Contains(t, doc, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08">`)
+	assert.Contains(t, doc, "<NbOfTxs>3</NbOfTxs>")
+	assert.Contains(t, doc, "<EndToEndId>NOTPROVIDED</EndToEndId>")
+	assert.Contains(t, doc, "<BICFI>NWBKGB2L</BICFI>")
+
+	dgh, dpms, err := iso20022.DecodePacs008(&buf, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, gh, dgh)
+	require.Len(t, dpms, len(pms))
+
+	for i, p := range pms {
+		assert.Equal(t, p.PymtUpsert, dpms[i], "payment %d", i)
+		assert.NoError(t, dpms[i].Validate(), "payment %d", i)
+	}
+}
+
+func TestEncodePacs008_error(t *testing.T) {
+	var p = payment.Pymt{ID: testutil.NewUUID(t), PymtUpsert: payment.PymtUpsert{Attributes: testutil.NewAttrs(t)}}
+	p.Attributes.PaymentType = "Debit"
+
+	var err = iso20022.EncodePacs008(&bytes.Buffer{}, iso20022.GroupHeader{}, p)
+	testutil.AssertError(t, err, iso20022.ErrInvalidPaymentType,
+		payment.ErrMDVar("id", p.ID), payment.ErrMDField("PaymentType", "Debit"),
+	)
+}
+
+func TestDecodePacs008_error(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08">
+  <FIToFICstmrCdtTrf>
+    <GrpHdr>
+      <MsgId>MSG-1</MsgId>
+      <CreDtTm>2019-02-28T10:00:00</CreDtTm>
+      <NbOfTxs>%s</NbOfTxs>
+      <CtrlSum>%s</CtrlSum>
+      <SttlmInf><SttlmMtd>CLRG</SttlmMtd></SttlmInf>
+    </GrpHdr>
+    <CdtTrfTxInf>
+      <PmtId><EndToEndId>E2E</EndToEndId></PmtId>
+      <IntrBkSttlmAmt Ccy="GBP">%s</IntrBkSttlmAmt>
+      <Dbtr><Nm>Debtor</Nm></Dbtr>
+      <DbtrAgt><FinInstnId><BICFI>NWBKGB2L</BICFI></FinInstnId></DbtrAgt>
+      <CdtrAgt><FinInstnId><BICFI>DEUTDEFF</BICFI></FinInstnId></CdtrAgt>
+      <Cdtr><Nm>Creditor</Nm></Cdtr>
+    </CdtTrfTxInf>
+  </FIToFICstmrCdtTrf>
+</Document>`
+
+	var orgID = testutil.NewUUID(t)
+	var tcases = []struct {
+		desc string
+		doc  string
+		code errors.Code
+	}{
+		{desc: "valid", doc: fmt.Sprintf(doc, "1", "10.1", "10.10")},
+		{desc: "number of transactions", doc: fmt.Sprintf(doc, "2", "10.1", "10.10"), code: iso20022.ErrInvalidNumberOfTxs},
+		{desc: "control sum", doc: fmt.Sprintf(doc, "1", "10.01", "10.10"), code: iso20022.ErrInvalidControlSum},
+		{desc: "amount", doc: fmt.Sprintf(doc, "1", "10.1", "ten"), code: iso20022.ErrInvalidAmount},
+		{desc: "other message", doc: strings.Replace(doc, "pacs.008", "pacs.009", 1), code: iso20022.ErrInvalidDocument},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var _, pms, err = iso20022.DecodePacs008(strings.NewReader(tc.doc), orgID)
+			if tc.code == nil {
+				require.NoError(t, err)
+				require.Len(t, pms, 1)
+				assert.Equal(t, 10.1, pms[0].Attributes.Amount)
+				assert.Equal(t, "NWBKGB2L", pms[0].Attributes.DebtorParty.BankID)
+				return
+			}
+
+			testutil.AssertError(t, err, tc.code)
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package iso20022
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// The list of XML namespaces of the customer credit transfer initiation and
+// payment status report documents.
+const (
+	Pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.09"
+	Pain002Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.002.001.10"
+)
+
+// The list of the statuses of the transactions and the groups of the status
+// reports.
+const (
+	StatusAccepted = "ACCP"
+	StatusPartial  = "PART"
+	StatusRejected = "RJCT"
+)
+
+type pain001Document struct {
+	XMLName          xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:pain.001.001.09 Document"`
+	CstmrCdtTrfInitn struct {
+		GrpHdr struct {
+			MsgID   string `xml:"MsgId"`
+			CreDtTm string `xml:"CreDtTm"`
+			NbOfTxs string `xml:"NbOfTxs"`
+			CtrlSum string `xml:"CtrlSum"`
+		} `xml:"GrpHdr"`
+		PmtInf []pain001PaymentInfo `xml:"PmtInf"`
+	} `xml:"CstmrCdtTrfInitn"`
+}
+
+type pain001PaymentInfo struct {
+	PmtInfID    string           `xml:"PmtInfId"`
+	PmtMtd      string           `xml:"PmtMtd"`
+	NbOfTxs     string           `xml:"NbOfTxs"`
+	CtrlSum     string           `xml:"CtrlSum"`
+	PmtTpInf    *paymentTypeInfo `xml:"PmtTpInf"`
+	ReqdExctnDt struct {
+		Dt   string `xml:"Dt"`
+		DtTm string `xml:"DtTm"`
+	} `xml:"ReqdExctnDt"`
+	Dbtr        partyID      `xml:"Dbtr"`
+	DbtrAcct    *cashAccount `xml:"DbtrAcct"`
+	DbtrAgt     agent        `xml:"DbtrAgt"`
+	ChrgBr      string       `xml:"ChrgBr"`
+	CdtTrfTxInf []pain001Tx  `xml:"CdtTrfTxInf"`
+}
+
+type pain001Tx struct {
+	PmtID struct {
+		InstrID    string `xml:"InstrId"`
+		EndToEndID string `xml:"EndToEndId"`
+	} `xml:"PmtId"`
+	PmtTpInf *paymentTypeInfo `xml:"PmtTpInf"`
+	Amt      struct {
+		InstdAmt *amount `xml:"InstdAmt"`
+	} `xml:"Amt"`
+	XchgRateInf *struct {
+		XchgRate string `xml:"XchgRate"`
+		CtrctID  string `xml:"CtrctId"`
+	} `xml:"XchgRateInf"`
+	ChrgBr         string             `xml:"ChrgBr"`
+	IntrmyAgt1     *agent             `xml:"IntrmyAgt1"`
+	IntrmyAgt1Acct *cashAccount       `xml:"IntrmyAgt1Acct"`
+	CdtrAgt        agent              `xml:"CdtrAgt"`
+	Cdtr           partyID            `xml:"Cdtr"`
+	CdtrAcct       *cashAccount       `xml:"CdtrAcct"`
+	Purp           *codeOrProprietary `xml:"Purp"`
+	RmtInf         *remittanceInfo    `xml:"RmtInf"`
+}
+
+// StatusReport is the status of the credit transfer transactions of an
+// imported pain.001 document, which can be encoded as a pain.002 document by
+// EncodePain002.
+type StatusReport struct {
+	OrgnlMsgID   string
+	OrgnlNbOfTxs int
+	Txs          []TxStatus
+}
+
+// GroupStatus returns StatusAccepted if all the transactions have been
+// accepted, StatusRejected if all of them have been rejected, otherwise
+// StatusPartial.
+func (sr StatusReport) GroupStatus() string {
+	var accepted int
+	for _, tx := range sr.Txs {
+		if tx.Status == StatusAccepted {
+			accepted++
+		}
+	}
+
+	switch accepted {
+	case len(sr.Txs):
+		return StatusAccepted
+	case 0:
+		return StatusRejected
+	}
+
+	return StatusPartial
+}
+
+// TxStatus is the status of a credit transfer transaction of an imported
+// document.
+type TxStatus struct {
+	PmtInfID   string
+	InstrID    string
+	EndToEndID string
+	// Status is StatusAccepted or StatusRejected.
+	Status string
+	// ID is the ID of the created payment when the transaction is accepted.
+	ID uuid.UUID
+	// Reason is the error code of the rejection when the transaction is rejected.
+	Reason errors.Code
+}
+
+// ImportPain001 reads from r a pain.001 CustomerCreditTransferInitiation
+// document (version 09) and creates through svc a payment of the organisation
+// orgID for each credit transfer transaction, returning the status of each of
+// them.
+//
+// The number of transactions and the control sums of the group header and the
+// payment information blocks are verified before creating any payment. The
+// transactions are rejected when their payment information block isn't of
+// credit transfers (i.e. the payment method isn't TRF) or their payments aren't
+// valid, the rejection reason is the error code returned by
+// payment.PymtUpsert.Validate or svc.Create.
+//
+// Each payment is created with an idempotency key composed by the message ID,
+// the payment information ID and the position of the transaction, hence
+// importing the same document again doesn't create the payments twice.
+//
+// The mapping of the elements to the payment fields is the same as the one
+// described in EncodePacs008, except that the debtor, its account and its agent
+// and the requested execution date (i.e. the processing date) are the ones of
+// the payment information block, the payment ID is the instruction ID or the
+// end to end ID when the former is empty, the instructed amount is the amount
+// and the exchange rate information contains the foreign exchange rate and
+// contract reference. The payment type information and the charges bearer of
+// the transactions take precedence over the ones of their block.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidAmount
+//
+// * ErrInvalidControlSum
+//
+// * ErrInvalidDocument
+//
+// * ErrInvalidNumberOfTxs
+//
+// * Any error returned by svc.Create which isn't a payment.ErrInvalidArg... or
+// payment.ErrInvalidPayment... code. The returned report contains the status of
+// the transactions processed before the error.
+func ImportPain001(
+	ctx context.Context, svc payment.Service, r io.Reader, orgID uuid.UUID,
+) (StatusReport, error) {
+	var (
+		doc pain001Document
+		sr  StatusReport
+	)
+
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return sr, errors.Wrap(err, ErrInvalidDocument)
+	}
+
+	var hdr = doc.CstmrCdtTrfInitn.GrpHdr
+	sr.OrgnlMsgID = hdr.MsgID
+
+	var ams []amount
+	for _, pi := range doc.CstmrCdtTrfInitn.PmtInf {
+		var pams = make([]amount, 0, len(pi.CdtTrfTxInf))
+		for _, tx := range pi.CdtTrfTxInf {
+			if tx.Amt.InstdAmt == nil {
+				return sr, errors.New(ErrInvalidAmount, payment.ErrMDVar("pmt_inf_id", pi.PmtInfID))
+			}
+
+			pams = append(pams, *tx.Amt.InstdAmt)
+		}
+
+		if err := verifyGroup(pi.NbOfTxs, pi.CtrlSum, pams); err != nil {
+			var c, _ = errors.GetCode(err)
+			return sr, errors.Wrap(err, c, payment.ErrMDVar("pmt_inf_id", pi.PmtInfID))
+		}
+
+		ams = append(ams, pams...)
+	}
+
+	if err := verifyGroup(hdr.NbOfTxs, hdr.CtrlSum, ams); err != nil {
+		return sr, err
+	}
+
+	sr.OrgnlNbOfTxs = len(ams)
+	for _, pi := range doc.CstmrCdtTrfInitn.PmtInf {
+		for i, tx := range pi.CdtTrfTxInf {
+			var txs = TxStatus{
+				PmtInfID:   pi.PmtInfID,
+				InstrID:    tx.PmtID.InstrID,
+				EndToEndID: tx.PmtID.EndToEndID,
+				Status:     StatusRejected,
+			}
+
+			var id, err = importPain001Tx(ctx, svc, hdr.MsgID, pi, i, orgID)
+			if err != nil {
+				var c, _ = errors.GetCode(err)
+				if !isRejection(c) {
+					return sr, err
+				}
+
+				txs.Reason = c
+			} else {
+				txs.Status = StatusAccepted
+				txs.ID = id
+			}
+
+			sr.Txs = append(sr.Txs, txs)
+		}
+	}
+
+	return sr, nil
+}
+
+// verifyGroup verifies that the number of transactions nbOfTxs and the control
+// sum ctrlSum, which is optional, match with the amounts of the transactions.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidAmount
+//
+// * ErrInvalidControlSum
+//
+// * ErrInvalidNumberOfTxs
+func verifyGroup(nbOfTxs string, ctrlSum string, ams []amount) error {
+	if nbOfTxs != strconv.Itoa(len(ams)) {
+		return errors.New(
+			ErrInvalidNumberOfTxs, payment.ErrMDField("NbOfTxs", nbOfTxs), payment.ErrMDFact("txs", len(ams)),
+		)
+	}
+
+	if ctrlSum == "" {
+		return nil
+	}
+
+	var cs, err = sum(ams)
+	if err != nil {
+		return err
+	}
+
+	if !equalDecimals(cs, ctrlSum) {
+		return errors.New(ErrInvalidControlSum, payment.ErrMDField("CtrlSum", ctrlSum), payment.ErrMDFact("sum", cs))
+	}
+
+	return nil
+}
+
+// isRejection returns true if c is an error code which rejects a transaction,
+// rather than aborting the import.
+func isRejection(c errors.Code) bool {
+	switch c {
+	case ErrInvalidAmount,
+		ErrInvalidDocument,
+		ErrInvalidPaymentType,
+		payment.ErrInvalidArgIdempotencyKeyReused,
+		payment.ErrInvalidPaymentID,
+		payment.ErrInvalidPaymentOrgID,
+		payment.ErrInvalidPaymentType,
+		payment.ErrInvalidPaymentAttrAccountNumber,
+		payment.ErrInvalidPaymentAttrAmount,
+		payment.ErrInvalidPaymentAttrBankID,
+		payment.ErrInvalidPaymentAttrCode,
+		payment.ErrInvalidPaymentAttrCurrency,
+		payment.ErrInvalidPaymentAttrDate,
+		payment.ErrInvalidPaymentAttrFormat,
+		payment.ErrInvalidPaymentAttrPaymentID,
+		payment.ErrInvalidPaymentAttrRequired:
+		return true
+	}
+
+	return false
+}
+
+// importPain001Tx creates the payment of the i-th transaction of the payment
+// information block pi of the message msgID.
+func importPain001Tx(
+	ctx context.Context, svc payment.Service, msgID string, pi pain001PaymentInfo, i int, orgID uuid.UUID,
+) (uuid.UUID, error) {
+	if pi.PmtMtd != "TRF" {
+		return uuid.Nil, errors.New(
+			ErrInvalidPaymentType, payment.ErrMDVar("pmt_inf_id", pi.PmtInfID), payment.ErrMDField("PmtMtd", pi.PmtMtd),
+		)
+	}
+
+	var p, err = pi.pymt(i, orgID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := p.Validate(); err != nil {
+		return uuid.Nil, err
+	}
+
+	var key = fmt.Sprintf("pain.001/%s/%s/%d", msgID, pi.PmtInfID, i)
+	return svc.Create(payment.WithIdempotencyKey(ctx, key), p)
+}
+
+// pymt returns the payment of the i-th transaction of pi.
+func (pi pain001PaymentInfo) pymt(i int, orgID uuid.UUID) (payment.PymtUpsert, error) {
+	var (
+		tx = pi.CdtTrfTxInf[i]
+		p  = payment.PymtUpsert{Type: "Payment", OrgID: orgID}
+		a  = &p.Attributes
+	)
+
+	a.PaymentID = tx.PmtID.InstrID
+	if a.PaymentID == "" {
+		a.PaymentID = tx.PmtID.EndToEndID
+	}
+
+	if tx.PmtID.EndToEndID != notProvided {
+		a.EndToEndReference = tx.PmtID.EndToEndID
+	}
+
+	a.PaymentType = "Credit"
+	pi.PmtTpInf.setAttrs(a)
+	tx.PmtTpInf.setAttrs(a)
+
+	var amt, err = tx.Amt.InstdAmt.float()
+	if err != nil {
+		return p, err
+	}
+
+	a.Amount = amt
+	a.Currency = tx.Amt.InstdAmt.Ccy
+
+	a.ProcessingDate = pi.ReqdExctnDt.Dt
+	if a.ProcessingDate == "" && pi.ReqdExctnDt.DtTm != "" {
+		var t, err = parseISODateTime(pi.ReqdExctnDt.DtTm)
+		if err != nil {
+			return p, err
+		}
+
+		a.ProcessingDate = t.Format(isoDateLayout)
+	}
+
+	if tx.XchgRateInf != nil {
+		a.Fx.ExchangeRate = tx.XchgRateInf.XchgRate
+		a.Fx.ContractReference = tx.XchgRateInf.CtrctID
+	}
+
+	if a.DebtorParty, err = party(&pi.Dbtr, pi.DbtrAcct, pi.DbtrAgt); err != nil {
+		return p, err
+	}
+
+	if a.BeneficiaryParty, err = party(&tx.Cdtr, tx.CdtrAcct, tx.CdtrAgt); err != nil {
+		return p, err
+	}
+
+	if a.SponsorParty, err = intermediaryParty(tx.IntrmyAgt1, tx.IntrmyAgt1Acct); err != nil {
+		return p, err
+	}
+
+	a.ChargesInformation.BearerCode = pi.ChrgBr
+	if tx.ChrgBr != "" {
+		a.ChargesInformation.BearerCode = tx.ChrgBr
+	}
+
+	a.PaymentPurpose = tx.Purp.value()
+	a.Reference, a.NumericReference = tx.RmtInf.references()
+
+	return p, nil
+}
+
+type pain002Document struct {
+	XMLName        xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:pain.002.001.10 Document"`
+	CstmrPmtStsRpt struct {
+		GrpHdr struct {
+			MsgID   string `xml:"MsgId"`
+			CreDtTm string `xml:"CreDtTm"`
+		} `xml:"GrpHdr"`
+		OrgnlGrpInfAndSts struct {
+			OrgnlMsgID   string `xml:"OrgnlMsgId"`
+			OrgnlMsgNmID string `xml:"OrgnlMsgNmId"`
+			OrgnlNbOfTxs string `xml:"OrgnlNbOfTxs"`
+			GrpSts       string `xml:"GrpSts"`
+		} `xml:"OrgnlGrpInfAndSts"`
+		OrgnlPmtInfAndSts []pain002PaymentInfoStatus `xml:"OrgnlPmtInfAndSts,omitempty"`
+	} `xml:"CstmrPmtStsRpt"`
+}
+
+type pain002PaymentInfoStatus struct {
+	OrgnlPmtInfID string            `xml:"OrgnlPmtInfId"`
+	TxInfAndSts   []pain002TxStatus `xml:"TxInfAndSts"`
+}
+
+type pain002TxStatus struct {
+	OrgnlInstrID    string                   `xml:"OrgnlInstrId,omitempty"`
+	OrgnlEndToEndID string                   `xml:"OrgnlEndToEndId,omitempty"`
+	TxSts           string                   `xml:"TxSts"`
+	StsRsnInf       *pain002StatusReasonInfo `xml:"StsRsnInf,omitempty"`
+}
+
+type pain002StatusReasonInfo struct {
+	Rsn      codeOrProprietary `xml:"Rsn"`
+	AddtlInf string            `xml:"AddtlInf,omitempty"`
+}
+
+// EncodePain002 writes to w the pain.002 CustomerPaymentStatusReport document
+// (version 10) identified by gh of the status report sr.
+//
+// The reason of the rejected transactions is a proprietary reason whose value is
+// the error code and its additional information is the message of the error
+// code, truncated to the maximum length of the element.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedOSError
+//
+// * payment.ErrUnexpectedSysError
+func EncodePain002(w io.Writer, gh GroupHeader, sr StatusReport) error {
+	var (
+		doc pain002Document
+		rpt = &doc.CstmrPmtStsRpt
+	)
+
+	rpt.GrpHdr.MsgID = gh.MsgID
+	rpt.GrpHdr.CreDtTm = gh.CreatedAt.Format(isoDateTimeLayout)
+	rpt.OrgnlGrpInfAndSts.OrgnlMsgID = sr.OrgnlMsgID
+	rpt.OrgnlGrpInfAndSts.OrgnlMsgNmID = "pain.001.001.09"
+	rpt.OrgnlGrpInfAndSts.OrgnlNbOfTxs = strconv.Itoa(sr.OrgnlNbOfTxs)
+	rpt.OrgnlGrpInfAndSts.GrpSts = sr.GroupStatus()
+
+	for _, tx := range sr.Txs {
+		var l = len(rpt.OrgnlPmtInfAndSts)
+		if l == 0 || rpt.OrgnlPmtInfAndSts[l-1].OrgnlPmtInfID != tx.PmtInfID {
+			rpt.OrgnlPmtInfAndSts = append(rpt.OrgnlPmtInfAndSts, pain002PaymentInfoStatus{OrgnlPmtInfID: tx.PmtInfID})
+			l++
+		}
+
+		var txs = pain002TxStatus{OrgnlInstrID: tx.InstrID, OrgnlEndToEndID: tx.EndToEndID, TxSts: tx.Status}
+		if tx.Reason != nil {
+			txs.StsRsnInf = &pain002StatusReasonInfo{
+				Rsn:      codeOrProprietary{Prtry: truncate(tx.Reason.String(), 35)},
+				AddtlInf: truncate(tx.Reason.Message(), 105),
+			}
+		}
+
+		var pis = &rpt.OrgnlPmtInfAndSts[l-1]
+		pis.TxInfAndSts = append(pis.TxInfAndSts, txs)
+	}
+
+	return writeDocument(w, doc)
+}
+
+// truncate returns s truncated to n runes.
+func truncate(s string, n int) string {
+	var rs = []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+
+	return string(rs[:n])
+}
@@ -0,0 +1,177 @@
+package iso20022_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/iso20022"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+const pain001Doc = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09">
+  <CstmrCdtTrfInitn>
+    <GrpHdr>
+      <MsgId>MSG-1</MsgId>
+      <CreDtTm>2019-02-27T10:00:00Z</CreDtTm>
+      <NbOfTxs>%s</NbOfTxs>
+      <CtrlSum>%s</CtrlSum>
+      <InitgPty><Nm>ACME</Nm></InitgPty>
+    </GrpHdr>
+    <PmtInf>
+      <PmtInfId>PMT-1</PmtInfId>
+      <PmtMtd>TRF</PmtMtd>
+      <NbOfTxs>2</NbOfTxs>
+      <CtrlSum>30.5</CtrlSum>
+      <PmtTpInf>
+        <SvcLvl><Prtry>FPS</Prtry></SvcLvl>
+        <LclInstrm><Prtry>ImmediatePayment</Prtry></LclInstrm>
+      </PmtTpInf>
+      <ReqdExctnDt><Dt>2019-02-28</Dt></ReqdExctnDt>
+      <Dbtr><Nm>Emelia Jane</Nm><PstlAdr><AdrLine>1 Acme Road</AdrLine></PstlAdr></Dbtr>
+      <DbtrAcct>
+        <Id><Othr><Id>66374958</Id><SchmeNm><Cd>BBAN</Cd></SchmeNm></Othr></Id>
+        <Nm>Emelia Jane Brown</Nm>
+      </DbtrAcct>
+      <DbtrAgt><FinInstnId><ClrSysMmbId><ClrSysId><Cd>GBDSC</Cd></ClrSysId><MmbId>089999</MmbId></ClrSysMmbId></FinInstnId></DbtrAgt>
+      <ChrgBr>SHAR</ChrgBr>
+      <CdtTrfTxInf>
+        <PmtId><InstrId>INSTR-1</InstrId><EndToEndId>E2E-1</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="GBP">10.50</InstdAmt></Amt>
+        <CdtrAgt><FinInstnId><ClrSysMmbId><ClrSysId><Cd>GBDSC</Cd></ClrSysId><MmbId>107999</MmbId></ClrSysMmbId></FinInstnId></CdtrAgt>
+        <Cdtr><Nm>Wilfred Owen</Nm></Cdtr>
+        <CdtrAcct><Id><Othr><Id>88837491</Id><SchmeNm><Cd>BBAN</Cd></SchmeNm></Othr></Id></CdtrAcct>
+        <RmtInf><Ustrd>Invoice 1</Ustrd></RmtInf>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <PmtId><InstrId>INSTR-2</InstrId><EndToEndId>E2E-2</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="XXX">20</InstdAmt></Amt>
+        <CdtrAgt><FinInstnId><BICFI>NWBKGB2L</BICFI></FinInstnId></CdtrAgt>
+        <Cdtr><Nm>Wilfred Owen</Nm></Cdtr>
+        <CdtrAcct><Id><IBAN>GB29NWBK60161331926819</IBAN></Id></CdtrAcct>
+      </CdtTrfTxInf>
+    </PmtInf>
+    <PmtInf>
+      <PmtInfId>PMT-2</PmtInfId>
+      <PmtMtd>CHK</PmtMtd>
+      <NbOfTxs>1</NbOfTxs>
+      <ReqdExctnDt><DtTm>2019-02-28T09:00:00Z</DtTm></ReqdExctnDt>
+      <Dbtr><Nm>Emelia Jane</Nm></Dbtr>
+      <DbtrAgt><FinInstnId><BICFI>NWBKGB2L</BICFI></FinInstnId></DbtrAgt>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-3</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="GBP">1</InstdAmt></Amt>
+        <CdtrAgt><FinInstnId><BICFI>NWBKGB2L</BICFI></FinInstnId></CdtrAgt>
+        <Cdtr><Nm>Wilfred Owen</Nm></Cdtr>
+      </CdtTrfTxInf>
+    </PmtInf>
+  </CstmrCdtTrfInitn>
+</Document>`
+
+func TestImportPain001(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		orgID = testutil.NewUUID(t)
+		id    = testutil.NewUUID(t)
+		pms   []payment.PymtUpsert
+		keys  []string
+		svc   = &testutil.SvcStub{
+			CreateFn: func(ctx context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
+				var k, _, err = payment.IdempotencyKey(ctx)
+				require.NoError(t, err)
+
+				pms = append(pms, p)
+				keys = append(keys, k)
+				return id, nil
+			},
+		}
+	)
+
+	var sr, err = iso20022.ImportPain001(ctx, svc, strings.NewReader(fmt.Sprintf(pain001Doc, "3", "31.5")), orgID)
+	require.NoError(t, err)
+
+	assert.Equal(t, iso20022.StatusReport{
+		OrgnlMsgID:   "MSG-1",
+		OrgnlNbOfTxs: 3,
+		Txs: []iso20022.TxStatus{
+			{PmtInfID: "PMT-1", InstrID: "INSTR-1", EndToEndID: "E2E-1", Status: iso20022.StatusAccepted, ID: id},
+			{
+				PmtInfID: "PMT-1", InstrID: "INSTR-2", EndToEndID: "E2E-2", Status: iso20022.StatusRejected,
+				Reason: payment.ErrInvalidPaymentAttrCurrency,
+			},
+			{
+				PmtInfID: "PMT-2", EndToEndID: "E2E-3", Status: iso20022.StatusRejected,
+				Reason: iso20022.ErrInvalidPaymentType,
+			},
+		},
+	}, sr)
+	assert.Equal(t, iso20022.StatusPartial, sr.GroupStatus())
+	assert.Equal(t, []string{"pain.001/MSG-1/PMT-1/0"}, keys)
+
+	var exp = payment.PymtUpsert{Type: "Payment", OrgID: orgID}
+	exp.Attributes = payment.Attrs{
+		Amount:            10.5,
+		Currency:          "GBP",
+		EndToEndReference: "E2E-1",
+		PaymentID:         "INSTR-1",
+		PaymentScheme:     "FPS",
+		PaymentType:       "Credit",
+		ProcessingDate:    "2019-02-28",
+		Reference:         "Invoice 1",
+		SchemePaymentType: "ImmediatePayment",
+		BeneficiaryParty: payment.Party{
+			AccountNumber: "88837491", AccountNumberCode: "BBAN", BankID: "107999", BankIDCode: "GBDSC",
+			Name: "Wilfred Owen",
+		},
+		DebtorParty: payment.Party{
+			AccountName: "Emelia Jane Brown", AccountNumber: "66374958", AccountNumberCode: "BBAN",
+			Address: "1 Acme Road", BankID: "089999", BankIDCode: "GBDSC", Name: "Emelia Jane",
+		},
+	}
+	exp.Attributes.ChargesInformation.BearerCode = "SHAR"
+	assert.Equal(t, []payment.PymtUpsert{exp}, pms)
+
+	t.Run("pain.002", func(t *testing.T) {
+		var buf bytes.Buffer
+		var err = iso20022.EncodePain002(&buf, iso20022.GroupHeader{MsgID: "RPT-1", CreatedAt: time.Now()}, sr)
+		require.NoError(t, err)
+
+		var doc = buf.String()
+		assert.Contains(t, doc, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.002.001.10">`)
+		assert.Contains(t, doc, "<OrgnlMsgId>MSG-1</OrgnlMsgId>")
+		assert.Contains(t, doc, "<GrpSts>PART</GrpSts>")
+		assert.Equal(t, 2, strings.Count(doc, "<OrgnlPmtInfAndSts>"))
+		assert.Contains(t, doc, "<Prtry>InvalidPaymentAttrCurrency</Prtry>")
+		assert.Equal(t, 1, strings.Count(doc, "<TxSts>ACCP</TxSts>"))
+		assert.Equal(t, 2, strings.Count(doc, "<TxSts>RJCT</TxSts>"))
+	})
+
+	t.Run("error: group header", func(t *testing.T) {
+		var _, err = iso20022.ImportPain001(ctx, svc, strings.NewReader(fmt.Sprintf(pain001Doc, "2", "31.5")), orgID)
+		testutil.AssertError(t, err, iso20022.ErrInvalidNumberOfTxs, payment.ErrMDField("NbOfTxs", "2"))
+
+		_, err = iso20022.ImportPain001(ctx, svc, strings.NewReader(fmt.Sprintf(pain001Doc, "3", "31")), orgID)
+		testutil.AssertError(t, err, iso20022.ErrInvalidControlSum, payment.ErrMDField("CtrlSum", "31"))
+	})
+
+	t.Run("error: service", func(t *testing.T) {
+		var svc = &testutil.SvcStub{
+			CreateFn: func(context.Context, payment.PymtUpsert) (uuid.UUID, error) {
+				return uuid.Nil, errors.New(payment.ErrAbortedOperation)
+			},
+		}
+
+		var sr, err = iso20022.ImportPain001(ctx, svc, strings.NewReader(fmt.Sprintf(pain001Doc, "3", "31.5")), orgID)
+		testutil.AssertError(t, err, payment.ErrAbortedOperation)
+		assert.Empty(t, sr.Txs)
+	})
+}
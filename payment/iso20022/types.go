@@ -0,0 +1,415 @@
+// Package iso20022 converts payments to and from ISO 20022 XML messages.
+//
+// Only the elements of the messages which map to the information of the
+// payments are supported.
+package iso20022
+
+import (
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// The list of the layouts of the ISO 20022 date and time values.
+const (
+	isoDateLayout     = "2006-01-02"
+	isoDateTimeLayout = "2006-01-02T15:04:05Z07:00"
+)
+
+// notProvided is the value of the mandatory references which aren't provided.
+const notProvided = "NOTPROVIDED"
+
+// GroupHeader contains the identification of a message.
+type GroupHeader struct {
+	MsgID     string
+	CreatedAt time.Time
+}
+
+// amount is an ActiveCurrencyAndAmount or ActiveOrHistoricCurrencyAndAmount
+// element.
+type amount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+// newAmount creates the amount a of the currency ccy with the decimal places of
+// the currency.
+func newAmount(a float64, ccy string) amount {
+	var dp = -1
+	if mu, ok := payment.CurrencyMinorUnits(ccy); ok {
+		dp = int(mu)
+	}
+
+	return amount{Ccy: ccy, Value: strconv.FormatFloat(a, 'f', dp, 64)}
+}
+
+// float returns the value of a.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidAmount
+func (a amount) float() (float64, error) {
+	var f, err = strconv.ParseFloat(a.Value, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, ErrInvalidAmount, payment.ErrMDVar("amount", a.Value))
+	}
+
+	return f, nil
+}
+
+// sum returns the sum of the values of the amounts ams, which must be decimal
+// numbers, formatted with the maximum number of decimal places of them.
+//
+// The sum is calculated with arbitrary precision for the sum of the control
+// sums not differing from the ones calculated from the documents.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidAmount
+func sum(ams []amount) (string, error) {
+	var (
+		s  = new(big.Rat)
+		dp int
+	)
+
+	for _, a := range ams {
+		var r, ok = new(big.Rat).SetString(a.Value)
+		if !ok {
+			return "", errors.New(ErrInvalidAmount, payment.ErrMDVar("amount", a.Value))
+		}
+
+		s.Add(s, r)
+
+		for i := len(a.Value) - 1; i >= 0; i-- {
+			if a.Value[i] == '.' {
+				if d := len(a.Value) - i - 1; d > dp {
+					dp = d
+				}
+
+				break
+			}
+		}
+	}
+
+	return s.FloatString(dp), nil
+}
+
+// equalDecimals returns true if a and b are the same decimal numbers.
+func equalDecimals(a string, b string) bool {
+	var ra, oka = new(big.Rat).SetString(a)
+	var rb, okb = new(big.Rat).SetString(b)
+
+	return oka && okb && ra.Cmp(rb) == 0
+}
+
+// codeOrProprietary is a choice element of a code or a proprietary value.
+type codeOrProprietary struct {
+	Cd    string `xml:"Cd,omitempty"`
+	Prtry string `xml:"Prtry,omitempty"`
+}
+
+func (c *codeOrProprietary) value() string {
+	if c == nil {
+		return ""
+	}
+
+	if c.Cd != "" {
+		return c.Cd
+	}
+
+	return c.Prtry
+}
+
+// proprietary returns a codeOrProprietary with v as proprietary value or nil if
+// v is empty.
+func proprietary(v string) *codeOrProprietary {
+	if v == "" {
+		return nil
+	}
+
+	return &codeOrProprietary{Prtry: v}
+}
+
+// postalAddress is a PostalAddress element.
+type postalAddress struct {
+	AdrLine []string `xml:"AdrLine,omitempty"`
+}
+
+func newPostalAddress(adr string) *postalAddress {
+	if adr == "" {
+		return nil
+	}
+
+	return &postalAddress{AdrLine: []string{adr}}
+}
+
+func (pa *postalAddress) address() string {
+	if pa == nil || len(pa.AdrLine) == 0 {
+		return ""
+	}
+
+	return pa.AdrLine[0]
+}
+
+// partyID is a PartyIdentification element.
+type partyID struct {
+	Nm      string         `xml:"Nm,omitempty"`
+	PstlAdr *postalAddress `xml:"PstlAdr,omitempty"`
+}
+
+// cashAccount is a CashAccount element.
+type cashAccount struct {
+	ID struct {
+		IBAN string            `xml:"IBAN,omitempty"`
+		Othr *genericAccountID `xml:"Othr,omitempty"`
+	} `xml:"Id"`
+	Tp *codeOrProprietary `xml:"Tp,omitempty"`
+	Nm string             `xml:"Nm,omitempty"`
+}
+
+// genericAccountID is a GenericAccountIdentification element.
+type genericAccountID struct {
+	ID      string             `xml:"Id"`
+	SchmeNm *codeOrProprietary `xml:"SchmeNm,omitempty"`
+}
+
+// agent is a BranchAndFinancialInstitutionIdentification element.
+type agent struct {
+	FinInstnID struct {
+		BICFI       string         `xml:"BICFI,omitempty"`
+		ClrSysMmbID *clrSysMmbID   `xml:"ClrSysMmbId,omitempty"`
+		Nm          string         `xml:"Nm,omitempty"`
+		PstlAdr     *postalAddress `xml:"PstlAdr,omitempty"`
+	} `xml:"FinInstnId"`
+}
+
+// clrSysMmbID is a ClearingSystemMemberIdentification element.
+type clrSysMmbID struct {
+	ClrSysID *codeOrProprietary `xml:"ClrSysId,omitempty"`
+	MmbID    string             `xml:"MmbId"`
+}
+
+// newPartyElems returns the elements which represent the party p: the party,
+// its account and its financial institution (agent).
+//
+// The account number is an IBAN when the account number code is IBAN, otherwise
+// it's an other identification whose scheme name is the account number code. The
+// bank ID is a BICFI when the bank ID code is SWBIC, otherwise it's a clearing
+// system member identification whose clearing system is the bank ID code. The
+// account type is a proprietary type, which is omitted when it's 0.
+func newPartyElems(p payment.Party) (partyID, *cashAccount, agent) {
+	var (
+		pty  = partyID{Nm: p.Name, PstlAdr: newPostalAddress(p.Address)}
+		acct *cashAccount
+		agt  agent
+	)
+
+	if p.AccountNumber != "" || p.AccountName != "" {
+		acct = &cashAccount{Nm: p.AccountName}
+		if p.AccountNumberCode == "IBAN" {
+			acct.ID.IBAN = p.AccountNumber
+		} else {
+			acct.ID.Othr = &genericAccountID{ID: p.AccountNumber}
+
+			switch p.AccountNumberCode {
+			case "":
+			case "BBAN":
+				acct.ID.Othr.SchmeNm = &codeOrProprietary{Cd: p.AccountNumberCode}
+			default:
+				acct.ID.Othr.SchmeNm = proprietary(p.AccountNumberCode)
+			}
+		}
+
+		if p.AccountType != 0 {
+			acct.Tp = proprietary(strconv.Itoa(p.AccountType))
+		}
+	}
+
+	if p.BankIDCode == "SWBIC" {
+		agt.FinInstnID.BICFI = p.BankID
+	} else if p.BankID != "" || p.BankIDCode != "" {
+		agt.FinInstnID.ClrSysMmbID = &clrSysMmbID{MmbID: p.BankID}
+
+		if p.BankIDCode != "" {
+			agt.FinInstnID.ClrSysMmbID.ClrSysID = &codeOrProprietary{Cd: p.BankIDCode}
+		}
+	}
+
+	return pty, acct, agt
+}
+
+// party returns the party represented by the elements pty, acct and agt, being
+// pty and acct optional.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidDocument
+func party(pty *partyID, acct *cashAccount, agt agent) (payment.Party, error) {
+	var p payment.Party
+	if pty != nil {
+		p.Name = pty.Nm
+		p.Address = pty.PstlAdr.address()
+	}
+
+	if acct != nil {
+		p.AccountName = acct.Nm
+		if acct.ID.IBAN != "" {
+			p.AccountNumber = acct.ID.IBAN
+			p.AccountNumberCode = "IBAN"
+		} else if acct.ID.Othr != nil {
+			p.AccountNumber = acct.ID.Othr.ID
+			p.AccountNumberCode = acct.ID.Othr.SchmeNm.value()
+		}
+
+		if acct.Tp != nil {
+			var at, err = strconv.Atoi(acct.Tp.value())
+			if err != nil {
+				return p, errors.Wrap(err, ErrInvalidDocument, payment.ErrMDVar("account_type", acct.Tp.value()))
+			}
+
+			p.AccountType = at
+		}
+	}
+
+	var fi = agt.FinInstnID
+	if fi.BICFI != "" {
+		p.BankID = fi.BICFI
+		p.BankIDCode = "SWBIC"
+	} else if fi.ClrSysMmbID != nil {
+		p.BankID = fi.ClrSysMmbID.MmbID
+		p.BankIDCode = fi.ClrSysMmbID.ClrSysID.value()
+	}
+
+	return p, nil
+}
+
+// newIntermediaryElems returns the agent and the account elements which
+// represent the intermediary party p (i.e. the sponsor party), whose name and
+// address are the ones of the financial institution, or nil if p is empty.
+func newIntermediaryElems(p payment.Party) (*agent, *cashAccount) {
+	if p == (payment.Party{}) {
+		return nil, nil
+	}
+
+	var pty, acct, agt = newPartyElems(p)
+	agt.FinInstnID.Nm = pty.Nm
+	agt.FinInstnID.PstlAdr = pty.PstlAdr
+
+	return &agt, acct
+}
+
+// intermediaryParty returns the party represented by the elements agt and acct
+// created by newIntermediaryElems, being both optional.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidDocument
+func intermediaryParty(agt *agent, acct *cashAccount) (payment.Party, error) {
+	if agt == nil {
+		return payment.Party{}, nil
+	}
+
+	var pty = partyID{Nm: agt.FinInstnID.Nm, PstlAdr: agt.FinInstnID.PstlAdr}
+	return party(&pty, acct, *agt)
+}
+
+// sameAgent returns true if a and b identify the same financial institution.
+func sameAgent(a agent, b agent) bool {
+	var fa, fb = a.FinInstnID, b.FinInstnID
+	if fa.BICFI != fb.BICFI || (fa.ClrSysMmbID == nil) != (fb.ClrSysMmbID == nil) {
+		return false
+	}
+
+	return fa.ClrSysMmbID == nil ||
+		(fa.ClrSysMmbID.MmbID == fb.ClrSysMmbID.MmbID &&
+			fa.ClrSysMmbID.ClrSysID.value() == fb.ClrSysMmbID.ClrSysID.value())
+}
+
+// paymentTypeInfo is a PaymentTypeInformation element, whose service level is
+// the payment scheme, its local instrument the scheme payment type and its
+// category purpose the scheme payment sub type.
+type paymentTypeInfo struct {
+	SvcLvl    *codeOrProprietary `xml:"SvcLvl,omitempty"`
+	LclInstrm *codeOrProprietary `xml:"LclInstrm,omitempty"`
+	CtgyPurp  *codeOrProprietary `xml:"CtgyPurp,omitempty"`
+}
+
+// newPaymentTypeInfo returns the payment type information of a or nil if a
+// doesn't have any of its values.
+func newPaymentTypeInfo(a payment.Attrs) *paymentTypeInfo {
+	if a.PaymentScheme == "" && a.SchemePaymentType == "" && a.SchemePaymentSubType == "" {
+		return nil
+	}
+
+	return &paymentTypeInfo{
+		SvcLvl:    proprietary(a.PaymentScheme),
+		LclInstrm: proprietary(a.SchemePaymentType),
+		CtgyPurp:  proprietary(a.SchemePaymentSubType),
+	}
+}
+
+// setAttrs sets the values of pti to a, when pti isn't nil.
+func (pti *paymentTypeInfo) setAttrs(a *payment.Attrs) {
+	if pti == nil {
+		return
+	}
+
+	a.PaymentScheme = pti.SvcLvl.value()
+	a.SchemePaymentType = pti.LclInstrm.value()
+	a.SchemePaymentSubType = pti.CtgyPurp.value()
+}
+
+// remittanceInfo is a RemittanceInformation element.
+type remittanceInfo struct {
+	Ustrd []string `xml:"Ustrd,omitempty"`
+	Strd  []struct {
+		CdtrRefInf struct {
+			Ref string `xml:"Ref"`
+		} `xml:"CdtrRefInf"`
+	} `xml:"Strd,omitempty"`
+}
+
+// newRemittanceInfo returns the remittance information of the reference and
+// the numeric reference of a payment or nil if both are empty.
+func newRemittanceInfo(ref string, numRef string) *remittanceInfo {
+	if ref == "" && numRef == "" {
+		return nil
+	}
+
+	var ri remittanceInfo
+	if ref != "" {
+		ri.Ustrd = []string{ref}
+	}
+
+	if numRef != "" {
+		ri.Strd = make([]struct {
+			CdtrRefInf struct {
+				Ref string `xml:"Ref"`
+			} `xml:"CdtrRefInf"`
+		}, 1)
+		ri.Strd[0].CdtrRefInf.Ref = numRef
+	}
+
+	return &ri
+}
+
+// references returns the reference and the numeric reference of ri.
+func (ri *remittanceInfo) references() (string, string) {
+	if ri == nil {
+		return "", ""
+	}
+
+	var ref, numRef string
+	if len(ri.Ustrd) > 0 {
+		ref = ri.Ustrd[0]
+	}
+
+	if len(ri.Strd) > 0 {
+		numRef = ri.Strd[0].CdtrRefInf.Ref
+	}
+
+	return ref, numRef
+}
@@ -0,0 +1,399 @@
+// Package logging provides a payment service decorator which logs each call to
+// the decorated service as a structured record.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/filter"
+	"go.fraixed.es/errors"
+)
+
+// The levels of the records.
+const (
+	LevelInfo  = "INFO"
+	LevelError = "ERROR"
+)
+
+type requestIDCtxKey struct{}
+
+// WithRequestID returns a copy of ctx which carries the request ID id, which is
+// logged in the records of the calls made with it, for correlating them with
+// the request which caused them.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx or empty if it doesn't carry
+// any.
+func RequestID(ctx context.Context) string {
+	var id, _ = ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// Config contains the parameters of the service returned by New. The zero value
+// of each field means to use its default value.
+type Config struct {
+	// SampleEvery makes to log only 1 of every SampleEvery successful calls. The
+	// calls which return an error are always logged. Default 1, which logs all
+	// of them.
+	SampleEvery uint32
+	// PaymentData makes to log the payment data passed to Create and Update and
+	// returned by Get. Default false.
+	PaymentData bool
+	// Unredacted makes to log the payment data without redacting it (see
+	// payment.Redact). It should only be used in development environments.
+	// Default false.
+	Unredacted bool
+	// Now returns the current time. Default time.Now.
+	Now func() time.Time
+}
+
+func (c Config) withDefaults() Config {
+	if c.SampleEvery == 0 {
+		c.SampleEvery = 1
+	}
+
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	return c
+}
+
+// Record is a record logged by the service returned by New. The JSON encoding
+// of the time, level and message use the same keys than the log/slog JSON
+// handler and the duration is encoded in nanoseconds, like it does, so the
+// records can be processed with the ones written by it.
+type Record struct {
+	Time      time.Time     `json:"time"`
+	Level     string        `json:"level"`
+	Msg       string        `json:"msg"`
+	Method    string        `json:"method"`
+	RequestID string        `json:"request_id,omitempty"`
+	OrgID     string        `json:"organisation_id,omitempty"`
+	PymtID    string        `json:"payment_id,omitempty"`
+	Version   *uint32       `json:"version,omitempty"`
+	Status    string        `json:"status,omitempty"`
+	Filter    string        `json:"filter,omitempty"`
+	PatchType string        `json:"patch_type,omitempty"`
+	Results   *int          `json:"results,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	ErrCode   string        `json:"error_code,omitempty"`
+	Pymt      interface{}   `json:"payment,omitempty"`
+}
+
+// New returns a payment.Service which delegates the operations to svc and
+// writes a record (see Record) per call, encoded in JSON and followed by a new
+// line, to w. w is never written concurrently.
+//
+// The organisation of the records is the scope carried by the context (see
+// payment.WithOrgScope and tenancy.WithOrgID) or, if it doesn't carry any, the
+// one of the payment when the method receives or returns it. The request ID is the one carried by the
+// context (see WithRequestID).
+//
+// The returned payment.Service also satisfies the payment.BatchCreator and
+// payment.ChangeFeed interfaces, delegating them to svc (see
+// payment.CreateBatch and payment.Changes). The records of CreateBatch have the
+// number of created payments as results and the calls to Changes aren't logged
+// because they last until its context is done.
+//
+// The errors returned by w are ignored, so logging never makes a call fail.
+// The methods return the same values than svc.
+func New(svc payment.Service, w io.Writer, cfg Config) payment.Service {
+	return &service{
+		svc: svc,
+		w:   w,
+		cfg: cfg.withDefaults(),
+	}
+}
+
+type service struct {
+	// calls is the first field because it's accessed atomically and it must be
+	// 64-bit aligned on 32-bit platforms.
+	calls uint64
+	svc   payment.Service
+	w     io.Writer
+	cfg   Config
+	mu    sync.Mutex
+}
+
+func (s *service) Aggregate(
+	ctx context.Context, f payment.Filter, a payment.Aggregation,
+) ([]payment.Aggregate, error) {
+	var r = s.start(ctx, "Aggregate")
+	r.Filter = filterString(f)
+
+	var aggs, err = s.svc.Aggregate(ctx, f, a)
+	if err == nil {
+		var n = len(aggs)
+		r.Results = &n
+	}
+
+	s.log(r, err, nil)
+	return aggs, err
+}
+
+func (s *service) Changes(ctx context.Context, fromSeq uint64) (<-chan payment.Event, <-chan error) {
+	return payment.Changes(ctx, s.svc, fromSeq)
+}
+
+func (s *service) Create(ctx context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
+	var (
+		r       = s.start(ctx, "Create")
+		id, err = s.svc.Create(ctx, p)
+	)
+
+	r.withOrgID(p.OrgID)
+	if id != uuid.Nil {
+		r.PymtID = id.String()
+	}
+
+	s.log(r, err, p)
+	return id, err
+}
+
+func (s *service) CreateBatch(ctx context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+	var r = s.start(ctx, "CreateBatch")
+
+	var ids, err = payment.CreateBatch(ctx, s.svc, ps)
+	if err == nil {
+		var n = len(ids)
+		r.Results = &n
+	}
+
+	s.log(r, err, nil)
+	return ids, err
+}
+
+func (s *service) Delete(ctx context.Context, id uuid.UUID, version uint32) error {
+	var r = s.start(ctx, "Delete")
+	r.PymtID = id.String()
+	r.Version = &version
+
+	var err = s.svc.Delete(ctx, id, version)
+	s.log(r, err, nil)
+	return err
+}
+
+func (s *service) Find(
+	ctx context.Context, f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk,
+) ([]payment.Pymt, error) {
+	var r = s.start(ctx, "Find")
+	r.Filter = filterString(f)
+
+	var ps, err = s.svc.Find(ctx, f, sl, st, c)
+	if err == nil {
+		var n = len(ps)
+		r.Results = &n
+	}
+
+	s.log(r, err, nil)
+	return ps, err
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID, sl payment.Selection) (payment.Pymt, error) {
+	var r = s.start(ctx, "Get")
+	r.PymtID = id.String()
+
+	var p, err = s.svc.Get(ctx, id, sl)
+	if err != nil {
+		s.log(r, err, nil)
+		return p, err
+	}
+
+	r.withOrgID(p.OrgID)
+	s.log(r, nil, p)
+	return p, nil
+}
+
+func (s *service) History(ctx context.Context, id uuid.UUID) ([]payment.StatusChange, error) {
+	var r = s.start(ctx, "History")
+	r.PymtID = id.String()
+
+	var scs, err = s.svc.History(ctx, id)
+	if err == nil {
+		var n = len(scs)
+		r.Results = &n
+	}
+
+	s.log(r, err, nil)
+	return scs, err
+}
+
+// Patch logs the type of p but not its document, which may contain sensitive
+// data.
+func (s *service) Patch(ctx context.Context, id uuid.UUID, version uint32, p payment.Patch) error {
+	var r = s.start(ctx, "Patch")
+	r.PymtID = id.String()
+	r.Version = &version
+	r.PatchType = p.Type().String()
+
+	var err = s.svc.Patch(ctx, id, version, p)
+	s.log(r, err, nil)
+	return err
+}
+
+func (s *service) Transition(
+	ctx context.Context, id uuid.UUID, version uint32, to payment.Status, reason string,
+) error {
+	var r = s.start(ctx, "Transition")
+	r.PymtID = id.String()
+	r.Version = &version
+	r.Status = to.String()
+
+	var err = s.svc.Transition(ctx, id, version, to, reason)
+	s.log(r, err, nil)
+	return err
+}
+
+func (s *service) Update(ctx context.Context, id uuid.UUID, version uint32, p payment.PymtUpsert) error {
+	var r = s.start(ctx, "Update")
+	r.PymtID = id.String()
+	r.Version = &version
+
+	var err = s.svc.Update(ctx, id, version, p)
+	r.withOrgID(p.OrgID)
+	s.log(r, err, p)
+	return err
+}
+
+// start returns the record of a call to the method m with ctx, setting the
+// fields which are known before the call.
+func (s *service) start(ctx context.Context, m string) *Record {
+	var r = &Record{
+		Time:      s.cfg.Now(),
+		Msg:       "payment service call",
+		Method:    m,
+		RequestID: RequestID(ctx),
+	}
+
+	if id, ok := payment.OrgScope(ctx); ok && id != uuid.Nil {
+		r.OrgID = id.String()
+	}
+
+	return r
+}
+
+// log finishes r with the result of the call, being err the returned error and
+// p the payment data, and writes it if it's sampled.
+func (s *service) log(r *Record, err error, p interface{}) {
+	r.Duration = s.cfg.Now().Sub(r.Time)
+	r.Level = LevelInfo
+	if err != nil {
+		r.Level = LevelError
+		r.ErrCode = errCode(err)
+	} else if (atomic.AddUint64(&s.calls, 1)-1)%uint64(s.cfg.SampleEvery) != 0 {
+		return
+	}
+
+	if s.cfg.PaymentData && p != nil {
+		r.Pymt = p
+		if !s.cfg.Unredacted {
+			r.Pymt = payment.Redact(p)
+		}
+	}
+
+	var b, merr = json.Marshal(r)
+	if merr != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.w.Write(append(b, '\n'))
+}
+
+// withOrgID sets id as the organisation of r if r doesn't have one and id isn't
+// uuid.Nil.
+func (r *Record) withOrgID(id uuid.UUID) {
+	if r.OrgID == "" && id != uuid.Nil {
+		r.OrgID = id.String()
+	}
+}
+
+// errCode returns the string representation of the code of err or "Unknown" if
+// err doesn't have a code.
+func errCode(err error) string {
+	var c, ok = errors.GetCode(err)
+	if !ok || c.String() == "" {
+		return "Unknown"
+	}
+
+	return c.String()
+}
+
+// filterString returns the string representation of f using the JSON names of
+// the fields of the payments.
+func filterString(f payment.Filter) string {
+	return filter.String(f, leafField, cmpString, logicalString)
+}
+
+func leafField(fl payment.FilterLeaf) string {
+	switch fl.(type) {
+	case payment.FilterLeafAmount:
+		return "attributes.amount"
+	case payment.FilterLeafType:
+		return "type"
+	case payment.FilterLeafID:
+		return "id"
+	case payment.FilterLeafOrgID:
+		return "organisation_id"
+	case payment.FilterLeafStatus:
+		return "status"
+	}
+
+	// This happens is that new filters have been added and this function has not
+	// been updated
+	return "unknown"
+}
+
+// cmpString returns the operator of c followed by v, quoted if it's a string
+// or a fmt.Stringer.
+func cmpString(c payment.FilterCmp, v interface{}) (string, bool) {
+	var op string
+	switch c {
+	case payment.FilterCmpEqual:
+		op = "="
+	case payment.FilterCmpNotEqual:
+		op = "!="
+	case payment.FilterCmpGreaterOrEqualThan:
+		op = ">="
+	case payment.FilterCmpGreaterThan:
+		op = ">"
+	case payment.FilterCmpLessOrEqualThan:
+		op = "<="
+	case payment.FilterCmpLessThan:
+		op = "<"
+	case payment.FilterCmpMatch:
+		op = "~"
+	}
+
+	switch v.(type) {
+	case nil:
+		return op + " null", true
+	case string, fmt.Stringer:
+		return fmt.Sprintf("%s %q", op, v), true
+	}
+
+	return fmt.Sprintf("%s %v", op, v), true
+}
+
+func logicalString(l payment.FilterLogical) string {
+	switch l {
+	case payment.FilterLogicalAnd:
+		return "AND"
+	case payment.FilterLogicalOr:
+		return "OR"
+	}
+
+	return ""
+}
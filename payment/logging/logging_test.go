@@ -0,0 +1,278 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/logging"
+	"github.com/ifraixedes/go-payments-api-example/payment/tenancy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestRequestID(t *testing.T) {
+	assert.Equal(t, "req-1", logging.RequestID(logging.WithRequestID(context.Background(), "req-1")))
+	assert.Equal(t, "", logging.RequestID(context.Background()))
+}
+
+func TestService(t *testing.T) {
+	var (
+		orgID  = testutil.NewUUID(t)
+		pid    = testutil.NewUUID(t)
+		ctx    = logging.WithRequestID(tenancy.WithOrgID(context.Background(), orgID), "req-1")
+		errSvc = errors.New(payment.ErrNotFound, payment.ErrMDVar("id", pid))
+		stub   = &testutil.SvcStub{
+			AggregateFn: func(context.Context, payment.Filter, payment.Aggregation) ([]payment.Aggregate, error) {
+				return make([]payment.Aggregate, 2), nil
+			},
+			CreateFn: func(context.Context, payment.PymtUpsert) (uuid.UUID, error) {
+				return pid, nil
+			},
+			CreateBatchFn: func(_ context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+				return make([]uuid.UUID, len(ps)), nil
+			},
+			DeleteFn: func(context.Context, uuid.UUID, uint32) error {
+				return errSvc
+			},
+			FindFn: func(
+				context.Context, payment.Filter, payment.Selection, payment.Sort, payment.Chunk,
+			) ([]payment.Pymt, error) {
+				return make([]payment.Pymt, 3), nil
+			},
+			HistoryFn: func(context.Context, uuid.UUID) ([]payment.StatusChange, error) {
+				return nil, errors.New(payment.ErrUnexpectedStoreError)
+			},
+			PatchFn: func(context.Context, uuid.UUID, uint32, payment.Patch) error {
+				return nil
+			},
+			TransitionFn: func(context.Context, uuid.UUID, uint32, payment.Status, string) error {
+				return nil
+			},
+		}
+	)
+
+	var (
+		now = time.Date(2026, 10, 19, 13, 0, 0, 0, time.UTC)
+		buf bytes.Buffer
+		svc = logging.New(stub, &buf, logging.Config{
+			Now: func() time.Time {
+				now = now.Add(time.Millisecond)
+				return now
+			},
+		})
+	)
+
+	var id, err = svc.Create(ctx, payment.PymtUpsert{Type: "Payment"})
+	require.NoError(t, err)
+	assert.Equal(t, pid, id)
+
+	err = svc.Delete(ctx, pid, 2)
+	assert.Equal(t, errSvc, err)
+
+	sf, err := payment.NewFilterByStatus(payment.FilterCmpEqual, payment.StatusPending)
+	require.NoError(t, err)
+	af, err := payment.NewFilterByAmount(payment.FilterCmpGreaterThan, 10.5)
+	require.NoError(t, err)
+	f, err := payment.NewFilter(payment.FilterLogicalAnd, sf, af)
+	require.NoError(t, err)
+	ps, err := svc.Find(ctx, f, payment.SelectAll(), payment.Sort{}, payment.Chunk{})
+	require.NoError(t, err)
+	assert.Len(t, ps, 3)
+
+	_, err = svc.History(context.Background(), pid)
+	testutil.AssertError(t, err, payment.ErrUnexpectedStoreError)
+
+	err = svc.Transition(ctx, pid, 2, payment.StatusSubmitted, "reviewed")
+	require.NoError(t, err)
+
+	pt, err := payment.NewPatch(payment.PatchMerge, []byte(`{"attributes":{"reference":"secret"}}`))
+	require.NoError(t, err)
+	err = svc.Patch(ctx, pid, 3, pt)
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "secret", "the patch document isn't logged")
+
+	aggs, err := svc.Aggregate(ctx, sf, payment.Aggregation{Fns: []payment.AggregateFn{payment.AggregateFnSum}})
+	require.NoError(t, err)
+	assert.Len(t, aggs, 2)
+
+	ids, err := svc.(payment.BatchCreator).CreateBatch(ctx, make([]payment.PymtUpsert, 3))
+	require.NoError(t, err)
+	assert.Len(t, ids, 3)
+
+	var (
+		two     = 2
+		three   = 3
+		v2      = uint32(2)
+		records = decodeRecords(t, &buf)
+	)
+	require.Len(t, records, 8)
+	assert.Equal(t, logging.Record{
+		Time:      time.Date(2026, 10, 19, 13, 0, 0, int(time.Millisecond), time.UTC),
+		Level:     logging.LevelInfo,
+		Msg:       "payment service call",
+		Method:    "Create",
+		RequestID: "req-1",
+		OrgID:     orgID.String(),
+		PymtID:    pid.String(),
+		Duration:  time.Millisecond,
+	}, records[0])
+
+	assert.Equal(t, "Delete", records[1].Method)
+	assert.Equal(t, logging.LevelError, records[1].Level)
+	assert.Equal(t, "NotFound", records[1].ErrCode)
+	assert.Equal(t, pid.String(), records[1].PymtID)
+	assert.Equal(t, &v2, records[1].Version)
+
+	assert.Equal(t, "Find", records[2].Method)
+	assert.Equal(t, `status = "Pending" AND attributes.amount > 10.5`, records[2].Filter)
+	assert.Equal(t, &three, records[2].Results)
+
+	assert.Equal(t, "History", records[3].Method)
+	assert.Equal(t, "UnexpectedStoreError", records[3].ErrCode)
+	assert.Equal(t, "", records[3].RequestID)
+	assert.Equal(t, "", records[3].OrgID)
+	assert.Nil(t, records[3].Results)
+
+	assert.Equal(t, "Transition", records[4].Method)
+	assert.Equal(t, &v2, records[4].Version)
+	assert.Equal(t, "Submitted", records[4].Status)
+	assert.Equal(t, logging.LevelInfo, records[4].Level)
+
+	assert.Equal(t, "Patch", records[5].Method)
+	assert.Equal(t, "merge", records[5].PatchType)
+
+	assert.Equal(t, "Aggregate", records[6].Method)
+	assert.Equal(t, `status = "Pending"`, records[6].Filter)
+	assert.Equal(t, &two, records[6].Results)
+
+	assert.Equal(t, "CreateBatch", records[7].Method)
+	assert.Equal(t, orgID.String(), records[7].OrgID)
+	assert.Equal(t, &three, records[7].Results)
+}
+
+func TestService_sampling(t *testing.T) {
+	var (
+		calls int
+		stub  = &testutil.SvcStub{
+			DeleteFn: func(context.Context, uuid.UUID, uint32) error {
+				calls++
+				if calls == 2 {
+					return errors.New(payment.ErrNotFound)
+				}
+
+				return nil
+			},
+		}
+		buf bytes.Buffer
+		svc = logging.New(stub, &buf, logging.Config{SampleEvery: 3})
+	)
+
+	for i := 0; i < 8; i++ {
+		_ = svc.Delete(context.Background(), testutil.NewUUID(t), 0)
+	}
+
+	var records = decodeRecords(t, &buf)
+	// The 1st, 4th and 7th successful calls plus the failed one
+	require.Len(t, records, 4)
+	assert.Equal(t, logging.LevelInfo, records[0].Level)
+	assert.Equal(t, logging.LevelError, records[1].Level)
+	assert.Equal(t, logging.LevelInfo, records[2].Level)
+	assert.Equal(t, logging.LevelInfo, records[3].Level)
+}
+
+func TestService_paymentData(t *testing.T) {
+	var (
+		orgID = testutil.NewUUID(t)
+		p     = payment.PymtUpsert{Type: "Payment", OrgID: orgID, Attributes: testutil.NewAttrs(t)}
+		stub  = &testutil.SvcStub{
+			UpdateFn: func(context.Context, uuid.UUID, uint32, payment.PymtUpsert) error {
+				return nil
+			},
+			GetFn: func(_ context.Context, id uuid.UUID, _ payment.Selection) (payment.Pymt, error) {
+				return payment.Pymt{ID: id, PymtUpsert: p}, nil
+			},
+		}
+	)
+
+	var tcases = []struct {
+		desc   string
+		cfg    logging.Config
+		assert func(t *testing.T, out string)
+	}{
+		{
+			desc: "not logged",
+			cfg:  logging.Config{},
+			assert: func(t *testing.T, out string) {
+				assert.NotContains(t, out, `"payment"`)
+				assert.NotContains(t, out, p.Attributes.DebtorParty.Name)
+			},
+		},
+		{
+			desc: "redacted",
+			cfg:  logging.Config{PaymentData: true},
+			assert: func(t *testing.T, out string) {
+				assert.Contains(t, out, `"payment"`)
+				assert.Contains(t, out, p.Attributes.Reference)
+				assert.Contains(t, out, `"account_number":"****`+p.Attributes.DebtorParty.AccountNumber[4:]+`"`)
+				assert.NotContains(t, out, p.Attributes.DebtorParty.AccountNumber)
+				assert.NotContains(t, out, p.Attributes.BeneficiaryParty.Name)
+			},
+		},
+		{
+			desc: "unredacted",
+			cfg:  logging.Config{PaymentData: true, Unredacted: true},
+			assert: func(t *testing.T, out string) {
+				assert.Contains(t, out, p.Attributes.DebtorParty.AccountNumber)
+				assert.Contains(t, out, p.Attributes.BeneficiaryParty.Name)
+			},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				buf bytes.Buffer
+				svc = logging.New(stub, &buf, tc.cfg)
+				id  = testutil.NewUUID(t)
+			)
+
+			require.NoError(t, svc.Update(context.Background(), id, 1, p))
+			var _, err = svc.Get(context.Background(), id, payment.SelectAll())
+			require.NoError(t, err)
+
+			var records = decodeRecords(t, bytes.NewReader(buf.Bytes()))
+			require.Len(t, records, 2)
+			for _, r := range records {
+				assert.Equal(t, orgID.String(), r.OrgID)
+			}
+
+			tc.assert(t, buf.String())
+		})
+	}
+}
+
+func decodeRecords(t *testing.T, r io.Reader) []logging.Record {
+	var (
+		records []logging.Record
+		dec     = json.NewDecoder(r)
+	)
+	for dec.More() {
+		var rec logging.Record
+		require.NoError(t, dec.Decode(&rec))
+		rec.Pymt = nil
+		records = append(records, rec)
+	}
+
+	return records
+}
@@ -0,0 +1,250 @@
+// Package metrics provides a payment service decorator and an HTTP handler
+// wrapper which record metrics of the calls and requests, and a Registry which
+// exposes them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// The names of the metrics recorded by the service returned by New and the
+// handler returned by WrapHandler.
+const (
+	MetricServiceCalls        = "payment_service_calls_total"
+	MetricServiceCallDuration = "payment_service_call_duration_seconds"
+	MetricHTTPRequests        = "http_requests_total"
+	MetricHTTPRequestDuration = "http_request_duration_seconds"
+)
+
+const (
+	codeLabelOK      = "OK"
+	codeLabelUnknown = "Unknown"
+)
+
+// New returns a payment.Service which delegates the operations to svc and
+// records, in reg, the following metrics of each call:
+//
+// * MetricServiceCalls - A counter with the labels "method" and "code", being
+// code the string representation of the code of the returned error (e.g.
+// "NotFound"), "OK" if it's nil or "Unknown" if it doesn't have a code.
+//
+// * MetricServiceCallDuration - A histogram, with DefaultBuckets, with the
+// label "method".
+//
+// The returned payment.Service also satisfies the payment.BatchCreator and
+// payment.ChangeFeed interfaces, delegating them to svc (see
+// payment.CreateBatch and payment.Changes). The calls to Changes aren't
+// recorded because they last until its context is done.
+//
+// The methods return the same values than svc.
+func New(svc payment.Service, reg *Registry) payment.Service {
+	return service{
+		svc: svc,
+		calls: reg.counter(
+			MetricServiceCalls, "Number of calls to the payment service by method and returned error code.",
+			"method", "code",
+		),
+		duration: reg.histogram(
+			MetricServiceCallDuration, "Duration of the calls to the payment service by method.",
+			DefaultBuckets, "method",
+		),
+	}
+}
+
+type service struct {
+	svc      payment.Service
+	calls    *family
+	duration *family
+}
+
+func (s service) Aggregate(
+	ctx context.Context, f payment.Filter, a payment.Aggregation,
+) ([]payment.Aggregate, error) {
+	var start = time.Now()
+	var aggs, err = s.svc.Aggregate(ctx, f, a)
+	s.record("Aggregate", start, err)
+	return aggs, err
+}
+
+func (s service) Changes(ctx context.Context, fromSeq uint64) (<-chan payment.Event, <-chan error) {
+	return payment.Changes(ctx, s.svc, fromSeq)
+}
+
+func (s service) Create(ctx context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
+	var start = time.Now()
+	var id, err = s.svc.Create(ctx, p)
+	s.record("Create", start, err)
+	return id, err
+}
+
+func (s service) CreateBatch(ctx context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+	var start = time.Now()
+	var ids, err = payment.CreateBatch(ctx, s.svc, ps)
+	s.record("CreateBatch", start, err)
+	return ids, err
+}
+
+func (s service) Delete(ctx context.Context, id uuid.UUID, version uint32) error {
+	var start = time.Now()
+	var err = s.svc.Delete(ctx, id, version)
+	s.record("Delete", start, err)
+	return err
+}
+
+func (s service) Find(
+	ctx context.Context, f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk,
+) ([]payment.Pymt, error) {
+	var start = time.Now()
+	var ps, err = s.svc.Find(ctx, f, sl, st, c)
+	s.record("Find", start, err)
+	return ps, err
+}
+
+func (s service) Get(ctx context.Context, id uuid.UUID, sl payment.Selection) (payment.Pymt, error) {
+	var start = time.Now()
+	var p, err = s.svc.Get(ctx, id, sl)
+	s.record("Get", start, err)
+	return p, err
+}
+
+func (s service) History(ctx context.Context, id uuid.UUID) ([]payment.StatusChange, error) {
+	var start = time.Now()
+	var scs, err = s.svc.History(ctx, id)
+	s.record("History", start, err)
+	return scs, err
+}
+
+func (s service) Patch(ctx context.Context, id uuid.UUID, version uint32, p payment.Patch) error {
+	var start = time.Now()
+	var err = s.svc.Patch(ctx, id, version, p)
+	s.record("Patch", start, err)
+	return err
+}
+
+func (s service) Transition(
+	ctx context.Context, id uuid.UUID, version uint32, to payment.Status, reason string,
+) error {
+	var start = time.Now()
+	var err = s.svc.Transition(ctx, id, version, to, reason)
+	s.record("Transition", start, err)
+	return err
+}
+
+func (s service) Update(ctx context.Context, id uuid.UUID, version uint32, p payment.PymtUpsert) error {
+	var start = time.Now()
+	var err = s.svc.Update(ctx, id, version, p)
+	s.record("Update", start, err)
+	return err
+}
+
+// record records the metrics of a call to the method m which started at start
+// and returned err.
+func (s service) record(m string, start time.Time, err error) {
+	s.duration.observe(time.Since(start).Seconds(), m)
+	s.calls.inc(m, codeLabel(err))
+}
+
+// codeLabel returns the value of the "code" label for err.
+func codeLabel(err error) string {
+	if err == nil {
+		return codeLabelOK
+	}
+
+	var c, ok = errors.GetCode(err)
+	if !ok || c.String() == "" {
+		return codeLabelUnknown
+	}
+
+	return c.String()
+}
+
+// WrapHandler returns an http.Handler which delegates the requests to h and
+// records, in reg, the following metrics of each request:
+//
+// * MetricHTTPRequests - A counter with the labels "handler", "method" and
+// "code", being code the status code of the response.
+//
+// * MetricHTTPRequestDuration - A histogram, with DefaultBuckets, with the
+// labels "handler" and "method".
+//
+// name is the value of the "handler" label, which distinguishes the metrics of
+// each wrapped handler; it's "default" when it's empty. The path of the
+// requests isn't a label for not creating a series for each payment ID, and the
+// "method" label is "OTHER" for the methods which aren't standard (see
+// methodLabel) for not creating a series for each arbitrary method.
+func WrapHandler(h http.Handler, reg *Registry, name string) http.Handler {
+	if name == "" {
+		name = "default"
+	}
+
+	var (
+		reqs = reg.counter(
+			MetricHTTPRequests, "Number of HTTP requests by handler, method and response status code.",
+			"handler", "method", "code",
+		)
+		dur = reg.histogram(
+			MetricHTTPRequestDuration, "Duration of the HTTP requests by handler and method.",
+			DefaultBuckets, "handler", "method",
+		)
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			start = time.Now()
+			sw    = &statusWriter{ResponseWriter: w}
+		)
+
+		h.ServeHTTP(sw, r)
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		var m = methodLabel(r.Method)
+		dur.observe(time.Since(start).Seconds(), name, m)
+		reqs.inc(name, m, strconv.Itoa(sw.status))
+	})
+}
+
+// methodLabel returns the value of the "method" label of the HTTP method m,
+// which is m when it's one of the methods defined by the net/http package and
+// "OTHER" otherwise.
+func methodLabel(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return m
+	default:
+		return "OTHER"
+	}
+}
+
+// statusWriter is an http.ResponseWriter which keeps the status code of the
+// response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(b)
+}
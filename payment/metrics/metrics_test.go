@@ -0,0 +1,138 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestNew(t *testing.T) {
+	var (
+		reg  = metrics.NewRegistry()
+		stub = &testutil.SvcStub{
+			GetFn: func(_ context.Context, id uuid.UUID, _ payment.Selection) (payment.Pymt, error) {
+				return payment.Pymt{ID: id}, nil
+			},
+			UpdateFn: func(_ context.Context, _ uuid.UUID, v uint32, _ payment.PymtUpsert) error {
+				switch v {
+				case 1:
+					return errors.New(payment.ErrInvalidArgVersionMismatch)
+				case 2:
+					return errors.New(payment.ErrUnexpectedStoreError)
+				}
+
+				return nil
+			},
+			DeleteFn: func(context.Context, uuid.UUID, uint32) error {
+				return context.Canceled
+			},
+			CreateBatchFn: func(_ context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+				return make([]uuid.UUID, len(ps)), nil
+			},
+		}
+		svc = metrics.New(stub, reg)
+		id  = testutil.NewUUID(t)
+	)
+
+	var p, err = svc.Get(context.Background(), id, payment.SelectAll())
+	require.NoError(t, err)
+	assert.Equal(t, id, p.ID)
+	_, _ = svc.Get(context.Background(), id, payment.SelectAll())
+
+	err = svc.Update(context.Background(), id, 1, payment.PymtUpsert{})
+	testutil.AssertError(t, err, payment.ErrInvalidArgVersionMismatch)
+	_ = svc.Update(context.Background(), id, 1, payment.PymtUpsert{})
+	_ = svc.Update(context.Background(), id, 2, payment.PymtUpsert{})
+	_ = svc.Update(context.Background(), id, 3, payment.PymtUpsert{})
+
+	err = svc.Delete(context.Background(), id, 3)
+	assert.Equal(t, context.Canceled, err)
+
+	ids, err := svc.(payment.BatchCreator).CreateBatch(context.Background(), make([]payment.PymtUpsert, 2))
+	require.NoError(t, err)
+	assert.Len(t, ids, 2)
+
+	// The decorated service doesn't satisfy payment.BatchCreator
+	var nbc = metrics.New(struct{ payment.Service }{stub}, reg)
+	_, err = nbc.(payment.BatchCreator).CreateBatch(context.Background(), make([]payment.PymtUpsert, 2))
+	testutil.AssertError(t, err, payment.ErrNotSupported)
+
+	var out = exposition(t, reg)
+	assert.Contains(t, out, "# TYPE "+metrics.MetricServiceCalls+" counter\n")
+	assert.Contains(t, out, metrics.MetricServiceCalls+`{method="Get",code="OK"} 2`+"\n")
+	assert.Contains(t, out, metrics.MetricServiceCalls+`{method="Update",code="InvalidArgVersionMismatch"} 2`+"\n")
+	assert.Contains(t, out, metrics.MetricServiceCalls+`{method="Update",code="UnexpectedStoreError"} 1`+"\n")
+	assert.Contains(t, out, metrics.MetricServiceCalls+`{method="Update",code="OK"} 1`+"\n")
+	assert.Contains(t, out, metrics.MetricServiceCalls+`{method="Delete",code="Unknown"} 1`+"\n")
+	assert.Contains(t, out, metrics.MetricServiceCalls+`{method="CreateBatch",code="OK"} 1`+"\n")
+	assert.Contains(t, out, metrics.MetricServiceCalls+`{method="CreateBatch",code="NotSupported"} 1`+"\n")
+
+	assert.Contains(t, out, "# TYPE "+metrics.MetricServiceCallDuration+" histogram\n")
+	assert.Contains(t, out, metrics.MetricServiceCallDuration+`_bucket{method="Update",le="+Inf"} 4`+"\n")
+	assert.Contains(t, out, metrics.MetricServiceCallDuration+`_count{method="Get"} 2`+"\n")
+
+	// Decorating other service with the same registry shares the metrics
+	_, _ = metrics.New(stub, reg).Get(context.Background(), id, payment.SelectAll())
+	assert.Contains(t, exposition(t, reg), metrics.MetricServiceCalls+`{method="Get",code="OK"} 3`+"\n")
+}
+
+func TestWrapHandler(t *testing.T) {
+	var (
+		reg = metrics.NewRegistry()
+		h   = metrics.WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/created":
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+			case "/body":
+				_, _ = w.Write([]byte("ok"))
+			case "/not-found":
+				http.NotFound(w, r)
+			}
+		}), reg, "api")
+	)
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodPost, "/created"},
+		{http.MethodGet, "/body"},
+		{http.MethodGet, "/not-found"},
+		{http.MethodGet, "/empty"},
+		{http.MethodGet, "/empty"},
+		{"PURGE", "/empty"},
+		{"get", "/empty"},
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	metrics.WrapHandler(http.NotFoundHandler(), reg, "").ServeHTTP(
+		httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/", nil),
+	)
+
+	var out = exposition(t, reg)
+	assert.Contains(t, out, metrics.MetricHTTPRequests+`{handler="api",method="POST",code="201"} 1`+"\n")
+	assert.Contains(t, out, metrics.MetricHTTPRequests+`{handler="api",method="GET",code="200"} 3`+"\n")
+	assert.Contains(t, out, metrics.MetricHTTPRequests+`{handler="api",method="GET",code="404"} 1`+"\n")
+	assert.Contains(t, out, metrics.MetricHTTPRequests+`{handler="api",method="OTHER",code="200"} 2`+"\n")
+	assert.NotContains(t, out, `method="PURGE"`)
+	assert.NotContains(t, out, `method="get"`)
+	assert.Contains(t, out, metrics.MetricHTTPRequests+`{handler="default",method="DELETE",code="404"} 1`+"\n")
+	assert.Contains(t, out, metrics.MetricHTTPRequestDuration+`_count{handler="api",method="GET"} 4`+"\n")
+	assert.Contains(t, out, metrics.MetricHTTPRequestDuration+`_bucket{handler="api",method="POST",le="10"} 1`+"\n")
+}
+
+func exposition(t *testing.T, reg *metrics.Registry) string {
+	var buf bytes.Buffer
+	var _, err = reg.WriteTo(&buf)
+	require.NoError(t, err)
+	return buf.String()
+}
@@ -0,0 +1,278 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets of the
+// latency histograms. They are the same than the default ones of the
+// Prometheus client libraries.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics recorded by the payment services and HTTP
+// handlers instrumented with it (see New and WrapHandler) and exposes them in
+// the Prometheus text exposition format.
+//
+// Registry is an http.Handler which serves the metrics, hence it's the handler
+// of the /metrics endpoint, e.g. mux.Handle("/metrics", reg).
+//
+// The zero value isn't usable, use NewRegistry for creating one. A Registry is
+// safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// ServeHTTP satisfies the http.Handler interface writing the metrics to w in
+// the Prometheus text exposition format. Only GET and HEAD are allowed.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodHead}, ", "))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if req.Method == http.MethodHead {
+		return
+	}
+
+	_, _ = r.WriteTo(w)
+}
+
+// WriteTo satisfies the io.WriterTo interface writing the metrics to w in the
+// Prometheus text exposition format. The metrics are sorted by name and their
+// series by their label values.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var names = make([]string, 0, len(r.families))
+	for n := range r.families {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var cw = &countWriter{w: w}
+	var bw = bufio.NewWriter(cw)
+	for _, n := range names {
+		r.families[n].write(bw)
+	}
+
+	var err = bw.Flush()
+	return cw.n, err
+}
+
+// counter returns the counter family with name, help and the label names
+// labels, creating it if it doesn't exist.
+func (r *Registry) counter(name, help string, labels ...string) *family {
+	return r.family(name, help, typeCounter, nil, labels)
+}
+
+// histogram returns the histogram family with name, help, the buckets upper
+// bounds buckets and the label names labels, creating it if it doesn't exist.
+func (r *Registry) histogram(name, help string, buckets []float64, labels ...string) *family {
+	return r.family(name, help, typeHistogram, buckets, labels)
+}
+
+func (r *Registry) family(name, help, typ string, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		return f
+	}
+
+	var f = &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  map[string]*series{},
+		mu:      &r.mu,
+	}
+	r.families[name] = f
+	return f
+}
+
+// The types of the metrics.
+const (
+	typeCounter   = "counter"
+	typeHistogram = "histogram"
+)
+
+// family is a metric with all its series.
+type family struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+	// mu is the mutex of the registry because the families are written while
+	// it's locked.
+	mu *sync.Mutex
+}
+
+// series is the series of a metric for a specific set of label values.
+type series struct {
+	values []string
+	// value is the value of a counter.
+	value float64
+	// counts, sum and count are the number of observations of each bucket, the
+	// sum of all the observations and the number of observations of a
+	// histogram. The counts aren't cumulative.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// inc increments the counter of the series of the label values values.
+func (f *family) inc(values ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.get(values).value++
+}
+
+// observe adds the observation v to the histogram of the series of the label
+// values values.
+func (f *family) observe(v float64, values ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var s = f.get(values)
+	var i = sort.SearchFloat64s(f.buckets, v)
+	if i < len(f.buckets) {
+		s.counts[i]++
+	}
+
+	s.sum += v
+	s.count++
+}
+
+// get returns the series of the label values values, creating it if it doesn't
+// exist. f.mu must be locked.
+func (f *family) get(values []string) *series {
+	var k = strings.Join(values, "\xff")
+	var s, ok = f.series[k]
+	if !ok {
+		s = &series{values: values}
+		if f.typ == typeHistogram {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+
+		f.series[k] = s
+	}
+
+	return s
+}
+
+// write writes f to w in the Prometheus text exposition format.
+func (f *family) write(w *bufio.Writer) {
+	var keys = make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	_, _ = w.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	_, _ = w.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+	for _, k := range keys {
+		var s = f.series[k]
+		if f.typ == typeCounter {
+			writeSample(w, f.name, f.labels, s.values, "", "", s.value)
+			continue
+		}
+
+		var cum uint64
+		for i, b := range f.buckets {
+			cum += s.counts[i]
+			writeSample(w, f.name+"_bucket", f.labels, s.values, "le", formatFloat(b), float64(cum))
+		}
+
+		writeSample(w, f.name+"_bucket", f.labels, s.values, "le", "+Inf", float64(s.count))
+		writeSample(w, f.name+"_sum", f.labels, s.values, "", "", s.sum)
+		writeSample(w, f.name+"_count", f.labels, s.values, "", "", float64(s.count))
+	}
+}
+
+// writeSample writes a sample line to w of the metric name with the labels
+// names and values and the value v. When extra isn't empty, it's added as the
+// last label with the value extraVal.
+func writeSample(w *bufio.Writer, name string, names, values []string, extra, extraVal string, v float64) {
+	_, _ = w.WriteString(name)
+	if len(names) > 0 || extra != "" {
+		_ = w.WriteByte('{')
+		for i, n := range names {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+
+			_, _ = w.WriteString(n + `="` + escapeLabelValue(values[i]) + `"`)
+		}
+
+		if extra != "" {
+			if len(names) > 0 {
+				_ = w.WriteByte(',')
+			}
+
+			_, _ = w.WriteString(extra + `="` + escapeLabelValue(extraVal) + `"`)
+		}
+
+		_ = w.WriteByte('}')
+	}
+
+	_, _ = w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
+
+// countWriter is an io.Writer which counts the number of bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	var n, err = cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
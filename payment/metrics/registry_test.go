@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	var reg = NewRegistry()
+
+	var c = reg.counter("requests_total", "Number of\nrequests \\ by path.", "path")
+	c.inc(`/a"b`)
+	c.inc(`/a"b`)
+	c.inc("/c\nd")
+	assert.True(t, c == reg.counter("requests_total", "ignored", "path"))
+
+	var h = reg.histogram("latency_seconds", "Latency.", []float64{0.1, 0.5, 1})
+	h.observe(0.1)
+	h.observe(0.3)
+	h.observe(2)
+
+	reg.counter("empty_total", "Empty.")
+
+	var buf bytes.Buffer
+	var n, err = reg.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.EqualValues(t, buf.Len(), n)
+	assert.Equal(t, `# HELP empty_total Empty.
+# TYPE empty_total counter
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="0.5"} 2
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 2.4
+latency_seconds_count 3
+# HELP requests_total Number of\nrequests \\ by path.
+# TYPE requests_total counter
+requests_total{path="/a\"b"} 2
+requests_total{path="/c\nd"} 1
+`, buf.String())
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	var reg = NewRegistry()
+	reg.counter("calls_total", "Calls.", "method").inc("Get")
+
+	var tcases = []struct {
+		desc   string
+		method string
+		status int
+		body   string
+	}{
+		{
+			desc:   "GET",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			body:   "# HELP calls_total Calls.\n# TYPE calls_total counter\ncalls_total{method=\"Get\"} 1\n",
+		},
+		{
+			desc:   "HEAD",
+			method: http.MethodHead,
+			status: http.StatusOK,
+		},
+		{
+			desc:   "POST",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var rec = httptest.NewRecorder()
+			reg.ServeHTTP(rec, httptest.NewRequest(tc.method, "/metrics", nil))
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Equal(t, tc.body, rec.Body.String())
+			if tc.status == http.StatusOK {
+				assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
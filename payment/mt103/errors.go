@@ -0,0 +1,51 @@
+package mt103
+
+type code uint8
+
+// The list of specific error codes that the mt103 package can return.
+const (
+	ErrInvalidFieldCharset code = iota + 1
+	ErrInvalidFieldFormat
+	ErrInvalidFieldLength
+	ErrInvalidMessage
+	ErrInvalidPaymentType
+	ErrMissingField
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrInvalidFieldCharset:
+		return "InvalidFieldCharset"
+	case ErrInvalidFieldFormat:
+		return "InvalidFieldFormat"
+	case ErrInvalidFieldLength:
+		return "InvalidFieldLength"
+	case ErrInvalidMessage:
+		return "InvalidMessage"
+	case ErrInvalidPaymentType:
+		return "InvalidPaymentType"
+	case ErrMissingField:
+		return "MissingField"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrInvalidFieldCharset:
+		return "The value of the field has characters which aren't in the SWIFT X character set"
+	case ErrInvalidFieldFormat:
+		return "The value of the field doesn't have the format of the field"
+	case ErrInvalidFieldLength:
+		return "The value of the field has more lines or characters than the field allows"
+	case ErrInvalidMessage:
+		return "The message doesn't have a text block with the MT103 fields"
+	case ErrInvalidPaymentType:
+		return "The payment type cannot be represented by an MT103 message"
+	case ErrMissingField:
+		return "A mandatory field of the message is missing"
+	}
+
+	return ""
+}
@@ -0,0 +1,640 @@
+// Package mt103 encodes and decodes payments as SWIFT MT103 single customer
+// credit transfer messages.
+//
+// Only the text block (block 4) of the messages is encoded and decoded; the
+// decoding ignores the header blocks when they are present.
+package mt103
+
+import (
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/accountid"
+	"go.fraixed.es/errors"
+)
+
+const (
+	// lineLen is the maximum length of the lines of the 35x fields.
+	lineLen = 35
+	crlf    = "\r\n"
+	// fxInstructionCode is the code word of the field 72 whose narrative is the
+	// reference of the foreign exchange contract.
+	fxInstructionCode = "/FXCTRCT/"
+	dateLayout        = "060102"
+)
+
+//nolint:gochecknoglobals
+var (
+	xCharsetRegexp      = regexp.MustCompile(`^[A-Za-z0-9/\-?:().,'+ ]*$`)
+	fieldRegexp         = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):(.*)$`)
+	currencyRegexp      = regexp.MustCompile(`^[A-Z]{3}$`)
+	ccyAmountRegexp     = regexp.MustCompile(`^([A-Z]{3})([0-9]+,[0-9]*)$`)
+	dateCcyAmountRegexp = regexp.MustCompile(`^([0-9]{6})([A-Z]{3})([0-9]+,[0-9]*)$`)
+	decimalRegexp       = regexp.MustCompile(`^[0-9]+,[0-9]*$`)
+
+	// bearerCodeDetails maps the charges bearer codes to the details of charges
+	// (field 71A) and detailsBearerCode the other way around.
+	bearerCodeDetails = map[string]string{"CRED": "BEN", "DEBT": "OUR", "SHAR": "SHA", "SLEV": "SHA"}
+	detailsBearerCode = map[string]string{"BEN": "CRED", "OUR": "DEBT", "SHA": "SHAR"}
+
+	// bankIDCodeClearing maps the bank ID codes to the national clearing system
+	// codes of the option D of the institution fields and clearingBankIDCode the
+	// other way around.
+	bankIDCodeClearing = map[string]string{"GBDSC": "SC"}
+	clearingBankIDCode = map[string]string{"SC": "GBDSC"}
+)
+
+// field is a field of the text block of a message.
+type field struct {
+	tag   string
+	lines []string
+}
+
+func (f field) value() string {
+	return strings.Join(f.lines, crlf)
+}
+
+// Encode writes to w the text block of the MT103 message of the payment p.
+//
+// The fields are mapped from the payment as follows:
+//
+// * 20 (sender's reference): the payment ID of the attributes.
+//
+// * 23B (bank operation code): CRED.
+//
+// * 32A (value date, currency and interbank settled amount): the processing
+// date, the currency and the amount.
+//
+// * 33B (instructed amount) and 36 (exchange rate): the foreign exchange
+// original currency and amount and the exchange rate.
+//
+// * 50K (ordering customer) and 59 (beneficiary customer): the account number,
+// the name and the address of the debtor and beneficiary parties.
+//
+// * 52a (ordering institution), 56a (intermediary) and 57a (account with
+// institution): the bank ID of the debtor, sponsor and beneficiary parties; the
+// option A when the bank ID code is SWBIC, otherwise the option D with the
+// national clearing code (e.g. //SC for GBDSC). The 56A contains the account
+// number of the sponsor party.
+//
+// * 70 (remittance information): the reference.
+//
+// * 71A (details of charges), 71F (sender's charges) and 71G (receiver's
+// charges): the charges information, being SHA the details of charges when the
+// bearer code is empty.
+//
+// * 72 (sender to receiver information): the foreign exchange contract
+// reference with the code word /FXCTRCT/.
+//
+// The rest of the payment fields aren't mapped.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidPaymentType - The payment isn't a credit transfer.
+//
+// * ErrInvalidFieldCharset, ErrInvalidFieldFormat, ErrInvalidFieldLength,
+// ErrMissingField - See payment.Violations.Err, the violations fields are the
+// field tags and the values of the 50K and 59 fields are payment.Sensitive.
+//
+// * payment.ErrUnexpectedOSError
+func Encode(w io.Writer, p payment.Pymt) error {
+	if p.Attributes.PaymentType != "Credit" {
+		return errors.New(
+			ErrInvalidPaymentType, payment.ErrMDVar("id", p.ID), payment.ErrMDField("PaymentType", p.Attributes.PaymentType),
+		)
+	}
+
+	var b = newFields(p.Attributes)
+	if err := b.vs.Err(); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{4:" + crlf)
+	for _, f := range b.fs {
+		sb.WriteString(":" + f.tag + ":" + f.value() + crlf)
+	}
+	sb.WriteString("-}")
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return errors.Wrap(err, payment.ErrUnexpectedOSError)
+	}
+
+	return nil
+}
+
+// fieldsBuilder accumulates the fields of a message and the violations of
+// their values.
+type fieldsBuilder struct {
+	fs []field
+	vs payment.Violations
+}
+
+func (b *fieldsBuilder) violation(tag string, c errors.Code, val interface{}) {
+	b.vs = append(b.vs, newViolation(tag, c, val))
+}
+
+// newViolation returns the violation of the field tag with code c and value
+// val. The non-empty values of the fields of the ordering customer and the
+// beneficiary customer are payment.Sensitive because they contain their
+// account numbers, names and addresses.
+func newViolation(tag string, c errors.Code, val interface{}) payment.Violation {
+	if s, ok := val.(string); ok && s != "" && (tag == "50K" || tag == "59") {
+		val = payment.Sensitive(s)
+	}
+
+	return payment.Violation{Field: tag, Code: c, Value: val}
+}
+
+// add adds the field tag with the lines ls, which cannot be more than maxLines
+// and each one longer than maxLen. A mandatory field without lines is a missing
+// field, otherwise it isn't added.
+func (b *fieldsBuilder) add(tag string, mandatory bool, maxLines int, maxLen int, ls ...string) {
+	if len(ls) == 0 {
+		if mandatory {
+			b.violation(tag, ErrMissingField, "")
+		}
+
+		return
+	}
+
+	var f = field{tag: tag, lines: ls}
+	if len(ls) > maxLines {
+		b.violation(tag, ErrInvalidFieldLength, f.value())
+		return
+	}
+
+	for _, l := range ls {
+		if len(l) > maxLen {
+			b.violation(tag, ErrInvalidFieldLength, f.value())
+			return
+		}
+
+		if !xCharsetRegexp.MatchString(l) {
+			b.violation(tag, ErrInvalidFieldCharset, f.value())
+			return
+		}
+	}
+
+	b.fs = append(b.fs, f)
+}
+
+func newFields(a payment.Attrs) fieldsBuilder {
+	var b fieldsBuilder
+
+	if strings.HasPrefix(a.PaymentID, "/") || strings.HasSuffix(a.PaymentID, "/") ||
+		strings.Contains(a.PaymentID, "//") {
+		b.violation("20", ErrInvalidFieldFormat, a.PaymentID)
+	} else {
+		b.add("20", true, 1, 16, nonEmpty(a.PaymentID)...)
+	}
+
+	b.add("23B", true, 1, 4, "CRED")
+
+	var vd, err = time.Parse("2006-01-02", a.ProcessingDate)
+	if err != nil {
+		b.violation("32A", ErrInvalidFieldFormat, a.ProcessingDate)
+	} else if amt, ok := ccyAmount("32A", a.Amount, a.Currency, &b); ok {
+		b.add("32A", true, 1, 24, vd.Format(dateLayout)+amt)
+	}
+
+	if a.Fx.OriginalAmount != "" || a.Fx.OriginalCurrency != "" {
+		var amt = strings.Replace(a.Fx.OriginalAmount, ".", ",", 1)
+		if !strings.Contains(amt, ",") {
+			amt += ","
+		}
+
+		if !currencyRegexp.MatchString(a.Fx.OriginalCurrency) || !decimalRegexp.MatchString(amt) {
+			b.violation("33B", ErrInvalidFieldFormat, a.Fx.OriginalCurrency+amt)
+		} else {
+			b.add("33B", false, 1, 18, a.Fx.OriginalCurrency+amt)
+		}
+	}
+
+	if a.Fx.ExchangeRate != "" {
+		var xr = strings.Replace(a.Fx.ExchangeRate, ".", ",", 1)
+		if !strings.Contains(xr, ",") {
+			xr += ","
+		}
+
+		if !decimalRegexp.MatchString(xr) {
+			b.violation("36", ErrInvalidFieldFormat, xr)
+		} else {
+			b.add("36", false, 1, 12, xr)
+		}
+	}
+
+	addCustomer(&b, "50K", a.DebtorParty)
+	addInstitution(&b, "52", a.DebtorParty, false)
+	if a.SponsorParty != (payment.Party{}) {
+		addInstitution(&b, "56", a.SponsorParty, true)
+	}
+	addInstitution(&b, "57", a.BeneficiaryParty, false)
+	addCustomer(&b, "59", a.BeneficiaryParty)
+
+	b.add("70", false, 4, lineLen, chunks(a.Reference)...)
+
+	var ci = a.ChargesInformation
+	var details, ok = bearerCodeDetails[ci.BearerCode]
+	switch {
+	case ci.BearerCode == "":
+		b.add("71A", true, 1, 3, "SHA")
+	case !ok:
+		b.violation("71A", ErrInvalidFieldFormat, ci.BearerCode)
+	default:
+		b.add("71A", true, 1, 3, details)
+	}
+
+	for _, sc := range ci.SenderCharges {
+		if amt, ok := ccyAmount("71F", sc.Amount, sc.Currency, &b); ok {
+			b.add("71F", false, 1, 18, amt)
+		}
+	}
+
+	if ci.ReceiverChargesAmount != 0 || ci.ReceiverChargesCurrency != "" {
+		if amt, ok := ccyAmount("71G", ci.ReceiverChargesAmount, ci.ReceiverChargesCurrency, &b); ok {
+			b.add("71G", false, 1, 18, amt)
+		}
+	}
+
+	if a.Fx.ContractReference != "" {
+		b.add("72", false, 6, lineLen, chunks(fxInstructionCode+a.Fx.ContractReference)...)
+	}
+
+	return b
+}
+
+// ccyAmount returns the currency and the amount a with the format of the MT
+// fields (3!a15d), adding a violation of the field tag to b when it isn't
+// possible.
+func ccyAmount(tag string, a float64, ccy string, b *fieldsBuilder) (string, bool) {
+	var dp = -1
+	if mu, ok := payment.CurrencyMinorUnits(ccy); ok {
+		dp = int(mu)
+	}
+
+	var amt = strings.Replace(strconv.FormatFloat(a, 'f', dp, 64), ".", ",", 1)
+	if !strings.Contains(amt, ",") {
+		amt += ","
+	}
+
+	if !currencyRegexp.MatchString(ccy) || !decimalRegexp.MatchString(amt) {
+		b.violation(tag, ErrInvalidFieldFormat, ccy+amt)
+		return "", false
+	}
+
+	if len(amt) > 15 {
+		b.violation(tag, ErrInvalidFieldLength, ccy+amt)
+		return "", false
+	}
+
+	return ccy + amt, true
+}
+
+// addCustomer adds the field tag of the customer p with the format
+// [/34x]4*35x, being the account number the first line and the name and the
+// address the rest of them.
+func addCustomer(b *fieldsBuilder, tag string, p payment.Party) {
+	var ls []string
+	if p.AccountNumber != "" {
+		ls = append(ls, "/"+p.AccountNumber)
+	}
+
+	ls = append(ls, nonEmpty(p.Name)...)
+	ls = append(ls, chunks(p.Address)...)
+
+	var maxLines = 4
+	if p.AccountNumber != "" {
+		maxLines++
+	}
+
+	b.add(tag, true, maxLines, lineLen, ls...)
+}
+
+// addInstitution adds the field of the institution of the party p whose tag
+// number is num, with the option A when the bank ID code is SWBIC, otherwise
+// with the option D. The option A contains the account number when withAcct is
+// true.
+func addInstitution(b *fieldsBuilder, num string, p payment.Party, withAcct bool) {
+	if p.BankID == "" {
+		return
+	}
+
+	if p.BankIDCode == "SWBIC" {
+		var tag = num + "A"
+		if accountid.ValidateBIC(p.BankID) != nil {
+			b.violation(tag, ErrInvalidFieldFormat, p.BankID)
+			return
+		}
+
+		var ls []string
+		if withAcct && p.AccountNumber != "" {
+			ls = append(ls, "/"+p.AccountNumber)
+		}
+
+		b.add(tag, false, 2, lineLen, append(ls, p.BankID)...)
+		return
+	}
+
+	var tag = num + "D"
+	var cc, ok = bankIDCodeClearing[p.BankIDCode]
+	if !ok {
+		b.violation(tag, ErrInvalidFieldFormat, p.BankIDCode)
+		return
+	}
+
+	b.add(tag, false, 1, lineLen, "//"+cc+p.BankID)
+}
+
+// Decode reads from r an MT103 message and returns the payment of the
+// organisation orgID which it represents, see Encode for the mapping of the
+// fields. The unknown fields are ignored.
+//
+// The account number codes are IBAN when the account numbers are valid IBANs,
+// otherwise BBAN.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidMessage
+//
+// * ErrInvalidFieldCharset, ErrInvalidFieldFormat, ErrInvalidFieldLength,
+// ErrMissingField - See payment.Violations.Err, the violations fields are the
+// field tags and the values of the 50K and 59 fields are payment.Sensitive.
+//
+// * payment.ErrUnexpectedOSError
+func Decode(r io.Reader, orgID uuid.UUID) (payment.PymtUpsert, error) {
+	var (
+		p = payment.PymtUpsert{Type: "Payment", OrgID: orgID}
+		a = &p.Attributes
+	)
+
+	var b, err = ioutil.ReadAll(r)
+	if err != nil {
+		return p, errors.Wrap(err, payment.ErrUnexpectedOSError)
+	}
+
+	fs, err := parseFields(string(b))
+	if err != nil {
+		return p, err
+	}
+
+	var (
+		vs    payment.Violations
+		found = map[string]bool{}
+	)
+
+	for _, f := range fs {
+		found[f.tag] = true
+
+		var v = f.value()
+		for _, l := range f.lines {
+			if !xCharsetRegexp.MatchString(l) {
+				vs = append(vs, newViolation(f.tag, ErrInvalidFieldCharset, v))
+				break
+			}
+		}
+
+		if c := decodeField(a, f); c != nil {
+			vs = append(vs, newViolation(f.tag, c, v))
+		}
+	}
+
+	for _, tag := range []string{"20", "23B", "32A", "50K", "59", "71A"} {
+		if !found[tag] {
+			vs = append(vs, payment.Violation{Field: tag, Code: ErrMissingField, Value: ""})
+		}
+	}
+
+	a.PaymentType = "Credit"
+	for _, pt := range []*payment.Party{&a.DebtorParty, &a.BeneficiaryParty, &a.SponsorParty} {
+		switch {
+		case pt.AccountNumber == "":
+		case accountid.ValidateIBAN(pt.AccountNumber) == nil:
+			pt.AccountNumberCode = "IBAN"
+		default:
+			pt.AccountNumberCode = "BBAN"
+		}
+	}
+
+	return p, vs.Err()
+}
+
+// parseFields returns the fields of the text block of the message msg.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidMessage
+func parseFields(msg string) ([]field, error) {
+	var i = strings.Index(msg, "{4:")
+	if i < 0 {
+		return nil, errors.New(ErrInvalidMessage)
+	}
+
+	var (
+		fs []field
+		ls = strings.Split(strings.Replace(msg[i+3:], crlf, "\n", -1), "\n")
+	)
+
+	for _, l := range ls {
+		if strings.HasPrefix(l, "-}") {
+			return fs, nil
+		}
+
+		if m := fieldRegexp.FindStringSubmatch(l); m != nil {
+			fs = append(fs, field{tag: m[1], lines: []string{m[2]}})
+			continue
+		}
+
+		if len(fs) > 0 {
+			fs[len(fs)-1].lines = append(fs[len(fs)-1].lines, l)
+		} else if l != "" {
+			return nil, errors.New(ErrInvalidMessage, payment.ErrMDVar("line", l))
+		}
+	}
+
+	return nil, errors.New(ErrInvalidMessage)
+}
+
+// decodeField sets the values of the field f to a and it returns the error code
+// of the violation of f or nil if there isn't.
+func decodeField(a *payment.Attrs, f field) errors.Code {
+	var v = f.value()
+	switch f.tag {
+	case "20":
+		if len(f.lines) > 1 || len(v) > 16 {
+			return ErrInvalidFieldLength
+		}
+
+		a.PaymentID = v
+	case "23B":
+		if len(v) != 4 {
+			return ErrInvalidFieldFormat
+		}
+	case "32A":
+		var m = dateCcyAmountRegexp.FindStringSubmatch(v)
+		if m == nil {
+			return ErrInvalidFieldFormat
+		}
+
+		var vd, err = time.Parse(dateLayout, m[1])
+		if err != nil {
+			return ErrInvalidFieldFormat
+		}
+
+		amt, err := strconv.ParseFloat(decimal(m[3]), 64)
+		if err != nil {
+			return ErrInvalidFieldFormat
+		}
+
+		a.ProcessingDate = vd.Format("2006-01-02")
+		a.Currency = m[2]
+		a.Amount = amt
+	case "33B":
+		var m = ccyAmountRegexp.FindStringSubmatch(v)
+		if m == nil {
+			return ErrInvalidFieldFormat
+		}
+
+		a.Fx.OriginalCurrency = m[1]
+		a.Fx.OriginalAmount = decimal(m[2])
+	case "36":
+		if !decimalRegexp.MatchString(v) {
+			return ErrInvalidFieldFormat
+		}
+
+		a.Fx.ExchangeRate = decimal(v)
+	case "50K":
+		return decodeCustomer(&a.DebtorParty, f)
+	case "52A", "52D":
+		return decodeInstitution(&a.DebtorParty, f)
+	case "56A", "56D":
+		return decodeInstitution(&a.SponsorParty, f)
+	case "57A", "57D":
+		return decodeInstitution(&a.BeneficiaryParty, f)
+	case "59":
+		return decodeCustomer(&a.BeneficiaryParty, f)
+	case "70":
+		if len(f.lines) > 4 {
+			return ErrInvalidFieldLength
+		}
+
+		a.Reference = strings.Join(f.lines, "")
+	case "71A":
+		var bc, ok = detailsBearerCode[v]
+		if !ok {
+			return ErrInvalidFieldFormat
+		}
+
+		a.ChargesInformation.BearerCode = bc
+	case "71F", "71G":
+		var m = ccyAmountRegexp.FindStringSubmatch(v)
+		if m == nil {
+			return ErrInvalidFieldFormat
+		}
+
+		var amt, err = strconv.ParseFloat(decimal(m[2]), 64)
+		if err != nil {
+			return ErrInvalidFieldFormat
+		}
+
+		if f.tag == "71G" {
+			a.ChargesInformation.ReceiverChargesAmount = amt
+			a.ChargesInformation.ReceiverChargesCurrency = m[1]
+		} else {
+			a.ChargesInformation.SenderCharges = append(
+				a.ChargesInformation.SenderCharges, payment.Charge{Amount: amt, Currency: m[1]},
+			)
+		}
+	case "72":
+		var ins = strings.Join(f.lines, "")
+		if strings.HasPrefix(ins, fxInstructionCode) {
+			a.Fx.ContractReference = strings.TrimPrefix(ins, fxInstructionCode)
+		}
+	}
+
+	return nil
+}
+
+// decodeCustomer sets the values of the customer field f to p.
+func decodeCustomer(p *payment.Party, f field) errors.Code {
+	var ls = f.lines
+	if strings.HasPrefix(ls[0], "/") {
+		p.AccountNumber = strings.TrimPrefix(ls[0], "/")
+		ls = ls[1:]
+	}
+
+	if len(ls) > 4 {
+		return ErrInvalidFieldLength
+	}
+
+	if len(ls) > 0 {
+		p.Name = ls[0]
+		p.Address = strings.Join(ls[1:], "")
+	}
+
+	return nil
+}
+
+// decodeInstitution sets the values of the institution field f to p.
+func decodeInstitution(p *payment.Party, f field) errors.Code {
+	var ls = f.lines
+	if strings.HasSuffix(f.tag, "A") {
+		if len(ls) > 1 && strings.HasPrefix(ls[0], "/") {
+			p.AccountNumber = strings.TrimPrefix(ls[0], "/")
+			ls = ls[1:]
+		}
+
+		if len(ls) != 1 || accountid.ValidateBIC(ls[0]) != nil {
+			return ErrInvalidFieldFormat
+		}
+
+		p.BankID = ls[0]
+		p.BankIDCode = "SWBIC"
+		return nil
+	}
+
+	if !strings.HasPrefix(ls[0], "//") || len(ls[0]) < 4 {
+		return ErrInvalidFieldFormat
+	}
+
+	var bic, ok = clearingBankIDCode[ls[0][2:4]]
+	if !ok {
+		return ErrInvalidFieldFormat
+	}
+
+	p.BankID = ls[0][4:]
+	p.BankIDCode = bic
+	return nil
+}
+
+// decimal returns the MT decimal number d (e.g. 10,5) as a decimal number with
+// '.' as decimal separator, without it when d doesn't have decimals.
+func decimal(d string) string {
+	return strings.TrimSuffix(strings.Replace(d, ",", ".", 1), ".")
+}
+
+// chunks splits s in lines of lineLen characters.
+func chunks(s string) []string {
+	var ls []string
+	for len(s) > lineLen {
+		ls = append(ls, s[:lineLen])
+		s = s[lineLen:]
+	}
+
+	return append(ls, nonEmpty(s)...)
+}
+
+// nonEmpty returns a slice with s or an empty slice if s is empty.
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return []string{s}
+}
@@ -0,0 +1,204 @@
+package mt103_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/mt103"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestEncode_Decode(t *testing.T) {
+	var (
+		orgID = testutil.NewUUID(t)
+		p     = newPymt(t, orgID)
+	)
+
+	var buf bytes.Buffer
+	var err = mt103.Encode(&buf, p)
+	require.NoError(t, err)
+
+	var msg = buf.String()
+	assert.True(t, strings.HasPrefix(msg, "{4:\r\n:20:REF-0001\r\n:23B:CRED\r\n"), msg)
+	assert.Contains(t, msg, ":32A:190304GBP1234,50\r\n")
+	assert.Contains(t, msg, ":50K:/GB29NWBK60161331926819\r\nJohn Doe\r\n")
+	assert.Contains(t, msg, ":52A:NWBKGB2L\r\n")
+	assert.Contains(t, msg, ":56A:/63748472\r\nDEUTDEFF\r\n")
+	assert.Contains(t, msg, ":57D://SC107999\r\n")
+	assert.Contains(t, msg, ":71A:OUR\r\n:71F:GBP5,00\r\n:71F:USD10,25\r\n:71G:EUR1,50\r\n")
+	assert.Contains(t, msg, ":72:/FXCTRCT/FX123\r\n")
+	assert.True(t, strings.HasSuffix(msg, "\r\n-}"), msg)
+
+	// The header blocks are ignored
+	dp, err := mt103.Decode(strings.NewReader("{1:F01BANKBEBBAXXX0000000000}{2:I103BANKDEFFXXXXN}"+msg), orgID)
+	require.NoError(t, err)
+	assert.Equal(t, p.PymtUpsert, dp)
+}
+
+func TestEncode_error(t *testing.T) {
+	var orgID = testutil.NewUUID(t)
+
+	t.Run("payment type", func(t *testing.T) {
+		var p = newPymt(t, orgID)
+		p.Attributes.PaymentType = "Debit"
+
+		var err = mt103.Encode(&bytes.Buffer{}, p)
+		testutil.AssertError(t, err, mt103.ErrInvalidPaymentType,
+			payment.ErrMDVar("id", p.ID), payment.ErrMDField("PaymentType", "Debit"),
+		)
+	})
+
+	var tcases = []struct {
+		desc   string
+		modify func(*payment.Attrs)
+		vs     []payment.Violation
+	}{
+		{
+			desc:   "missing sender's reference",
+			modify: func(a *payment.Attrs) { a.PaymentID = "" },
+			vs:     []payment.Violation{{Field: "20", Code: mt103.ErrMissingField, Value: ""}},
+		},
+		{
+			desc:   "long sender's reference",
+			modify: func(a *payment.Attrs) { a.PaymentID = "12345678901234567" },
+			vs:     []payment.Violation{{Field: "20", Code: mt103.ErrInvalidFieldLength, Value: "12345678901234567"}},
+		},
+		{
+			desc:   "invalid charset",
+			modify: func(a *payment.Attrs) { a.Reference = "Payment for €10" },
+			vs:     []payment.Violation{{Field: "70", Code: mt103.ErrInvalidFieldCharset, Value: "Payment for €10"}},
+		},
+		{
+			desc: "invalid value date and bearer code",
+			modify: func(a *payment.Attrs) {
+				a.ProcessingDate = "04/03/2019"
+				a.ChargesInformation.BearerCode = "NONE"
+			},
+			vs: []payment.Violation{
+				{Field: "32A", Code: mt103.ErrInvalidFieldFormat, Value: "04/03/2019"},
+				{Field: "71A", Code: mt103.ErrInvalidFieldFormat, Value: "NONE"},
+			},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var p = newPymt(t, orgID)
+			tc.modify(&p.Attributes)
+
+			var err = mt103.Encode(&bytes.Buffer{}, p)
+			var mds []errors.MD
+			for _, v := range tc.vs {
+				mds = append(mds, payment.ErrMDField(v.Field, v))
+			}
+
+			testutil.AssertError(t, err, tc.vs[0].Code, mds...)
+		})
+	}
+}
+
+func TestDecode_error(t *testing.T) {
+	var orgID = testutil.NewUUID(t)
+
+	t.Run("no text block", func(t *testing.T) {
+		var _, err = mt103.Decode(strings.NewReader("{1:F01BANKBEBBAXXX0000000000}"), orgID)
+		testutil.AssertError(t, err, mt103.ErrInvalidMessage)
+	})
+
+	t.Run("unterminated text block", func(t *testing.T) {
+		var _, err = mt103.Decode(strings.NewReader("{4:\r\n:20:REF\r\n"), orgID)
+		testutil.AssertError(t, err, mt103.ErrInvalidMessage)
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		const msg = "{4:\r\n:20:REF\r\n:23B:CRED\r\n:32A:1903GBP10,\r\n:50K:/12345678\r\nJohn Doe\r\n" +
+			":52D://XX123456\r\n:71A:SHA\r\n-}"
+
+		var _, err = mt103.Decode(strings.NewReader(msg), orgID)
+		testutil.AssertError(t, err, mt103.ErrInvalidFieldFormat,
+			payment.ErrMDField("32A", payment.Violation{Field: "32A", Code: mt103.ErrInvalidFieldFormat, Value: "1903GBP10,"}),
+		)
+		testutil.AssertError(t, err, mt103.ErrInvalidFieldFormat,
+			payment.ErrMDField("52D", payment.Violation{Field: "52D", Code: mt103.ErrInvalidFieldFormat, Value: "//XX123456"}),
+		)
+		testutil.AssertError(t, err, mt103.ErrInvalidFieldFormat,
+			payment.ErrMDField("59", payment.Violation{Field: "59", Code: mt103.ErrMissingField, Value: ""}),
+		)
+	})
+
+	t.Run("customer fields are redacted", func(t *testing.T) {
+		const msg = "{4:\r\n:20:REF\r\n:23B:CRED\r\n:32A:190304GBP10,\r\n:50K:/12345678\r\nJohn € Doe\r\n" +
+			":59:/87654321\r\nJane Doe\r\n:71A:SHA\r\n-}"
+
+		var _, err = mt103.Decode(strings.NewReader(msg), orgID)
+		testutil.AssertError(t, err, mt103.ErrInvalidFieldCharset)
+
+		var emsg = fmt.Sprintf("%+v", err)
+		assert.Contains(t, emsg, "field:50K")
+		assert.NotContains(t, emsg, "12345678")
+		assert.NotContains(t, emsg, "John")
+	})
+}
+
+// newPymt returns a payment whose fields are all mapped to the MT103 fields.
+func newPymt(t *testing.T, orgID uuid.UUID) payment.Pymt {
+	var p = payment.Pymt{
+		ID: testutil.NewUUID(t),
+		PymtUpsert: payment.PymtUpsert{
+			Type:  "Payment",
+			OrgID: orgID,
+			Attributes: payment.Attrs{
+				Amount:         1234.5,
+				Currency:       "GBP",
+				PaymentID:      "REF-0001",
+				PaymentType:    "Credit",
+				ProcessingDate: "2019-03-04",
+				Reference:      strings.Repeat("Payment for invoice 42 ", 3),
+				BeneficiaryParty: payment.Party{
+					AccountNumber:     "88837491",
+					AccountNumberCode: "BBAN",
+					Address:           "1 The Street, London",
+					BankID:            "107999",
+					BankIDCode:        "GBDSC",
+					Name:              "Jane Doe",
+				},
+				DebtorParty: payment.Party{
+					AccountNumber:     "GB29NWBK60161331926819",
+					AccountNumberCode: "IBAN",
+					Address:           "2 The Avenue, Manchester",
+					BankID:            "NWBKGB2L",
+					BankIDCode:        "SWBIC",
+					Name:              "John Doe",
+				},
+				SponsorParty: payment.Party{
+					AccountNumber:     "63748472",
+					AccountNumberCode: "BBAN",
+					BankID:            "DEUTDEFF",
+					BankIDCode:        "SWBIC",
+				},
+			},
+		},
+	}
+
+	var a = &p.Attributes
+	a.ChargesInformation.BearerCode = "DEBT"
+	a.ChargesInformation.SenderCharges = []payment.Charge{{Amount: 5, Currency: "GBP"}, {Amount: 10.25, Currency: "USD"}}
+	a.ChargesInformation.ReceiverChargesAmount = 1.5
+	a.ChargesInformation.ReceiverChargesCurrency = "EUR"
+	a.Fx.ContractReference = "FX123"
+	a.Fx.ExchangeRate = "1.25"
+	a.Fx.OriginalAmount = "1543.13"
+	a.Fx.OriginalCurrency = "USD"
+
+	return p
+}
@@ -0,0 +1,522 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.fraixed.es/errors"
+)
+
+// PatchType is the type of the document of a Patch.
+type PatchType uint8
+
+// The list of valid PatchType values.
+const (
+	patchTypeNone PatchType = iota
+	// PatchMerge is a JSON Merge Patch document (RFC 7396).
+	PatchMerge
+	// PatchJSON is a JSON Patch document (RFC 6902).
+	PatchJSON
+)
+
+// String returns the string representation of t.
+func (t PatchType) String() string {
+	switch t {
+	case PatchMerge:
+		return "merge"
+	case PatchJSON:
+		return "json"
+	}
+
+	return ""
+}
+
+// Patch is a partial modification of a payment, which is applied to its JSON
+// representation.
+//
+// The fields of the payment which aren't part of PymtUpsert (i.e. id, version
+// and status) and its organisation cannot be modified by a patch.
+type Patch struct {
+	typ   PatchType
+	merge interface{}
+	ops   []patchOp
+}
+
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+
+	path  []string
+	from  []string
+	value interface{}
+}
+
+// NewPatch creates a Patch of the type t from the document doc.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidArgPatch - When t isn't a valid PatchType or doc isn't a valid
+// document of the type t.
+func NewPatch(t PatchType, doc []byte) (Patch, error) {
+	var p = Patch{typ: t}
+	switch t {
+	case PatchMerge:
+		var err = decodeJSON(doc, &p.merge)
+		if err != nil {
+			return Patch{}, errors.Wrap(err, ErrInvalidArgPatch, ErrMDArg("t", t))
+		}
+	case PatchJSON:
+		var err = decodeJSON(doc, &p.ops)
+		if err != nil {
+			return Patch{}, errors.Wrap(err, ErrInvalidArgPatch, ErrMDArg("t", t))
+		}
+
+		for i := range p.ops {
+			if err := p.ops[i].init(); err != nil {
+				return Patch{}, errors.Wrap(err, ErrInvalidArgPatch, ErrMDArg("t", t), ErrMDVar("operation", i))
+			}
+		}
+	default:
+		return Patch{}, errors.New(ErrInvalidArgPatch, ErrMDArg("t", t))
+	}
+
+	return p, nil
+}
+
+// Type returns the type of the document of p.
+func (p Patch) Type() PatchType {
+	return p.typ
+}
+
+// Apply applies p to the JSON representation of pymt and returns the resulting
+// payment. The operations of a JSON Patch are applied in order and if any of
+// them fails, none is applied. The resulting payment isn't validated.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidArgPatch - When p is the zero value, an operation cannot be
+// applied or the result isn't a valid JSON representation of a payment.
+//
+// * ErrInvalidArgPatchReadOnly - When p modifies the ID, version, status or
+// organisation of the payment.
+//
+// * ErrInvalidArgPatchTestFailed - When a test operation of a JSON Patch fails.
+func (p Patch) Apply(pymt Pymt) (PymtUpsert, error) {
+	var b, err = json.Marshal(pymt)
+	if err != nil {
+		return PymtUpsert{}, errors.Wrap(err, ErrUnexpectedSysError, ErrMDFnCall("json.Marshal", pymt))
+	}
+
+	var doc interface{}
+	if err := decodeJSON(b, &doc); err != nil {
+		return PymtUpsert{}, errors.Wrap(err, ErrUnexpectedSysError, ErrMDFnCall("json.Decoder.Decode"))
+	}
+
+	switch p.typ {
+	case PatchMerge:
+		doc = mergePatch(doc, p.merge)
+	case PatchJSON:
+		for i, op := range p.ops {
+			doc, err = op.apply(doc)
+			if err != nil {
+				var c, _ = errors.GetCode(err)
+				return PymtUpsert{}, errors.Wrap(err, c, ErrMDVar("operation", i))
+			}
+		}
+	default:
+		return PymtUpsert{}, errors.New(ErrInvalidArgPatch, ErrMDArg("p", p.typ))
+	}
+
+	b, err = json.Marshal(doc)
+	if err != nil {
+		return PymtUpsert{}, errors.Wrap(err, ErrUnexpectedSysError, ErrMDFnCall("json.Marshal", doc))
+	}
+
+	var pp Pymt
+	var dec = json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&pp); err != nil {
+		return PymtUpsert{}, errors.Wrap(err, ErrInvalidArgPatch)
+	}
+
+	for _, f := range []struct {
+		name     string
+		modified bool
+	}{
+		{name: "ID", modified: pp.ID != pymt.ID},
+		{name: "Version", modified: pp.Version != pymt.Version},
+		{name: "Status", modified: pp.Status != pymt.Status},
+		{name: "OrgID", modified: pp.OrgID != pymt.OrgID},
+	} {
+		if f.modified {
+			return PymtUpsert{}, errors.New(ErrInvalidArgPatchReadOnly, ErrMDField(f.name, nil))
+		}
+	}
+
+	return pp.PymtUpsert, nil
+}
+
+// mergePatch applies the merge patch p to the target t as described by
+// RFC 7396.
+func mergePatch(t interface{}, p interface{}) interface{} {
+	var pm, ok = p.(map[string]interface{})
+	if !ok {
+		return p
+	}
+
+	tm, ok := t.(map[string]interface{})
+	if !ok {
+		tm = map[string]interface{}{}
+	}
+
+	for k, v := range pm {
+		if v == nil {
+			delete(tm, k)
+			continue
+		}
+
+		tm[k] = mergePatch(tm[k], v)
+	}
+
+	return tm
+}
+
+// init validates the members of op and initializes its unexported fields.
+func (op *patchOp) init() error {
+	if op.Path == nil {
+		return errors.New(ErrInvalidArgPatch, ErrMDField("path", nil))
+	}
+
+	var err error
+	op.path, err = parsePointer(*op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return errors.New(ErrInvalidArgPatch, ErrMDField("value", nil))
+		}
+
+		if err := decodeJSON(op.Value, &op.value); err != nil {
+			return errors.Wrap(err, ErrInvalidArgPatch, ErrMDField("value", string(op.Value)))
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return errors.New(ErrInvalidArgPatch, ErrMDField("from", nil))
+		}
+
+		op.from, err = parsePointer(*op.From)
+		if err != nil {
+			return err
+		}
+
+		if op.Op == "move" && len(op.from) < len(op.path) && isPrefix(op.from, op.path) {
+			return errors.New(ErrInvalidArgPatch, ErrMDField("from", *op.From), ErrMDField("path", *op.Path))
+		}
+	case "remove":
+	default:
+		return errors.New(ErrInvalidArgPatch, ErrMDField("op", op.Op))
+	}
+
+	return nil
+}
+
+// apply applies op to doc, modifying it, and returns the result.
+func (op patchOp) apply(doc interface{}) (interface{}, error) {
+	switch op.Op {
+	case "add":
+		return addValue(doc, op.path, copyValue(op.value))
+	case "remove":
+		var doc, _, err = removeValue(doc, op.path)
+		return doc, err
+	case "replace":
+		return updateValue(doc, op.path, func(interface{}) (interface{}, error) {
+			return copyValue(op.value), nil
+		})
+	case "move":
+		var doc, v, err = removeValue(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+
+		return addValue(doc, op.path, v)
+	case "copy":
+		var v, err = getValue(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+
+		return addValue(doc, op.path, copyValue(v))
+	case "test":
+		var v, err = getValue(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !equalValues(v, op.value) {
+			return nil, errors.New(ErrInvalidArgPatchTestFailed, ErrMDField("path", *op.Path))
+		}
+
+		return doc, nil
+	}
+
+	return nil, errors.New(ErrInvalidArgPatch, ErrMDField("op", op.Op))
+}
+
+// parsePointer parses the JSON Pointer (RFC 6901) p and returns its reference
+// tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+
+	if p[0] != '/' {
+		return nil, errors.New(ErrInvalidArgPatch, ErrMDArg("p", p))
+	}
+
+	var toks = strings.Split(p[1:], "/")
+	for i, t := range toks {
+		toks[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+
+	return toks, nil
+}
+
+func isPrefix(prefix []string, path []string) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// arrayIndex returns the index of the reference token t of an array of length
+// l. When end is true, "-" and l are valid indexes, which reference the end of
+// the array.
+func arrayIndex(t string, l int, end bool) (int, error) {
+	if end && t == "-" {
+		return l, nil
+	}
+
+	var i, err = strconv.Atoi(t)
+	if err != nil || i < 0 || (len(t) > 1 && t[0] == '0') || t[0] == '+' {
+		return 0, errors.New(ErrInvalidArgPatch, ErrMDArg("t", t))
+	}
+
+	if i > l || (i == l && !end) {
+		return 0, errors.New(ErrInvalidArgPatch, ErrMDArg("t", t), ErrMDFact("length", l))
+	}
+
+	return i, nil
+}
+
+// updateValue replaces the value of doc referenced by path by the value
+// returned by fn, which receives the current one, and returns the result.
+// The value must exist.
+func updateValue(
+	doc interface{}, path []string, fn func(interface{}) (interface{}, error),
+) (interface{}, error) {
+	if len(path) == 0 {
+		return fn(doc)
+	}
+
+	switch n := doc.(type) {
+	case map[string]interface{}:
+		var v, ok = n[path[0]]
+		if !ok {
+			return nil, errors.New(ErrInvalidArgPatch, ErrMDArg("path", path))
+		}
+
+		v, err := updateValue(v, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		n[path[0]] = v
+		return n, nil
+	case []interface{}:
+		var i, err = arrayIndex(path[0], len(n), false)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := updateValue(n[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		n[i] = v
+		return n, nil
+	}
+
+	return nil, errors.New(ErrInvalidArgPatch, ErrMDArg("path", path))
+}
+
+func getValue(doc interface{}, path []string) (interface{}, error) {
+	var v interface{}
+	var _, err = updateValue(doc, path, func(cv interface{}) (interface{}, error) {
+		v = cv
+		return cv, nil
+	})
+
+	return v, err
+}
+
+// addValue adds v to doc at path, replacing the member of an object if it
+// exists or inserting it in an array, and returns the result.
+func addValue(doc interface{}, path []string, v interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+
+	var last = path[len(path)-1]
+	return updateValue(doc, path[:len(path)-1], func(p interface{}) (interface{}, error) {
+		switch n := p.(type) {
+		case map[string]interface{}:
+			n[last] = v
+			return n, nil
+		case []interface{}:
+			var i, err = arrayIndex(last, len(n), true)
+			if err != nil {
+				return nil, err
+			}
+
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = v
+			return n, nil
+		}
+
+		return nil, errors.New(ErrInvalidArgPatch, ErrMDArg("path", path))
+	})
+}
+
+// removeValue removes the value of doc referenced by path and returns the
+// result and the removed value.
+func removeValue(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New(ErrInvalidArgPatch, ErrMDArg("path", path))
+	}
+
+	var (
+		last = path[len(path)-1]
+		rv   interface{}
+	)
+
+	doc, err := updateValue(doc, path[:len(path)-1], func(p interface{}) (interface{}, error) {
+		switch n := p.(type) {
+		case map[string]interface{}:
+			var v, ok = n[last]
+			if !ok {
+				return nil, errors.New(ErrInvalidArgPatch, ErrMDArg("path", path))
+			}
+
+			rv = v
+			delete(n, last)
+			return n, nil
+		case []interface{}:
+			var i, err = arrayIndex(last, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+
+			rv = n[i]
+			return append(n[:i], n[i+1:]...), nil
+		}
+
+		return nil, errors.New(ErrInvalidArgPatch, ErrMDArg("path", path))
+	})
+
+	return doc, rv, err
+}
+
+// copyValue returns a deep copy of the JSON value v.
+func copyValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		var m = make(map[string]interface{}, len(n))
+		for k, v := range n {
+			m[k] = copyValue(v)
+		}
+
+		return m
+	case []interface{}:
+		var a = make([]interface{}, len(n))
+		for i, v := range n {
+			a[i] = copyValue(v)
+		}
+
+		return a
+	}
+
+	return v
+}
+
+// equalValues returns true if the JSON values a and b are equal, comparing
+// the numbers by their value (e.g. 1 and 1.0 are equal).
+func equalValues(a interface{}, b interface{}) bool {
+	switch an := a.(type) {
+	case map[string]interface{}:
+		var bn, ok = b.(map[string]interface{})
+		if !ok || len(an) != len(bn) {
+			return false
+		}
+
+		for k, v := range an {
+			if bv, ok := bn[k]; !ok || !equalValues(v, bv) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		var bn, ok = b.([]interface{})
+		if !ok || len(an) != len(bn) {
+			return false
+		}
+
+		for i := range an {
+			if !equalValues(an[i], bn[i]) {
+				return false
+			}
+		}
+
+		return true
+	case json.Number:
+		var bn, ok = b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		var ar, aok = new(big.Rat).SetString(an.String())
+		var br, bok = new(big.Rat).SetString(bn.String())
+		return aok && bok && ar.Cmp(br) == 0
+	}
+
+	return a == b
+}
+
+// decodeJSON decodes the JSON document b into v, decoding the numbers as
+// json.Number for not losing precision. b must only contain one JSON value.
+func decodeJSON(b []byte, v interface{}) error {
+	var dec = json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	if dec.More() {
+		return errors.New(ErrInvalidArgPatch)
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package payment_test
+
+import (
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestNewPatch(t *testing.T) {
+	var tcases = []struct {
+		desc string
+		typ  payment.PatchType
+		doc  string
+		err  bool
+	}{
+		{desc: "merge", typ: payment.PatchMerge, doc: `{"attributes":{"reference":"x"}}`},
+		{desc: "json", typ: payment.PatchJSON, doc: `[{"op":"add","path":"/a/~1b","value":null}]`},
+		{desc: "json empty", typ: payment.PatchJSON, doc: `[]`},
+		{desc: "error: type", typ: 0, doc: `{}`, err: true},
+		{desc: "error: merge syntax", typ: payment.PatchMerge, doc: `{"a":`, err: true},
+		{desc: "error: json not array", typ: payment.PatchJSON, doc: `{"op":"remove","path":"/a"}`, err: true},
+		{desc: "error: json op", typ: payment.PatchJSON, doc: `[{"op":"delete","path":"/a"}]`, err: true},
+		{desc: "error: json path", typ: payment.PatchJSON, doc: `[{"op":"remove"}]`, err: true},
+		{desc: "error: json pointer", typ: payment.PatchJSON, doc: `[{"op":"remove","path":"a"}]`, err: true},
+		{desc: "error: json value", typ: payment.PatchJSON, doc: `[{"op":"add","path":"/a"}]`, err: true},
+		{desc: "error: json from", typ: payment.PatchJSON, doc: `[{"op":"copy","path":"/a"}]`, err: true},
+		{
+			desc: "error: json move to a child",
+			typ:  payment.PatchJSON,
+			doc:  `[{"op":"move","from":"/a","path":"/a/b"}]`,
+			err:  true,
+		},
+		{desc: "error: trailing data", typ: payment.PatchJSON, doc: `[] []`, err: true},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var p, err = payment.NewPatch(tc.typ, []byte(tc.doc))
+			if tc.err {
+				testutil.AssertError(t, err, payment.ErrInvalidArgPatch)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.typ, p.Type())
+		})
+	}
+}
+
+func TestPatch_Apply(t *testing.T) {
+	var pymt = payment.Pymt{
+		ID:      testutil.NewUUID(t),
+		Version: 3,
+		Status:  payment.StatusPending,
+		PymtUpsert: payment.PymtUpsert{
+			Type:       "Payment",
+			OrgID:      testutil.NewUUID(t),
+			Attributes: testutil.NewAttrs(t),
+		},
+	}
+	pymt.Attributes.Amount = 10.5
+	pymt.Attributes.ChargesInformation.SenderCharges = []payment.Charge{
+		{Amount: 1, Currency: "GBP"}, {Amount: 2, Currency: "USD"},
+	}
+
+	var tcases = []struct {
+		desc   string
+		typ    payment.PatchType
+		doc    string
+		expect func() payment.PymtUpsert
+		err    errors.Code
+	}{
+		{
+			desc: "merge",
+			typ:  payment.PatchMerge,
+			doc:  `{"attributes":{"reference":"new ref","fx":null,"sponsor_party":{"name":"Sponsor"}}}`,
+			expect: func() payment.PymtUpsert {
+				var p = pymt.PymtUpsert
+				p.Attributes.Reference = "new ref"
+				p.Attributes.Fx.ContractReference = ""
+				p.Attributes.Fx.ExchangeRate = ""
+				p.Attributes.Fx.OriginalAmount = ""
+				p.Attributes.Fx.OriginalCurrency = ""
+				p.Attributes.SponsorParty.Name = "Sponsor"
+				return p
+			},
+		},
+		{
+			desc: "merge replaces arrays",
+			typ:  payment.PatchMerge,
+			doc:  `{"attributes":{"charges_information":{"sender_charges":[{"amount":3,"currency":"EUR"}]}}}`,
+			expect: func() payment.PymtUpsert {
+				var p = pymt.PymtUpsert
+				p.Attributes.ChargesInformation.SenderCharges = []payment.Charge{{Amount: 3, Currency: "EUR"}}
+				return p
+			},
+		},
+		{
+			desc: "json",
+			typ:  payment.PatchJSON,
+			doc: `[
+				{"op":"test","path":"/attributes/amount","value":10.50},
+				{"op":"replace","path":"/attributes/reference","value":"new ref"},
+				{"op":"add","path":"/attributes/charges_information/sender_charges/0",
+					"value":{"amount":3,"currency":"EUR"}},
+				{"op":"remove","path":"/attributes/charges_information/sender_charges/2"},
+				{"op":"copy","from":"/attributes/debtor_party","path":"/attributes/sponsor_party"},
+				{"op":"move","from":"/attributes/charges_information/sender_charges/0","path":"/attributes/charges_information/sender_charges/-"}
+			]`,
+			expect: func() payment.PymtUpsert {
+				var p = pymt.PymtUpsert
+				p.Attributes.Reference = "new ref"
+				p.Attributes.ChargesInformation.SenderCharges = []payment.Charge{
+					{Amount: 1, Currency: "GBP"}, {Amount: 3, Currency: "EUR"},
+				}
+				p.Attributes.SponsorParty = p.Attributes.DebtorParty
+				return p
+			},
+		},
+		{
+			desc: "json test with the same values",
+			typ:  payment.PatchJSON,
+			doc: `[
+				{"op":"test","path":"/attributes/charges_information/sender_charges/1","value":{"currency":"USD","amount":2.0}}
+			]`,
+			expect: func() payment.PymtUpsert {
+				return pymt.PymtUpsert
+			},
+		},
+		{
+			desc: "error: json test failed",
+			typ:  payment.PatchJSON,
+			doc: `[
+				{"op":"replace","path":"/attributes/reference","value":"new ref"},
+				{"op":"test","path":"/attributes/amount","value":11}
+			]`,
+			err: payment.ErrInvalidArgPatchTestFailed,
+		},
+		{
+			desc: "error: json path doesn't exist",
+			typ:  payment.PatchJSON,
+			doc:  `[{"op":"replace","path":"/attributes/unknown","value":"x"}]`,
+			err:  payment.ErrInvalidArgPatch,
+		},
+		{
+			desc: "error: json index out of range",
+			typ:  payment.PatchJSON,
+			doc:  `[{"op":"remove","path":"/attributes/charges_information/sender_charges/2"}]`,
+			err:  payment.ErrInvalidArgPatch,
+		},
+		{
+			desc: "error: json invalid index",
+			typ:  payment.PatchJSON,
+			doc:  `[{"op":"add","path":"/attributes/charges_information/sender_charges/01","value":{}}]`,
+			err:  payment.ErrInvalidArgPatch,
+		},
+		{
+			desc: "error: unknown field",
+			typ:  payment.PatchJSON,
+			doc:  `[{"op":"add","path":"/attributes/unknown","value":"x"}]`,
+			err:  payment.ErrInvalidArgPatch,
+		},
+		{
+			desc: "error: invalid type",
+			typ:  payment.PatchMerge,
+			doc:  `{"attributes":{"amount":"ten"}}`,
+			err:  payment.ErrInvalidArgPatch,
+		},
+		{
+			desc: "error: not an object",
+			typ:  payment.PatchMerge,
+			doc:  `[]`,
+			err:  payment.ErrInvalidArgPatch,
+		},
+		{
+			desc: "error: read only version",
+			typ:  payment.PatchMerge,
+			doc:  `{"version":4}`,
+			err:  payment.ErrInvalidArgPatchReadOnly,
+		},
+		{
+			desc: "error: read only status",
+			typ:  payment.PatchJSON,
+			doc:  `[{"op":"replace","path":"/status","value":"Settled"}]`,
+			err:  payment.ErrInvalidArgPatchReadOnly,
+		},
+		{
+			desc: "error: read only organisation",
+			typ:  payment.PatchJSON,
+			doc:  `[{"op":"remove","path":"/organisation_id"}]`,
+			err:  payment.ErrInvalidArgPatchReadOnly,
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var p, err = payment.NewPatch(tc.typ, []byte(tc.doc))
+			require.NoError(t, err)
+
+			pu, err := p.Apply(pymt)
+			if tc.err != nil {
+				testutil.AssertError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect(), pu)
+		})
+	}
+
+	t.Run("error: zero value", func(t *testing.T) {
+		var _, err = payment.Patch{}.Apply(pymt)
+		testutil.AssertError(t, err, payment.ErrInvalidArgPatch)
+	})
+}
@@ -1,8 +1,11 @@
 package payment
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/gofrs/uuid"
-	"go.fraixed.es/errors"
+	"github.com/ifraixedes/go-payments-api-example/payment/accountid"
 )
 
 // Pymt contains all the information which a single payment has.
@@ -10,6 +13,7 @@ type Pymt struct {
 	PymtUpsert
 	ID      uuid.UUID `json:"id"`
 	Version uint32    `json:"version"`
+	Status  Status    `json:"status"`
 }
 
 // PymtUpsert contains the information required to create or update a payment.
@@ -21,24 +25,26 @@ type PymtUpsert struct {
 
 // Validate validates that the input payment contains all the required values
 // and their values respect the requirments of the business domain.
+//
+// It returns an error with all the violations, see Violations.Err.
 func (p PymtUpsert) Validate() error {
-	// TODO: won't be implemented
-	// Some other fields should be also validated.
+	return p.Violations().Err()
+}
 
+// Violations returns all the violations of the payment, being the fields of the
+// attributes prefixed by "Attributes".
+func (p PymtUpsert) Violations() Violations {
+	var vs Violations
 	if p.OrgID == uuid.Nil {
-		return errors.New(ErrInvalidPaymentOrgID, ErrMDField("OrgID", p.OrgID))
+		vs.add("OrgID", ErrInvalidPaymentOrgID, p.OrgID)
 	}
 
 	if p.Type != "Payment" {
-		return errors.New(ErrInvalidPaymentType, ErrMDField("Type", p.Type))
-	}
-
-	if err := p.Attributes.Validate(); err != nil {
-		var c, _ = errors.GetCode(err)
-		return errors.Wrap(err, c, ErrMDField("Attributes", p.Attributes))
+		vs.add("Type", ErrInvalidPaymentType, p.Type)
 	}
 
-	return nil
+	vs.merge("Attributes", p.Attributes.Violations())
+	return vs
 }
 
 // Attrs contains the information of the attributes attached to a payment.
@@ -59,13 +65,10 @@ type Attrs struct {
 	DebtorParty          Party   `json:"debtor_party"`
 	SponsorParty         Party   `json:"sponsor_party"`
 	ChargesInformation   struct {
-		BearerCode    string `json:"bearer_code"`
-		SenderCharges []struct {
-			Amount   float64 `json:"amount"`
-			Currency string  `json:"currency"`
-		} `json:"sender_charges"`
-		ReceiverChargesAmount   float64 `json:"receiver_charges_amount"`
-		ReceiverChargesCurrency string  `json:"receiver_charges_currency"`
+		BearerCode              string   `json:"bearer_code"`
+		SenderCharges           []Charge `json:"sender_charges"`
+		ReceiverChargesAmount   float64  `json:"receiver_charges_amount"`
+		ReceiverChargesCurrency string   `json:"receiver_charges_currency"`
 	} `json:"charges_information"`
 	Fx struct {
 		ContractReference string `json:"contract_reference"`
@@ -75,17 +78,96 @@ type Attrs struct {
 	} `json:"fx"`
 }
 
+// Charge contains the information of a charge applied to a payment.
+type Charge struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
 // Validate validates that the attributes contains alls the required values and
 // their values respect the requirements of the business domain.
+//
+// It returns an error with all the violations, see Violations.Err.
 func (a Attrs) Validate() error {
-	// TODO: won't be implemented
-	// Some other fields should be also validated.
+	return a.Violations().Err()
+}
 
+// Violations returns all the violations of the attributes, being the fields of
+// the parties prefixed by their attribute name.
+func (a Attrs) Violations() Violations {
+	var vs Violations
 	if a.PaymentID == "" {
-		return errors.New(ErrInvalidPaymentAttrPaymentID, ErrMDField("PaymentID", ""))
+		vs.add("PaymentID", ErrInvalidPaymentAttrPaymentID, a.PaymentID)
+	}
+
+	vs = append(vs, amountViolations("Amount", a.Amount, "Currency", a.Currency)...)
+
+	if _, err := time.Parse(processingDateTimeLayout, a.ProcessingDate); err != nil {
+		vs.add("ProcessingDate", ErrInvalidPaymentAttrDate, a.ProcessingDate)
+	}
+
+	if a.NumericReference != "" && !numericReferenceRegexp.MatchString(a.NumericReference) {
+		vs.add("NumericReference", ErrInvalidPaymentAttrFormat, a.NumericReference)
 	}
 
-	return nil
+	if !isOneOf(a.PaymentScheme, knownPaymentSchemes) {
+		vs.add("PaymentScheme", ErrInvalidPaymentAttrCode, a.PaymentScheme)
+	}
+
+	if !isOneOf(a.PaymentType, knownPaymentTypes) {
+		vs.add("PaymentType", ErrInvalidPaymentAttrCode, a.PaymentType)
+	}
+
+	if a.SchemePaymentSubType != "" && !isOneOf(a.SchemePaymentSubType, knownSchemePaymentSubTypes) {
+		vs.add("SchemePaymentSubType", ErrInvalidPaymentAttrCode, a.SchemePaymentSubType)
+	}
+
+	if !isOneOf(a.SchemePaymentType, knownSchemePaymentTypes) {
+		vs.add("SchemePaymentType", ErrInvalidPaymentAttrCode, a.SchemePaymentType)
+	}
+
+	// The beneficiary and the debtor are the account holders, hence their names
+	// are required, meanwhile the sponsor is optional.
+	for _, pt := range []struct {
+		field string
+		party Party
+	}{{"BeneficiaryParty", a.BeneficiaryParty}, {"DebtorParty", a.DebtorParty}} {
+		if pt.party.Name == "" {
+			vs.add(pt.field+".Name", ErrInvalidPaymentAttrRequired, pt.party.Name)
+		}
+
+		vs.merge(pt.field, pt.party.Violations())
+	}
+
+	if a.SponsorParty != (Party{}) {
+		vs.merge("SponsorParty", a.SponsorParty.Violations())
+	}
+
+	var ci = a.ChargesInformation
+	if ci.BearerCode != "" && !isOneOf(ci.BearerCode, knownBearerCodes) {
+		vs.add("ChargesInformation.BearerCode", ErrInvalidPaymentAttrCode, ci.BearerCode)
+	}
+
+	for i, sc := range ci.SenderCharges {
+		var f = fmt.Sprintf("SenderCharges.%d", i)
+		vs.merge("ChargesInformation", amountViolations(f+".Amount", sc.Amount, f+".Currency", sc.Currency))
+	}
+
+	if ci.ReceiverChargesAmount != 0 || ci.ReceiverChargesCurrency != "" {
+		vs.merge("ChargesInformation", amountViolations(
+			"ReceiverChargesAmount", ci.ReceiverChargesAmount,
+			"ReceiverChargesCurrency", ci.ReceiverChargesCurrency,
+		))
+	}
+
+	vs.merge("ChargesInformation", a.chargesCurrencyViolations())
+
+	var fx = a.Fx
+	if fx.ContractReference != "" || fx.ExchangeRate != "" || fx.OriginalAmount != "" || fx.OriginalCurrency != "" {
+		vs.merge("Fx", a.fxViolations())
+	}
+
+	return vs
 }
 
 // Party contains the information of each single party involved in a payment.
@@ -102,7 +184,62 @@ type Party struct {
 
 // Validate valides that the party contains all the required values and their
 // values respect the requirments of the business domain.
+//
+// It returns an error with all the violations, see Violations.Err.
 func (p Party) Validate() error {
-	// TODO: won't be implemented
-	return nil
+	return p.Violations().Err()
+}
+
+// Violations returns all the violations of the party.
+//
+// The account number and the bank ID are validated according to their codes:
+// IBAN account numbers and SWBIC bank IDs must be valid IBANs and BICs and
+// GBDSC bank IDs must be UK sort codes, which must pass the modulus check with
+// the account number when its code is BBAN or empty.
+//
+// The name isn't required because it isn't for the sponsor party, the
+// attributes validate it for the parties which require it.
+func (p Party) Violations() Violations {
+	var vs Violations
+	switch {
+	case p.AccountNumber == "":
+		vs.add("AccountNumber", ErrInvalidPaymentAttrRequired, p.AccountNumber)
+	case p.AccountNumberCode == "IBAN":
+		if accountid.ValidateIBAN(p.AccountNumber) != nil {
+			vs.add("AccountNumber", ErrInvalidPaymentAttrAccountNumber, p.AccountNumber)
+		}
+	}
+
+	if p.AccountNumberCode != "" && !isOneOf(p.AccountNumberCode, knownAccountNumberCodes) {
+		vs.add("AccountNumberCode", ErrInvalidPaymentAttrCode, p.AccountNumberCode)
+	}
+
+	if p.AccountType != 0 && p.AccountType != 1 {
+		vs.add("AccountType", ErrInvalidPaymentAttrCode, p.AccountType)
+	}
+
+	switch {
+	case p.BankID == "":
+		vs.add("BankID", ErrInvalidPaymentAttrRequired, p.BankID)
+	case p.BankIDCode == "SWBIC":
+		if accountid.ValidateBIC(p.BankID) != nil {
+			vs.add("BankID", ErrInvalidPaymentAttrBankID, p.BankID)
+		}
+	case p.BankIDCode == "GBDSC":
+		if accountid.ValidateSortCode(p.BankID) != nil {
+			vs.add("BankID", ErrInvalidPaymentAttrBankID, p.BankID)
+			break
+		}
+
+		if p.AccountNumber != "" && (p.AccountNumberCode == "" || p.AccountNumberCode == "BBAN") &&
+			accountid.ValidateUKAccount(p.BankID, p.AccountNumber) != nil {
+			vs.add("AccountNumber", ErrInvalidPaymentAttrAccountNumber, p.AccountNumber)
+		}
+	}
+
+	if !isOneOf(p.BankIDCode, knownBankIDCodes) {
+		vs.add("BankIDCode", ErrInvalidPaymentAttrCode, p.BankIDCode)
+	}
+
+	return vs
 }
@@ -25,10 +25,8 @@ func TestPymtUpsert_Validate(t *testing.T) {
 
 					return id
 				}(),
-				Type: "Payment",
-				Attributes: payment.Attrs{
-					PaymentID: "some-id",
-				},
+				Type:       "Payment",
+				Attributes: testutil.NewAttrs(t),
 			},
 			assert: func(t *testing.T, _ payment.PymtUpsert, err error) {
 				assert.NoError(t, err)
@@ -37,13 +35,13 @@ func TestPymtUpsert_Validate(t *testing.T) {
 		{
 			desc: "Invalid: OrgID",
 			p: payment.PymtUpsert{
-				Type: "Payment",
-				Attributes: payment.Attrs{
-					PaymentID: "some-id",
-				},
+				Type:       "Payment",
+				Attributes: testutil.NewAttrs(t),
 			},
 			assert: func(t *testing.T, p payment.PymtUpsert, err error) {
-				testutil.AssertError(t, err, payment.ErrInvalidPaymentOrgID, payment.ErrMDField("OrgID", p.OrgID))
+				testutil.AssertError(t, err, payment.ErrInvalidPaymentOrgID, payment.ErrMDField("OrgID", payment.Violation{
+					Field: "OrgID", Code: payment.ErrInvalidPaymentOrgID, Value: p.OrgID,
+				}))
 			},
 		},
 		{
@@ -55,13 +53,13 @@ func TestPymtUpsert_Validate(t *testing.T) {
 
 					return id
 				}(),
-				Type: "Transfer",
-				Attributes: payment.Attrs{
-					PaymentID: "some-id",
-				},
+				Type:       "Transfer",
+				Attributes: testutil.NewAttrs(t),
 			},
 			assert: func(t *testing.T, p payment.PymtUpsert, err error) {
-				testutil.AssertError(t, err, payment.ErrInvalidPaymentType, payment.ErrMDField("Type", p.Type))
+				testutil.AssertError(t, err, payment.ErrInvalidPaymentType, payment.ErrMDField("Type", payment.Violation{
+					Field: "Type", Code: payment.ErrInvalidPaymentType, Value: p.Type,
+				}))
 			},
 		},
 		{
@@ -74,9 +72,42 @@ func TestPymtUpsert_Validate(t *testing.T) {
 					return id
 				}(),
 				Type: "Payment",
+				Attributes: func() payment.Attrs {
+					var a = testutil.NewAttrs(t)
+					a.PaymentID = ""
+					return a
+				}(),
+			},
+			assert: func(t *testing.T, p payment.PymtUpsert, err error) {
+				testutil.AssertError(t, err, payment.ErrInvalidPaymentAttrPaymentID,
+					payment.ErrMDField("Attributes.PaymentID", payment.Violation{
+						Field: "Attributes.PaymentID", Code: payment.ErrInvalidPaymentAttrPaymentID, Value: "",
+					}),
+				)
+			},
+		},
+		{
+			desc: "Invalid: all the violations",
+			p: payment.PymtUpsert{
+				Type: "Transfer",
+				Attributes: func() payment.Attrs {
+					var a = testutil.NewAttrs(t)
+					a.Currency = "XXX"
+					return a
+				}(),
 			},
 			assert: func(t *testing.T, p payment.PymtUpsert, err error) {
-				testutil.AssertError(t, err, payment.ErrInvalidPaymentAttrPaymentID, payment.ErrMDField("Attributes", p.Attributes))
+				testutil.AssertError(t, err, payment.ErrInvalidPaymentOrgID,
+					payment.ErrMDField("OrgID", payment.Violation{
+						Field: "OrgID", Code: payment.ErrInvalidPaymentOrgID, Value: p.OrgID,
+					}),
+					payment.ErrMDField("Type", payment.Violation{
+						Field: "Type", Code: payment.ErrInvalidPaymentType, Value: p.Type,
+					}),
+					payment.ErrMDField("Attributes.Currency", payment.Violation{
+						Field: "Attributes.Currency", Code: payment.ErrInvalidPaymentAttrCurrency, Value: "XXX",
+					}),
+				)
 			},
 		},
 	}
@@ -90,6 +121,167 @@ func TestPymtUpsert_Validate(t *testing.T) {
 	}
 }
 
+func TestAttrs_Violations(t *testing.T) {
+	var tcases = []struct {
+		desc  string
+		a     func() payment.Attrs
+		expvs payment.Violations
+	}{
+		{
+			desc:  "Valid",
+			a:     func() payment.Attrs { return testutil.NewAttrs(t) },
+			expvs: nil,
+		},
+		{
+			desc: "Valid: only required values",
+			a: func() payment.Attrs {
+				return payment.Attrs{
+					Amount:            10,
+					Currency:          "JPY",
+					PaymentID:         "some-id",
+					PaymentScheme:     "SWIFT",
+					PaymentType:       "Debit",
+					ProcessingDate:    "2019-02-28",
+					SchemePaymentType: "StandingOrder",
+					BeneficiaryParty: payment.Party{
+						AccountNumber: "31926819", BankID: "403000", BankIDCode: "GBDSC", Name: "Wilfred Owen",
+					},
+					DebtorParty: payment.Party{
+						AccountNumber: "GB29NWBK60161331926819", AccountNumberCode: "IBAN", BankID: "NWBKGB2L",
+						BankIDCode: "SWBIC", Name: "Emelia Jane",
+					},
+				}
+			},
+			expvs: nil,
+		},
+		{
+			desc: "Invalid: empty",
+			a:    func() payment.Attrs { return payment.Attrs{} },
+			expvs: payment.Violations{
+				{Field: "PaymentID", Code: payment.ErrInvalidPaymentAttrPaymentID, Value: ""},
+				{Field: "Currency", Code: payment.ErrInvalidPaymentAttrCurrency, Value: ""},
+				{Field: "Amount", Code: payment.ErrInvalidPaymentAttrAmount, Value: float64(0)},
+				{Field: "ProcessingDate", Code: payment.ErrInvalidPaymentAttrDate, Value: ""},
+				{Field: "PaymentScheme", Code: payment.ErrInvalidPaymentAttrCode, Value: ""},
+				{Field: "PaymentType", Code: payment.ErrInvalidPaymentAttrCode, Value: ""},
+				{Field: "SchemePaymentType", Code: payment.ErrInvalidPaymentAttrCode, Value: ""},
+				{Field: "BeneficiaryParty.Name", Code: payment.ErrInvalidPaymentAttrRequired, Value: ""},
+				{Field: "BeneficiaryParty.AccountNumber", Code: payment.ErrInvalidPaymentAttrRequired, Value: ""},
+				{Field: "BeneficiaryParty.BankID", Code: payment.ErrInvalidPaymentAttrRequired, Value: ""},
+				{Field: "BeneficiaryParty.BankIDCode", Code: payment.ErrInvalidPaymentAttrCode, Value: ""},
+				{Field: "DebtorParty.Name", Code: payment.ErrInvalidPaymentAttrRequired, Value: ""},
+				{Field: "DebtorParty.AccountNumber", Code: payment.ErrInvalidPaymentAttrRequired, Value: ""},
+				{Field: "DebtorParty.BankID", Code: payment.ErrInvalidPaymentAttrRequired, Value: ""},
+				{Field: "DebtorParty.BankIDCode", Code: payment.ErrInvalidPaymentAttrCode, Value: ""},
+			},
+		},
+		{
+			desc: "Invalid: amounts and dates",
+			a: func() payment.Attrs {
+				var a = testutil.NewAttrs(t)
+				a.Amount = 10.5
+				a.Currency = "JPY"
+				a.ProcessingDate = "28/02/2019"
+				a.NumericReference = "10A"
+				return a
+			},
+			expvs: payment.Violations{
+				{Field: "Amount", Code: payment.ErrInvalidPaymentAttrAmount, Value: 10.5},
+				{Field: "ProcessingDate", Code: payment.ErrInvalidPaymentAttrDate, Value: "28/02/2019"},
+				{Field: "NumericReference", Code: payment.ErrInvalidPaymentAttrFormat, Value: "10A"},
+			},
+		},
+		{
+			desc: "Invalid: codes",
+			a: func() payment.Attrs {
+				var a = testutil.NewAttrs(t)
+				a.PaymentScheme = "Cheque"
+				a.SchemePaymentSubType = "Post"
+				a.SponsorParty.AccountNumberCode = "PAN"
+				a.SponsorParty.AccountType = 3
+				a.ChargesInformation.BearerCode = "ALL"
+				return a
+			},
+			expvs: payment.Violations{
+				{Field: "PaymentScheme", Code: payment.ErrInvalidPaymentAttrCode, Value: "Cheque"},
+				{Field: "SchemePaymentSubType", Code: payment.ErrInvalidPaymentAttrCode, Value: "Post"},
+				{Field: "SponsorParty.AccountNumberCode", Code: payment.ErrInvalidPaymentAttrCode, Value: "PAN"},
+				{Field: "SponsorParty.AccountType", Code: payment.ErrInvalidPaymentAttrCode, Value: 3},
+				{Field: "ChargesInformation.BearerCode", Code: payment.ErrInvalidPaymentAttrCode, Value: "ALL"},
+			},
+		},
+		{
+			desc: "Invalid: charges and foreign exchange",
+			a: func() payment.Attrs {
+				var a = testutil.NewAttrs(t)
+				a.ChargesInformation.SenderCharges = append(a.ChargesInformation.SenderCharges[:0],
+					payment.Charge{Amount: 1.001, Currency: "GBP"},
+				)
+				a.ChargesInformation.ReceiverChargesAmount = 1
+				a.ChargesInformation.ReceiverChargesCurrency = ""
+				a.Fx.ContractReference = ""
+				a.Fx.ExchangeRate = "-1"
+				a.Fx.OriginalAmount = "10.123"
+				return a
+			},
+			expvs: payment.Violations{
+				{Field: "ChargesInformation.SenderCharges.0.Amount", Code: payment.ErrInvalidPaymentAttrAmount, Value: 1.001},
+				{Field: "ChargesInformation.ReceiverChargesCurrency", Code: payment.ErrInvalidPaymentAttrCurrency, Value: ""},
+				{Field: "Fx.ContractReference", Code: payment.ErrInvalidPaymentAttrRequired, Value: ""},
+				{Field: "Fx.ExchangeRate", Code: payment.ErrInvalidPaymentAttrAmount, Value: "-1"},
+				{Field: "Fx.OriginalAmount", Code: payment.ErrInvalidPaymentAttrAmount, Value: "10.123"},
+			},
+		},
+		{
+			desc: "Valid: receiver charges in the payment currency",
+			a: func() payment.Attrs {
+				var a = testutil.NewAttrs(t)
+				a.ChargesInformation.ReceiverChargesCurrency = a.Currency
+				return a
+			},
+			expvs: nil,
+		},
+		{
+			desc: "Valid: receiver charges without foreign exchange",
+			a: func() payment.Attrs {
+				var a = testutil.NewAttrs(t)
+				a.ChargesInformation.ReceiverChargesCurrency = a.Currency
+				a.Fx.ContractReference = ""
+				a.Fx.ExchangeRate = ""
+				a.Fx.OriginalAmount = ""
+				a.Fx.OriginalCurrency = ""
+				return a
+			},
+			expvs: nil,
+		},
+		{
+			desc: "Invalid: charges currencies",
+			a: func() payment.Attrs {
+				var a = testutil.NewAttrs(t)
+				a.ChargesInformation.SenderCharges = []payment.Charge{
+					{Amount: 1, Currency: "GBP"}, {Amount: 2, Currency: "GBP"}, {Amount: 3, Currency: "USD"},
+					{Amount: 4, Currency: "XXX"},
+				}
+				a.ChargesInformation.ReceiverChargesCurrency = "EUR"
+				return a
+			},
+			expvs: payment.Violations{
+				{Field: "ChargesInformation.SenderCharges.3.Currency", Code: payment.ErrInvalidPaymentAttrCurrency, Value: "XXX"},
+				{Field: "ChargesInformation.SenderCharges.2.Currency", Code: payment.ErrInvalidPaymentAttrCurrency, Value: "USD"},
+				{Field: "ChargesInformation.ReceiverChargesCurrency", Code: payment.ErrInvalidPaymentAttrCurrency, Value: "EUR"},
+			},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expvs, tc.a().Violations())
+		})
+	}
+}
+
 func TestAttrs_Validate(t *testing.T) {
 	var tcases = []struct {
 		desc   string
@@ -98,18 +290,24 @@ func TestAttrs_Validate(t *testing.T) {
 	}{
 		{
 			desc: "Valid",
-			a: payment.Attrs{
-				PaymentID: "some-id",
-			},
+			a:    testutil.NewAttrs(t),
 			assert: func(t *testing.T, _ payment.Attrs, err error) {
 				assert.NoError(t, err)
 			},
 		},
 		{
 			desc: "Invalid: PaymentID",
-			a:    payment.Attrs{},
+			a: func() payment.Attrs {
+				var a = testutil.NewAttrs(t)
+				a.PaymentID = ""
+				return a
+			}(),
 			assert: func(t *testing.T, a payment.Attrs, err error) {
-				testutil.AssertError(t, err, payment.ErrInvalidPaymentAttrPaymentID, payment.ErrMDField("PaymentID", a.PaymentID))
+				testutil.AssertError(t, err, payment.ErrInvalidPaymentAttrPaymentID,
+					payment.ErrMDField("PaymentID", payment.Violation{
+						Field: "PaymentID", Code: payment.ErrInvalidPaymentAttrPaymentID, Value: a.PaymentID,
+					}),
+				)
 			},
 		},
 	}
@@ -122,3 +320,75 @@ func TestAttrs_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestParty_Validate(t *testing.T) {
+	var p = payment.Party{AccountNumber: "31926819", BankID: "403000", BankIDCode: "GBDSC"}
+	assert.NoError(t, p.Validate())
+
+	p.BankID = ""
+	p.BankIDCode = "BIC"
+	var err = p.Validate()
+	testutil.AssertError(t, err, payment.ErrInvalidPaymentAttrRequired,
+		payment.ErrMDField("BankID", payment.Violation{
+			Field: "BankID", Code: payment.ErrInvalidPaymentAttrRequired, Value: "",
+		}),
+		payment.ErrMDField("BankIDCode", payment.Violation{
+			Field: "BankIDCode", Code: payment.ErrInvalidPaymentAttrCode, Value: "BIC",
+		}),
+	)
+}
+
+func TestParty_Violations(t *testing.T) {
+	var tcases = []struct {
+		desc  string
+		p     payment.Party
+		expvs payment.Violations
+	}{
+		{
+			desc: "Valid: IBAN and BIC",
+			p: payment.Party{
+				AccountNumber: "GB29NWBK60161331926819", AccountNumberCode: "IBAN", BankID: "NWBKGB2L", BankIDCode: "SWBIC",
+			},
+		},
+		{
+			desc: "Valid: UK account",
+			p:    payment.Party{AccountNumber: "88837491", AccountNumberCode: "BBAN", BankID: "107999", BankIDCode: "GBDSC"},
+		},
+		{
+			desc: "Valid: UK account whose sort code isn't in the weights table",
+			p:    payment.Party{AccountNumber: "31926819", BankID: "403000", BankIDCode: "GBDSC"},
+		},
+		{
+			desc: "Invalid: IBAN and BIC",
+			p: payment.Party{
+				AccountNumber: "GB28NWBK60161331926819", AccountNumberCode: "IBAN", BankID: "NWBKGB", BankIDCode: "SWBIC",
+			},
+			expvs: payment.Violations{
+				{Field: "AccountNumber", Code: payment.ErrInvalidPaymentAttrAccountNumber, Value: "GB28NWBK60161331926819"},
+				{Field: "BankID", Code: payment.ErrInvalidPaymentAttrBankID, Value: "NWBKGB"},
+			},
+		},
+		{
+			desc: "Invalid: UK account modulus check",
+			p:    payment.Party{AccountNumber: "88837492", BankID: "107999", BankIDCode: "GBDSC"},
+			expvs: payment.Violations{
+				{Field: "AccountNumber", Code: payment.ErrInvalidPaymentAttrAccountNumber, Value: "88837492"},
+			},
+		},
+		{
+			desc: "Invalid: sort code",
+			p:    payment.Party{AccountNumber: "88837491", BankID: "10-79-99", BankIDCode: "GBDSC"},
+			expvs: payment.Violations{
+				{Field: "BankID", Code: payment.ErrInvalidPaymentAttrBankID, Value: "10-79-99"},
+			},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expvs, tc.p.Violations())
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package ratelimit
+
+type code uint8
+
+// The list of specific error codes that the ratelimit package can return.
+const (
+	ErrInvalidArgLimits code = iota + 1
+	ErrRateLimited
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrInvalidArgLimits:
+		return "InvalidArgLimits"
+	case ErrRateLimited:
+		return "RateLimited"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrInvalidArgLimits:
+		return "The rate of the limits must be greater than 0 and their burst at least 1"
+	case ErrRateLimited:
+		return "The rate limit of the operations has been exceeded, they can be retried later"
+	}
+
+	return ""
+}
@@ -0,0 +1,310 @@
+// Package ratelimit limits the rate of the operations of each organisation and
+// API key with token buckets, so a single organisation cannot saturate the
+// payment service.
+//
+// The reads and the writes have separate budgets (see Op) and the limits of
+// each organisation can be configured (see LimitsStore); the default ones
+// apply to the organisations without specific limits.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"go.fraixed.es/errors"
+)
+
+// Op is the class of an operation, each one has a separate budget.
+type Op uint8
+
+// The list of valid Op values.
+const (
+	opNone Op = iota
+	// OpRead is the class of the operations which retrieve payments (i.e.
+	// payment.Service Find, Get and History).
+	OpRead
+	// OpWrite is the class of the operations which modify payments (i.e.
+	// payment.Service Create, Delete, Transition and Update).
+	OpWrite
+)
+
+// String returns the string representation of o.
+func (o Op) String() string {
+	switch o {
+	case OpRead:
+		return "read"
+	case OpWrite:
+		return "write"
+	}
+
+	return ""
+}
+
+// Limit is the limit of a token bucket.
+type Limit struct {
+	// Rate is the number of operations per second.
+	Rate float64
+	// Burst is the maximum number of operations which can be performed at once,
+	// which is the capacity of the bucket.
+	Burst uint32
+}
+
+// Limits are the limits of each class of operations.
+type Limits struct {
+	Read  Limit
+	Write Limit
+}
+
+// Validate validates that the rates are greater than 0 and the bursts are at
+// least 1.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidArgLimits
+func (l Limits) Validate() error {
+	for _, lm := range []Limit{l.Read, l.Write} {
+		if !(lm.Rate > 0) || math.IsInf(lm.Rate, 1) || lm.Burst < 1 {
+			return errors.New(ErrInvalidArgLimits, payment.ErrMDArg("limits", l))
+		}
+	}
+
+	return nil
+}
+
+func (l Limits) of(o Op) Limit {
+	if o == OpRead {
+		return l.Read
+	}
+
+	return l.Write
+}
+
+// LimitsStore is the interface which any specific implementation for
+// persisting the limits of the organisations must satisfy.
+//
+// All the methods can return the general error codes documented in
+// payment.Service.
+type LimitsStore interface {
+	// OrgLimits returns the limits of the organisation orgID. It returns false
+	// if the organisation doesn't have specific limits.
+	OrgLimits(ctx context.Context, orgID uuid.UUID) (Limits, bool, error)
+
+	// SetOrgLimits sets the limits of the organisation orgID, replacing the
+	// current ones, if any.
+	//
+	// The following error codes can be returned:
+	//
+	// * auth.ErrInvalidOrgID - When orgID is uuid.Nil.
+	//
+	// * Any of the errors returned by l.Validate.
+	SetOrgLimits(ctx context.Context, orgID uuid.UUID, l Limits) error
+
+	// DeleteOrgLimits deletes the limits of the organisation orgID, so the
+	// default ones apply to it. It doesn't return an error if the organisation
+	// doesn't have specific limits.
+	DeleteOrgLimits(ctx context.Context, orgID uuid.UUID) error
+}
+
+// Config contains the parameters of a Limiter. The zero value of each field
+// means to use its default value.
+type Config struct {
+	// Default are the limits of the organisations which don't have specific
+	// ones. Default 20 reads per second with a burst of 40 and 5 writes per
+	// second with a burst of 10.
+	Default Limits
+	// LimitsTTL is the time during which the limits of an organisation are
+	// cached, hence the time that a change of them takes to apply. Default 1
+	// minute.
+	LimitsTTL time.Duration
+	// Now returns the current time. Default time.Now.
+	Now func() time.Time
+}
+
+func (c Config) withDefaults() Config {
+	if c.Default == (Limits{}) {
+		c.Default = Limits{
+			Read:  Limit{Rate: 20, Burst: 40},
+			Write: Limit{Rate: 5, Burst: 10},
+		}
+	}
+
+	if c.LimitsTTL == 0 {
+		c.LimitsTTL = time.Minute
+	}
+
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	return c
+}
+
+// maxBuckets is the number of buckets from which the full ones are removed,
+// because they are in the same state than a new one.
+const maxBuckets = 10000
+
+// Limiter limits the rate of the operations with a token bucket for each
+// organisation, API key and class of operation. It's safe for concurrent use.
+type Limiter struct {
+	ls  LimitsStore
+	cfg Config
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+	limits  map[uuid.UUID]cachedLimits
+}
+
+type bucketKey struct {
+	orgID uuid.UUID
+	keyID uuid.UUID
+	op    Op
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+type cachedLimits struct {
+	limits  Limits
+	expires time.Time
+}
+
+// NewLimiter creates a Limiter which gets the limits of the organisations from
+// ls. ls can be nil, then the default limits apply to all of them.
+//
+// The following error codes can be returned:
+//
+// * Any of the errors returned by cfg.Default.Validate, when it isn't the zero
+// value.
+func NewLimiter(ls LimitsStore, cfg Config) (*Limiter, error) {
+	if cfg.Default != (Limits{}) {
+		if err := cfg.Default.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Limiter{
+		ls:      ls,
+		cfg:     cfg.withDefaults(),
+		buckets: map[bucketKey]*bucket{},
+		limits:  map[uuid.UUID]cachedLimits{},
+	}, nil
+}
+
+// Allow takes a token from the bucket of the organisation and API key of p for
+// the class of operations o. When the bucket is empty, it returns the time to
+// wait until it has a token.
+//
+// The principals authenticated with a bearer token, which don't have an API
+// key, share the bucket of their organisation.
+//
+// The following error codes can be returned:
+//
+// * ErrRateLimited - When the bucket is empty.
+//
+// * Any of the errors returned by the LimitsStore.
+func (l *Limiter) Allow(ctx context.Context, p auth.Principal, o Op) (time.Duration, error) {
+	var lms, err = l.orgLimits(ctx, p.OrgID)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		now = l.cfg.Now()
+		lm  = lms.of(o)
+		k   = bucketKey{orgID: p.OrgID, keyID: p.KeyID, op: o}
+	)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var b, ok = l.buckets[k]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.sweep(now)
+		}
+
+		b = &bucket{tokens: float64(lm.Burst), last: now, limit: lm}
+		l.buckets[k] = b
+	}
+
+	b.refill(now, lm)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, nil
+	}
+
+	var wait = time.Duration((1 - b.tokens) / lm.Rate * float64(time.Second))
+	return wait, errors.New(ErrRateLimited,
+		payment.ErrMDField("OrgID", p.OrgID), payment.ErrMDArg("o", o),
+		payment.ErrMDFact("retry_after", wait),
+	)
+}
+
+// orgLimits returns the limits of the organisation orgID, from the cache when
+// they haven't expired.
+func (l *Limiter) orgLimits(ctx context.Context, orgID uuid.UUID) (Limits, error) {
+	if l.ls == nil {
+		return l.cfg.Default, nil
+	}
+
+	var now = l.cfg.Now()
+	l.mu.Lock()
+	var cl, ok = l.limits[orgID]
+	l.mu.Unlock()
+	if ok && now.Before(cl.expires) {
+		return cl.limits, nil
+	}
+
+	var lms, found, err = l.ls.OrgLimits(ctx, orgID)
+	if err != nil {
+		return Limits{}, err
+	}
+
+	if !found {
+		lms = l.cfg.Default
+	}
+
+	l.mu.Lock()
+	l.limits[orgID] = cachedLimits{limits: lms, expires: now.Add(l.cfg.LimitsTTL)}
+	l.mu.Unlock()
+	return lms, nil
+}
+
+// sweep removes the buckets which are full at now and the expired cached
+// limits. l.mu must be locked.
+func (l *Limiter) sweep(now time.Time) {
+	for k, b := range l.buckets {
+		b.refill(now, b.limit)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, k)
+		}
+	}
+
+	for id, cl := range l.limits {
+		if !now.Before(cl.expires) {
+			delete(l.limits, id)
+		}
+	}
+}
+
+// refill adds the tokens generated since the last refill, with the limit lm,
+// without exceeding its burst.
+func (b *bucket) refill(now time.Time, lm Limit) {
+	if el := now.Sub(b.last); el > 0 {
+		b.tokens += el.Seconds() * lm.Rate
+		b.last = now
+	}
+
+	b.limit = lm
+	if b.tokens > float64(lm.Burst) {
+		b.tokens = float64(lm.Burst)
+	}
+}
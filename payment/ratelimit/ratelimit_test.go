@@ -0,0 +1,230 @@
+package ratelimit_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestLimits_Validate(t *testing.T) {
+	var valid = ratelimit.Limits{
+		Read:  ratelimit.Limit{Rate: 0.5, Burst: 1},
+		Write: ratelimit.Limit{Rate: 10, Burst: 20},
+	}
+
+	var tcases = []struct {
+		desc   string
+		limits func() ratelimit.Limits
+		err    bool
+	}{
+		{
+			desc:   "valid",
+			limits: func() ratelimit.Limits { return valid },
+		},
+		{
+			desc: "error: zero rate",
+			limits: func() ratelimit.Limits {
+				var l = valid
+				l.Read.Rate = 0
+				return l
+			},
+			err: true,
+		},
+		{
+			desc: "error: NaN rate",
+			limits: func() ratelimit.Limits {
+				var l = valid
+				l.Write.Rate = math.NaN()
+				return l
+			},
+			err: true,
+		},
+		{
+			desc: "error: infinite rate",
+			limits: func() ratelimit.Limits {
+				var l = valid
+				l.Write.Rate = math.Inf(1)
+				return l
+			},
+			err: true,
+		},
+		{
+			desc: "error: zero burst",
+			limits: func() ratelimit.Limits {
+				var l = valid
+				l.Write.Burst = 0
+				return l
+			},
+			err: true,
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var err = tc.limits().Validate()
+			if tc.err {
+				testutil.AssertError(t, err, ratelimit.ErrInvalidArgLimits)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+
+	var _, err = ratelimit.NewLimiter(nil, ratelimit.Config{Default: ratelimit.Limits{Read: valid.Read}})
+	testutil.AssertError(t, err, ratelimit.ErrInvalidArgLimits)
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	var (
+		ctx = context.Background()
+		now = time.Date(2026, 10, 19, 14, 0, 0, 0, time.UTC)
+		l   = newLimiter(t, nil, &now)
+		p   = auth.Principal{OrgID: testutil.NewUUID(t), KeyID: testutil.NewUUID(t)}
+	)
+
+	// The write burst is 2 and the rate 1 per second
+	for i := 0; i < 2; i++ {
+		var _, err = l.Allow(ctx, p, ratelimit.OpWrite)
+		require.NoError(t, err)
+	}
+
+	var wait, err = l.Allow(ctx, p, ratelimit.OpWrite)
+	testutil.AssertError(t, err, ratelimit.ErrRateLimited,
+		payment.ErrMDField("OrgID", p.OrgID), payment.ErrMDArg("o", ratelimit.OpWrite),
+	)
+	assert.Equal(t, time.Second, wait)
+
+	t.Run("reads have a separate budget", func(t *testing.T) {
+		var _, err = l.Allow(ctx, p, ratelimit.OpRead)
+		require.NoError(t, err)
+	})
+
+	t.Run("other API keys and bearer tokens have a separate budget", func(t *testing.T) {
+		var _, err = l.Allow(ctx, auth.Principal{OrgID: p.OrgID, KeyID: testutil.NewUUID(t)}, ratelimit.OpWrite)
+		require.NoError(t, err)
+		_, err = l.Allow(ctx, auth.Principal{OrgID: p.OrgID}, ratelimit.OpWrite)
+		require.NoError(t, err)
+	})
+
+	t.Run("tokens are refilled", func(t *testing.T) {
+		now = now.Add(500 * time.Millisecond)
+		var wait, err = l.Allow(ctx, p, ratelimit.OpWrite)
+		testutil.AssertError(t, err, ratelimit.ErrRateLimited)
+		assert.Equal(t, 500*time.Millisecond, wait)
+
+		now = now.Add(500 * time.Millisecond)
+		_, err = l.Allow(ctx, p, ratelimit.OpWrite)
+		require.NoError(t, err)
+
+		// The bucket doesn't exceed the burst
+		now = now.Add(time.Hour)
+		for i := 0; i < 2; i++ {
+			var _, err = l.Allow(ctx, p, ratelimit.OpWrite)
+			require.NoError(t, err)
+		}
+
+		_, err = l.Allow(ctx, p, ratelimit.OpWrite)
+		testutil.AssertError(t, err, ratelimit.ErrRateLimited)
+	})
+}
+
+func TestLimiter_Allow_orgLimits(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		now   = time.Date(2026, 10, 19, 14, 0, 0, 0, time.UTC)
+		orgID = testutil.NewUUID(t)
+		calls int
+		ls    = &limitsStoreStub{
+			orgLimits: func(_ context.Context, id uuid.UUID) (ratelimit.Limits, bool, error) {
+				calls++
+				if id != orgID {
+					return ratelimit.Limits{}, false, nil
+				}
+
+				return ratelimit.Limits{
+					Read:  ratelimit.Limit{Rate: 1, Burst: 1},
+					Write: ratelimit.Limit{Rate: 1, Burst: 1},
+				}, true, nil
+			},
+		}
+		l = newLimiter(t, ls, &now)
+	)
+
+	var _, err = l.Allow(ctx, auth.Principal{OrgID: orgID}, ratelimit.OpRead)
+	require.NoError(t, err)
+	_, err = l.Allow(ctx, auth.Principal{OrgID: orgID}, ratelimit.OpRead)
+	testutil.AssertError(t, err, ratelimit.ErrRateLimited)
+
+	// The default read burst is 3
+	var oorgID = testutil.NewUUID(t)
+	for i := 0; i < 3; i++ {
+		var _, err = l.Allow(ctx, auth.Principal{OrgID: oorgID}, ratelimit.OpRead)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, calls, "the limits are cached")
+	now = now.Add(time.Minute)
+	_, err = l.Allow(ctx, auth.Principal{OrgID: orgID}, ratelimit.OpRead)
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls, "the cached limits expire")
+
+	t.Run("error: store", func(t *testing.T) {
+		var ls = &limitsStoreStub{
+			orgLimits: func(context.Context, uuid.UUID) (ratelimit.Limits, bool, error) {
+				return ratelimit.Limits{}, false, errors.New(payment.ErrUnexpectedStoreError)
+			},
+		}
+
+		var _, err = newLimiter(t, ls, &now).Allow(ctx, auth.Principal{OrgID: orgID}, ratelimit.OpRead)
+		testutil.AssertError(t, err, payment.ErrUnexpectedStoreError)
+	})
+}
+
+// newLimiter creates a limiter, whose current time is the value pointed by now,
+// with the default limits of 3 reads per second with a burst of 3 and 1 write
+// per second with a burst of 2.
+func newLimiter(t *testing.T, ls ratelimit.LimitsStore, now *time.Time) *ratelimit.Limiter {
+	var l, err = ratelimit.NewLimiter(ls, ratelimit.Config{
+		Default: ratelimit.Limits{
+			Read:  ratelimit.Limit{Rate: 3, Burst: 3},
+			Write: ratelimit.Limit{Rate: 1, Burst: 2},
+		},
+		Now: func() time.Time { return *now },
+	})
+	require.NoError(t, err)
+	return l
+}
+
+// limitsStoreStub is a ratelimit.LimitsStore whose methods call the function of
+// the field with the same name. They panic if the field isn't set.
+type limitsStoreStub struct {
+	orgLimits       func(context.Context, uuid.UUID) (ratelimit.Limits, bool, error)
+	setOrgLimits    func(context.Context, uuid.UUID, ratelimit.Limits) error
+	deleteOrgLimits func(context.Context, uuid.UUID) error
+}
+
+func (s *limitsStoreStub) OrgLimits(ctx context.Context, orgID uuid.UUID) (ratelimit.Limits, bool, error) {
+	return s.orgLimits(ctx, orgID)
+}
+
+func (s *limitsStoreStub) SetOrgLimits(ctx context.Context, orgID uuid.UUID, l ratelimit.Limits) error {
+	return s.setOrgLimits(ctx, orgID, l)
+}
+
+func (s *limitsStoreStub) DeleteOrgLimits(ctx context.Context, orgID uuid.UUID) error {
+	return s.deleteOrgLimits(ctx, orgID)
+}
@@ -0,0 +1,181 @@
+package payment
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maskedValue is the value which replaces the sensitive values.
+const maskedValue = "****"
+
+// Redactor is the interface implemented by the values which contain sensitive
+// data (e.g. account numbers, names, addresses) for masking it when they are
+// part of the errors metadata or they are logged.
+//
+// The metadata created by the ErrMD functions are redacted, hence any code
+// which formats the errors, for example with "%+v", doesn't leak sensitive
+// data.
+type Redactor interface {
+	// Redacted returns a copy of the value with the sensitive data masked.
+	Redacted() interface{}
+}
+
+// Redact returns the redacted copy of v if v is a Redactor, otherwise v.
+func Redact(v interface{}) interface{} {
+	if r, ok := v.(Redactor); ok {
+		return r.Redacted()
+	}
+
+	return v
+}
+
+// Sensitive is a string which contains sensitive data; it's masked when it's
+// redacted or formatted with the fmt package. The value can be obtained by
+// converting it to string.
+type Sensitive string
+
+// Redacted satisfies the Redactor interface.
+func (s Sensitive) Redacted() interface{} {
+	return Sensitive(mask(string(s)))
+}
+
+// String returns s masked.
+func (s Sensitive) String() string {
+	return mask(string(s))
+}
+
+// Format satisfies the fmt.Formatter interface formatting s masked.
+func (s Sensitive) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmtDirective(f, verb), mask(string(s)))
+}
+
+// Redacted satisfies the Redactor interface masking the sensitive fields of
+// the attributes of the payment.
+func (p Pymt) Redacted() interface{} {
+	p.Attributes = p.Attributes.redacted()
+	return p
+}
+
+// Redacted satisfies the Redactor interface masking the sensitive fields of
+// the attributes.
+func (p PymtUpsert) Redacted() interface{} {
+	p.Attributes = p.Attributes.redacted()
+	return p
+}
+
+// Redacted satisfies the Redactor interface masking the sensitive fields of
+// the parties.
+func (a Attrs) Redacted() interface{} {
+	return a.redacted()
+}
+
+func (a Attrs) redacted() Attrs {
+	a.BeneficiaryParty = a.BeneficiaryParty.redacted()
+	a.DebtorParty = a.DebtorParty.redacted()
+	a.SponsorParty = a.SponsorParty.redacted()
+	return a
+}
+
+// Redacted satisfies the Redactor interface masking the account name, account
+// number, address and name. The account number keeps its last 4 characters
+// when it's long enough for not revealing it.
+func (p Party) Redacted() interface{} {
+	return p.redacted()
+}
+
+// Format satisfies the fmt.Formatter interface formatting the party redacted,
+// so the sensitive data isn't leaked by the formatted values which contain it.
+func (p Party) Format(f fmt.State, verb rune) {
+	// party doesn't have the methods of Party, otherwise Fprintf would call
+	// this method indefinitely
+	type party Party
+	fmt.Fprintf(f, fmtDirective(f, verb), party(p.redacted()))
+}
+
+func (p Party) redacted() Party {
+	p.AccountName = mask(p.AccountName)
+	p.AccountNumber = maskAccountNumber(p.AccountNumber)
+	p.Address = mask(p.Address)
+	p.Name = mask(p.Name)
+	return p
+}
+
+// Redacted satisfies the Redactor interface redacting the value and masking it
+// when the field is a sensitive one (see SensitiveField).
+func (v Violation) Redacted() interface{} {
+	v.Value = Redact(v.Value)
+	if SensitiveField(v.Field) {
+		if s, ok := v.Value.(string); ok {
+			v.Value = Sensitive(s).Redacted()
+		}
+	}
+
+	return v
+}
+
+// SensitiveField returns true if path is the path of a sensitive field of a
+// party, being the path parts separated by '.' and the names of the fields the
+// Go names (e.g. "Attributes.DebtorParty.AccountNumber") or the JSON names
+// (e.g. "attributes.debtor_party.account_number").
+func SensitiveField(path string) bool {
+	var parts = strings.Split(path, ".")
+	if len(parts) < 2 {
+		return false
+	}
+
+	var parent, field = parts[len(parts)-2], parts[len(parts)-1]
+	switch {
+	case strings.HasSuffix(parent, "Party"):
+		return isOneOf(field, []string{"AccountName", "AccountNumber", "Address", "Name"})
+	case strings.HasSuffix(parent, "_party"):
+		return isOneOf(field, []string{"account_name", "account_number", "address", "name"})
+	}
+
+	return false
+}
+
+// mask returns s masked, or empty if s is empty.
+func mask(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return maskedValue
+}
+
+// maskAccountNumber returns n masked keeping its last 4 characters if it has
+// 8 or more characters, or empty if n is empty.
+func maskAccountNumber(n string) string {
+	var l = utf8.RuneCountInString(n)
+	if l < 8 {
+		return mask(n)
+	}
+
+	var rs = []rune(n)
+	return maskedValue + string(rs[len(rs)-4:])
+}
+
+// fmtDirective returns the fmt directive, with its flags, width and precision,
+// which is being formatted by f with verb.
+func fmtDirective(f fmt.State, verb rune) string {
+	var b strings.Builder
+	b.WriteByte('%')
+	for _, fl := range "+-# 0" {
+		if f.Flag(int(fl)) {
+			b.WriteRune(fl)
+		}
+	}
+
+	if w, ok := f.Width(); ok {
+		b.WriteString(strconv.Itoa(w))
+	}
+
+	if p, ok := f.Precision(); ok {
+		b.WriteString("." + strconv.Itoa(p))
+	}
+
+	b.WriteRune(verb)
+	return b.String()
+}
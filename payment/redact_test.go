@@ -0,0 +1,176 @@
+package payment_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestRedaction_errors(t *testing.T) {
+	var (
+		p = payment.PymtUpsert{Type: "Payment", OrgID: testutil.NewUUID(t), Attributes: testutil.NewAttrs(t)}
+		a = &p.Attributes
+	)
+
+	a.BeneficiaryParty.AccountNumber = "GB28NWBK60161331926819"
+	a.BeneficiaryParty.AccountNumberCode = "IBAN"
+	a.DebtorParty.AccountNumber = "88837492"
+
+	var sensitive = []string{
+		a.BeneficiaryParty.AccountNumber,
+		a.BeneficiaryParty.AccountName,
+		a.BeneficiaryParty.Address,
+		a.BeneficiaryParty.Name,
+		a.DebtorParty.AccountNumber,
+		a.DebtorParty.AccountName,
+		a.DebtorParty.Address,
+		a.DebtorParty.Name,
+		a.SponsorParty.AccountNumber,
+		a.SponsorParty.Name,
+	}
+
+	var tcases = []struct {
+		desc string
+		err  error
+	}{
+		{desc: "validation", err: p.Validate()},
+		{
+			desc: "field metadata",
+			err:  errors.New(payment.ErrInvalidPaymentType, payment.ErrMDField("Attributes", p.Attributes)),
+		},
+		{desc: "var metadata", err: errors.New(payment.ErrNotFound, payment.ErrMDVar("p", p))},
+		{desc: "arg metadata", err: errors.New(payment.ErrNotFound, payment.ErrMDArg("p", payment.Pymt{PymtUpsert: p}))},
+		{desc: "fact metadata", err: errors.New(payment.ErrNotFound, payment.ErrMDFact("party", a.DebtorParty))},
+		{desc: "function call metadata", err: errors.New(payment.ErrNotFound, payment.ErrMDFnCall("f", a.DebtorParty, p))},
+		{
+			desc: "wrapped",
+			err:  errors.Wrap(p.Validate(), payment.ErrUnexpectedStoreError, payment.ErrMDArg("p", p)),
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			require.Error(t, tc.err)
+			for _, f := range []string{"%v", "%+v", "%s"} {
+				var msg = fmt.Sprintf(f, tc.err)
+				for _, s := range sensitive {
+					assert.NotContains(t, msg, s)
+				}
+			}
+		})
+	}
+
+	t.Run("validation keeps the values", func(t *testing.T) {
+		var vs = p.Violations()
+		require.Len(t, vs, 2)
+		assert.Equal(t, "GB28NWBK60161331926819", vs[0].Value)
+		assert.Contains(t, fmt.Sprintf("%+v", p.Validate()), "field:Attributes.BeneficiaryParty.AccountNumber")
+	})
+}
+
+func TestParty_Redacted(t *testing.T) {
+	var p = payment.Party{
+		AccountName:       "J Doe",
+		AccountNumber:     "GB28NWBK60161331926819",
+		AccountNumberCode: "IBAN",
+		Address:           "1 The Street",
+		BankID:            "NWBKGB22",
+		BankIDCode:        "SWBIC",
+		Name:              "John Doe",
+	}
+
+	var rp = payment.Party{
+		AccountName:       "****",
+		AccountNumber:     "****6819",
+		AccountNumberCode: "IBAN",
+		Address:           "****",
+		BankID:            "NWBKGB22",
+		BankIDCode:        "SWBIC",
+		Name:              "****",
+	}
+
+	assert.Equal(t, rp, p.Redacted())
+	assert.Equal(t, rp, rp.Redacted())
+	assert.Equal(t, payment.Party{AccountNumber: "****"}, payment.Party{AccountNumber: "1234567"}.Redacted())
+
+	for _, f := range []string{"%v", "%+v", "%#v", "%s"} {
+		var s = fmt.Sprintf(f, p)
+		assert.NotContains(t, s, p.AccountNumber)
+		assert.NotContains(t, s, p.Name)
+		assert.Contains(t, s, p.BankID)
+	}
+
+	assert.Equal(t,
+		"{AccountName:**** AccountNumber:****6819 AccountNumberCode:IBAN AccountType:0 Address:**** "+
+			"BankID:NWBKGB22 BankIDCode:SWBIC Name:****}",
+		fmt.Sprintf("%+v", p),
+	)
+}
+
+func TestSensitive(t *testing.T) {
+	var s = payment.Sensitive("12345678")
+
+	assert.Equal(t, "****", s.String())
+	assert.Equal(t, "****", fmt.Sprintf("%v", s))
+	assert.Equal(t, `"****"`, fmt.Sprintf("%q", s))
+	assert.Equal(t, payment.Sensitive("****"), s.Redacted())
+	assert.Equal(t, "12345678", string(s))
+	assert.Equal(t, "", payment.Sensitive("").String())
+}
+
+func TestViolation_Redacted(t *testing.T) {
+	var tcases = []struct {
+		desc     string
+		v        payment.Violation
+		expected interface{}
+	}{
+		{
+			desc: "Go path",
+			v: payment.Violation{
+				Field: "Attributes.DebtorParty.AccountNumber", Code: payment.ErrInvalidPaymentAttrAccountNumber, Value: "88837492",
+			},
+			expected: payment.Sensitive("****"),
+		},
+		{
+			desc: "JSON path",
+			v: payment.Violation{
+				Field: "attributes.debtor_party.name", Code: payment.ErrInvalidPaymentAttrRequired, Value: "John",
+			},
+			expected: payment.Sensitive("****"),
+		},
+		{
+			desc: "not sensitive",
+			v: payment.Violation{
+				Field: "Attributes.DebtorParty.BankID", Code: payment.ErrInvalidPaymentAttrBankID, Value: "089999",
+			},
+			expected: "089999",
+		},
+		{
+			desc: "redactor",
+			v: payment.Violation{
+				Field: "Attributes.DebtorParty", Code: payment.ErrInvalidPaymentAttrRequired, Value: payment.Party{Name: "John"},
+			},
+			expected: payment.Party{Name: "****"},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var rv = tc.v.Redacted().(payment.Violation)
+			assert.Equal(t, tc.expected, rv.Value)
+			assert.Equal(t, tc.v.Field, rv.Field)
+			assert.NotContains(t, tc.v.String(), "John")
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"go.fraixed.es/errors"
+)
+
+// AdminHandler is the http.Handler which serves the administration API of the
+// API keys. All its operations require the auth.ScopeAdmin scope.
+//
+// The operations are:
+//
+// * POST /api-keys - Issues a new API key for the organisation and scopes of
+// the body (e.g. {"organisation_id":"...","scopes":["payments:read"]}).
+//
+// * GET /api-keys?organisation_id={id} - Lists the API keys of an organisation.
+//
+// * GET /api-keys/{id} - Retrieves an API key.
+//
+// * DELETE /api-keys/{id} - Revokes an API key.
+//
+// * POST /api-keys/{id}/rotate - Revokes an API key and issues a new one for
+// the same organisation and scopes.
+//
+// The key itself is only part of the responses of the operations which issue
+// it, because it cannot be retrieved afterwards.
+type AdminHandler struct {
+	keys  auth.KeyStore
+	authn Authenticator
+	mux   *http.ServeMux
+}
+
+// NewAdminHandler creates an AdminHandler which manages the API keys of ks and
+// authenticates the requests with authn.
+func NewAdminHandler(ks auth.KeyStore, authn Authenticator) *AdminHandler {
+	var h = &AdminHandler{
+		keys:  ks,
+		authn: authn,
+		mux:   http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("/api-keys", h.apiKeys)
+	h.mux.HandleFunc("/api-keys/", h.apiKey)
+	return h
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !acceptsV1(r) {
+		writeError(w, errors.New(ErrUnavailableContentType))
+		return
+	}
+
+	if _, err := authorize(h.authn, r, auth.ScopeAdmin); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+// apiKeyRepr is the representation of an API key in the responses.
+type apiKeyRepr struct {
+	ID        uuid.UUID    `json:"id"`
+	OrgID     uuid.UUID    `json:"organisation_id"`
+	Scopes    []auth.Scope `json:"scopes"`
+	CreatedAt time.Time    `json:"created_at"`
+	RevokedAt *time.Time   `json:"revoked_at,omitempty"`
+	Key       string       `json:"key,omitempty"`
+}
+
+func newAPIKeyRepr(k auth.APIKey, key string) apiKeyRepr {
+	var kr = apiKeyRepr{
+		ID:        k.ID,
+		OrgID:     k.OrgID,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+		Key:       key,
+	}
+
+	if kr.Scopes == nil {
+		kr.Scopes = []auth.Scope{}
+	}
+
+	if k.Revoked() {
+		var rat = k.RevokedAt
+		kr.RevokedAt = &rat
+	}
+
+	return kr
+}
+
+func (h *AdminHandler) apiKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.apiKeysGet(w, r)
+	case http.MethodPost:
+		h.apiKeysPost(w, r)
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPost}, ", "))
+		writeError(w, errors.New(ErrMethodNotAllowed))
+	}
+}
+
+// apiKeysGet lists the API keys of the organisation of the organisation_id
+// query parameter.
+func (h *AdminHandler) apiKeysGet(w http.ResponseWriter, r *http.Request) {
+	var oid = r.URL.Query().Get("organisation_id")
+	var orgID, err = uuid.FromString(oid)
+	if err != nil || orgID == uuid.Nil {
+		writeError(w, errors.New(auth.ErrInvalidOrgID, payment.ErrMDArg("organisation_id", oid)))
+		return
+	}
+
+	keys, err := h.keys.FindKeys(r.Context(), orgID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var krs = make([]apiKeyRepr, len(keys))
+	for i, k := range keys {
+		krs[i] = newAPIKeyRepr(k, "")
+	}
+
+	writeJSON(w, http.StatusOK, dataEnvelop{Data: krs})
+}
+
+// apiKeysPost issues a new API key.
+func (h *AdminHandler) apiKeysPost(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		OrgID  uuid.UUID    `json:"organisation_id"`
+		Scopes []auth.Scope `json:"scopes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, errors.Wrap(err, ErrInvalidBody))
+		return
+	}
+
+	var k, key, err = h.keys.IssueKey(r.Context(), auth.Principal{OrgID: body.OrgID, Scopes: body.Scopes})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, dataEnvelop{Data: newAPIKeyRepr(k, key)})
+}
+
+// apiKey serves the operations on a specific API key, whose ID is the path
+// segment after /api-keys/.
+func (h *AdminHandler) apiKey(w http.ResponseWriter, r *http.Request) {
+	var (
+		segs    = strings.Split(strings.TrimPrefix(r.URL.Path, "/api-keys/"), "/")
+		id, err = uuid.FromString(segs[0])
+	)
+	if err != nil || len(segs) > 2 || (len(segs) == 2 && segs[1] != "rotate") {
+		writeError(w, errors.New(payment.ErrNotFound, payment.ErrMDArg("path", r.URL.Path)))
+		return
+	}
+
+	if len(segs) == 2 {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, errors.New(ErrMethodNotAllowed))
+			return
+		}
+
+		k, key, err := h.keys.RotateKey(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, dataEnvelop{Data: newAPIKeyRepr(k, key)})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var k, err = h.keys.GetKey(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, dataEnvelop{Data: newAPIKeyRepr(k, "")})
+	case http.MethodDelete:
+		if err := h.keys.RevokeKey(r.Context(), id); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodDelete}, ", "))
+		writeError(w, errors.New(ErrMethodNotAllowed))
+	}
+}
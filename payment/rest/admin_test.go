@@ -0,0 +1,235 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/rest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestAdminHandler(t *testing.T) {
+	var (
+		orgID = testutil.NewUUID(t)
+		kid   = testutil.NewUUID(t)
+		admin = authnStub{principal: auth.Principal{OrgID: testutil.NewUUID(t), Scopes: []auth.Scope{auth.ScopeAdmin}}}
+		key   = auth.APIKey{
+			ID:        kid,
+			OrgID:     orgID,
+			Scopes:    []auth.Scope{auth.ScopePaymentsRead},
+			CreatedAt: time.Date(2026, 10, 19, 13, 0, 0, 0, time.UTC),
+		}
+		ks = keyStoreStub{
+			issueKey: func(_ context.Context, p auth.Principal) (auth.APIKey, string, error) {
+				if err := p.Validate(); err != nil {
+					return auth.APIKey{}, "", err
+				}
+
+				return key, "pk_the-key", nil
+			},
+			revokeKey: func(_ context.Context, id uuid.UUID) error {
+				if id != kid {
+					return errors.New(payment.ErrNotFound)
+				}
+
+				return nil
+			},
+			rotateKey: func(_ context.Context, id uuid.UUID) (auth.APIKey, string, error) {
+				return key, "pk_the-rotated-key", nil
+			},
+			getKey: func(_ context.Context, id uuid.UUID) (auth.APIKey, error) {
+				return key, nil
+			},
+			findKeys: func(_ context.Context, oid uuid.UUID) ([]auth.APIKey, error) {
+				assert.Equal(t, orgID, oid)
+				return []auth.APIKey{key}, nil
+			},
+		}
+		keyData = map[string]interface{}{
+			"id":              kid.String(),
+			"organisation_id": orgID.String(),
+			"scopes":          []interface{}{"payments:read"},
+			"created_at":      "2026-10-19T13:00:00Z",
+		}
+	)
+
+	var tcases = []struct {
+		desc   string
+		req    *http.Request
+		authn  authnStub
+		status int
+		assert func(*testing.T, map[string]interface{})
+	}{
+		{
+			desc: "issue",
+			req: httptest.NewRequest(http.MethodPost, "/api-keys",
+				strings.NewReader(`{"organisation_id":"`+orgID.String()+`","scopes":["payments:read"]}`),
+			),
+			authn:  admin,
+			status: http.StatusCreated,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				var d = copyMap(keyData)
+				d["key"] = "pk_the-key"
+				assert.Equal(t, d, b["data"])
+			},
+		},
+		{
+			desc:   "list",
+			req:    httptest.NewRequest(http.MethodGet, "/api-keys?organisation_id="+orgID.String(), nil),
+			authn:  admin,
+			status: http.StatusOK,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assert.Equal(t, []interface{}{keyData}, b["data"])
+			},
+		},
+		{
+			desc:   "get",
+			req:    httptest.NewRequest(http.MethodGet, "/api-keys/"+kid.String(), nil),
+			authn:  admin,
+			status: http.StatusOK,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assert.Equal(t, keyData, b["data"])
+			},
+		},
+		{
+			desc:   "rotate",
+			req:    httptest.NewRequest(http.MethodPost, "/api-keys/"+kid.String()+"/rotate", nil),
+			authn:  admin,
+			status: http.StatusCreated,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				var d = copyMap(keyData)
+				d["key"] = "pk_the-rotated-key"
+				assert.Equal(t, d, b["data"])
+			},
+		},
+		{
+			desc:   "revoke",
+			req:    httptest.NewRequest(http.MethodDelete, "/api-keys/"+kid.String(), nil),
+			authn:  admin,
+			status: http.StatusNoContent,
+		},
+		{
+			desc:   "error: revoke not found",
+			req:    httptest.NewRequest(http.MethodDelete, "/api-keys/"+testutil.NewUUID(t).String(), nil),
+			authn:  admin,
+			status: http.StatusNotFound,
+			assert: assertErrorCode(payment.ErrNotFound.String()),
+		},
+		{
+			desc:   "error: unknown path",
+			req:    httptest.NewRequest(http.MethodPost, "/api-keys/"+kid.String()+"/other", nil),
+			authn:  admin,
+			status: http.StatusNotFound,
+			assert: assertErrorCode(payment.ErrNotFound.String()),
+		},
+		{
+			desc: "error: issue invalid scope",
+			req: httptest.NewRequest(http.MethodPost, "/api-keys",
+				strings.NewReader(`{"organisation_id":"`+orgID.String()+`","scopes":["payments:all"]}`),
+			),
+			authn:  admin,
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(auth.ErrInvalidScope.String()),
+		},
+		{
+			desc:   "error: list without organisation",
+			req:    httptest.NewRequest(http.MethodGet, "/api-keys", nil),
+			authn:  admin,
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(auth.ErrInvalidOrgID.String()),
+		},
+		{
+			desc:   "error: unauthenticated",
+			req:    httptest.NewRequest(http.MethodGet, "/api-keys/"+kid.String(), nil),
+			authn:  authnStub{err: errors.New(auth.ErrInvalidToken)},
+			status: http.StatusUnauthorized,
+			assert: assertErrorCode(rest.ErrUnauthenticated.String()),
+		},
+		{
+			desc: "error: not admin",
+			req:  httptest.NewRequest(http.MethodGet, "/api-keys/"+kid.String(), nil),
+			authn: authnStub{
+				principal: auth.Principal{OrgID: orgID, Scopes: []auth.Scope{auth.ScopePaymentsWrite}},
+			},
+			status: http.StatusForbidden,
+			assert: assertErrorCode(rest.ErrUnauthorized.String()),
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				h = rest.NewAdminHandler(ks, tc.authn)
+				w = httptest.NewRecorder()
+			)
+
+			h.ServeHTTP(w, tc.req)
+			assert.Equal(t, tc.status, w.Code)
+			if tc.assert == nil {
+				assert.Empty(t, w.Body.Bytes())
+				return
+			}
+
+			var b map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &b))
+			tc.assert(t, b)
+		})
+	}
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	var c = make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+
+	return c
+}
+
+// keyStoreStub is an auth.KeyStore whose methods call the function of the
+// field with the same name. They panic if the field isn't set.
+type keyStoreStub struct {
+	issueKey  func(context.Context, auth.Principal) (auth.APIKey, string, error)
+	revokeKey func(context.Context, uuid.UUID) error
+	rotateKey func(context.Context, uuid.UUID) (auth.APIKey, string, error)
+	getKey    func(context.Context, uuid.UUID) (auth.APIKey, error)
+	findKeys  func(context.Context, uuid.UUID) ([]auth.APIKey, error)
+}
+
+func (s keyStoreStub) IssueKey(ctx context.Context, p auth.Principal) (auth.APIKey, string, error) {
+	return s.issueKey(ctx, p)
+}
+
+func (s keyStoreStub) RevokeKey(ctx context.Context, id uuid.UUID) error {
+	return s.revokeKey(ctx, id)
+}
+
+func (s keyStoreStub) RotateKey(ctx context.Context, id uuid.UUID) (auth.APIKey, string, error) {
+	return s.rotateKey(ctx, id)
+}
+
+func (s keyStoreStub) GetKey(ctx context.Context, id uuid.UUID) (auth.APIKey, error) {
+	return s.getKey(ctx, id)
+}
+
+func (s keyStoreStub) FindKeys(ctx context.Context, orgID uuid.UUID) ([]auth.APIKey, error) {
+	return s.findKeys(ctx, orgID)
+}
+
+func (s keyStoreStub) VerifyKey(context.Context, string) (auth.Principal, error) {
+	panic("not implemented")
+}
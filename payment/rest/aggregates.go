@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/ratelimit"
+	"go.fraixed.es/errors"
+)
+
+// aggregate is the representation of a payment.Aggregate in the responses. The
+// group is indexed by the path of each key and the amounts are strings for not
+// losing precision when they are decoded as floating point numbers.
+type aggregate struct {
+	Group map[string]string `json:"group,omitempty"`
+	Count uint64            `json:"count,omitempty"`
+	Sum   string            `json:"sum,omitempty"`
+	Min   string            `json:"min,omitempty"`
+	Max   string            `json:"max,omitempty"`
+	Avg   string            `json:"avg,omitempty"`
+}
+
+func (h *Handler) aggregates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, errors.New(ErrMethodNotAllowed))
+		return
+	}
+
+	h.aggregatesGet(w, r)
+}
+
+// aggregatesGet aggregates the amounts of the payments filtered, grouped and
+// with the functions of the query parameters (see parseFilter and
+// parseAggregation).
+func (h *Handler) aggregatesGet(w http.ResponseWriter, r *http.Request) {
+	var ctx, err = authorize(h.authn, r, auth.ScopePaymentsRead)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !h.limit(ctx, w, ratelimit.OpRead) {
+		return
+	}
+
+	f, err := parseFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	a, err := parseAggregation(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	aggs, err := h.svc.Aggregate(ctx, f, a)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var data = make([]aggregate, len(aggs))
+	for i, agg := range aggs {
+		var d = aggregate{Sum: agg.Sum, Min: agg.Min, Max: agg.Max, Avg: agg.Avg}
+		if a.Has(payment.AggregateFnCount) {
+			d.Count = agg.Count
+		}
+
+		if len(a.GroupBy) > 0 {
+			d.Group = make(map[string]string, len(a.GroupBy))
+			for j, k := range a.GroupBy {
+				d.Group[k.String()] = agg.Group[j]
+			}
+		}
+
+		data[i] = d
+	}
+
+	writeJSON(w, http.StatusOK, dataEnvelop{Data: data})
+}
+
+// parseAggregation returns the aggregation of the group_by and functions query
+// parameters of r. Each value of group_by is the path of a field (see
+// payment.AggregateKey) and each value of functions the name of a function
+// (see payment.AggregateFn).
+//
+// The following error codes can be returned:
+//
+// * payment.ErrInvalidArgAggregation
+func parseAggregation(r *http.Request) (payment.Aggregation, error) {
+	var a payment.Aggregation
+	for _, g := range queryValues(r, "group_by") {
+		var k, ok = payment.ParseAggregateKey(g)
+		if !ok {
+			return payment.Aggregation{}, errors.New(payment.ErrInvalidArgAggregation, payment.ErrMDArg("group_by", g))
+		}
+
+		a.GroupBy = append(a.GroupBy, k)
+	}
+
+	for _, n := range queryValues(r, "functions") {
+		var f, ok = payment.ParseAggregateFn(n)
+		if !ok {
+			return payment.Aggregation{}, errors.New(payment.ErrInvalidArgAggregation, payment.ErrMDArg("functions", n))
+		}
+
+		a.Fns = append(a.Fns, f)
+	}
+
+	return a, a.Validate()
+}
@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// etag returns the entity tag of the payment version v, which is the version
+// as a strong validator (e.g. "3").
+func etag(v uint32) string {
+	return strconv.Quote(strconv.FormatUint(uint64(v), 10))
+}
+
+// entityTag is an entity tag of a conditional header.
+type entityTag struct {
+	weak bool
+	// version is the payment version of the tag, it's only valid when ok is true.
+	version uint32
+	ok      bool
+}
+
+// parseETags parses the list of entity tags of the header value hv. It returns
+// true if it's "*".
+//
+// The tags can be quoted (e.g. "3" or W/"3") or, as the If-Match header of the
+// API definition, only the number. The tags which aren't a payment version are
+// returned, but they never match.
+func parseETags(hv string) ([]entityTag, bool) {
+	if strings.TrimSpace(hv) == "*" {
+		return nil, true
+	}
+
+	var tags []entityTag
+	for _, t := range strings.Split(hv, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+
+		var et entityTag
+		if strings.HasPrefix(t, "W/") {
+			et.weak = true
+			t = t[2:]
+		}
+
+		if len(t) >= 2 && t[0] == '"' && t[len(t)-1] == '"' {
+			t = t[1 : len(t)-1]
+		}
+
+		if v, err := strconv.ParseUint(t, 10, 32); err == nil {
+			et.version = uint32(v)
+			et.ok = true
+		}
+
+		tags = append(tags, et)
+	}
+
+	return tags, false
+}
+
+// noneMatch returns false if the If-None-Match header of r matches the payment
+// version v, using the weak comparison, otherwise true, also when r doesn't
+// have the header.
+func noneMatch(r *http.Request, v uint32) bool {
+	var hv, ok = r.Header["If-None-Match"]
+	if !ok {
+		return true
+	}
+
+	var tags, star = parseETags(strings.Join(hv, ","))
+	if star {
+		return false
+	}
+
+	for _, t := range tags {
+		if t.ok && t.version == v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchVersion returns the payment version which the If-Match header of r
+// matches, using the strong comparison, retrieving the current version of the
+// payment id from svc. When checks is true and the header has a single tag, its
+// version is returned without retrieving it, because the operation of svc
+// which modifies the payment checks it.
+//
+// The following error codes can be returned:
+//
+// * ErrPreconditionRequired - When r doesn't have the If-Match header.
+//
+// * payment.ErrInvalidArgVersionMismatch - When the header doesn't match.
+//
+// * Any of the errors returned by svc.Get.
+func matchVersion(
+	ctx context.Context, svc payment.Service, r *http.Request, id uuid.UUID, checks bool,
+) (uint32, error) {
+	var hv, ok = r.Header["If-Match"]
+	if !ok {
+		return 0, errors.New(ErrPreconditionRequired)
+	}
+
+	var hs = strings.Join(hv, ",")
+	var tags, star = parseETags(hs)
+	if checks && len(tags) == 1 && tags[0].ok && !tags[0].weak {
+		return tags[0].version, nil
+	}
+
+	var p, err = svc.Get(ctx, id, payment.Selection{Version: true})
+	if err != nil {
+		return 0, err
+	}
+
+	if star {
+		return p.Version, nil
+	}
+
+	for _, t := range tags {
+		if t.ok && !t.weak && t.version == p.Version {
+			return p.Version, nil
+		}
+	}
+
+	return 0, errors.New(payment.ErrInvalidArgVersionMismatch, payment.ErrMDVar("If-Match", hs))
+}
@@ -0,0 +1,75 @@
+package rest
+
+type code uint8
+
+// The list of specific error codes that the rest package can return in the
+// error responses.
+const (
+	ErrInternalError code = iota + 1
+
+	ErrInvalidBody
+
+	ErrInvalidQueryParam
+
+	ErrMethodNotAllowed
+
+	ErrPreconditionRequired
+
+	ErrUnauthenticated
+
+	ErrUnauthorized
+
+	ErrUnavailableContentType
+
+	ErrUnsupportedMediaType
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrInternalError:
+		return "InternalError"
+	case ErrInvalidBody:
+		return "InvalidBody"
+	case ErrInvalidQueryParam:
+		return "InvalidQueryParam"
+	case ErrMethodNotAllowed:
+		return "MethodNotAllowed"
+	case ErrPreconditionRequired:
+		return "PreconditionRequired"
+	case ErrUnauthenticated:
+		return "Unauthenticated"
+	case ErrUnauthorized:
+		return "Unauthorized"
+	case ErrUnavailableContentType:
+		return "UnavailableContentType"
+	case ErrUnsupportedMediaType:
+		return "UnsupportedMediaType"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrInternalError:
+		return "An application internal error has happened."
+	case ErrInvalidBody:
+		return "The body isn't a valid JSON document of the expected type."
+	case ErrInvalidQueryParam:
+		return "A query parameter doesn't have a valid format."
+	case ErrMethodNotAllowed:
+		return "The method isn't allowed for the requested resource."
+	case ErrPreconditionRequired:
+		return "The request must be conditional, with the If-Match header, for not overwriting a concurrent change."
+	case ErrUnauthenticated:
+		return "Authentication is required."
+	case ErrUnauthorized:
+		return "Not enough permissions."
+	case ErrUnavailableContentType:
+		return "Any of the accepted content types are available."
+	case ErrUnsupportedMediaType:
+		return "The content type of the body isn't supported by the operation."
+	}
+
+	return ""
+}
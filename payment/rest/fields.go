@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// selection returns the selection of the fields query parameter of r, which are
+// separated by ",", and true or payment.SelectAll and false when r doesn't have
+// it.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrInvalidArgSelection
+func selection(r *http.Request) (payment.Selection, bool, error) {
+	var qv, ok = r.URL.Query()["fields"]
+	if !ok {
+		return payment.SelectAll(), false, nil
+	}
+
+	var fields []string
+	for _, v := range qv {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	var sl, err = payment.NewSelection(fields...)
+	return sl, true, err
+}
+
+// project returns the JSON representation of p which only contains the ID and
+// the fields selected by sl, so the response doesn't contain the zero values
+// of the fields which aren't selected.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedSysError
+func project(p payment.Pymt, sl payment.Selection) (map[string]interface{}, error) {
+	var b, err = json.Marshal(p)
+	if err != nil {
+		return nil, errors.Wrap(err, payment.ErrUnexpectedSysError, payment.ErrMDFnCall("json.Marshal", p))
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, errors.Wrap(err, payment.ErrUnexpectedSysError, payment.ErrMDFnCall("json.Unmarshal"))
+	}
+
+	var pp = map[string]interface{}{"id": doc["id"]}
+	for f, ok := range map[string]bool{
+		"version":         sl.Version,
+		"type":            sl.Type,
+		"organisation_id": sl.OrgID,
+		"status":          sl.Status,
+		"attributes":      sl.Attributes,
+	} {
+		if ok {
+			pp[f] = doc[f]
+		}
+	}
+
+	var paths = sl.AttrPaths()
+	if len(paths) == 0 {
+		return pp, nil
+	}
+
+	var attrs = map[string]interface{}{}
+	for _, path := range paths {
+		var (
+			src   = doc["attributes"]
+			dst   = attrs
+			names = strings.Split(path, ".")
+		)
+		for i, n := range names {
+			var obj, _ = src.(map[string]interface{})
+			src = obj[n]
+			if i == len(names)-1 {
+				dst[n] = src
+				break
+			}
+
+			var o, ok = dst[n].(map[string]interface{})
+			if !ok {
+				o = map[string]interface{}{}
+				dst[n] = o
+			}
+
+			dst = o
+		}
+	}
+
+	pp["attributes"] = attrs
+	return pp, nil
+}
@@ -0,0 +1,186 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// The page sizes of the lists of payments, see parsePage.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// queryValues returns the values of the query parameter name of r, which can
+// be repeated and each one can contain several values separated by ",".
+func queryValues(r *http.Request, name string) []string {
+	var vals []string
+	for _, qv := range r.URL.Query()[name] {
+		for _, v := range strings.Split(qv, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				vals = append(vals, v)
+			}
+		}
+	}
+
+	return vals
+}
+
+// parseOrder returns the sort of the order query parameter of r. Each value is
+// the path of a field (see payment.SortField) prefixed by "+" or "-" for
+// sorting in ascending or descending direction.
+//
+// The "+" prefix is optional because it's decoded as a space when it isn't
+// percent-encoded.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrInvalidArgSort
+func parseOrder(r *http.Request) (payment.Sort, error) {
+	var st payment.Sort
+	for _, o := range queryValues(r, "order") {
+		var k = payment.SortKey{Dir: payment.SortAscending}
+		switch o[0] {
+		case '+':
+			o = o[1:]
+		case '-':
+			k.Dir = payment.SortDescending
+			o = o[1:]
+		}
+
+		var ok bool
+		k.Field, ok = payment.ParseSortField(o)
+		if !ok {
+			return nil, errors.New(payment.ErrInvalidArgSort, payment.ErrMDArg("order", o))
+		}
+
+		st = append(st, k)
+	}
+
+	return st, st.Validate()
+}
+
+// parseFilter returns the filter of the filter query parameter of r, which is
+// the logical AND of all its values. Each value is the path of a field followed
+// by "=" and the comparison, which is "=", "<" or ">", and the value (e.g.
+// "attributes.amount=>100").
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidQueryParam - When any value doesn't have a valid format.
+//
+// * Any of the errors returned by the payment.Filter constructors.
+func parseFilter(r *http.Request) (payment.Filter, error) {
+	var f payment.Filter
+	for _, v := range queryValues(r, "filter") {
+		var i = strings.Index(v, "=")
+		if i < 1 || len(v) < i+3 {
+			return payment.Filter{}, errors.New(ErrInvalidQueryParam, payment.ErrMDArg("filter", v))
+		}
+
+		var cmp payment.FilterCmp
+		switch v[i+1] {
+		case '=':
+			cmp = payment.FilterCmpEqual
+		case '<':
+			cmp = payment.FilterCmpLessThan
+		case '>':
+			cmp = payment.FilterCmpGreaterThan
+		default:
+			return payment.Filter{}, errors.New(ErrInvalidQueryParam, payment.ErrMDArg("filter", v))
+		}
+
+		var lf, err = filterLeaf(v[:i], cmp, v[i+2:])
+		if err != nil {
+			return payment.Filter{}, err
+		}
+
+		if f.NodeType() == payment.FilterNodeTypeEmpty {
+			f = lf
+			continue
+		}
+
+		f, err = payment.NewFilter(payment.FilterLogicalAnd, f, lf)
+		if err != nil {
+			return payment.Filter{}, err
+		}
+	}
+
+	return f, nil
+}
+
+// filterLeaf returns the filter of the field path compared by cmp with the
+// value v.
+func filterLeaf(path string, cmp payment.FilterCmp, v string) (payment.Filter, error) {
+	var invalid = errors.New(ErrInvalidQueryParam, payment.ErrMDArg("filter", path+"="+v))
+	switch path {
+	case "id", "organisation_id":
+		var id, err = uuid.FromString(v)
+		if err != nil {
+			return payment.Filter{}, invalid
+		}
+
+		if path == "id" {
+			return payment.NewFilterByID(cmp, id)
+		}
+
+		return payment.NewFilterByOrgID(cmp, id)
+	case "type":
+		return payment.NewFilterByType(cmp, v)
+	case "status":
+		var s, ok = payment.ParseStatus(v)
+		if !ok {
+			return payment.Filter{}, errors.New(payment.ErrInvalidArgFilterValue, payment.ErrMDArg("val", v))
+		}
+
+		return payment.NewFilterByStatus(cmp, s)
+	case "attributes.amount":
+		var a, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return payment.Filter{}, invalid
+		}
+
+		return payment.NewFilterByAmount(cmp, a)
+	}
+
+	return payment.Filter{}, invalid
+}
+
+// parsePage returns the chunk of the page query parameter of r, whose number
+// starts by 1. The default size is defaultPageSize and it cannot be greater
+// than maxPageSize.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidQueryParam
+func parsePage(r *http.Request) (payment.Chunk, error) {
+	var (
+		q          = r.URL.Query()
+		num, size  = uint64(1), uint64(defaultPageSize)
+		err        error
+		invalidArg = func(n string) error {
+			return errors.New(ErrInvalidQueryParam, payment.ErrMDArg(n, q.Get(n)))
+		}
+	)
+
+	if v := q.Get("page[number]"); v != "" {
+		num, err = strconv.ParseUint(v, 10, 64)
+		if err != nil || num == 0 {
+			return payment.Chunk{}, invalidArg("page[number]")
+		}
+	}
+
+	if v := q.Get("page[size]"); v != "" {
+		size, err = strconv.ParseUint(v, 10, 32)
+		if err != nil || size == 0 || size > maxPageSize {
+			return payment.Chunk{}, invalidArg("page[size]")
+		}
+	}
+
+	return payment.Chunk{Limit: uint32(size), Offset: (num - 1) * size}, nil
+}
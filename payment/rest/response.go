@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/ratelimit"
+	"github.com/ifraixedes/go-payments-api-example/payment/tenancy"
+	"go.fraixed.es/errors"
+)
+
+// errorEnvelop is the body of the error responses, see
+// docs/api/schemas/error-envelop.json.
+type errorEnvelop struct {
+	Error errorInfo `json:"error"`
+}
+
+type errorInfo struct {
+	Code   string                 `json:"code"`
+	Detail string                 `json:"detail"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+}
+
+// dataEnvelop is the body of the successful responses.
+type dataEnvelop struct {
+	Data interface{} `json:"data"`
+	Meta interface{} `json:"meta,omitempty"`
+}
+
+// listMeta is the meta of the responses of lists.
+type listMeta struct {
+	Total uint64 `json:"total"`
+}
+
+// writeJSON writes a response with status code status and v encoded as JSON as
+// body.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	var b, err = json.Marshal(v)
+	if err != nil {
+		writeError(w, errors.Wrap(err, ErrInternalError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+// writeError writes the error response which corresponds to err.
+//
+// The error codes which aren't known by this package are responded as
+// ErrInternalError for not exposing internal details to the clients.
+func writeError(w http.ResponseWriter, err error) {
+	var (
+		c, _   = errors.GetCode(err)
+		status = errStatus(c)
+	)
+
+	if status == http.StatusInternalServerError {
+		c = ErrInternalError
+	}
+
+	var ee = errorEnvelop{
+		Error: errorInfo{
+			Code:   c.String(),
+			Detail: c.Message(),
+		},
+	}
+
+	switch c {
+	case ErrUnavailableContentType:
+		ee.Error.Meta = map[string]interface{}{
+			"acceptedContentTypes": []string{MediaTypeV1},
+		}
+	case ErrUnsupportedMediaType:
+		ee.Error.Meta = map[string]interface{}{
+			"supportedContentTypes": []string{MediaTypeMergePatch, MediaTypeJSONPatch},
+		}
+	case ErrUnauthenticated:
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+
+	var b, merr = json.Marshal(ee)
+	if merr != nil {
+		http.Error(w, ErrInternalError.Message(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+// errStatus returns the HTTP status code which corresponds to the error code c.
+func errStatus(c errors.Code) int {
+	switch c {
+	case ErrInvalidBody,
+		ErrInvalidQueryParam,
+		payment.ErrInvalidArgAggregation,
+		payment.ErrInvalidArgFilterCmpNotSupported,
+		payment.ErrInvalidArgFilterValue,
+		payment.ErrInvalidArgIdempotencyKey,
+		payment.ErrInvalidArgIdempotencyKeyReused,
+		payment.ErrInvalidArgPatch,
+		payment.ErrInvalidArgPatchReadOnly,
+		payment.ErrInvalidArgSelection,
+		payment.ErrInvalidArgSort,
+		payment.ErrInvalidArgSortNotSupported,
+		payment.ErrInvalidArgStatus,
+		payment.ErrInvalidArgStatusTransition,
+		payment.ErrInvalidPaymentID,
+		payment.ErrInvalidPaymentOrgID,
+		payment.ErrInvalidPaymentType,
+		payment.ErrInvalidPaymentAttrAccountNumber,
+		payment.ErrInvalidPaymentAttrAmount,
+		payment.ErrInvalidPaymentAttrBankID,
+		payment.ErrInvalidPaymentAttrCode,
+		payment.ErrInvalidPaymentAttrCurrency,
+		payment.ErrInvalidPaymentAttrDate,
+		payment.ErrInvalidPaymentAttrFormat,
+		payment.ErrInvalidPaymentAttrPaymentID,
+		payment.ErrInvalidPaymentAttrRequired,
+		auth.ErrInvalidOrgID,
+		auth.ErrInvalidScope:
+		return http.StatusUnprocessableEntity
+	case ErrMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case ErrUnauthenticated:
+		return http.StatusUnauthorized
+	case ErrUnauthorized, tenancy.ErrOrganisationMismatch:
+		return http.StatusForbidden
+	case ErrUnavailableContentType:
+		return http.StatusNotAcceptable
+	case ErrUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case payment.ErrNotEditable, payment.ErrInvalidArgPatchTestFailed:
+		return http.StatusConflict
+	case payment.ErrNotFound:
+		return http.StatusNotFound
+	case payment.ErrInvalidArgVersionMismatch:
+		return http.StatusPreconditionFailed
+	case ErrPreconditionRequired:
+		return http.StatusPreconditionRequired
+	case payment.ErrAbortedOperation:
+		return http.StatusServiceUnavailable
+	case ratelimit.ErrRateLimited:
+		return http.StatusTooManyRequests
+	}
+
+	return http.StatusInternalServerError
+}
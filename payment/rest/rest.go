@@ -0,0 +1,532 @@
+// Package rest implements the HTTP RESTful API of the payments, which is
+// described by the OpenAPI definition contained in the docs/api directory of
+// this repository.
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/ratelimit"
+	"github.com/ifraixedes/go-payments-api-example/payment/tenancy"
+	"github.com/ifraixedes/go-payments-api-example/payment/tracing"
+	"go.fraixed.es/errors"
+)
+
+// MediaTypeV1 is the media type of the version 1 of the API.
+const MediaTypeV1 = "application/vnd.payments.v1+json"
+
+// The media types of the body of the requests which patch a payment, see
+// payment.Patch.
+const (
+	MediaTypeMergePatch = "application/merge-patch+json"
+	MediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// HeaderIdempotencyKey is the HTTP header which contains the idempotency key
+// used when creating a payment, see payment.WithIdempotencyKey.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// Authenticator authenticates the clients of the API by the credentials of the
+// requests.
+//
+// Authenticate must return any of the error codes of the auth package when the
+// credentials are missing or invalid, which are responded as
+// ErrUnauthenticated; the rest of the error codes are responded as any other
+// error. auth.Authenticator satisfies this interface.
+type Authenticator interface {
+	Authenticate(r *http.Request) (auth.Principal, error)
+}
+
+// RateLimiter limits the rate of the operations of the clients of the API.
+//
+// Allow must return ratelimit.ErrRateLimited, with the time to wait before
+// retrying, when the principal p has exceeded the rate of the class of
+// operations o. *ratelimit.Limiter satisfies this interface.
+type RateLimiter interface {
+	Allow(ctx context.Context, p auth.Principal, o ratelimit.Op) (time.Duration, error)
+}
+
+// Handler is the http.Handler which serves the API.
+type Handler struct {
+	svc     payment.Service
+	authn   Authenticator
+	limiter RateLimiter
+	mux     *http.ServeMux
+}
+
+// Option sets an optional parameter of the Handler created by NewHandler.
+type Option func(*Handler)
+
+// WithRateLimiter limits the rate of the operations of the authenticated
+// principals with l. The requests which exceed it are responded with the 429
+// status code and the Retry-After header. By default the rate isn't limited.
+func WithRateLimiter(l RateLimiter) Option {
+	return func(h *Handler) {
+		h.limiter = l
+	}
+}
+
+// NewHandler creates a Handler which serves the API using svc and
+// authenticating the requests with authn.
+//
+// svc is scoped to the organisation of the authenticated principal of each
+// request (see tenancy.New) and each operation requires the principal to have
+// a specific scope.
+func NewHandler(svc payment.Service, authn Authenticator, opts ...Option) *Handler {
+	var h = &Handler{
+		svc:   tenancy.New(svc),
+		authn: authn,
+		mux:   http.NewServeMux(),
+	}
+
+	for _, o := range opts {
+		o(h)
+	}
+
+	h.mux.HandleFunc("/payments", h.payments)
+	h.mux.HandleFunc("/payments/", h.payment)
+	h.mux.HandleFunc("/payments/aggregates", h.aggregates)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !acceptsV1(r) {
+		writeError(w, errors.New(ErrUnavailableContentType))
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) payments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.paymentsGet(w, r)
+	case http.MethodPost:
+		h.paymentsPost(w, r)
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPost}, ", "))
+		writeError(w, errors.New(ErrMethodNotAllowed))
+	}
+}
+
+// paymentsGet retrieves the list of payments of the page, filtered, sorted and
+// only with the fields of the query parameters (see parsePage, parseFilter,
+// parseOrder and selection).
+func (h *Handler) paymentsGet(w http.ResponseWriter, r *http.Request) {
+	var ctx, err = authorize(h.authn, r, auth.ScopePaymentsRead)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !h.limit(ctx, w, ratelimit.OpRead) {
+		return
+	}
+
+	sl, partial, err := selection(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	f, err := parseFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	st, err := parseOrder(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	c, err := parsePage(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	pms, err := h.svc.Find(ctx, f, sl, st, c)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	total, err := h.total(ctx, f, c, len(pms))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var data = make([]interface{}, len(pms))
+	for i, p := range pms {
+		if !partial {
+			data[i] = p
+			continue
+		}
+
+		if data[i], err = project(p, sl); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, dataEnvelop{Data: data, Meta: listMeta{Total: total}})
+}
+
+// total returns the total number of payments which fulfill f, being n the
+// number of payments of the chunk c. They are only counted when the chunk
+// doesn't contain all of them.
+func (h *Handler) total(ctx context.Context, f payment.Filter, c payment.Chunk, n int) (uint64, error) {
+	if c.Offset == 0 && n < int(c.Limit) {
+		return uint64(n), nil
+	}
+
+	var aggs, err = h.svc.Aggregate(ctx, f, payment.Aggregation{Fns: []payment.AggregateFn{payment.AggregateFnCount}})
+	if err != nil || len(aggs) == 0 {
+		return 0, err
+	}
+
+	return aggs[0].Count, nil
+}
+
+// paymentsPost creates a new payment. When the request has the
+// HeaderIdempotencyKey header, the payment is created with it, so retrying
+// the request with the same key and body responds with the same payment ID
+// rather than creating a new payment.
+func (h *Handler) paymentsPost(w http.ResponseWriter, r *http.Request) {
+	var ctx, err = authorize(h.authn, r, auth.ScopePaymentsWrite)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !h.limit(ctx, w, ratelimit.OpWrite) {
+		return
+	}
+
+	var p payment.PymtUpsert
+	var _, span = tracing.Start(ctx, "rest.decode")
+	err = json.NewDecoder(r.Body).Decode(&p)
+	span.End()
+	if err != nil {
+		writeError(w, errors.Wrap(err, ErrInvalidBody))
+		return
+	}
+
+	if keys, ok := r.Header[HeaderIdempotencyKey]; ok {
+		ctx = payment.WithIdempotencyKey(ctx, strings.Join(keys, ","))
+	}
+
+	id, err := h.svc.Create(ctx, p)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, dataEnvelop{
+		Data: struct {
+			ID uuid.UUID `json:"id"`
+		}{ID: id},
+	})
+}
+
+// payment serves the operations on a specific payment, whose ID is the path
+// segment after /payments/.
+//
+// The responses of the payment have the ETag header with its version (see
+// etag) and the operations which modify it require the If-Match header, so
+// they don't overwrite a concurrent modification.
+func (h *Handler) payment(w http.ResponseWriter, r *http.Request) {
+	var (
+		ps      = strings.TrimPrefix(r.URL.Path, "/payments/")
+		id, err = uuid.FromString(ps)
+	)
+	if err != nil || strings.Contains(ps, "/") {
+		writeError(w, errors.New(payment.ErrNotFound, payment.ErrMDArg("path", r.URL.Path)))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.paymentGet(w, r, id)
+	case http.MethodPut:
+		h.paymentPut(w, r, id)
+	case http.MethodPatch:
+		h.paymentPatch(w, r, id)
+	case http.MethodDelete:
+		h.paymentDelete(w, r, id)
+	default:
+		w.Header().Set("Allow", strings.Join(
+			[]string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete}, ", ",
+		))
+		writeError(w, errors.New(ErrMethodNotAllowed))
+	}
+}
+
+// paymentGet retrieves the payment id. It responds with the 304 status code
+// and without body when the If-None-Match header matches its version.
+//
+// When the request has the fields query parameter, the payment is retrieved
+// and responded only with the selected fields (see selection).
+func (h *Handler) paymentGet(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var ctx, err = authorize(h.authn, r, auth.ScopePaymentsRead)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !h.limit(ctx, w, ratelimit.OpRead) {
+		return
+	}
+
+	sl, partial, err := selection(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// The version is always retrieved for the ETag header
+	var vsl = sl
+	vsl.Version = true
+	p, err := h.svc.Get(ctx, id, vsl)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(p.Version))
+	if !noneMatch(r, p.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if !partial {
+		writeJSON(w, http.StatusOK, dataEnvelop{Data: p})
+		return
+	}
+
+	pp, err := project(p, sl)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dataEnvelop{Data: pp})
+}
+
+// paymentPut updates the payment id, if the If-Match header matches its
+// version.
+func (h *Handler) paymentPut(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var ctx, err = authorize(h.authn, r, auth.ScopePaymentsWrite)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !h.limit(ctx, w, ratelimit.OpWrite) {
+		return
+	}
+
+	v, err := matchVersion(ctx, h.svc, r, id, true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var p payment.PymtUpsert
+	var _, span = tracing.Start(ctx, "rest.decode")
+	err = json.NewDecoder(r.Body).Decode(&p)
+	span.End()
+	if err != nil {
+		writeError(w, errors.Wrap(err, ErrInvalidBody))
+		return
+	}
+
+	if err := h.svc.Update(ctx, id, v, p); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// paymentPatch partially updates the payment id, if the If-Match header matches
+// its version. The body is a JSON merge patch or a JSON patch, according to its
+// content type (see MediaTypeMergePatch and MediaTypeJSONPatch).
+func (h *Handler) paymentPatch(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var ctx, err = authorize(h.authn, r, auth.ScopePaymentsWrite)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !h.limit(ctx, w, ratelimit.OpWrite) {
+		return
+	}
+
+	var pt payment.PatchType
+	var mt, _, _ = mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mt {
+	case MediaTypeMergePatch:
+		pt = payment.PatchMerge
+	case MediaTypeJSONPatch:
+		pt = payment.PatchJSON
+	default:
+		writeError(w, errors.New(ErrUnsupportedMediaType, payment.ErrMDVar("Content-Type", mt)))
+		return
+	}
+
+	v, err := matchVersion(ctx, h.svc, r, id, true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var _, span = tracing.Start(ctx, "rest.decode")
+	doc, err := ioutil.ReadAll(r.Body)
+	span.End()
+	if err != nil {
+		writeError(w, errors.Wrap(err, ErrInvalidBody))
+		return
+	}
+
+	p, err := payment.NewPatch(pt, doc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := h.svc.Patch(ctx, id, v, p); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// paymentDelete deletes the payment id, if the If-Match header matches its
+// version.
+func (h *Handler) paymentDelete(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var ctx, err = authorize(h.authn, r, auth.ScopePaymentsWrite)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !h.limit(ctx, w, ratelimit.OpWrite) {
+		return
+	}
+
+	v, err := matchVersion(ctx, h.svc, r, id, true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := h.svc.Delete(ctx, id, v); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorize authenticates r with authn and checks that the principal has the
+// scope s, returning the context of r carrying the principal and its
+// organisation (see auth.WithPrincipal and tenancy.WithOrgID).
+//
+// The following error codes can be returned:
+//
+// * ErrUnauthenticated - When authn returns an error code of the auth package.
+//
+// * ErrUnauthorized - When the principal doesn't have the scope s.
+//
+// * Any other error returned by authn.
+func authorize(authn Authenticator, r *http.Request, s auth.Scope) (context.Context, error) {
+	var p, err = authn.Authenticate(r)
+	if err != nil {
+		var c, _ = errors.GetCode(err)
+		switch c {
+		case auth.ErrExpiredToken,
+			auth.ErrInvalidAPIKey,
+			auth.ErrInvalidOrgID,
+			auth.ErrInvalidScope,
+			auth.ErrInvalidToken,
+			auth.ErrMissingCredentials:
+			return nil, errors.Wrap(err, ErrUnauthenticated)
+		}
+
+		return nil, err
+	}
+
+	if !p.HasScope(s) {
+		return nil, errors.New(ErrUnauthorized, payment.ErrMDVar("scope", s))
+	}
+
+	var ctx = auth.WithPrincipal(r.Context(), p)
+	return tenancy.WithOrgID(ctx, p.OrgID), nil
+}
+
+// limit takes a token of the class of operations o for the principal carried by
+// ctx from the rate limiter of h, if it has one. It writes the error response
+// to w and returns false when the rate is exceeded or the limiter fails.
+func (h *Handler) limit(ctx context.Context, w http.ResponseWriter, o ratelimit.Op) bool {
+	if h.limiter == nil {
+		return true
+	}
+
+	var p, _ = auth.PrincipalFrom(ctx)
+	var wait, err = h.limiter.Allow(ctx, p, o)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, ratelimit.ErrRateLimited) {
+		var secs = int64(math.Ceil(wait.Seconds()))
+		if secs < 1 {
+			secs = 1
+		}
+
+		w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	}
+
+	writeError(w, err)
+	return false
+}
+
+// acceptsV1 returns true if the Accept header of r accepts MediaTypeV1. The
+// requests without Accept header are considered that accept it.
+func acceptsV1(r *http.Request) bool {
+	var accept = r.Header.Get("Accept")
+	if accept == "" {
+		return true
+	}
+
+	for _, a := range strings.Split(accept, ",") {
+		var mt, _, err = mime.ParseMediaType(strings.TrimSpace(a))
+		if err != nil {
+			continue
+		}
+
+		switch mt {
+		case MediaTypeV1, "application/*", "*/*":
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,970 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/ratelimit"
+	"github.com/ifraixedes/go-payments-api-example/payment/rest"
+	"github.com/ifraixedes/go-payments-api-example/payment/tenancy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestHandler_paymentsPost(t *testing.T) {
+	var (
+		pid    = testutil.NewUUID(t)
+		orgID  = testutil.NewUUID(t)
+		body   = `{"type":"Payment","organisation_id":"` + orgID.String() + `","attributes":{"payment_id":"1"}}`
+		writer = auth.Principal{OrgID: orgID, Scopes: []auth.Scope{auth.ScopePaymentsWrite}}
+	)
+
+	var tcases = []struct {
+		desc   string
+		req    func() *http.Request
+		authn  authnStub
+		create func(context.Context, payment.PymtUpsert) (uuid.UUID, error)
+		status int
+		assert func(*testing.T, map[string]interface{})
+	}{
+		{
+			desc: "created",
+			req: func() *http.Request {
+				var r = httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+				r.Header.Set("Accept", rest.MediaTypeV1)
+				return r
+			},
+			authn: authnStub{principal: writer},
+			create: func(ctx context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
+				var _, ok, _ = payment.IdempotencyKey(ctx)
+				assert.False(t, ok)
+				assert.Equal(t, orgID, p.OrgID)
+
+				var pp, _ = auth.PrincipalFrom(ctx)
+				assert.Equal(t, writer, pp)
+				return pid, nil
+			},
+			status: http.StatusCreated,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assert.Equal(t, map[string]interface{}{"id": pid.String()}, b["data"])
+			},
+		},
+		{
+			desc: "created with idempotency key",
+			req: func() *http.Request {
+				var r = httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+				r.Header.Set(rest.HeaderIdempotencyKey, "key-1")
+				return r
+			},
+			authn: authnStub{principal: writer},
+			create: func(ctx context.Context, _ payment.PymtUpsert) (uuid.UUID, error) {
+				var key, ok, err = payment.IdempotencyKey(ctx)
+				assert.NoError(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, "key-1", key)
+				return pid, nil
+			},
+			status: http.StatusCreated,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assert.Equal(t, map[string]interface{}{"id": pid.String()}, b["data"])
+			},
+		},
+		{
+			desc: "error: idempotency key reused",
+			req: func() *http.Request {
+				var r = httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+				r.Header.Set(rest.HeaderIdempotencyKey, "key-1")
+				return r
+			},
+			authn: authnStub{principal: writer},
+			create: func(context.Context, payment.PymtUpsert) (uuid.UUID, error) {
+				return uuid.Nil, errors.New(payment.ErrInvalidArgIdempotencyKeyReused)
+			},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgIdempotencyKeyReused.String()),
+		},
+		{
+			desc: "error: invalid body",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader("{"))
+			},
+			authn:  authnStub{principal: writer},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(rest.ErrInvalidBody.String()),
+		},
+		{
+			desc: "error: unexpected",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+			},
+			authn: authnStub{principal: writer},
+			create: func(context.Context, payment.PymtUpsert) (uuid.UUID, error) {
+				return uuid.Nil, errors.New(payment.ErrUnexpectedStoreError)
+			},
+			status: http.StatusInternalServerError,
+			assert: assertErrorCode(rest.ErrInternalError.String()),
+		},
+		{
+			desc: "error: unauthenticated",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+			},
+			authn:  authnStub{err: errors.New(auth.ErrMissingCredentials)},
+			status: http.StatusUnauthorized,
+			assert: assertErrorCode(rest.ErrUnauthenticated.String()),
+		},
+		{
+			desc: "error: authentication unexpected",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+			},
+			authn:  authnStub{err: errors.New(payment.ErrUnexpectedStoreError)},
+			status: http.StatusInternalServerError,
+			assert: assertErrorCode(rest.ErrInternalError.String()),
+		},
+		{
+			desc: "error: unauthorized",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+			},
+			authn: authnStub{
+				principal: auth.Principal{OrgID: orgID, Scopes: []auth.Scope{auth.ScopePaymentsRead}},
+			},
+			status: http.StatusForbidden,
+			assert: assertErrorCode(rest.ErrUnauthorized.String()),
+		},
+		{
+			desc: "error: other organisation",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+			},
+			authn: authnStub{
+				principal: auth.Principal{OrgID: testutil.NewUUID(t), Scopes: []auth.Scope{auth.ScopePaymentsWrite}},
+			},
+			status: http.StatusForbidden,
+			assert: assertErrorCode(tenancy.ErrOrganisationMismatch.String()),
+		},
+		{
+			desc: "error: not acceptable",
+			req: func() *http.Request {
+				var r = httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+				r.Header.Set("Accept", "text/html")
+				return r
+			},
+			status: http.StatusNotAcceptable,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assertErrorCode(rest.ErrUnavailableContentType.String())(t, b)
+				assert.Equal(t,
+					map[string]interface{}{"acceptedContentTypes": []interface{}{rest.MediaTypeV1}},
+					b["error"].(map[string]interface{})["meta"],
+				)
+			},
+		},
+		{
+			desc: "error: method not allowed",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPatch, "/payments", nil)
+			},
+			status: http.StatusMethodNotAllowed,
+			assert: assertErrorCode(rest.ErrMethodNotAllowed.String()),
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			var (
+				h = rest.NewHandler(&testutil.SvcStub{CreateFn: tc.create}, tc.authn)
+				w = httptest.NewRecorder()
+			)
+
+			h.ServeHTTP(w, tc.req())
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			if tc.status == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+			}
+
+			var b map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &b))
+			tc.assert(t, b)
+		})
+	}
+}
+
+func TestHandler_paymentsGet(t *testing.T) {
+	var (
+		orgID  = testutil.NewUUID(t)
+		reader = auth.Principal{OrgID: orgID, Scopes: []auth.Scope{auth.ScopePaymentsRead}}
+		pms    = []payment.Pymt{{ID: testutil.NewUUID(t)}, {ID: testutil.NewUUID(t)}}
+	)
+
+	pms[0].Attributes.Amount = 10.5
+	pms[1].Attributes.Amount = 3
+
+	var tcases = []struct {
+		desc   string
+		query  string
+		authn  authnStub
+		find   func(context.Context, payment.Filter, payment.Selection, payment.Sort, payment.Chunk) ([]payment.Pymt, error)
+		status int
+		assert func(*testing.T, map[string]interface{})
+	}{
+		{
+			desc:  "default",
+			authn: authnStub{principal: reader},
+			find: func(
+				_ context.Context, f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk,
+			) ([]payment.Pymt, error) {
+				assert.Equal(t, payment.FilterNodeTypeLeaf, f.NodeType(), "only the organisation filter")
+				assert.Equal(t, payment.SelectAll(), sl)
+				assert.Empty(t, st)
+				assert.Equal(t, payment.Chunk{Limit: 100}, c)
+				return pms, nil
+			},
+			status: http.StatusOK,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assert.Len(t, b["data"], 2)
+				assert.Equal(t, map[string]interface{}{"total": float64(2)}, b["meta"])
+			},
+		},
+		{
+			desc: "order, filter, page and fields",
+			query: "?order=-attributes.amount,%2Bid&filter=attributes.amount%3D%3E1&filter=type%3D%3DPayment" +
+				"&page[number]=2&page[size]=2&fields=attributes.amount",
+			authn: authnStub{principal: reader},
+			find: func(
+				_ context.Context, f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk,
+			) ([]payment.Pymt, error) {
+				assert.Equal(t, payment.FilterNodeTypeNonLeaf, f.NodeType())
+				assert.Equal(t, []string{"amount"}, sl.AttrPaths())
+				assert.Equal(t, payment.Sort{
+					{Field: payment.SortFieldAmount, Dir: payment.SortDescending},
+					{Field: payment.SortFieldID, Dir: payment.SortAscending},
+				}, st)
+				assert.Equal(t, payment.Chunk{Limit: 2, Offset: 2}, c)
+				return pms, nil
+			},
+			status: http.StatusOK,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{
+						"id": pms[0].ID.String(), "attributes": map[string]interface{}{"amount": 10.5},
+					},
+					map[string]interface{}{
+						"id": pms[1].ID.String(), "attributes": map[string]interface{}{"amount": float64(3)},
+					},
+				}, b["data"])
+				assert.Equal(t, map[string]interface{}{"total": float64(5)}, b["meta"])
+			},
+		},
+		{
+			desc:   "error: unauthorized",
+			authn:  authnStub{principal: auth.Principal{OrgID: orgID}},
+			status: http.StatusForbidden,
+			assert: assertErrorCode(rest.ErrUnauthorized.String()),
+		},
+		{
+			desc:   "error: invalid order",
+			query:  "?order=-attributes.fx",
+			authn:  authnStub{principal: reader},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgSort.String()),
+		},
+		{
+			desc:   "error: duplicated order",
+			query:  "?order=id,-id",
+			authn:  authnStub{principal: reader},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgSort.String()),
+		},
+		{
+			desc:   "error: invalid filter",
+			query:  "?filter=attributes.amount%3D~1",
+			authn:  authnStub{principal: reader},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(rest.ErrInvalidQueryParam.String()),
+		},
+		{
+			desc:   "error: invalid page",
+			query:  "?page[size]=1001",
+			authn:  authnStub{principal: reader},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(rest.ErrInvalidQueryParam.String()),
+		},
+		{
+			desc:  "error: sort not supported",
+			query: "?order=attributes.debtor_party.name",
+			authn: authnStub{principal: reader},
+			find: func(context.Context, payment.Filter, payment.Selection, payment.Sort, payment.Chunk) ([]payment.Pymt, error) {
+				return nil, errors.New(payment.ErrInvalidArgSortNotSupported)
+			},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgSortNotSupported.String()),
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				svc = &testutil.SvcStub{
+					AggregateFn: func(_ context.Context, _ payment.Filter, a payment.Aggregation) ([]payment.Aggregate, error) {
+						assert.Equal(t, payment.Aggregation{Fns: []payment.AggregateFn{payment.AggregateFnCount}}, a)
+						return []payment.Aggregate{{Count: 5}}, nil
+					},
+					FindFn: tc.find,
+				}
+				h = rest.NewHandler(svc, tc.authn)
+				w = httptest.NewRecorder()
+			)
+
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/payments"+tc.query, nil))
+			assert.Equal(t, tc.status, w.Code)
+
+			var b map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &b))
+			tc.assert(t, b)
+		})
+	}
+}
+
+func TestHandler_aggregates(t *testing.T) {
+	var (
+		orgID  = testutil.NewUUID(t)
+		reader = auth.Principal{OrgID: orgID, Scopes: []auth.Scope{auth.ScopePaymentsRead}}
+	)
+
+	var tcases = []struct {
+		desc      string
+		method    string
+		query     string
+		authn     authnStub
+		aggregate func(context.Context, payment.Filter, payment.Aggregation) ([]payment.Aggregate, error)
+		status    int
+		assert    func(*testing.T, map[string]interface{})
+	}{
+		{
+			desc:  "group by currency",
+			query: "?group_by=attributes.currency&functions=count,sum&filter=status%3D%3DSettled",
+			authn: authnStub{principal: reader},
+			aggregate: func(_ context.Context, f payment.Filter, a payment.Aggregation) ([]payment.Aggregate, error) {
+				assert.Equal(t, payment.FilterNodeTypeNonLeaf, f.NodeType(), "status and organisation filters")
+				assert.Equal(t, payment.Aggregation{
+					GroupBy: []payment.AggregateKey{payment.AggregateKeyCurrency},
+					Fns:     []payment.AggregateFn{payment.AggregateFnCount, payment.AggregateFnSum},
+				}, a)
+				return []payment.Aggregate{
+					{Group: []string{"GBP"}, Count: 2, Sum: "0.3"},
+					{Group: []string{"USD"}, Count: 1, Sum: "1500"},
+				}, nil
+			},
+			status: http.StatusOK,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{
+						"group": map[string]interface{}{"attributes.currency": "GBP"}, "count": float64(2), "sum": "0.3",
+					},
+					map[string]interface{}{
+						"group": map[string]interface{}{"attributes.currency": "USD"}, "count": float64(1), "sum": "1500",
+					},
+				}, b["data"])
+			},
+		},
+		{
+			desc:  "without groups",
+			query: "?functions=avg",
+			authn: authnStub{principal: reader},
+			aggregate: func(context.Context, payment.Filter, payment.Aggregation) ([]payment.Aggregate, error) {
+				return []payment.Aggregate{{Group: []string{}, Count: 3, Avg: "0.133"}}, nil
+			},
+			status: http.StatusOK,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assert.Equal(t, []interface{}{map[string]interface{}{"avg": "0.133"}}, b["data"])
+			},
+		},
+		{
+			desc:   "error: method not allowed",
+			method: http.MethodPost,
+			authn:  authnStub{principal: reader},
+			status: http.StatusMethodNotAllowed,
+			assert: assertErrorCode(rest.ErrMethodNotAllowed.String()),
+		},
+		{
+			desc:   "error: unauthorized",
+			query:  "?functions=count",
+			authn:  authnStub{principal: auth.Principal{OrgID: orgID}},
+			status: http.StatusForbidden,
+			assert: assertErrorCode(rest.ErrUnauthorized.String()),
+		},
+		{
+			desc:   "error: invalid filter",
+			query:  "?functions=sum&filter=attributes.currency%3D%3DGBP",
+			authn:  authnStub{principal: reader},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(rest.ErrInvalidQueryParam.String()),
+		},
+		{
+			desc:   "error: without functions",
+			query:  "?group_by=attributes.currency",
+			authn:  authnStub{principal: reader},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgAggregation.String()),
+		},
+		{
+			desc:   "error: invalid group",
+			query:  "?group_by=attributes.reference&functions=sum",
+			authn:  authnStub{principal: reader},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgAggregation.String()),
+		},
+		{
+			desc:   "error: invalid function",
+			query:  "?functions=median",
+			authn:  authnStub{principal: reader},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgAggregation.String()),
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				h      = rest.NewHandler(&testutil.SvcStub{AggregateFn: tc.aggregate}, tc.authn)
+				w      = httptest.NewRecorder()
+				method = tc.method
+			)
+
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			h.ServeHTTP(w, httptest.NewRequest(method, "/payments/aggregates"+tc.query, nil))
+			assert.Equal(t, tc.status, w.Code)
+
+			var b map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &b))
+			tc.assert(t, b)
+		})
+	}
+}
+
+func TestHandler_payment(t *testing.T) {
+	var (
+		pid    = testutil.NewUUID(t)
+		orgID  = testutil.NewUUID(t)
+		path   = "/payments/" + pid.String()
+		body   = `{"type":"Payment","organisation_id":"` + orgID.String() + `","attributes":{"payment_id":"1"}}`
+		reader = auth.Principal{OrgID: orgID, Scopes: []auth.Scope{auth.ScopePaymentsRead}}
+		writer = auth.Principal{OrgID: orgID, Scopes: []auth.Scope{auth.ScopePaymentsWrite}}
+	)
+
+	// newReq returns a request with the method m to the payment and the headers
+	// hs, which are pairs of name and value.
+	var newReq = func(m string, hs ...string) func() *http.Request {
+		return func() *http.Request {
+			var r = httptest.NewRequest(m, path, strings.NewReader(body))
+			for i := 0; i < len(hs); i += 2 {
+				r.Header.Add(hs[i], hs[i+1])
+			}
+
+			return r
+		}
+	}
+
+	var tcases = []struct {
+		desc    string
+		req     func() *http.Request
+		authn   authnStub
+		update  func(context.Context, uuid.UUID, uint32, payment.PymtUpsert) error
+		deleted bool
+		status  int
+		etag    string
+		assert  func(*testing.T, map[string]interface{})
+	}{
+		{
+			desc:   "get",
+			req:    newReq(http.MethodGet, "If-None-Match", `"1"`),
+			authn:  authnStub{principal: reader},
+			status: http.StatusOK,
+			etag:   `"2"`,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				var d = b["data"].(map[string]interface{})
+				assert.Equal(t, pid.String(), d["id"])
+				assert.Equal(t, float64(2), d["version"])
+				assert.Equal(t, "Pending", d["status"])
+			},
+		},
+		{
+			desc: "get fields",
+			req: func() *http.Request {
+				return httptest.NewRequest(
+					http.MethodGet, path+"?fields=status,attributes.amount,attributes.debtor_party.name", nil,
+				)
+			},
+			authn:  authnStub{principal: reader},
+			status: http.StatusOK,
+			etag:   `"2"`,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assert.Equal(t, map[string]interface{}{
+					"id":     pid.String(),
+					"status": "Pending",
+					"attributes": map[string]interface{}{
+						"amount":       float64(10),
+						"debtor_party": map[string]interface{}{"name": "Debtor"},
+					},
+				}, b["data"])
+			},
+		},
+		{
+			desc:   "get not modified",
+			req:    newReq(http.MethodGet, "If-None-Match", `"1", W/"2"`),
+			authn:  authnStub{principal: reader},
+			status: http.StatusNotModified,
+			etag:   `"2"`,
+		},
+		{
+			desc:   "get not modified any",
+			req:    newReq(http.MethodGet, "If-None-Match", "*"),
+			authn:  authnStub{principal: reader},
+			status: http.StatusNotModified,
+			etag:   `"2"`,
+		},
+		{
+			desc:   "update",
+			req:    newReq(http.MethodPut, "If-Match", `"2"`),
+			authn:  authnStub{principal: writer},
+			status: http.StatusNoContent,
+		},
+		{
+			desc:   "update with the API definition version",
+			req:    newReq(http.MethodPut, "If-Match", "2"),
+			authn:  authnStub{principal: writer},
+			status: http.StatusNoContent,
+		},
+		{
+			desc:   "update with a list of tags",
+			req:    newReq(http.MethodPut, "If-Match", `"1", "2"`),
+			authn:  authnStub{principal: writer},
+			status: http.StatusNoContent,
+		},
+		{
+			desc:   "update any",
+			req:    newReq(http.MethodPut, "If-Match", "*"),
+			authn:  authnStub{principal: writer},
+			status: http.StatusNoContent,
+		},
+		{
+			desc:    "delete",
+			req:     newReq(http.MethodDelete, "If-Match", `"2"`),
+			authn:   authnStub{principal: writer},
+			deleted: true,
+			status:  http.StatusNoContent,
+		},
+		{
+			desc:    "delete any",
+			req:     newReq(http.MethodDelete, "If-Match", "*"),
+			authn:   authnStub{principal: writer},
+			deleted: true,
+			status:  http.StatusNoContent,
+		},
+		{
+			desc:   "error: get not found",
+			req:    newReq(http.MethodGet),
+			authn:  authnStub{principal: auth.Principal{OrgID: testutil.NewUUID(t), Scopes: reader.Scopes}},
+			status: http.StatusNotFound,
+			assert: assertErrorCode(payment.ErrNotFound.String()),
+		},
+		{
+			desc: "error: get invalid fields",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, path+"?fields=status,amount", nil)
+			},
+			authn:  authnStub{principal: reader},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgSelection.String()),
+		},
+		{
+			desc: "error: invalid path",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, path+"/status", nil)
+			},
+			status: http.StatusNotFound,
+			assert: assertErrorCode(payment.ErrNotFound.String()),
+		},
+		{
+			desc:   "error: update unauthorized",
+			req:    newReq(http.MethodPut, "If-Match", `"2"`),
+			authn:  authnStub{principal: reader},
+			status: http.StatusForbidden,
+			assert: assertErrorCode(rest.ErrUnauthorized.String()),
+		},
+		{
+			desc:   "error: update precondition required",
+			req:    newReq(http.MethodPut),
+			authn:  authnStub{principal: writer},
+			status: http.StatusPreconditionRequired,
+			assert: assertErrorCode(rest.ErrPreconditionRequired.String()),
+		},
+		{
+			desc:  "error: update version mismatch",
+			req:   newReq(http.MethodPut, "If-Match", `"1"`),
+			authn: authnStub{principal: writer},
+			update: func(context.Context, uuid.UUID, uint32, payment.PymtUpsert) error {
+				return errors.New(payment.ErrInvalidArgVersionMismatch)
+			},
+			status: http.StatusPreconditionFailed,
+			assert: assertErrorCode(payment.ErrInvalidArgVersionMismatch.String()),
+		},
+		{
+			desc:   "error: update weak tag",
+			req:    newReq(http.MethodPut, "If-Match", `W/"2"`),
+			authn:  authnStub{principal: writer},
+			status: http.StatusPreconditionFailed,
+			assert: assertErrorCode(payment.ErrInvalidArgVersionMismatch.String()),
+		},
+		{
+			desc: "error: update invalid body",
+			req: func() *http.Request {
+				var r = httptest.NewRequest(http.MethodPut, path, strings.NewReader("{"))
+				r.Header.Set("If-Match", `"2"`)
+				return r
+			},
+			authn:  authnStub{principal: writer},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(rest.ErrInvalidBody.String()),
+		},
+		{
+			desc:   "error: delete precondition required",
+			req:    newReq(http.MethodDelete),
+			authn:  authnStub{principal: writer},
+			status: http.StatusPreconditionRequired,
+			assert: assertErrorCode(rest.ErrPreconditionRequired.String()),
+		},
+		{
+			desc:   "error: delete version mismatch",
+			req:    newReq(http.MethodDelete, "If-Match", `"1"`),
+			authn:  authnStub{principal: writer},
+			status: http.StatusPreconditionFailed,
+			assert: assertErrorCode(payment.ErrInvalidArgVersionMismatch.String()),
+		},
+		{
+			desc:   "error: method not allowed",
+			req:    newReq(http.MethodPost),
+			status: http.StatusMethodNotAllowed,
+			assert: assertErrorCode(rest.ErrMethodNotAllowed.String()),
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				deleted bool
+				svc     = &testutil.SvcStub{
+					DeleteFn: func(_ context.Context, id uuid.UUID, v uint32) error {
+						assert.Equal(t, pid, id)
+						if v != 2 {
+							return errors.New(payment.ErrInvalidArgVersionMismatch, payment.ErrMDArg("version", v))
+						}
+
+						deleted = true
+						return nil
+					},
+					GetFn: func(_ context.Context, id uuid.UUID, sl payment.Selection) (payment.Pymt, error) {
+						var p = payment.Pymt{ID: id, Status: payment.StatusPending}
+						p.OrgID = orgID
+						p.Attributes.Amount = 10
+						p.Attributes.DebtorParty.Name = "Debtor"
+						if sl.Version {
+							p.Version = 2
+						}
+
+						return p, nil
+					},
+					UpdateFn: func(ctx context.Context, id uuid.UUID, v uint32, p payment.PymtUpsert) error {
+						if tc.update != nil {
+							return tc.update(ctx, id, v, p)
+						}
+
+						assert.Equal(t, uint32(2), v)
+						assert.Equal(t, "1", p.Attributes.PaymentID)
+						return nil
+					},
+				}
+				h = rest.NewHandler(svc, tc.authn)
+				w = httptest.NewRecorder()
+			)
+
+			h.ServeHTTP(w, tc.req())
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, tc.etag, w.Header().Get("ETag"))
+			assert.Equal(t, tc.deleted, deleted)
+			if tc.assert == nil {
+				assert.Empty(t, w.Body.Bytes())
+				return
+			}
+
+			var b map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &b))
+			tc.assert(t, b)
+		})
+	}
+}
+
+func TestHandler_paymentPatch(t *testing.T) {
+	var (
+		pid    = testutil.NewUUID(t)
+		path   = "/payments/" + pid.String()
+		writer = auth.Principal{OrgID: testutil.NewUUID(t), Scopes: []auth.Scope{auth.ScopePaymentsWrite}}
+	)
+
+	// newReq returns a PATCH request to the payment with the content type ct, the
+	// If-Match header im, if it isn't empty, and the body b.
+	var newReq = func(ct string, im string, b string) *http.Request {
+		var r = httptest.NewRequest(http.MethodPatch, path, strings.NewReader(b))
+		r.Header.Set("Content-Type", ct)
+		if im != "" {
+			r.Header.Set("If-Match", im)
+		}
+
+		return r
+	}
+
+	var tcases = []struct {
+		desc   string
+		req    *http.Request
+		authn  authnStub
+		patch  func(context.Context, uuid.UUID, uint32, payment.Patch) error
+		status int
+		assert func(*testing.T, map[string]interface{})
+	}{
+		{
+			desc:  "merge patch",
+			req:   newReq(rest.MediaTypeMergePatch, `"2"`, `{"attributes":{"reference":"x"}}`),
+			authn: authnStub{principal: writer},
+			patch: func(_ context.Context, id uuid.UUID, v uint32, p payment.Patch) error {
+				assert.Equal(t, pid, id)
+				assert.Equal(t, uint32(2), v)
+				assert.Equal(t, payment.PatchMerge, p.Type())
+				return nil
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			desc:  "json patch",
+			req:   newReq(rest.MediaTypeJSONPatch+"; charset=utf-8", "*", `[{"op":"remove","path":"/attributes/fx"}]`),
+			authn: authnStub{principal: writer},
+			patch: func(_ context.Context, _ uuid.UUID, v uint32, p payment.Patch) error {
+				assert.Equal(t, uint32(3), v, "version retrieved with Get")
+				assert.Equal(t, payment.PatchJSON, p.Type())
+				return nil
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			desc:   "error: unauthorized",
+			req:    newReq(rest.MediaTypeMergePatch, `"2"`, `{}`),
+			authn:  authnStub{principal: auth.Principal{OrgID: writer.OrgID}},
+			status: http.StatusForbidden,
+			assert: assertErrorCode(rest.ErrUnauthorized.String()),
+		},
+		{
+			desc:   "error: unsupported media type",
+			req:    newReq("application/json", `"2"`, `{}`),
+			authn:  authnStub{principal: writer},
+			status: http.StatusUnsupportedMediaType,
+			assert: func(t *testing.T, b map[string]interface{}) {
+				assertErrorCode(rest.ErrUnsupportedMediaType.String())(t, b)
+				assert.Equal(t, map[string]interface{}{
+					"supportedContentTypes": []interface{}{rest.MediaTypeMergePatch, rest.MediaTypeJSONPatch},
+				}, b["error"].(map[string]interface{})["meta"])
+			},
+		},
+		{
+			desc:   "error: precondition required",
+			req:    newReq(rest.MediaTypeMergePatch, "", `{}`),
+			authn:  authnStub{principal: writer},
+			status: http.StatusPreconditionRequired,
+			assert: assertErrorCode(rest.ErrPreconditionRequired.String()),
+		},
+		{
+			desc:   "error: invalid patch",
+			req:    newReq(rest.MediaTypeJSONPatch, `"2"`, `[{"op":"delete","path":"/type"}]`),
+			authn:  authnStub{principal: writer},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgPatch.String()),
+		},
+		{
+			desc:  "error: read only",
+			req:   newReq(rest.MediaTypeMergePatch, `"2"`, `{"status":"Settled"}`),
+			authn: authnStub{principal: writer},
+			patch: func(context.Context, uuid.UUID, uint32, payment.Patch) error {
+				return errors.New(payment.ErrInvalidArgPatchReadOnly)
+			},
+			status: http.StatusUnprocessableEntity,
+			assert: assertErrorCode(payment.ErrInvalidArgPatchReadOnly.String()),
+		},
+		{
+			desc:  "error: test failed",
+			req:   newReq(rest.MediaTypeJSONPatch, `"2"`, `[{"op":"test","path":"/type","value":"x"}]`),
+			authn: authnStub{principal: writer},
+			patch: func(context.Context, uuid.UUID, uint32, payment.Patch) error {
+				return errors.New(payment.ErrInvalidArgPatchTestFailed)
+			},
+			status: http.StatusConflict,
+			assert: assertErrorCode(payment.ErrInvalidArgPatchTestFailed.String()),
+		},
+		{
+			desc:  "error: version mismatch",
+			req:   newReq(rest.MediaTypeMergePatch, `"1"`, `{}`),
+			authn: authnStub{principal: writer},
+			patch: func(context.Context, uuid.UUID, uint32, payment.Patch) error {
+				return errors.New(payment.ErrInvalidArgVersionMismatch)
+			},
+			status: http.StatusPreconditionFailed,
+			assert: assertErrorCode(payment.ErrInvalidArgVersionMismatch.String()),
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				svc = &testutil.SvcStub{
+					GetFn: func(_ context.Context, id uuid.UUID, _ payment.Selection) (payment.Pymt, error) {
+						var p = payment.Pymt{ID: id, Version: 3}
+						p.OrgID = writer.OrgID
+						return p, nil
+					},
+					PatchFn: tc.patch,
+				}
+				h = rest.NewHandler(svc, tc.authn)
+				w = httptest.NewRecorder()
+			)
+
+			h.ServeHTTP(w, tc.req)
+			assert.Equal(t, tc.status, w.Code)
+			if tc.assert == nil {
+				assert.Empty(t, w.Body.Bytes())
+				return
+			}
+
+			var b map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &b))
+			tc.assert(t, b)
+		})
+	}
+}
+
+func TestHandler_rateLimit(t *testing.T) {
+	var (
+		orgID  = testutil.NewUUID(t)
+		body   = `{"type":"Payment","organisation_id":"` + orgID.String() + `","attributes":{"payment_id":"1"}}`
+		writer = auth.Principal{OrgID: orgID, Scopes: []auth.Scope{auth.ScopePaymentsWrite}}
+	)
+
+	var tcases = []struct {
+		desc       string
+		limiter    limiterStub
+		status     int
+		retryAfter string
+		code       string
+	}{
+		{
+			desc: "error: rate limited",
+			limiter: limiterStub{
+				wait: 1500 * time.Millisecond, err: errors.New(ratelimit.ErrRateLimited),
+			},
+			status:     http.StatusTooManyRequests,
+			retryAfter: "2",
+			code:       ratelimit.ErrRateLimited.String(),
+		},
+		{
+			desc:    "error: limits store",
+			limiter: limiterStub{err: errors.New(payment.ErrUnexpectedStoreError)},
+			status:  http.StatusInternalServerError,
+			code:    rest.ErrInternalError.String(),
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				h = rest.NewHandler(
+					&testutil.SvcStub{}, authnStub{principal: writer}, rest.WithRateLimiter(&tc.limiter),
+				)
+				w = httptest.NewRecorder()
+			)
+
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body)))
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, tc.retryAfter, w.Header().Get("Retry-After"))
+			assert.Equal(t, writer, tc.limiter.principal)
+			assert.Equal(t, ratelimit.OpWrite, tc.limiter.op)
+
+			var b map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &b))
+			assertErrorCode(tc.code)(t, b)
+		})
+	}
+}
+
+func assertErrorCode(code string) func(*testing.T, map[string]interface{}) {
+	return func(t *testing.T, b map[string]interface{}) {
+		require.Contains(t, b, "error")
+		assert.Equal(t, code, b["error"].(map[string]interface{})["code"])
+	}
+}
+
+// authnStub is a rest.Authenticator which returns the principal, or err if
+// it isn't nil.
+type authnStub struct {
+	principal auth.Principal
+	err       error
+}
+
+func (a authnStub) Authenticate(*http.Request) (auth.Principal, error) {
+	if a.err != nil {
+		return auth.Principal{}, a.err
+	}
+
+	return a.principal, nil
+}
+
+// limiterStub is a rest.RateLimiter which records the principal and the
+// operation of the last call and returns wait and err.
+type limiterStub struct {
+	principal auth.Principal
+	op        ratelimit.Op
+	wait      time.Duration
+	err       error
+}
+
+func (l *limiterStub) Allow(_ context.Context, p auth.Principal, o ratelimit.Op) (time.Duration, error) {
+	l.principal = p
+	l.op = o
+	return l.wait, l.err
+}
@@ -0,0 +1,23 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+)
+
+type orgScopeCtxKey struct{}
+
+// WithOrgScope returns a copy of ctx which carries the organisation ID orgID as
+// the scope of the Service's methods which operate on a single payment; see
+// Service for the behavior.
+func WithOrgScope(ctx context.Context, orgID uuid.UUID) context.Context {
+	return context.WithValue(ctx, orgScopeCtxKey{}, orgID)
+}
+
+// OrgScope returns the organisation ID of the scope carried by ctx. It returns
+// false if ctx doesn't carry any.
+func OrgScope(ctx context.Context) (uuid.UUID, bool) {
+	var id, ok = ctx.Value(orgScopeCtxKey{}).(uuid.UUID)
+	return id, ok
+}
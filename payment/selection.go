@@ -1,14 +1,30 @@
 package payment
 
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.fraixed.es/errors"
+)
+
 // Selection specifies the fields of a single payment to be retrieved.
 // The payment's fields which aren't present are always retrieved.
 // Each file is a boolean, when it's true, the value is retrieved otherwise it
 // won't be.
+//
+// The attributes can also be partially selected, when Attributes is false, by
+// the paths of the selection created by NewSelection.
 type Selection struct {
 	Version    bool
 	Type       bool
 	OrgID      bool
+	Status     bool
 	Attributes bool
+
+	// attrs are the sorted paths of the selected attributes separated by ",",
+	// which is a string rather than a slice for keeping Selection comparable.
+	attrs string
 }
 
 // SelectAll returns the value which indicates to retrieve all the fields of a
@@ -18,6 +34,122 @@ func SelectAll() Selection {
 		Type:       true,
 		Version:    true,
 		OrgID:      true,
+		Status:     true,
 		Attributes: true,
 	}
 }
+
+// NewSelection creates a Selection of the fields, which are the names of the
+// JSON representation of a payment (e.g. "version" or "attributes") or the
+// paths of the attributes, which are the names of the nested fields separated
+// by "." (e.g. "attributes.amount" or "attributes.beneficiary_party.name").
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidArgSelection - When any of the fields doesn't exist.
+func NewSelection(fields ...string) (Selection, error) {
+	var (
+		s     Selection
+		paths []string
+	)
+	for _, f := range fields {
+		switch f {
+		case "id":
+		case "version":
+			s.Version = true
+		case "type":
+			s.Type = true
+		case "organisation_id":
+			s.OrgID = true
+		case "status":
+			s.Status = true
+		case "attributes":
+			s.Attributes = true
+		default:
+			var p = strings.TrimPrefix(f, "attributes.")
+			if p == f || !isAttrPath(p) {
+				return Selection{}, errors.New(ErrInvalidArgSelection, ErrMDArg("fields", f))
+			}
+
+			paths = append(paths, p)
+		}
+	}
+
+	if !s.Attributes {
+		s.attrs = strings.Join(compactPaths(paths), ",")
+	}
+
+	return s, nil
+}
+
+// AttrPaths returns the paths of the selected attributes, without the
+// "attributes." prefix, when Attributes is false, otherwise nil. The paths are
+// sorted and none of them is nested in other.
+func (s Selection) AttrPaths() []string {
+	if s.Attributes || s.attrs == "" {
+		return nil
+	}
+
+	return strings.Split(s.attrs, ",")
+}
+
+// Attr reports if the attribute path, without the "attributes." prefix, is
+// selected, which is when all the attributes are selected or it's nested in any
+// of the selected paths.
+func (s Selection) Attr(path string) bool {
+	if s.Attributes {
+		return true
+	}
+
+	for _, p := range s.AttrPaths() {
+		if p == path || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compactPaths returns paths sorted, without duplicates and without the paths
+// nested in other.
+func compactPaths(paths []string) []string {
+	sort.Strings(paths)
+
+	var cps []string
+	for _, p := range paths {
+		if l := len(cps); l > 0 && (cps[l-1] == p || strings.HasPrefix(p, cps[l-1]+".")) {
+			continue
+		}
+
+		cps = append(cps, p)
+	}
+
+	return cps
+}
+
+// isAttrPath reports if p is the path of a field of the JSON representation of
+// Attrs. The elements of the arrays cannot be selected.
+func isAttrPath(p string) bool {
+	var t = reflect.TypeOf(Attrs{})
+	for _, n := range strings.Split(p, ".") {
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+
+		var found bool
+		for i := 0; i < t.NumField(); i++ {
+			var f = t.Field(i)
+			if strings.Split(f.Tag.Get("json"), ",")[0] == n {
+				t = f.Type
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,94 @@
+package payment_test
+
+import (
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSelection(t *testing.T) {
+	var tcases = []struct {
+		desc   string
+		fields []string
+		expect payment.Selection
+		paths  []string
+		err    string
+	}{
+		{desc: "none", expect: payment.Selection{}},
+		{
+			desc:   "all",
+			fields: []string{"id", "version", "type", "organisation_id", "status", "attributes"},
+			expect: payment.SelectAll(),
+		},
+		{
+			desc:   "attributes paths",
+			fields: []string{"status", "attributes.fx", "attributes.amount", "attributes.beneficiary_party.name"},
+			expect: payment.Selection{Status: true},
+			paths:  []string{"amount", "beneficiary_party.name", "fx"},
+		},
+		{
+			desc: "nested and duplicated paths",
+			fields: []string{
+				"attributes.debtor_party.name", "attributes.debtor_party", "attributes.amount", "attributes.amount",
+				"attributes.charges_information.sender_charges",
+			},
+			paths: []string{"amount", "charges_information.sender_charges", "debtor_party"},
+		},
+		{
+			desc:   "attributes and paths",
+			fields: []string{"attributes.amount", "attributes"},
+			expect: payment.Selection{Attributes: true},
+		},
+		{desc: "error: unknown field", fields: []string{"amount"}, err: "amount"},
+		{desc: "error: unknown attribute", fields: []string{"attributes.unknown"}, err: "attributes.unknown"},
+		{desc: "error: nested in scalar", fields: []string{"attributes.amount.value"}, err: "attributes.amount.value"},
+		{
+			desc:   "error: array element",
+			fields: []string{"attributes.charges_information.sender_charges.amount"},
+			err:    "attributes.charges_information.sender_charges.amount",
+		},
+		{desc: "error: empty path", fields: []string{"attributes."}, err: "attributes."},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var sl, err = payment.NewSelection(tc.fields...)
+			if tc.err != "" {
+				testutil.AssertError(t, err, payment.ErrInvalidArgSelection, payment.ErrMDArg("fields", tc.err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.paths, sl.AttrPaths())
+
+			sl2, err := payment.NewSelection(append(tc.fields, "id")...)
+			require.NoError(t, err)
+			assert.Equal(t, sl, sl2, "the selections of the same fields are equal")
+
+			assert.Equal(t, tc.expect, payment.Selection{
+				Version: sl.Version, Type: sl.Type, OrgID: sl.OrgID, Status: sl.Status, Attributes: sl.Attributes,
+			})
+		})
+	}
+}
+
+func TestSelection_Attr(t *testing.T) {
+	var sl, err = payment.NewSelection("attributes.amount", "attributes.beneficiary_party")
+	require.NoError(t, err)
+
+	assert.True(t, sl.Attr("amount"))
+	assert.True(t, sl.Attr("beneficiary_party"))
+	assert.True(t, sl.Attr("beneficiary_party.name"))
+	assert.False(t, sl.Attr("currency"))
+	assert.False(t, sl.Attr("beneficiary"))
+	assert.False(t, sl.Attr("amount_x"))
+
+	assert.True(t, payment.SelectAll().Attr("currency"))
+	assert.False(t, payment.Selection{}.Attr("currency"))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/gofrs/uuid"
+	"go.fraixed.es/errors"
 )
 
 // Service is the interface which any specific implementation of a payment
@@ -22,20 +23,54 @@ import (
 // * ErrUnexpectedStoreError
 //
 // * ErrUnexpectedSysError
+//
+// When ctx carries an organisation scope (see WithOrgScope), the methods which
+// operate on a single payment (Delete, Get, History, Patch, Transition and
+// Update) return ErrNotFound for the payments of other organisations, checking
+// it in the same operation, so the payments of other organisations are never
+// modified.
 type Service interface {
-	// Create creates a new payment returning its ID.
+	// Aggregate aggregates the amounts of the payments which fulfill f as a
+	// specifies, returning an Aggregate for each group of payments, sorted by
+	// the values of the group in ascending order. If there is not payments which
+	// fulfill f, an empty list and nil error are returned.
+	//
+	// The following error codes can be returned:
+	//
+	// * ErrInvalidArgAggregation
+	Aggregate(ctx context.Context, f Filter, a Aggregation) ([]Aggregate, error)
+
+	// Create creates a new payment, with StatusPending, returning its ID.
+	//
+	// When ctx carries an idempotency key (see WithIdempotencyKey) which has
+	// been used with the same organisation and payment, and it hasn't expired,
+	// no payment is created and the ID of the payment created with it is
+	// returned. Implementations decide how long the keys are kept.
+	//
+	// This method can return any of the errors returned by p.Validate and
+	// IdempotencyKey plus the following error codes:
 	//
-	// This method can return any of the errors returned by p.Validate.
+	// * ErrInvalidArgIdempotencyKeyReused - When the idempotency key has been
+	// used for creating a different payment.
 	Create(ctx context.Context, p PymtUpsert) (uuid.UUID, error)
 
-	// Delete deletes the payment which has associated the passed ID.
+	// Delete deletes the payment which has associated the passed ID, if its
+	// version matches with version and its status is editable. The version and
+	// the status are checked in the same operation which deletes the payment.
 	//
 	// The following error codes can be returned:
 	//
+	// * ErrInvalidArgVersionMismatch - When the version doesn't match with the
+	// current payment version for avoiding to delete a payment which has been
+	// modified concurrently.
+	//
 	// * ErrInvalidPaymentID
 	//
+	// * ErrNotEditable - When the payment status isn't editable (see
+	// Status.Editable).
+	//
 	// * ErrNotFound
-	Delete(context.Context, uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID, version uint32) error
 
 	// Find retrieve list of payments which fulfill f, sorted by o and chunked by
 	// c. Each payment only contains the fields indicated by s.
@@ -53,6 +88,56 @@ type Service interface {
 	// * ErrNotFound
 	Get(ctx context.Context, id uuid.UUID, s Selection) (Pymt, error)
 
+	// History retrieves the status changes of the payment which has associated
+	// the passed ID, sorted from the oldest to the newest. The first one is the
+	// change done when the payment was created.
+	//
+	// The following error codes can be returned:
+	//
+	// * ErrInvalidPaymentID
+	//
+	// * ErrNotFound
+	History(ctx context.Context, id uuid.UUID) ([]StatusChange, error)
+
+	// Patch applies p to the payment with the associated ID (see Patch.Apply),
+	// if its version matches with version, and updates it with the result, which
+	// is validated as the payments passed to Update. The payment is read, patched
+	// and updated atomically. The payment version is incremented if the update
+	// succeeds.
+	//
+	// The following error codes can be returned:
+	//
+	// * Any of the errors returned by Patch.Apply.
+	//
+	// * ErrInvalidArgVersionMismatch - When the version doesn't match with the
+	// current payment version for avoiding to override the payment concurrently.
+	//
+	// * ErrInvalidPaymentID
+	//
+	// * ErrNotEditable - When the payment status isn't editable (see
+	// Status.Editable).
+	//
+	// * ErrNotFound
+	Patch(ctx context.Context, id uuid.UUID, version uint32, p Patch) error
+
+	// Transition changes the status of the payment with the associated ID to to,
+	// if its version matches with version and its current status can transition
+	// to to (see Status). reason is recorded in the status change. The payment
+	// version is incremented if the transition succeeds.
+	//
+	// The following error codes can be returned:
+	//
+	// * ErrInvalidArgStatus
+	//
+	// * ErrInvalidArgStatusTransition
+	//
+	// * ErrInvalidArgVersionMismatch
+	//
+	// * ErrInvalidPaymentID
+	//
+	// * ErrNotFound
+	Transition(ctx context.Context, id uuid.UUID, version uint32, to Status, reason string) error
+
 	// Update updates the payment with the associated ID, if its version matches
 	// with version. The payment version is incremented if the update succeeds.
 	//
@@ -61,6 +146,44 @@ type Service interface {
 	// * ErrInvalidArgVersionMismatch - When the version doesn't match with the
 	// current payment version for avoiding to override the payment concurrently.
 	//
+	// * ErrNotEditable - When the payment status isn't editable (see
+	// Status.Editable).
+	//
 	// * ErrNotFound
 	Update(ctx context.Context, id uuid.UUID, version uint32, p PymtUpsert) error
 }
+
+// BatchCreator is the interface which any specific implementation of a payment
+// service which can create several payments atomically must satisfy.
+type BatchCreator interface {
+	// CreateBatch creates the payments ps, with StatusPending, in a single
+	// transaction, returning their IDs in the same order than ps. When any of
+	// them cannot be created, none of them is created.
+	//
+	// The idempotency key carried by ctx, if any, is ignored.
+	//
+	// This method can return the general errors documented in Service and any of
+	// the errors returned by Validate of each payment, both with the metadata
+	// "index" whose value is the index in ps of the payment which caused the
+	// error.
+	CreateBatch(ctx context.Context, ps []PymtUpsert) ([]uuid.UUID, error)
+}
+
+// CreateBatch creates the payments ps with svc, as BatchCreator documents, when
+// svc satisfies BatchCreator. It's intended for the services which decorate
+// another service, so they satisfy BatchCreator whatever the decorated service
+// is.
+//
+// The following error codes can be returned:
+//
+// * ErrNotSupported - When svc doesn't satisfy BatchCreator.
+//
+// * Any of the errors returned by svc.CreateBatch.
+func CreateBatch(ctx context.Context, svc Service, ps []PymtUpsert) ([]uuid.UUID, error) {
+	var bc, ok = svc.(BatchCreator)
+	if !ok {
+		return nil, errors.New(ErrNotSupported, ErrMDFnCall("CreateBatch"))
+	}
+
+	return bc.CreateBatch(ctx, ps)
+}
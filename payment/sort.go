@@ -1,5 +1,7 @@
 package payment
 
+import "go.fraixed.es/errors"
+
 // SortDir is the type which represents the direction when sorting values.
 type SortDir uint8
 
@@ -15,19 +17,119 @@ func (s SortDir) Valid() bool {
 	return s == SortAscending || s == SortDescending
 }
 
-// Sort specifies the allowed fields for sorting a list of payments.
-//
-// The payment's fields which aren't present aren't allowed to be used for
-// sorting.
-type Sort struct {
-	Type       SortDir
-	ID         SortDir
-	Version    SortDir
-	OrgID      SortDir
-	Attributes SortAttributes
+// SortField is the type which represents the fields of the payments which are
+// allowed for sorting.
+type SortField uint8
+
+// The list of valid SortField values.
+const (
+	sortFieldNone SortField = iota
+	SortFieldID
+	SortFieldType
+	SortFieldVersion
+	SortFieldOrgID
+	SortFieldStatus
+	SortFieldAmount
+	SortFieldCurrency
+	SortFieldProcessingDate
+	SortFieldReference
+	SortFieldEndToEndReference
+	SortFieldNumericReference
+	SortFieldPaymentID
+	SortFieldPaymentScheme
+	SortFieldBeneficiaryName
+	SortFieldDebtorName
+	SortFieldSponsorName
+	sortFieldEnd
+)
+
+// String returns the path of the field of the JSON representation of a payment.
+func (f SortField) String() string {
+	switch f {
+	case SortFieldID:
+		return "id"
+	case SortFieldType:
+		return "type"
+	case SortFieldVersion:
+		return "version"
+	case SortFieldOrgID:
+		return "organisation_id"
+	case SortFieldStatus:
+		return "status"
+	case SortFieldAmount:
+		return "attributes.amount"
+	case SortFieldCurrency:
+		return "attributes.currency"
+	case SortFieldProcessingDate:
+		return "attributes.processing_date"
+	case SortFieldReference:
+		return "attributes.reference"
+	case SortFieldEndToEndReference:
+		return "attributes.end_to_end_reference"
+	case SortFieldNumericReference:
+		return "attributes.numeric_reference"
+	case SortFieldPaymentID:
+		return "attributes.payment_id"
+	case SortFieldPaymentScheme:
+		return "attributes.payment_scheme"
+	case SortFieldBeneficiaryName:
+		return "attributes.beneficiary_party.name"
+	case SortFieldDebtorName:
+		return "attributes.debtor_party.name"
+	case SortFieldSponsorName:
+		return "attributes.sponsor_party.name"
+	}
+
+	return ""
+}
+
+// Valid returns true if f is a valid SortField value, otherwise false.
+func (f SortField) Valid() bool {
+	return f > sortFieldNone && f < sortFieldEnd
+}
+
+// ParseSortField returns the SortField whose path is p (see SortField.String)
+// and true, otherwise false.
+func ParseSortField(p string) (SortField, bool) {
+	for f := sortFieldNone + 1; f < sortFieldEnd; f++ {
+		if f.String() == p {
+			return f, true
+		}
+	}
+
+	return sortFieldNone, false
 }
 
-// SortAttributes is the type of the Attributes field of the Sort type.
-type SortAttributes struct {
-	Amount SortDir
+// SortKey is a key for sorting a list of payments.
+type SortKey struct {
+	Field SortField
+	Dir   SortDir
+}
+
+// Sort specifies the keys for sorting a list of payments, in order of
+// precedence, so the payments are sorted by the first key and the ones with the
+// same value by the next one and so on.
+//
+// The payments which have the same value for all the keys are sorted by their
+// ID in ascending order, so the order is stable, for example, when the list is
+// retrieved in chunks.
+type Sort []SortKey
+
+// Validate validates that all the keys of s have a valid field and direction
+// and each field is only present once.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidArgSort
+func (s Sort) Validate() error {
+	var seen = map[SortField]bool{}
+	for i, k := range s {
+		if !k.Field.Valid() || !k.Dir.Valid() || seen[k.Field] {
+			return errors.New(ErrInvalidArgSort, ErrMDArg("s", k), ErrMDVar("index", i))
+		}
+
+		seen[k.Field] = true
+	}
+
+	return nil
 }
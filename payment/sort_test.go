@@ -0,0 +1,44 @@
+package payment_test
+
+import (
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSortField(t *testing.T) {
+	for _, f := range []payment.SortField{
+		payment.SortFieldID, payment.SortFieldStatus, payment.SortFieldAmount, payment.SortFieldProcessingDate,
+		payment.SortFieldBeneficiaryName, payment.SortFieldSponsorName,
+	} {
+		var pf, ok = payment.ParseSortField(f.String())
+		assert.True(t, ok)
+		assert.Equal(t, f, pf)
+		assert.True(t, pf.Valid())
+	}
+
+	var f, ok = payment.ParseSortField("amount")
+	assert.False(t, ok)
+	assert.False(t, f.Valid())
+}
+
+func TestSort_Validate(t *testing.T) {
+	var (
+		amount = payment.SortKey{Field: payment.SortFieldAmount, Dir: payment.SortDescending}
+		id     = payment.SortKey{Field: payment.SortFieldID, Dir: payment.SortAscending}
+	)
+
+	require.NoError(t, payment.Sort{}.Validate())
+	require.NoError(t, payment.Sort{amount, id}.Validate())
+
+	for _, s := range []payment.Sort{
+		{{Field: payment.SortFieldAmount}},
+		{{Dir: payment.SortAscending}},
+		{amount, id, {Field: payment.SortFieldAmount, Dir: payment.SortAscending}},
+	} {
+		testutil.AssertError(t, s.Validate(), payment.ErrInvalidArgSort)
+	}
+}
@@ -0,0 +1,165 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/filter"
+	"github.com/ifraixedes/go-payments-api-example/payment/tracing"
+	"go.fraixed.es/errors"
+)
+
+// amountScale is the number of units of an amount which are aggregated, so the
+// amounts are aggregated as integers and the results are exact. It's 1000
+// because the currencies have 3 minor units at most.
+const amountScale = 1000
+
+// amountUnits is the expression which converts the amount of a payment to
+// amountScale units.
+var amountUnits = fmt.Sprintf("CAST(round(json_extract(data, '$.amount') * %d) AS INTEGER)", amountScale)
+
+// Aggregate satisfies the payment.Service interface.
+//
+// The amounts are aggregated as integer units (see amountScale), hence the sum
+// of a group fails with payment.ErrUnexpectedStoreError if it overflows an
+// int64.
+func (s *service) Aggregate(
+	ctx context.Context, pf payment.Filter, a payment.Aggregation,
+) ([]payment.Aggregate, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		stmtargs []interface{}
+		cols     = groupColumns(a.GroupBy)
+		sel      = append([]string{
+			"count(*)",
+			fmt.Sprintf("sum(%s)", amountUnits),
+			fmt.Sprintf("min(%s)", amountUnits),
+			fmt.Sprintf("max(%s)", amountUnits),
+		}, cols...)
+		//nolint:gosec
+		query = fmt.Sprintf("SELECT %s FROM payments", strings.Join(sel, ", "))
+	)
+
+	var _, fspan = tracing.Start(ctx, spanFilterSQL)
+	var where, whereargs = filter.SQL(pf, leafField)
+	fspan.End()
+
+	if where != "" {
+		//nolint:gosec
+		query = fmt.Sprintf("%s WHERE %s", query, where)
+		stmtargs = adaptArgsToSQL(whereargs)
+	}
+
+	if len(cols) > 0 {
+		var gb = strings.Join(cols, ", ")
+		query = fmt.Sprintf("%s GROUP BY %s ORDER BY %s", query, gb, gb)
+	}
+
+	conn, opc, err := s.openConn(ctx)
+	if err != nil {
+		return nil, wrapOpenConnErr(err, opc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	stmt, err := prepare(ctx, conn, query, stmtargs...)
+	if err != nil {
+		return nil, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	var aggs []payment.Aggregate
+	var span = startStep(ctx)
+	defer func() {
+		span.SetAttr("db.rows", len(aggs))
+		span.End()
+	}()
+
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			return nil, handleSQLiteErr(err)
+		}
+		if !ok {
+			break
+		}
+
+		var (
+			count, sum, min, max int64
+			group                = make([]string, len(cols))
+			dst                  = []interface{}{&count, &sum, &min, &max}
+		)
+		for i := range group {
+			dst = append(dst, &group[i])
+		}
+
+		if err := stmt.Scan(dst...); err != nil {
+			return nil, errors.Wrap(err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.Scan"))
+		}
+
+		// Without grouping, the aggregation of no payments is a row with 0 count
+		if count == 0 {
+			continue
+		}
+
+		var agg = payment.Aggregate{Group: group, Count: uint64(count)}
+		if a.Has(payment.AggregateFnSum) {
+			agg.Sum = formatUnits(big.NewRat(sum, 1))
+		}
+
+		if a.Has(payment.AggregateFnMin) {
+			agg.Min = formatUnits(big.NewRat(min, 1))
+		}
+
+		if a.Has(payment.AggregateFnMax) {
+			agg.Max = formatUnits(big.NewRat(max, 1))
+		}
+
+		if a.Has(payment.AggregateFnAvg) {
+			agg.Avg = formatUnits(big.NewRat(sum, count))
+		}
+
+		aggs = append(aggs, agg)
+	}
+
+	return aggs, nil
+}
+
+// groupColumns returns the columns of the GROUP BY clause of the keys ks.
+func groupColumns(ks []payment.AggregateKey) []string {
+	var cols = make([]string, 0, len(ks))
+	for _, k := range ks {
+		if k == payment.AggregateKeyOrgID {
+			cols = append(cols, "organisation_id")
+			continue
+		}
+
+		// The paths are interpolated because they are the names of the attributes
+		// of the valid keys
+		cols = append(cols, fmt.Sprintf("json_extract(data, '$.%s')", strings.TrimPrefix(k.String(), "attributes.")))
+	}
+
+	return cols
+}
+
+// formatUnits returns the decimal number of the amountScale units u, rounded to
+// the decimal places of amountScale and without trailing zeros.
+func formatUnits(u *big.Rat) string {
+	var (
+		d = u.Quo(u, big.NewRat(amountScale, 1)).FloatString(len(strconv.Itoa(amountScale)) - 1)
+		s = strings.TrimRight(d, "0")
+	)
+
+	return strings.TrimSuffix(s, ".")
+}
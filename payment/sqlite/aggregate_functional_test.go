@@ -0,0 +1,111 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Aggregate(t *testing.T) {
+	var svc, err = sqlite.New(testingDB)
+	require.NoError(t, err)
+
+	var (
+		ctx   = context.Background()
+		orgID = testutil.NewUUID(t)
+	)
+
+	for _, v := range []struct {
+		currency string
+		amount   float64
+		date     string
+	}{
+		{"USD", 0.1, "2026-10-19"}, {"USD", 0.2, "2026-10-19"}, {"USD", 0.1, "2026-10-20"},
+		{"JPY", 1500, "2026-10-19"}, {"BHD", 1.005, "2026-10-20"},
+	} {
+		var np = payment.PymtUpsert{Type: "Payment", OrgID: orgID, Attributes: testutil.NewAttrs(t)}
+		np.Attributes.Currency = v.currency
+		np.Attributes.Amount = v.amount
+		np.Attributes.ProcessingDate = v.date
+
+		var id, err = svc.Create(ctx, np)
+		require.NoError(t, err)
+		defer func() {
+			_ = svc.Delete(ctx, id, 0)
+		}()
+	}
+
+	f, err := payment.NewFilterByOrgID(payment.FilterCmpEqual, orgID)
+	require.NoError(t, err)
+
+	var allFns = []payment.AggregateFn{
+		payment.AggregateFnCount, payment.AggregateFnSum, payment.AggregateFnMin,
+		payment.AggregateFnMax, payment.AggregateFnAvg,
+	}
+
+	t.Run("group by currency", func(t *testing.T) {
+		var aggs, err = svc.Aggregate(ctx, f, payment.Aggregation{
+			GroupBy: []payment.AggregateKey{payment.AggregateKeyCurrency},
+			Fns:     allFns,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []payment.Aggregate{
+			{Group: []string{"BHD"}, Count: 1, Sum: "1.005", Min: "1.005", Max: "1.005", Avg: "1.005"},
+			{Group: []string{"JPY"}, Count: 1, Sum: "1500", Min: "1500", Max: "1500", Avg: "1500"},
+			{Group: []string{"USD"}, Count: 3, Sum: "0.4", Min: "0.1", Max: "0.2", Avg: "0.133"},
+		}, aggs)
+	})
+
+	t.Run("group by currency and processing date", func(t *testing.T) {
+		var aggs, err = svc.Aggregate(ctx, f, payment.Aggregation{
+			GroupBy: []payment.AggregateKey{payment.AggregateKeyCurrency, payment.AggregateKeyProcessingDate},
+			Fns:     []payment.AggregateFn{payment.AggregateFnSum},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []payment.Aggregate{
+			{Group: []string{"BHD", "2026-10-20"}, Count: 1, Sum: "1.005"},
+			{Group: []string{"JPY", "2026-10-19"}, Count: 1, Sum: "1500"},
+			{Group: []string{"USD", "2026-10-19"}, Count: 2, Sum: "0.3"},
+			{Group: []string{"USD", "2026-10-20"}, Count: 1, Sum: "0.1"},
+		}, aggs)
+	})
+
+	t.Run("without groups", func(t *testing.T) {
+		var af, err = payment.NewFilterByAmount(payment.FilterCmpLessThan, 1)
+		require.NoError(t, err)
+		af, err = payment.NewFilter(payment.FilterLogicalAnd, f, af)
+		require.NoError(t, err)
+
+		aggs, err := svc.Aggregate(ctx, af, payment.Aggregation{
+			Fns: []payment.AggregateFn{payment.AggregateFnCount, payment.AggregateFnSum},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []payment.Aggregate{{Group: []string{}, Count: 3, Sum: "0.4"}}, aggs)
+	})
+
+	t.Run("no payments", func(t *testing.T) {
+		var of, err = payment.NewFilterByOrgID(payment.FilterCmpEqual, testutil.NewUUID(t))
+		require.NoError(t, err)
+
+		aggs, err := svc.Aggregate(ctx, of, payment.Aggregation{Fns: allFns})
+		require.NoError(t, err)
+		assert.Empty(t, aggs)
+
+		aggs, err = svc.Aggregate(ctx, of, payment.Aggregation{
+			GroupBy: []payment.AggregateKey{payment.AggregateKeyOrgID},
+			Fns:     allFns,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, aggs)
+	})
+
+	t.Run("error: invalid aggregation", func(t *testing.T) {
+		var _, err = svc.Aggregate(ctx, f, payment.Aggregation{})
+		testutil.AssertError(t, err, payment.ErrInvalidArgAggregation)
+	})
+}
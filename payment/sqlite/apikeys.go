@@ -0,0 +1,378 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"go.fraixed.es/errors"
+)
+
+// NewKeyStore creates an instance of the SQLite implementation of the auth
+// KeyStore.
+//
+// fname accepts the same values than New and the same error codes can be
+// returned.
+func NewKeyStore(fname string) (auth.KeyStore, error) {
+	var svc, err = newService(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyStore{svc: svc}, nil
+}
+
+type keyStore struct {
+	svc *service
+}
+
+// IssueKey satisfies the auth.KeyStore interface.
+//
+// The function will return all the errors that auth.KeyStore documents plus
+// ErrDBCantOpen.
+func (ks *keyStore) IssueKey(ctx context.Context, p auth.Principal) (auth.APIKey, string, error) {
+	if err := p.Validate(); err != nil {
+		return auth.APIKey{}, "", err
+	}
+
+	var conn, pc, err = ks.svc.openConn(ctx)
+	if err != nil {
+		return auth.APIKey{}, "", wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	return insertKey(conn, p)
+}
+
+// RevokeKey satisfies the auth.KeyStore interface.
+//
+// The function will return all the errors that auth.KeyStore documents plus
+// ErrDBCantOpen.
+func (ks *keyStore) RevokeKey(ctx context.Context, id uuid.UUID) error {
+	var conn, pc, err = ks.svc.openConn(ctx)
+	if err != nil {
+		return wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	err = conn.Exec(
+		"UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?", time.Now().UnixNano(), id.String(),
+	)
+	if err != nil {
+		return handleSQLiteErr(err)
+	}
+
+	if conn.Changes() == 0 {
+		return errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+	}
+
+	return nil
+}
+
+// RotateKey satisfies the auth.KeyStore interface.
+//
+// The function will return all the errors that auth.KeyStore documents plus
+// the following ones:
+//
+// * ErrDBCantOpen
+//
+// * ErrInvalidFormatBlob
+//
+// * ErrInvalidFormatID
+func (ks *keyStore) RotateKey(ctx context.Context, id uuid.UUID) (auth.APIKey, string, error) {
+	var conn, pc, err = ks.svc.openConn(ctx)
+	if err != nil {
+		return auth.APIKey{}, "", wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var (
+		nk  auth.APIKey
+		key string
+	)
+
+	// See the comment in the service Update method about why errtx var exists.
+	// The transaction is immediate because it reads the key before revoking it
+	// and a deferred one could fail when upgrading the read lock to a write lock
+	// if another connection is writing.
+	var errtx = conn.WithTxImmediate(func() error {
+		var ks []auth.APIKey
+		ks, err = findKeys(conn, "id = ? AND revoked_at IS NULL", id.String())
+		if err != nil {
+			return err
+		}
+
+		if len(ks) == 0 {
+			err = errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+			return err
+		}
+
+		err = conn.Exec("UPDATE api_keys SET revoked_at = ? WHERE id = ?", time.Now().UnixNano(), id.String())
+		if err != nil {
+			err = handleSQLiteErr(err)
+			return err
+		}
+
+		nk, key, err = insertKey(conn, ks[0].Principal())
+		return err
+	})
+
+	if err == nil && errtx != nil {
+		return auth.APIKey{}, "", errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
+	}
+
+	if err != nil {
+		return auth.APIKey{}, "", err
+	}
+
+	return nk, key, nil
+}
+
+// GetKey satisfies the auth.KeyStore interface.
+//
+// The function will return all the errors that auth.KeyStore documents plus
+// the following ones:
+//
+// * ErrDBCantOpen
+//
+// * ErrInvalidFormatBlob
+//
+// * ErrInvalidFormatID
+func (ks *keyStore) GetKey(ctx context.Context, id uuid.UUID) (auth.APIKey, error) {
+	var conn, pc, err = ks.svc.openConn(ctx)
+	if err != nil {
+		return auth.APIKey{}, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	keys, err := findKeys(conn, "id = ?", id.String())
+	if err != nil {
+		return auth.APIKey{}, err
+	}
+
+	if len(keys) == 0 {
+		return auth.APIKey{}, errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+	}
+
+	return keys[0], nil
+}
+
+// FindKeys satisfies the auth.KeyStore interface.
+//
+// The function will return all the errors that auth.KeyStore documents plus
+// the following ones:
+//
+// * ErrDBCantOpen
+//
+// * ErrInvalidFormatBlob
+//
+// * ErrInvalidFormatID
+func (ks *keyStore) FindKeys(ctx context.Context, orgID uuid.UUID) ([]auth.APIKey, error) {
+	var conn, pc, err = ks.svc.openConn(ctx)
+	if err != nil {
+		return nil, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	return findKeys(conn, "organisation_id = ?", orgID.String())
+}
+
+// VerifyKey satisfies the auth.KeyStore interface.
+//
+// The function will return all the errors that auth.KeyStore documents plus
+// the following ones:
+//
+// * ErrDBCantOpen
+//
+// * ErrInvalidFormatBlob
+//
+// * ErrInvalidFormatID
+func (ks *keyStore) VerifyKey(ctx context.Context, key string) (auth.Principal, error) {
+	var id, hash, err = auth.ParseKey(key)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	conn, pc, err := ks.svc.openConn(ctx)
+	if err != nil {
+		return auth.Principal{}, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	stmt, err := conn.Prepare("SELECT key_hash FROM api_keys WHERE id = ? AND revoked_at IS NULL", id.String())
+	if err != nil {
+		return auth.Principal{}, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	ok, err := stmt.Step()
+	if err != nil {
+		return auth.Principal{}, handleSQLiteErr(err)
+	}
+	if !ok {
+		return auth.Principal{}, errors.New(auth.ErrInvalidAPIKey, payment.ErrMDVar("id", id))
+	}
+
+	var hhex string
+	if err := stmt.Scan(&hhex); err != nil {
+		return auth.Principal{}, errors.Wrap(
+			err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.Scan"),
+		)
+	}
+
+	shash, err := hex.DecodeString(hhex)
+	if err != nil {
+		return auth.Principal{}, errors.Wrap(err, ErrInvalidFormatBlob, payment.ErrMDVar("key_hash", hhex))
+	}
+
+	if !hmac.Equal(shash, hash) {
+		return auth.Principal{}, errors.New(auth.ErrInvalidAPIKey, payment.ErrMDVar("id", id))
+	}
+
+	keys, err := findKeys(conn, "id = ?", id.String())
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	if len(keys) == 0 || keys[0].Revoked() {
+		return auth.Principal{}, errors.New(auth.ErrInvalidAPIKey, payment.ErrMDVar("id", id))
+	}
+
+	return keys[0].Principal(), nil
+}
+
+// insertKey inserts, using conn, a new key for the principal p returning its
+// information and the key.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedStoreError
+//
+// * payment.ErrUnexpectedSysError
+//
+// * Any of the errors returned by handleSQLiteErr
+func insertKey(conn *sqlite3.Conn, p auth.Principal) (auth.APIKey, string, error) {
+	var id, err = uuid.NewV4()
+	if err != nil {
+		return auth.APIKey{}, "", errors.Wrap(err, payment.ErrUnexpectedStoreError)
+	}
+
+	key, hash, err := auth.GenerateKey(id)
+	if err != nil {
+		return auth.APIKey{}, "", err
+	}
+
+	var scopes = p.Scopes
+	if scopes == nil {
+		scopes = []auth.Scope{}
+	}
+
+	sb, err := json.Marshal(scopes)
+	if err != nil {
+		return auth.APIKey{}, "", errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	var k = auth.APIKey{ID: id, OrgID: p.OrgID, Scopes: p.Scopes, CreatedAt: time.Now()}
+	err = conn.Exec(
+		"INSERT INTO api_keys(id, organisation_id, key_hash, scopes, created_at) VALUES (?, ?, ?, ?, ?)",
+		id.String(), p.OrgID.String(), hex.EncodeToString(hash), string(sb), k.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		return auth.APIKey{}, "", handleSQLiteErr(err)
+	}
+
+	return k, key, nil
+}
+
+// findKeys returns, using conn, the keys which fulfill the SQL where condition
+// with its args, sorted by their creation time.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidFormatBlob
+//
+// * ErrInvalidFormatID
+//
+// * payment.ErrUnexpectedStoreError
+//
+// * Any of the errors returned by handleSQLiteErr
+func findKeys(conn *sqlite3.Conn, where string, args ...interface{}) ([]auth.APIKey, error) {
+	var stmt, err = conn.Prepare(
+		`SELECT id, organisation_id, scopes, created_at, COALESCE(revoked_at, 0) FROM api_keys
+		WHERE `+where+" ORDER BY created_at, id",
+		args...,
+	)
+	if err != nil {
+		return nil, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	var keys []auth.APIKey
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			return nil, handleSQLiteErr(err)
+		}
+		if !ok {
+			break
+		}
+
+		var (
+			k                 auth.APIKey
+			id, orgID, scopes string
+			cat, rat          int64
+		)
+		if err := stmt.Scan(&id, &orgID, &scopes, &cat, &rat); err != nil {
+			return nil, errors.Wrap(err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.Scan"))
+		}
+
+		if k.ID, err = uuid.FromString(id); err != nil {
+			return nil, errors.Wrap(err, ErrInvalidFormatID, payment.ErrMDVar("id", id))
+		}
+
+		if k.OrgID, err = uuid.FromString(orgID); err != nil {
+			return nil, errors.Wrap(err, ErrInvalidFormatID, payment.ErrMDVar("organisation_id", orgID))
+		}
+
+		if err := json.Unmarshal([]byte(scopes), &k.Scopes); err != nil {
+			return nil, errors.Wrap(err, ErrInvalidFormatBlob, payment.ErrMDVar("scopes", scopes))
+		}
+
+		k.CreatedAt = time.Unix(0, cat)
+		if rat != 0 {
+			k.RevokedAt = time.Unix(0, rat)
+		}
+
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
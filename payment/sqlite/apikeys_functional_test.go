@@ -0,0 +1,112 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyStore(t *testing.T) {
+	var ks, err = sqlite.NewKeyStore(testingDB)
+	require.NoError(t, err)
+
+	var (
+		ctx = context.Background()
+		p   = auth.Principal{
+			OrgID:  testutil.NewUUID(t),
+			Scopes: []auth.Scope{auth.ScopePaymentsRead, auth.ScopePaymentsWrite},
+		}
+		// keyP returns p authenticated by the API key k.
+		keyP = func(k auth.APIKey) auth.Principal {
+			var kp = p
+			kp.KeyID = k.ID
+			return kp
+		}
+	)
+
+	t.Run("error invalid principal", func(t *testing.T) {
+		var _, _, err = ks.IssueKey(ctx, auth.Principal{OrgID: p.OrgID, Scopes: []auth.Scope{"payments:all"}})
+		testutil.AssertError(t, err, auth.ErrInvalidScope, payment.ErrMDField("Scopes", []auth.Scope{"payments:all"}))
+	})
+
+	k, key, err := ks.IssueKey(ctx, p)
+	require.NoError(t, err)
+	assert.Equal(t, keyP(k), k.Principal())
+	assert.False(t, k.Revoked())
+
+	vp, err := ks.VerifyKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, keyP(k), vp)
+
+	t.Run("error verifying a key with a wrong secret", func(t *testing.T) {
+		var _, err = ks.VerifyKey(ctx, key[:len(key)-2]+"xx")
+		testutil.AssertError(t, err, auth.ErrInvalidAPIKey, payment.ErrMDVar("id", k.ID))
+	})
+
+	t.Run("error verifying a malformed key", func(t *testing.T) {
+		var _, err = ks.VerifyKey(ctx, "pk_not-a-key")
+		testutil.AssertError(t, err, auth.ErrInvalidAPIKey)
+	})
+
+	gk, err := ks.GetKey(ctx, k.ID)
+	require.NoError(t, err)
+	assert.Equal(t, k.ID, gk.ID)
+	assert.Equal(t, keyP(k), gk.Principal())
+	assert.True(t, k.CreatedAt.Equal(gk.CreatedAt))
+
+	nk, nkey, err := ks.RotateKey(ctx, k.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, k.ID, nk.ID)
+	assert.Equal(t, keyP(nk), nk.Principal())
+
+	_, err = ks.VerifyKey(ctx, key)
+	testutil.AssertError(t, err, auth.ErrInvalidAPIKey, payment.ErrMDVar("id", k.ID))
+
+	vp, err = ks.VerifyKey(ctx, nkey)
+	require.NoError(t, err)
+	assert.Equal(t, keyP(nk), vp)
+
+	_, _, err = ks.RotateKey(ctx, k.ID)
+	testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", k.ID))
+
+	gk, err = ks.GetKey(ctx, k.ID)
+	require.NoError(t, err)
+	assert.True(t, gk.Revoked())
+
+	err = ks.RevokeKey(ctx, nk.ID)
+	require.NoError(t, err)
+
+	_, err = ks.VerifyKey(ctx, nkey)
+	testutil.AssertError(t, err, auth.ErrInvalidAPIKey, payment.ErrMDVar("id", nk.ID))
+
+	t.Run("revoking a revoked key keeps the revocation time", func(t *testing.T) {
+		var rk, err = ks.GetKey(ctx, nk.ID)
+		require.NoError(t, err)
+
+		err = ks.RevokeKey(ctx, nk.ID)
+		require.NoError(t, err)
+
+		gk, err := ks.GetKey(ctx, nk.ID)
+		require.NoError(t, err)
+		assert.True(t, rk.RevokedAt.Equal(gk.RevokedAt))
+	})
+
+	keys, err := ks.FindKeys(ctx, p.OrgID)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, k.ID, keys[0].ID)
+	assert.Equal(t, nk.ID, keys[1].ID)
+
+	var id = testutil.NewUUID(t)
+	_, err = ks.GetKey(ctx, id)
+	testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", id))
+
+	err = ks.RevokeKey(ctx, id)
+	testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", id))
+}
@@ -0,0 +1,24 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE payment_events (
+  seq INTEGER PRIMARY KEY AUTOINCREMENT,
+  type TEXT
+    CONSTRAINT ct__payment_events_type__not_null NOT NULL
+    CONSTRAINT ct__payment_events_type__enum CHECK (type IN ('Created', 'Updated', 'Deleted')),
+  payment_id TEXT
+    CONSTRAINT ct__payment_events_payment_id__not_null NOT NULL
+    CONSTRAINT ct__payment_events_payment_id__uuid CHECK (length(payment_id) == 36),
+  payment_version INTEGER
+    CONSTRAINT ct__payment_events_payment_version__not_null NOT NULL
+    CONSTRAINT ct__payment_events_payment_version__gte_zero CHECK (payment_version >= 0),
+  payload TEXT
+    CONSTRAINT ct__payment_events_payload__not_null NOT NULL
+    CONSTRAINT ct__payment_events_payload__json_valid CHECK (length(json(payload)) > 1)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Rollback migrations are not used, see the first migration file for knowing
+-- the reasons.
@@ -0,0 +1,71 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE webhook_endpoints (
+  id TEXT CONSTRAINT ct__webhook_endpoints_id__uuid CHECK (length(id) == 36),
+  organisation_id TEXT
+    CONSTRAINT ct__webhook_endpoints_organisation_id__not_null NOT NULL
+    CONSTRAINT ct__webhook_endpoints_organisation_id__uuid CHECK (length(organisation_id) == 36),
+  url TEXT
+    CONSTRAINT ct__webhook_endpoints_url__not_null NOT NULL,
+  secret TEXT
+    CONSTRAINT ct__webhook_endpoints_secret__not_null NOT NULL,
+  event_types TEXT
+    CONSTRAINT ct__webhook_endpoints_event_types__not_null NOT NULL
+    CONSTRAINT ct__webhook_endpoints_event_types__json_array CHECK (json_type(event_types) == 'array'),
+  CONSTRAINT uq__webhook_endpoints_id UNIQUE (id)
+);
+
+CREATE INDEX ix__webhook_endpoints_organisation_id ON webhook_endpoints (organisation_id);
+
+CREATE TABLE webhook_deliveries (
+  id TEXT CONSTRAINT ct__webhook_deliveries_id__uuid CHECK (length(id) == 36),
+  endpoint_id TEXT
+    CONSTRAINT ct__webhook_deliveries_endpoint_id__not_null NOT NULL
+    CONSTRAINT ct__webhook_deliveries_endpoint_id__uuid CHECK (length(endpoint_id) == 36),
+  organisation_id TEXT
+    CONSTRAINT ct__webhook_deliveries_organisation_id__not_null NOT NULL
+    CONSTRAINT ct__webhook_deliveries_organisation_id__uuid CHECK (length(organisation_id) == 36),
+  event_seq INTEGER
+    CONSTRAINT ct__webhook_deliveries_event_seq__not_null NOT NULL,
+  event_type TEXT
+    CONSTRAINT ct__webhook_deliveries_event_type__not_null NOT NULL
+    CONSTRAINT ct__webhook_deliveries_event_type__enum CHECK (event_type IN ('Created', 'Updated', 'Deleted')),
+  payload TEXT
+    CONSTRAINT ct__webhook_deliveries_payload__not_null NOT NULL,
+  status TEXT
+    CONSTRAINT ct__webhook_deliveries_status__not_null NOT NULL
+    CONSTRAINT ct__webhook_deliveries_status__enum CHECK (status IN ('Pending', 'Succeeded', 'Dead')),
+  attempts INTEGER DEFAULT 0
+    CONSTRAINT ct__webhook_deliveries_attempts__not_null NOT NULL
+    CONSTRAINT ct__webhook_deliveries_attempts__gte_zero CHECK (attempts >= 0),
+  -- Unix time in nanoseconds
+  next_attempt_at INTEGER
+    CONSTRAINT ct__webhook_deliveries_next_attempt_at__not_null NOT NULL,
+  last_error TEXT DEFAULT ''
+    CONSTRAINT ct__webhook_deliveries_last_error__not_null NOT NULL,
+  last_status_code INTEGER DEFAULT 0
+    CONSTRAINT ct__webhook_deliveries_last_status_code__not_null NOT NULL,
+  CONSTRAINT uq__webhook_deliveries_id UNIQUE (id),
+  CONSTRAINT uq__webhook_deliveries_endpoint_id_event_seq UNIQUE (endpoint_id, event_seq)
+);
+
+CREATE INDEX ix__webhook_deliveries_status_next_attempt_at ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX ix__webhook_deliveries_organisation_id_status ON webhook_deliveries (organisation_id, status);
+
+-- webhook_cursor only has one row which holds the sequence number of the next
+-- payment event to process.
+CREATE TABLE webhook_cursor (
+  id INTEGER PRIMARY KEY CONSTRAINT ct__webhook_cursor_id__single_row CHECK (id == 1),
+  next_seq INTEGER
+    CONSTRAINT ct__webhook_cursor_next_seq__not_null NOT NULL
+    CONSTRAINT ct__webhook_cursor_next_seq__gte_zero CHECK (next_seq >= 0)
+);
+
+INSERT INTO webhook_cursor(id, next_seq) VALUES (1, 0);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Rollback migrations are not used, see the first migration file for knowing
+-- the reasons.
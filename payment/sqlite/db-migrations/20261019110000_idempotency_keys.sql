@@ -0,0 +1,28 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE idempotency_keys (
+  organisation_id TEXT
+    CONSTRAINT ct__idempotency_keys_organisation_id__not_null NOT NULL
+    CONSTRAINT ct__idempotency_keys_organisation_id__uuid CHECK (length(organisation_id) == 36),
+  key TEXT
+    CONSTRAINT ct__idempotency_keys_key__not_null NOT NULL
+    CONSTRAINT ct__idempotency_keys_key__length CHECK (length(key) BETWEEN 1 AND 255),
+  request_hash TEXT
+    CONSTRAINT ct__idempotency_keys_request_hash__not_null NOT NULL,
+  payment_id TEXT
+    CONSTRAINT ct__idempotency_keys_payment_id__not_null NOT NULL
+    CONSTRAINT ct__idempotency_keys_payment_id__uuid CHECK (length(payment_id) == 36),
+  -- Unix time in nanoseconds
+  expires_at INTEGER
+    CONSTRAINT ct__idempotency_keys_expires_at__not_null NOT NULL,
+  CONSTRAINT uq__idempotency_keys_organisation_id_key UNIQUE (organisation_id, key)
+);
+
+CREATE INDEX ix__idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Rollback migrations are not used, see the first migration file for knowing
+-- the reasons.
@@ -0,0 +1,42 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+ALTER TABLE payments ADD COLUMN status TEXT DEFAULT 'Pending'
+  CONSTRAINT ct__payments_status__not_null NOT NULL
+  CONSTRAINT ct__payments_status__enum CHECK (status IN ('Pending', 'Submitted', 'Settled', 'Rejected', 'Returned'));
+
+CREATE TABLE payment_status_changes (
+  seq INTEGER PRIMARY KEY AUTOINCREMENT,
+  payment_id TEXT
+    CONSTRAINT ct__payment_status_changes_payment_id__not_null NOT NULL
+    CONSTRAINT ct__payment_status_changes_payment_id__uuid CHECK (length(payment_id) == 36),
+  -- NULL for the change done when the payment is created
+  from_status TEXT
+    CONSTRAINT ct__payment_status_changes_from_status__enum
+      CHECK (from_status IN ('Pending', 'Submitted', 'Settled', 'Rejected', 'Returned')),
+  to_status TEXT
+    CONSTRAINT ct__payment_status_changes_to_status__not_null NOT NULL
+    CONSTRAINT ct__payment_status_changes_to_status__enum
+      CHECK (to_status IN ('Pending', 'Submitted', 'Settled', 'Rejected', 'Returned')),
+  reason TEXT DEFAULT ''
+    CONSTRAINT ct__payment_status_changes_reason__not_null NOT NULL,
+  payment_version INTEGER
+    CONSTRAINT ct__payment_status_changes_payment_version__not_null NOT NULL
+    CONSTRAINT ct__payment_status_changes_payment_version__gte_zero CHECK (payment_version >= 0),
+  -- Unix time in nanoseconds
+  created_at INTEGER
+    CONSTRAINT ct__payment_status_changes_created_at__not_null NOT NULL
+);
+
+CREATE INDEX ix__payment_status_changes_payment_id ON payment_status_changes (payment_id);
+
+-- Payments created before this migration don't have the change of their
+-- creation
+INSERT INTO payment_status_changes(payment_id, from_status, to_status, reason, payment_version, created_at)
+  SELECT id, NULL, status, '', version, CAST(strftime('%s', 'now') AS INTEGER) * 1000000000 FROM payments;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Rollback migrations are not used, see the first migration file for knowing
+-- the reasons.
@@ -0,0 +1,30 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+CREATE TABLE api_keys (
+  id TEXT CONSTRAINT ct__api_keys_id__uuid CHECK (length(id) == 36),
+  organisation_id TEXT
+    CONSTRAINT ct__api_keys_organisation_id__not_null NOT NULL
+    CONSTRAINT ct__api_keys_organisation_id__uuid CHECK (length(organisation_id) == 36),
+  -- Hex encoded SHA-256 of the key's secret
+  key_hash TEXT
+    CONSTRAINT ct__api_keys_key_hash__not_null NOT NULL
+    CONSTRAINT ct__api_keys_key_hash__sha256 CHECK (length(key_hash) == 64),
+  scopes TEXT
+    CONSTRAINT ct__api_keys_scopes__not_null NOT NULL
+    CONSTRAINT ct__api_keys_scopes__json_array CHECK (json_type(scopes) == 'array'),
+  -- Unix time in nanoseconds
+  created_at INTEGER
+    CONSTRAINT ct__api_keys_created_at__not_null NOT NULL,
+  -- Unix time in nanoseconds; NULL when the key isn't revoked
+  revoked_at INTEGER,
+  CONSTRAINT uq__api_keys_id UNIQUE (id)
+);
+
+CREATE INDEX ix__api_keys_organisation_id ON api_keys (organisation_id);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Rollback migrations are not used, see the first migration file for knowing
+-- the reasons.
@@ -0,0 +1,27 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+
+-- The settings of the organisations. The NULL values mean that the defaults of
+-- the application apply.
+CREATE TABLE organisation_settings (
+  organisation_id TEXT
+    CONSTRAINT ct__organisation_settings_organisation_id__uuid CHECK (length(organisation_id) == 36),
+  -- Rate limits: operations per second and burst of the reads and the writes.
+  -- All of them are set or none of them.
+  read_rate REAL CONSTRAINT ct__organisation_settings_read_rate__positive CHECK (read_rate > 0),
+  read_burst INTEGER CONSTRAINT ct__organisation_settings_read_burst__positive CHECK (read_burst > 0),
+  write_rate REAL CONSTRAINT ct__organisation_settings_write_rate__positive CHECK (write_rate > 0),
+  write_burst INTEGER CONSTRAINT ct__organisation_settings_write_burst__positive CHECK (write_burst > 0),
+  CONSTRAINT uq__organisation_settings_organisation_id UNIQUE (organisation_id),
+  CONSTRAINT ct__organisation_settings_rate_limits__all_or_none CHECK (
+    (read_rate IS NULL) == (read_burst IS NULL)
+    AND (read_rate IS NULL) == (write_rate IS NULL)
+    AND (read_rate IS NULL) == (write_burst IS NULL)
+  )
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+
+-- Rollback migrations are not used, see the first migration file for knowing
+-- the reasons.
@@ -0,0 +1,657 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// EncryptionKeyLen is the length of the keys used by the field-level
+// encryption, which is AES-256-GCM.
+const EncryptionKeyLen = 32
+
+// reencryptBatchSize is the number of payments which ReencryptPymts processes
+// in each transaction.
+const reencryptBatchSize = 100
+
+// KeyProvider provides the key-encryption keys used by the field-level
+// encryption of the payments (see WithFieldEncryption).
+//
+// Each payment is encrypted with its own random data key, which is stored
+// encrypted with the current key-encryption key along with its ID, so rotating
+// the key-encryption key only requires to re-encrypt the data keys (see
+// ReencryptPymts), while the previous keys must be provided until then.
+//
+// The keys must be EncryptionKeyLen bytes long.
+type KeyProvider interface {
+	// CurrentKey returns the ID and the key used for encrypting.
+	CurrentKey() (string, []byte, error)
+	// Key returns the key which has associated id for decrypting. It must
+	// return an error with the ErrUnknownEncryptionKey code if there isn't any
+	// key with such ID.
+	Key(id string) ([]byte, error)
+}
+
+// NewKeyRing creates a KeyProvider which holds the keys in memory; keys maps
+// the key IDs to the keys and current is the ID of the key used for
+// encrypting.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidArgEncryptionKey - When current isn't in keys or any of the keys
+// doesn't have EncryptionKeyLen bytes.
+func NewKeyRing(current string, keys map[string][]byte) (KeyProvider, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, errors.New(ErrInvalidArgEncryptionKey, payment.ErrMDArg("current", current))
+	}
+
+	var kr = keyRing{current: current, keys: make(map[string][]byte, len(keys))}
+	for id, k := range keys {
+		if len(k) != EncryptionKeyLen {
+			return nil, errors.New(
+				ErrInvalidArgEncryptionKey, payment.ErrMDArg("keys", id), payment.ErrMDFact("length", EncryptionKeyLen),
+			)
+		}
+
+		kr.keys[id] = append([]byte(nil), k...)
+	}
+
+	return kr, nil
+}
+
+type keyRing struct {
+	current string
+	keys    map[string][]byte
+}
+
+func (kr keyRing) CurrentKey() (string, []byte, error) {
+	return kr.current, kr.keys[kr.current], nil
+}
+
+func (kr keyRing) Key(id string) ([]byte, error) {
+	var k, ok = kr.keys[id]
+	if !ok {
+		return nil, errors.New(ErrUnknownEncryptionKey, payment.ErrMDArg("id", id))
+	}
+
+	return k, nil
+}
+
+// WithFieldEncryption enables the encryption at rest of the sensitive fields of
+// the parties of the payments, which are the account name, account number,
+// address and name, using kp. The payments are transparently decrypted when
+// they are retrieved.
+//
+// The same fields of the payments contained in the payloads of the payment
+// events are also encrypted and, when it's passed to NewWebhookStore, the whole
+// payloads of the webhook deliveries, because they contain the payments.
+//
+// The payments, events and deliveries stored without encryption, or with a
+// key-encryption key which isn't the current one, are readable and they can be
+// re-encrypted with ReencryptPymts.
+func WithFieldEncryption(kp KeyProvider) Option {
+	return func(s *service) {
+		s.keys = kp
+	}
+}
+
+// ReencryptPymts re-encrypts, with the current key of kp, the payments, the
+// payloads of the payment events and the payloads of the webhook deliveries
+// stored in the DB fname which are stored without encryption or with other key,
+// returning the number of re-encrypted payments, events and deliveries.
+//
+// They are processed in batches, each one in a transaction, and the payments
+// which are updated while they are processed are skipped because they get
+// encrypted with the current key of the service which updates them. The
+// version of the payments isn't changed.
+//
+// fname accepts the same values than New and the same error codes can be
+// returned plus the following ones:
+//
+// * ErrInvalidFormatBlob
+//
+// * ErrUnknownEncryptionKey
+//
+// * payment.ErrUnexpectedSysError
+//
+// * Any of the errors returned by kp.
+func ReencryptPymts(ctx context.Context, fname string, kp KeyProvider) (int, error) {
+	var svc, err = newService(fname, WithFieldEncryption(kp))
+	if err != nil {
+		return 0, err
+	}
+
+	kid, _, err := kp.CurrentKey()
+	if err != nil {
+		return 0, err
+	}
+
+	conn, pc, err := svc.openConn(ctx)
+	if err != nil {
+		return 0, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var (
+		lastID     string
+		lastEvtID  int64
+		lastDlvrID int64
+		total      int
+	)
+	for _, batch := range []func() (int, bool, error){
+		func() (n int, more bool, err error) {
+			n, lastID, more, err = reencryptBatch(conn, kp, kid, lastID)
+			return n, more, err
+		},
+		func() (n int, more bool, err error) {
+			n, lastEvtID, more, err = reencryptPayloadsBatch(
+				conn, "payment_events", kid, lastEvtID, func(pl []byte) (interface{}, error) {
+					var p, err = decodeEventPayload(pl, kp)
+					if err != nil {
+						return nil, err
+					}
+
+					return encodeEventPayload(p, kp)
+				},
+			)
+			return n, more, err
+		},
+		func() (n int, more bool, err error) {
+			n, lastDlvrID, more, err = reencryptPayloadsBatch(
+				conn, "webhook_deliveries", kid, lastDlvrID, func(pl []byte) (interface{}, error) {
+					var b, err = decryptDeliveryPayload(pl, kp)
+					if err != nil {
+						return nil, err
+					}
+
+					b, err = encryptDeliveryPayload(b, kp)
+					return string(b), err
+				},
+			)
+			return n, more, err
+		},
+	} {
+		var n, err = reencryptAll(ctx, conn, batch)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// reencryptAll calls batch, each time in a transaction of conn, until it
+// reports that there aren't more rows to process, returning the sum of the
+// re-encrypted rows that it returns.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrAbortedOperation - When ctx is done.
+//
+// * payment.ErrUnexpectedStoreError
+//
+// * Any of the errors returned by batch.
+func reencryptAll(ctx context.Context, conn *sqlite3.Conn, batch func() (int, bool, error)) (int, error) {
+	var total int
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, errors.Wrap(err, payment.ErrAbortedOperation)
+		}
+
+		var (
+			n, more = 0, false
+			err     error
+		)
+		// See the comment in the service Update method about why errtx var exists
+		var errtx = conn.WithTx(func() error {
+			n, more, err = batch()
+			return err
+		})
+
+		if err == nil && errtx != nil {
+			return total, errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
+		}
+
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		if !more {
+			return total, nil
+		}
+	}
+}
+
+// reencryptBatch re-encrypts, using conn, the payments whose ID is greater than
+// after and which aren't encrypted with the key kid, up to
+// reencryptBatchSize. It returns the number of re-encrypted payments, the ID of
+// the last processed payment and if there may be more payments to process.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidFormatBlob
+//
+// * ErrUnknownEncryptionKey
+//
+// * payment.ErrUnexpectedStoreError
+//
+// * payment.ErrUnexpectedSysError
+//
+// * Any of the errors returned by kp and handleSQLiteErr.
+func reencryptBatch(conn *sqlite3.Conn, kp KeyProvider, kid string, after string) (int, string, bool, error) {
+	var stmt, err = conn.Prepare(
+		`SELECT id, version, data FROM payments WHERE id > ? AND json_extract(data, '$.encryption.kid') IS NOT ?
+		ORDER BY id LIMIT ?`,
+		after, kid, reencryptBatchSize,
+	)
+	if err != nil {
+		return 0, after, false, handleSQLiteErr(err)
+	}
+
+	type row struct {
+		id      string
+		version int64
+		data    []byte
+	}
+
+	var rows []row
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			_ = stmt.Close()
+			return 0, after, false, handleSQLiteErr(err)
+		}
+		if !ok {
+			break
+		}
+
+		var r row
+		if err := stmt.Scan(&r.id, &r.version, &r.data); err != nil {
+			_ = stmt.Close()
+			return 0, after, false, errors.Wrap(
+				err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.Scan"),
+			)
+		}
+
+		rows = append(rows, r)
+	}
+
+	if err := stmt.Close(); err != nil {
+		return 0, after, false, errors.Wrap(err, payment.ErrUnexpectedStoreError)
+	}
+
+	var n int
+	for _, r := range rows {
+		var pd pymtData
+		if err := pd.Deserialize(r.data, kp); err != nil {
+			var c, _ = errors.GetCode(err)
+			return n, after, false, errors.Wrap(err, c, payment.ErrMDVar("id", r.id))
+		}
+
+		b, err := pd.Serialize(kp)
+		if err != nil {
+			return n, after, false, err
+		}
+
+		// The version is compared for not overwriting the changes made after it
+		// was read
+		err = conn.Exec("UPDATE payments SET data = ? WHERE id = ? AND version = ?", b, r.id, r.version)
+		if err != nil {
+			return n, after, false, handleSQLiteErr(err)
+		}
+
+		n += conn.Changes()
+	}
+
+	if len(rows) > 0 {
+		after = rows[len(rows)-1].id
+	}
+
+	return n, after, len(rows) == reencryptBatchSize, nil
+}
+
+// reencryptPayloadsBatch re-encrypts, using conn, the payload column of the
+// rows of the table tbl whose rowid is greater than after and which aren't
+// encrypted with the key kid, up to reencryptBatchSize. reencrypt returns the
+// value to store of the passed payload re-encrypted. It returns the number of
+// re-encrypted rows, the rowid of the last processed row and if there may be
+// more rows to process.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedStoreError
+//
+// * Any of the errors returned by reencrypt and handleSQLiteErr.
+func reencryptPayloadsBatch(
+	conn *sqlite3.Conn, tbl string, kid string, after int64, reencrypt func([]byte) (interface{}, error),
+) (int, int64, bool, error) {
+	var stmt, err = conn.Prepare(
+		//nolint:gosec
+		fmt.Sprintf(`SELECT rowid, payload FROM %s WHERE rowid > ?
+		AND json_extract(payload, '$.encryption.kid') IS NOT ? ORDER BY rowid LIMIT ?`, tbl),
+		after, kid, reencryptBatchSize,
+	)
+	if err != nil {
+		return 0, after, false, handleSQLiteErr(err)
+	}
+
+	type row struct {
+		id      int64
+		payload []byte
+	}
+
+	var rows []row
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			_ = stmt.Close()
+			return 0, after, false, handleSQLiteErr(err)
+		}
+		if !ok {
+			break
+		}
+
+		var r row
+		if err := stmt.Scan(&r.id, &r.payload); err != nil {
+			_ = stmt.Close()
+			return 0, after, false, errors.Wrap(
+				err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.Scan"),
+			)
+		}
+
+		rows = append(rows, r)
+	}
+
+	if err := stmt.Close(); err != nil {
+		return 0, after, false, errors.Wrap(err, payment.ErrUnexpectedStoreError)
+	}
+
+	var n int
+	for _, r := range rows {
+		var pl, err = reencrypt(r.payload)
+		if err != nil {
+			var c, _ = errors.GetCode(err)
+			return n, after, false, errors.Wrap(err, c, payment.ErrMDVar(tbl+".rowid", r.id))
+		}
+
+		//nolint:gosec
+		err = conn.Exec(fmt.Sprintf("UPDATE %s SET payload = ? WHERE rowid = ?", tbl), pl, r.id)
+		if err != nil {
+			return n, after, false, handleSQLiteErr(err)
+		}
+
+		n += conn.Changes()
+	}
+
+	if len(rows) > 0 {
+		after = rows[len(rows)-1].id
+	}
+
+	return n, after, len(rows) == reencryptBatchSize, nil
+}
+
+// envelope contains the data key used for encrypting the fields of a payment,
+// encrypted with the key-encryption key KeyID.
+type envelope struct {
+	KeyID string `json:"kid"`
+	DEK   []byte `json:"dek"`
+}
+
+// sensitiveFields returns pointers to the sensitive fields of the parties of
+// a, each with a label which identifies it.
+func sensitiveFields(a *payment.Attrs) map[string]*string {
+	var fields = map[string]*string{}
+	for _, pt := range []struct {
+		name  string
+		party *payment.Party
+	}{
+		{"beneficiary_party", &a.BeneficiaryParty},
+		{"debtor_party", &a.DebtorParty},
+		{"sponsor_party", &a.SponsorParty},
+	} {
+		fields[pt.name+".account_name"] = &pt.party.AccountName
+		fields[pt.name+".account_number"] = &pt.party.AccountNumber
+		fields[pt.name+".address"] = &pt.party.Address
+		fields[pt.name+".name"] = &pt.party.Name
+	}
+
+	return fields
+}
+
+// selectsSensitiveFields reports if any of the attribute paths is, or contains,
+// a sensitive field.
+func selectsSensitiveFields(paths []string) bool {
+	for label := range sensitiveFields(&payment.Attrs{}) {
+		for _, p := range paths {
+			if p == label || strings.HasPrefix(label, p+".") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// encrypt encrypts the sensitive fields of pd (see encryptFields).
+//
+// The following error codes can be returned:
+//
+// * Any of the errors returned by encryptFields.
+func (pd *pymtData) encrypt(kp KeyProvider) error {
+	var env, err = encryptFields(&pd.Attrs, kp)
+	if err != nil {
+		return err
+	}
+
+	pd.Encryption = env
+	return nil
+}
+
+// decrypt decrypts the sensitive fields of pd, if they are encrypted (see
+// decryptFields).
+//
+// The following error codes can be returned:
+//
+// * Any of the errors returned by decryptFields.
+func (pd *pymtData) decrypt(kp KeyProvider) error {
+	if pd.Encryption == nil {
+		return nil
+	}
+
+	if err := decryptFields(&pd.Attrs, pd.Encryption, kp); err != nil {
+		return err
+	}
+
+	pd.Encryption = nil
+	return nil
+}
+
+// encryptFields encrypts the sensitive fields of a with a new data key and
+// returns the envelope of the data key. The empty fields aren't encrypted.
+//
+// The following error codes can be returned:
+//
+// * Any of the errors returned by newDataKey and seal.
+func encryptFields(a *payment.Attrs, kp KeyProvider) (*envelope, error) {
+	var dek, env, err = newDataKey(kp)
+	if err != nil {
+		return nil, err
+	}
+
+	for label, f := range sensitiveFields(a) {
+		if *f == "" {
+			continue
+		}
+
+		ct, err := seal(dek, []byte(*f), []byte(label))
+		if err != nil {
+			return nil, err
+		}
+
+		*f = base64.StdEncoding.EncodeToString(ct)
+	}
+
+	return env, nil
+}
+
+// decryptFields decrypts the sensitive fields of a, which have been encrypted
+// by encryptFields with the data key of env.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidFormatBlob - When any of the encrypted values cannot be
+// decrypted.
+//
+// * Any of the errors returned by dataKey.
+func decryptFields(a *payment.Attrs, env *envelope, kp KeyProvider) error {
+	var dek, err = dataKey(env, kp)
+	if err != nil {
+		return err
+	}
+
+	for label, f := range sensitiveFields(a) {
+		if *f == "" {
+			continue
+		}
+
+		var ct, err = base64.StdEncoding.DecodeString(*f)
+		if err != nil {
+			return errors.Wrap(err, ErrInvalidFormatBlob, payment.ErrMDVar("field", label))
+		}
+
+		pt, err := open(dek, ct, []byte(label))
+		if err != nil {
+			return errors.Wrap(err, ErrInvalidFormatBlob, payment.ErrMDVar("field", label))
+		}
+
+		*f = string(pt)
+	}
+
+	return nil
+}
+
+// newDataKey returns a new random data key and its envelope, which contains it
+// encrypted with the current key of kp.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedSysError
+//
+// * Any of the errors returned by kp.
+func newDataKey(kp KeyProvider) ([]byte, *envelope, error) {
+	var kid, kek, err = kp.CurrentKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dek = make([]byte, EncryptionKeyLen)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, nil, errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	wdek, err := seal(kek, dek, []byte(kid))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dek, &envelope{KeyID: kid, DEK: wdek}, nil
+}
+
+// dataKey returns the data key contained in env, decrypting it with the key of
+// kp which encrypted it.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidFormatBlob - When the data key cannot be decrypted.
+//
+// * ErrUnknownEncryptionKey - When kp is nil or it doesn't have the key used
+// for encrypting.
+//
+// * Any of the errors returned by kp.
+func dataKey(env *envelope, kp KeyProvider) ([]byte, error) {
+	if kp == nil {
+		return nil, errors.New(ErrUnknownEncryptionKey, payment.ErrMDVar("kid", env.KeyID))
+	}
+
+	var kek, err = kp.Key(env.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	dek, err := open(kek, env.DEK, []byte(env.KeyID))
+	if err != nil {
+		return nil, errors.Wrap(err, ErrInvalidFormatBlob, payment.ErrMDVar("kid", env.KeyID))
+	}
+
+	return dek, nil
+}
+
+// seal encrypts pt with key using AES-GCM and returns the ciphertext prefixed
+// by the random nonce; ad is authenticated but not encrypted.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedSysError
+func seal(key, pt, ad []byte) ([]byte, error) {
+	var gcm, err = newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce = make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(pt)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	return gcm.Seal(nonce, nonce, pt, ad), nil
+}
+
+// open decrypts ct, which has been encrypted by seal with key and ad.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedSysError
+func open(key, ct, ad []byte) ([]byte, error) {
+	var gcm, err = newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ct) < gcm.NonceSize() {
+		return nil, errors.New(payment.ErrUnexpectedSysError, payment.ErrMDFact("ciphertext_length", len(ct)))
+	}
+
+	pt, err := gcm.Open(nil, ct[:gcm.NonceSize()], ct[gcm.NonceSize():], ad)
+	if err != nil {
+		return nil, errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	return pt, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	var b, err = aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, payment.ErrUnexpectedSysError, payment.ErrMDFnCall("aes.NewCipher"))
+	}
+
+	gcm, err := cipher.NewGCM(b)
+	if err != nil {
+		return nil, errors.Wrap(err, payment.ErrUnexpectedSysError, payment.ErrMDFnCall("cipher.NewGCM"))
+	}
+
+	return gcm, nil
+}
@@ -0,0 +1,330 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/ifraixedes/go-payments-api-example/payment/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_FieldEncryption(t *testing.T) {
+	var (
+		ctx = context.Background()
+		k1  = bytes.Repeat([]byte{1}, sqlite.EncryptionKeyLen)
+		k2  = bytes.Repeat([]byte{2}, sqlite.EncryptionKeyLen)
+		np  = payment.PymtUpsert{Type: "Payment", OrgID: testutil.NewUUID(t), Attributes: testutil.NewAttrs(t)}
+	)
+
+	var dbfn, rmdb = newSchemaDB(t)
+	defer rmdb()
+
+	kr1, err := sqlite.NewKeyRing("k1", map[string][]byte{"k1": k1})
+	require.NoError(t, err)
+
+	svc, err := sqlite.New(dbfn, sqlite.WithFieldEncryption(kr1))
+	require.NoError(t, err)
+
+	pid, err := svc.Create(ctx, np)
+	require.NoError(t, err)
+
+	p, err := svc.Get(ctx, pid, payment.SelectAll())
+	require.NoError(t, err)
+	assert.Equal(t, np.Attributes, p.Attributes)
+
+	var sensitive = []string{
+		np.Attributes.BeneficiaryParty.AccountNumber,
+		np.Attributes.BeneficiaryParty.Name,
+		np.Attributes.DebtorParty.AccountNumber,
+		np.Attributes.DebtorParty.Address,
+		np.Attributes.SponsorParty.AccountNumber,
+	}
+
+	var data = pymtRawData(t, dbfn, pid)
+	for _, v := range sensitive {
+		assert.NotContains(t, data, v)
+	}
+	assert.Contains(t, data, np.Attributes.BeneficiaryParty.BankID)
+
+	// The webhook deliveries contain the payment
+	ws, err := sqlite.NewWebhookStore(dbfn, sqlite.WithFieldEncryption(kr1))
+	require.NoError(t, err)
+
+	var dl = webhook.Delivery{
+		ID:            testutil.NewUUID(t),
+		EndpointID:    testutil.NewUUID(t),
+		OrgID:         np.OrgID,
+		EventSeq:      1,
+		EventType:     payment.EventTypeCreated,
+		Status:        webhook.DeliveryStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	dl.Payload, err = json.Marshal(p)
+	require.NoError(t, err)
+	require.NoError(t, ws.Enqueue(ctx, 2, []webhook.Delivery{dl}))
+
+	for _, tbl := range []string{"payment_events", "webhook_deliveries"} {
+		var pls = rawPayloads(t, dbfn, tbl)
+		require.NotEmpty(t, pls)
+		for _, v := range sensitive {
+			assert.NotContains(t, pls, v, tbl)
+		}
+	}
+
+	dls, err := ws.DueDeliveries(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, dls, 1)
+	assert.JSONEq(t, string(dl.Payload), string(dls[0].Payload))
+
+	t.Run("error: without the key", func(t *testing.T) {
+		var psvc, err = sqlite.New(dbfn)
+		require.NoError(t, err)
+
+		_, err = psvc.Get(ctx, pid, payment.SelectAll())
+		testutil.AssertError(t, err, sqlite.ErrUnknownEncryptionKey, payment.ErrMDVar("id", pid))
+
+		// The rest of fields can be retrieved
+		_, err = psvc.Get(ctx, pid, payment.Selection{Status: true, Type: true})
+		assert.NoError(t, err)
+
+		sl, err := payment.NewSelection("attributes.amount", "attributes.beneficiary_party.bank_id")
+		require.NoError(t, err)
+		_, err = psvc.Get(ctx, pid, sl)
+		assert.NoError(t, err)
+
+		sl, err = payment.NewSelection("attributes.debtor_party")
+		require.NoError(t, err)
+		_, err = psvc.Get(ctx, pid, sl)
+		testutil.AssertError(t, err, sqlite.ErrUnknownEncryptionKey, payment.ErrMDVar("id", pid))
+	})
+
+	t.Run("attribute paths", func(t *testing.T) {
+		var sl, err = payment.NewSelection("attributes.beneficiary_party.name", "attributes.debtor_party")
+		require.NoError(t, err)
+
+		p, err := svc.Get(ctx, pid, sl)
+		require.NoError(t, err)
+
+		var ea payment.Attrs
+		ea.BeneficiaryParty.Name = np.Attributes.BeneficiaryParty.Name
+		ea.DebtorParty = np.Attributes.DebtorParty
+		assert.Equal(t, ea, p.Attributes)
+	})
+
+	// A payment stored before enabling the encryption
+	psvc, err := sqlite.New(dbfn)
+	require.NoError(t, err)
+
+	ppid, err := psvc.Create(ctx, np)
+	require.NoError(t, err)
+	assert.Contains(t, pymtRawData(t, dbfn, ppid), np.Attributes.DebtorParty.AccountNumber)
+	assert.Contains(t, rawPayloads(t, dbfn, "payment_events"), np.Attributes.DebtorParty.AccountNumber)
+
+	pws, err := sqlite.NewWebhookStore(dbfn)
+	require.NoError(t, err)
+
+	var pdl = dl
+	pdl.ID = testutil.NewUUID(t)
+	pdl.EventSeq = 2
+	require.NoError(t, pws.Enqueue(ctx, 3, []webhook.Delivery{pdl}))
+	assert.Contains(t, rawPayloads(t, dbfn, "webhook_deliveries"), np.Attributes.DebtorParty.AccountNumber)
+
+	// Rotate the key-encryption key
+	kr2, err := sqlite.NewKeyRing("k2", map[string][]byte{"k1": k1, "k2": k2})
+	require.NoError(t, err)
+
+	n, err := sqlite.ReencryptPymts(ctx, dbfn, kr2)
+	require.NoError(t, err)
+	assert.Equal(t, 6, n, "2 payments, 2 events and 2 deliveries")
+
+	n, err = sqlite.ReencryptPymts(ctx, dbfn, kr2)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	kr3, err := sqlite.NewKeyRing("k2", map[string][]byte{"k2": k2})
+	require.NoError(t, err)
+
+	svc, err = sqlite.New(dbfn, sqlite.WithFieldEncryption(kr3))
+	require.NoError(t, err)
+
+	for _, id := range []uuid.UUID{pid, ppid} {
+		p, err := svc.Get(ctx, id, payment.SelectAll())
+		require.NoError(t, err)
+		assert.Equal(t, np.Attributes, p.Attributes)
+		assert.Equal(t, uint32(0), p.Version)
+		assert.NotContains(t, pymtRawData(t, dbfn, id), np.Attributes.DebtorParty.AccountNumber)
+	}
+
+	for _, tbl := range []string{"payment_events", "webhook_deliveries"} {
+		assert.NotContains(t, rawPayloads(t, dbfn, tbl), np.Attributes.DebtorParty.AccountNumber, tbl)
+	}
+
+	var subCtx, subCancel = context.WithCancel(ctx)
+	defer subCancel()
+
+	var evtc, _ = svc.(payment.ChangeFeed).Changes(subCtx, 0)
+	for _, id := range []uuid.UUID{pid, ppid} {
+		var e = <-evtc
+		assert.Equal(t, id, e.PymtID)
+		assert.Equal(t, np.Attributes, e.Pymt.Attributes)
+	}
+
+	ws, err = sqlite.NewWebhookStore(dbfn, sqlite.WithFieldEncryption(kr3))
+	require.NoError(t, err)
+
+	dls, err = ws.DueDeliveries(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, dls, 2)
+	for _, d := range dls {
+		assert.JSONEq(t, string(dl.Payload), string(d.Payload))
+	}
+
+	t.Run("error: retired key", func(t *testing.T) {
+		var _, err = sqlite.ReencryptPymts(ctx, dbfn, kr1)
+		testutil.AssertError(t, err, sqlite.ErrUnknownEncryptionKey)
+	})
+}
+
+func TestNewKeyRing(t *testing.T) {
+	var k = bytes.Repeat([]byte{1}, sqlite.EncryptionKeyLen)
+
+	var _, err = sqlite.NewKeyRing("k2", map[string][]byte{"k1": k})
+	testutil.AssertError(t, err, sqlite.ErrInvalidArgEncryptionKey, payment.ErrMDArg("current", "k2"))
+
+	_, err = sqlite.NewKeyRing("k1", map[string][]byte{"k1": k, "k2": k[1:]})
+	testutil.AssertError(t, err, sqlite.ErrInvalidArgEncryptionKey, payment.ErrMDArg("keys", "k2"))
+
+	kr, err := sqlite.NewKeyRing("k1", map[string][]byte{"k1": k})
+	require.NoError(t, err)
+
+	_, err = kr.Key("k2")
+	testutil.AssertError(t, err, sqlite.ErrUnknownEncryptionKey, payment.ErrMDArg("id", "k2"))
+}
+
+// newSchemaDB creates a DB file with the schema of the testing DB, for the
+// tests which cannot share the data with other tests. It returns the path of
+// the file and a function which removes it.
+func newSchemaDB(t *testing.T) (string, func()) {
+	var dir, err = ioutil.TempDir(os.TempDir(), "pymt-api-ex-sqlite-")
+	require.NoError(t, err)
+
+	var rm = func() {
+		_ = os.RemoveAll(dir)
+	}
+
+	src, err := sqlite3.Open(testingDB, sqlite3.OPEN_READONLY)
+	require.NoError(t, err)
+	defer func() {
+		_ = src.Close()
+	}()
+
+	var fname = filepath.Join(dir, "test.db")
+	dst, err := sqlite3.Open(fname, sqlite3.OPEN_READWRITE|sqlite3.OPEN_CREATE)
+	require.NoError(t, err)
+	defer func() {
+		_ = dst.Close()
+	}()
+
+	stmt, err := src.Prepare(
+		"SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY rowid",
+	)
+	if err != nil {
+		rm()
+		require.NoError(t, err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			rm()
+			require.NoError(t, err)
+		}
+		if !ok {
+			break
+		}
+
+		var sql string
+		if err := stmt.Scan(&sql); err != nil {
+			rm()
+			require.NoError(t, err)
+		}
+
+		if err := dst.Exec(sql); err != nil {
+			rm()
+			require.NoError(t, err)
+		}
+	}
+
+	return fname, rm
+}
+
+// rawPayloads returns the payload column, as stored in the DB fname, of all the
+// rows of the table tbl joined by new lines.
+func rawPayloads(t *testing.T, fname string, tbl string) string {
+	var conn, err = sqlite3.Open(fname, sqlite3.OPEN_READONLY)
+	require.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	stmt, err := conn.Prepare("SELECT payload FROM " + tbl)
+	require.NoError(t, err)
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	var pls []string
+	for {
+		ok, err := stmt.Step()
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+
+		var pl []byte
+		require.NoError(t, stmt.Scan(&pl))
+		pls = append(pls, string(pl))
+	}
+
+	return strings.Join(pls, "\n")
+}
+
+// pymtRawData returns the data column stored in the DB fname of the payment
+// with the associated id.
+func pymtRawData(t *testing.T, fname string, id uuid.UUID) string {
+	var conn, err = sqlite3.Open(fname, sqlite3.OPEN_READONLY)
+	require.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	stmt, err := conn.Prepare("SELECT data FROM payments WHERE id = ?", id.String())
+	require.NoError(t, err)
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	ok, err := stmt.Step()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var data []byte
+	require.NoError(t, stmt.Scan(&data))
+	return string(data)
+}
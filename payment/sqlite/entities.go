@@ -11,11 +11,23 @@ import (
 type pymtData struct {
 	Type string `json:"type"`
 	payment.Attrs
+	// Encryption is the envelope of the encrypted fields; it's nil when they
+	// aren't encrypted.
+	Encryption *envelope `json:"encryption,omitempty"`
 }
 
-// Serialize returns blob to store pd in the DB.
-func (pd *pymtData) Serialize() ([]byte, error) {
-	b, err := json.Marshal(pd)
+// Serialize returns blob to store pd in the DB. The sensitive fields are
+// encrypted with kp when it isn't nil, without modifying pd.
+func (pd *pymtData) Serialize(kp KeyProvider) ([]byte, error) {
+	var d = *pd
+	d.Encryption = nil
+	if kp != nil {
+		if err := d.encrypt(kp); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := json.Marshal(&d)
 	if err != nil {
 		return nil, errors.Wrap(err, payment.ErrUnexpectedSysError)
 	}
@@ -24,13 +36,14 @@ func (pd *pymtData) Serialize() ([]byte, error) {
 }
 
 // Deserialize initializes pd from b. b is usually the bob stored in the DB.
-func (pd *pymtData) Deserialize(b []byte) error {
+// The encrypted fields are decrypted with kp.
+func (pd *pymtData) Deserialize(b []byte, kp KeyProvider) error {
 	err := json.Unmarshal(b, pd)
 	if err != nil {
 		return errors.Wrap(err, ErrInvalidFormatBlob)
 	}
 
-	return nil
+	return pd.decrypt(kp)
 }
 
 // Init initializes the pd from p.
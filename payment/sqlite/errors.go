@@ -18,11 +18,17 @@ const (
 	ErrDBSchemaChanged
 
 	ErrInvalidArgDBFname
+	ErrInvalidArgEncryptionKey
 
 	ErrInvalidFormatBlob
+	ErrInvalidFormatDeliveryStatus
+	ErrInvalidFormatEventType
 	ErrInvalidFormatID
+	ErrInvalidFormatStatus
 
 	ErrInvalidPayment
+
+	ErrUnknownEncryptionKey
 )
 
 func (c code) String() string {
@@ -35,12 +41,22 @@ func (c code) String() string {
 		return "DBSchemaChanged"
 	case ErrInvalidArgDBFname:
 		return "InvalidArgDBFname"
+	case ErrInvalidArgEncryptionKey:
+		return "InvalidArgEncryptionKey"
 	case ErrInvalidFormatBlob:
 		return "InvalidFormatBlob"
+	case ErrInvalidFormatDeliveryStatus:
+		return "InvalidFormatDeliveryStatus"
+	case ErrInvalidFormatEventType:
+		return "InvalidFormatEventType"
 	case ErrInvalidFormatID:
 		return "InvalidFormatID"
+	case ErrInvalidFormatStatus:
+		return "InvalidFormatStatus"
 	case ErrInvalidPayment:
 		return "InvalidPayment"
+	case ErrUnknownEncryptionKey:
+		return "UnknownEncryptionKey"
 	}
 
 	return ""
@@ -60,12 +76,22 @@ func (c code) Message() string {
 			"run it again with the new version"
 	case ErrInvalidArgDBFname:
 		return "the SQLite filename isn't of a valid format"
+	case ErrInvalidArgEncryptionKey:
+		return "the encryption key isn't valid or it isn't in the list of keys"
 	case ErrInvalidFormatBlob:
 		return "the blob stored in the DB isn't of a valid format"
+	case ErrInvalidFormatDeliveryStatus:
+		return "the webhook delivery status stored in the DB isn't a valid one"
+	case ErrInvalidFormatEventType:
+		return "the event type stored in the DB isn't a valid one"
 	case ErrInvalidFormatID:
 		return "the ID stored in the DB isn't of a valid format"
+	case ErrInvalidFormatStatus:
+		return "the payment status stored in the DB isn't a valid one"
 	case ErrInvalidPayment:
 		return "the payment is valid due the constrains imposed by the DB schema"
+	case ErrUnknownEncryptionKey:
+		return "the key used for encrypting the data stored in the DB isn't available"
 	}
 
 	return ""
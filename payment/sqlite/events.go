@@ -0,0 +1,320 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+const (
+	// changesBatchSize is the maximum number of events which are read from the
+	// DB in each query performed by the Changes method.
+	changesBatchSize = 100
+	// changesPollInterval is the maximum time that the Changes method waits for
+	// checking if there are new events. Changes made by the same service
+	// instance are notified without waiting, however, the ones made by others
+	// processes which use the same DB can only be found out by polling.
+	changesPollInterval = time.Second
+	// changesRetryInterval is the time that the Changes method waits before
+	// reading again the events when the read has been aborted.
+	changesRetryInterval = 10 * time.Millisecond
+)
+
+// Changes satisfies the payment.ChangeFeed interface.
+//
+// The function will send to the errors channel all the errors that
+// payment.ChangeFeed documents plus the following ones:
+//
+// * ErrDBCantOpen
+//
+// * ErrInvalidFormatBlob
+//
+// * ErrInvalidFormatEventType
+//
+// * ErrInvalidFormatID
+func (s *service) Changes(ctx context.Context, fromSeq uint64) (<-chan payment.Event, <-chan error) {
+	var (
+		evtc = make(chan payment.Event)
+		errc = make(chan error, 1)
+	)
+
+	go func() {
+		defer close(errc)
+		defer close(evtc)
+
+		var seq = fromSeq
+		for {
+			// Get the notification channel before reading the events to not miss
+			// any change done between the read and the wait
+			var notified = s.changes.wait()
+
+			var evts, err = s.eventsFrom(ctx, seq, changesBatchSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				// The read can be aborted because the table is locked by a writer, so
+				// it's retried rather than interrupting the delivery
+				if errors.Is(err, payment.ErrAbortedOperation) {
+					var t = time.NewTimer(changesRetryInterval)
+					select {
+					case <-t.C:
+						continue
+					case <-ctx.Done():
+						t.Stop()
+						return
+					}
+				}
+
+				errc <- err
+				return
+			}
+
+			for _, e := range evts {
+				select {
+				case evtc <- e:
+					seq = e.Seq + 1
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if len(evts) == changesBatchSize {
+				continue
+			}
+
+			var t = time.NewTimer(s.changesPollInterval)
+			select {
+			case <-notified:
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return
+			}
+
+			t.Stop()
+		}
+	}()
+
+	return evtc, errc
+}
+
+// eventsFrom returns at most limit events whose sequence number is equal or
+// greater than seq sorted by sequence number.
+func (s *service) eventsFrom(ctx context.Context, seq uint64, limit uint32) ([]payment.Event, error) {
+	var conn, pc, err = s.openConn(ctx)
+	if err != nil {
+		return nil, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	stmt, err := conn.Prepare(
+		"SELECT seq, type, payment_id, payment_version, payload FROM payment_events "+
+			"WHERE seq >= ? ORDER BY seq ASC LIMIT ?",
+		adaptArgsToSQL([]interface{}{seq, limit})...,
+	)
+	if err != nil {
+		return nil, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	var evts []payment.Event
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			return nil, handleSQLiteErr(err)
+		}
+		if !ok {
+			break
+		}
+
+		e, err := dbScanEvent(stmt, s.keys)
+		if err != nil {
+			return nil, err
+		}
+
+		evts = append(evts, e)
+	}
+
+	return evts, nil
+}
+
+// eventPayload is the payload of the events stored in the DB.
+type eventPayload struct {
+	payment.Pymt
+	// Encryption is the envelope of the encrypted fields of the payment; it's nil
+	// when they aren't encrypted.
+	Encryption *envelope `json:"encryption,omitempty"`
+}
+
+// insertEvent inserts, using conn, the event of type t for the payment p. It's
+// meant to be called inside of the transaction which applies the change to
+// the payment. The sensitive fields of p are encrypted with kp, as the ones of
+// the payments are, when it isn't nil.
+func insertEvent(conn *sqlite3.Conn, t payment.EventType, p payment.Pymt, kp KeyProvider) error {
+	var pl, err = encodeEventPayload(p, kp)
+	if err != nil {
+		return err
+	}
+
+	err = conn.Exec(
+		"INSERT INTO payment_events(type, payment_id, payment_version, payload) VALUES (?, ?, ?, ?)",
+		t.String(), p.ID.String(), int64(p.Version), pl,
+	)
+	if err != nil {
+		return handleSQLiteErr(err)
+	}
+
+	return nil
+}
+
+// dbScanEvent scans the columns of a row of payment_events select statement
+// which selects seq, type, payment_id, payment_version and payload columns in
+// that order. The encrypted fields of the payload are decrypted with kp.
+func dbScanEvent(stmt *sqlite3.Stmt, kp KeyProvider) (payment.Event, error) {
+	var e payment.Event
+
+	seq, _, err := stmt.ColumnInt64(0)
+	if err != nil {
+		return e, errors.Wrap(
+			err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.ColumnInt64", 0),
+		)
+	}
+	e.Seq = uint64(seq)
+
+	ts, _, err := stmt.ColumnText(1)
+	if err != nil {
+		return e, errors.Wrap(
+			err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.ColumnText", 1),
+		)
+	}
+
+	var ok bool
+	if e.Type, ok = payment.ParseEventType(ts); !ok {
+		return e, errors.New(ErrInvalidFormatEventType, payment.ErrMDVar("type", ts))
+	}
+
+	id, _, err := stmt.ColumnText(2)
+	if err != nil {
+		return e, errors.Wrap(
+			err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.ColumnText", 2),
+		)
+	}
+
+	e.PymtID, err = uuid.FromString(id)
+	if err != nil {
+		return e, errors.Wrap(err, ErrInvalidFormatID, payment.ErrMDVar("payment_id", id))
+	}
+
+	v, _, err := stmt.ColumnInt64(3)
+	if err != nil {
+		return e, errors.Wrap(
+			err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.ColumnInt64", 3),
+		)
+	}
+	e.Version = uint32(v)
+
+	pl, err := stmt.ColumnBlob(4)
+	if err != nil {
+		return e, errors.Wrap(
+			err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.ColumnBlob", 4),
+		)
+	}
+
+	if e.Pymt, err = decodeEventPayload(pl, kp); err != nil {
+		var c, _ = errors.GetCode(err)
+		return e, errors.Wrap(err, c, payment.ErrMDVar("seq", e.Seq))
+	}
+
+	return e, nil
+}
+
+// encodeEventPayload returns the payload of an event of p to store in the DB.
+// The sensitive fields of p are encrypted with kp when it isn't nil.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedSysError
+//
+// * Any of the errors returned by encryptFields.
+func encodeEventPayload(p payment.Pymt, kp KeyProvider) ([]byte, error) {
+	var ep = eventPayload{Pymt: p}
+	if kp != nil {
+		var err error
+		if ep.Encryption, err = encryptFields(&ep.Attributes, kp); err != nil {
+			return nil, err
+		}
+	}
+
+	var pl, err = json.Marshal(ep)
+	if err != nil {
+		return nil, errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	return pl, nil
+}
+
+// decodeEventPayload returns the payment of the event payload pl stored in the
+// DB. The encrypted fields are decrypted with kp.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidFormatBlob
+//
+// * Any of the errors returned by decryptFields.
+func decodeEventPayload(pl []byte, kp KeyProvider) (payment.Pymt, error) {
+	var ep eventPayload
+	if err := json.Unmarshal(pl, &ep); err != nil {
+		return payment.Pymt{}, errors.Wrap(err, ErrInvalidFormatBlob)
+	}
+
+	if ep.Encryption != nil {
+		if err := decryptFields(&ep.Attributes, ep.Encryption, kp); err != nil {
+			return payment.Pymt{}, err
+		}
+	}
+
+	return ep.Pymt, nil
+}
+
+// notifier allows to wait for being notified when a new change happens.
+// The zero value is ready to use.
+type notifier struct {
+	mu sync.Mutex
+	c  chan struct{}
+}
+
+// wait returns a channel which is closed the next time that notify is called.
+func (n *notifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.c == nil {
+		n.c = make(chan struct{})
+	}
+
+	return n.c
+}
+
+// notify notifies to all the current waiters.
+func (n *notifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.c != nil {
+		close(n.c)
+		n.c = nil
+	}
+}
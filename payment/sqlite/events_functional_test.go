@@ -0,0 +1,123 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Changes(t *testing.T) {
+	var svc, err = sqlite.New(testingDB)
+	require.NoError(t, err)
+
+	var cf, ok = svc.(payment.ChangeFeed)
+	require.True(t, ok, "sqlite service must satisfy payment.ChangeFeed")
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Subscribe before applying any change for checking that the new events are
+	// tailed
+	var subCtx, subCancel = context.WithCancel(ctx)
+	evtc, errc := cf.Changes(subCtx, 0)
+
+	var npymt = payment.PymtUpsert{
+		Type:  "Payment",
+		OrgID: testutil.NewUUID(t),
+	}
+	npymt.Attributes = testutil.NewAttrs(t)
+
+	pid, err := svc.Create(ctx, npymt)
+	require.NoError(t, err)
+
+	var upymt = npymt
+	upymt.Attributes.Reference = "updated reference"
+	err = svc.Update(ctx, pid, 0, upymt)
+	require.NoError(t, err)
+
+	err = svc.Delete(ctx, pid, 1)
+	require.NoError(t, err)
+
+	var evts = receiveEvents(t, evtc, pid, 3)
+	subCancel()
+
+	for range evtc {
+	}
+	_, ok = <-errc
+	assert.False(t, ok, "errors channel must be closed without errors when the context is canceled")
+
+	require.Len(t, evts, 3)
+	assert.Equal(t, payment.EventTypeCreated, evts[0].Type)
+	assert.Equal(t, uint32(0), evts[0].Version)
+	assert.Equal(t, payment.Pymt{ID: pid, Status: payment.StatusPending, PymtUpsert: npymt}, evts[0].Pymt)
+
+	assert.Equal(t, payment.EventTypeUpdated, evts[1].Type)
+	assert.Equal(t, uint32(1), evts[1].Version)
+	assert.Equal(t, payment.Pymt{ID: pid, Version: 1, Status: payment.StatusPending, PymtUpsert: upymt}, evts[1].Pymt)
+
+	assert.Equal(t, payment.EventTypeDeleted, evts[2].Type)
+	assert.Equal(t, uint32(1), evts[2].Version)
+	assert.Equal(t, payment.Pymt{ID: pid, Version: 1, Status: payment.StatusPending, PymtUpsert: upymt}, evts[2].Pymt)
+
+	assert.True(t, evts[0].Seq < evts[1].Seq && evts[1].Seq < evts[2].Seq, "sequence numbers must grow")
+
+	t.Run("replay from a sequence number", func(t *testing.T) {
+		var subCtx, subCancel = context.WithCancel(ctx)
+		defer subCancel()
+
+		var evtc, _ = cf.Changes(subCtx, evts[1].Seq)
+		var revts = receiveEvents(t, evtc, pid, 2)
+		assert.Equal(t, evts[1:], revts)
+	})
+
+	t.Run("failed operations don't emit events", func(t *testing.T) {
+		var err = svc.Delete(ctx, pid, 1)
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", pid))
+
+		var subCtx, subCancel = context.WithCancel(ctx)
+		defer subCancel()
+
+		var evtc, _ = cf.Changes(subCtx, evts[2].Seq)
+		var e, ok = <-evtc
+		require.True(t, ok)
+		assert.Equal(t, evts[2], e)
+
+		select {
+		case e := <-evtc:
+			assert.NotEqual(t, pid, e.PymtID, "unexpected event")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+// receiveEvents receives from evtc the events of the payment pid until getting
+// n of them. It fails the test if they aren't received before 5 seconds.
+func receiveEvents(t *testing.T, evtc <-chan payment.Event, pid uuid.UUID, n int) []payment.Event {
+	t.Helper()
+
+	var (
+		evts    []payment.Event
+		timeout = time.After(5 * time.Second)
+	)
+
+	for len(evts) < n {
+		select {
+		case e, ok := <-evtc:
+			require.True(t, ok, "events channel closed unexpectedly")
+			if e.PymtID == pid {
+				evts = append(evts, e)
+			}
+		case <-timeout:
+			require.FailNow(t, "timeout waiting for events", "received %d of %d", len(evts), n)
+		}
+	}
+
+	return evts
+}
@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// idempotencyKeyTTL is the default time during which the idempotency keys are
+// kept.
+const idempotencyKeyTTL = 24 * time.Hour
+
+// pymtHash returns the hex representation of the SHA-256 of the JSON encoding
+// of p.
+func pymtHash(p payment.PymtUpsert) (string, error) {
+	var b, err = json.Marshal(p)
+	if err != nil {
+		return "", errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	var h = sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// idempotentPymtID returns the ID of the payment created by the organisation
+// orgID with the idempotency key key if it hasn't expired at now. It returns
+// uuid.Nil if there isn't any or it has expired.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrInvalidArgIdempotencyKeyReused - when the key was used for
+//   creating a payment whose hash isn't phash.
+//
+// * ErrInvalidFormatID
+//
+// * Any of the errors returned by handleSQLiteErr
+func idempotentPymtID(
+	conn *sqlite3.Conn, orgID uuid.UUID, key string, phash string, now time.Time,
+) (uuid.UUID, error) {
+	var stmt, err = conn.Prepare(
+		"SELECT request_hash, payment_id FROM idempotency_keys WHERE organisation_id = ? AND key = ? AND expires_at > ?",
+		orgID.String(), key, now.UnixNano(),
+	)
+	if err != nil {
+		return uuid.Nil, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	ok, err := stmt.Step()
+	if err != nil {
+		return uuid.Nil, handleSQLiteErr(err)
+	}
+	if !ok {
+		return uuid.Nil, nil
+	}
+
+	var h, sid string
+	if err := stmt.Scan(&h, &sid); err != nil {
+		return uuid.Nil, handleSQLiteErr(err)
+	}
+
+	if h != phash {
+		return uuid.Nil, errors.New(payment.ErrInvalidArgIdempotencyKeyReused,
+			payment.ErrMDVar("idempotency_key", key), payment.ErrMDFact("payment_id", sid),
+		)
+	}
+
+	id, err := uuid.FromString(sid)
+	if err != nil {
+		return uuid.Nil, errors.Wrap(err, ErrInvalidFormatID, payment.ErrMDVar("payment_id", sid))
+	}
+
+	return id, nil
+}
+
+// insertIdempotencyKey stores the idempotency key key of the organisation orgID
+// associated to the payment with ID pid and hash phash, which expires at
+// expiresAt. It also removes all the keys which have expired at now.
+//
+// The following error codes can be returned:
+//
+// * Any of the errors returned by handleSQLiteErr
+func insertIdempotencyKey(
+	conn *sqlite3.Conn, orgID uuid.UUID, key string, phash string, pid uuid.UUID, now, expiresAt time.Time,
+) error {
+	var err = conn.Exec("DELETE FROM idempotency_keys WHERE expires_at <= ?", now.UnixNano())
+	if err != nil {
+		return handleSQLiteErr(err)
+	}
+
+	err = conn.Exec(
+		`INSERT INTO idempotency_keys(organisation_id, key, request_hash, payment_id, expires_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		orgID.String(), key, phash, pid.String(), expiresAt.UnixNano(),
+	)
+	if err != nil {
+		return handleSQLiteErr(err)
+	}
+
+	return nil
+}
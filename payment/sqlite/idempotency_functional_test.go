@@ -0,0 +1,79 @@
+package sqlite_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Create_IdempotencyKey(t *testing.T) {
+	var svc, err = sqlite.New(testingDB, sqlite.WithIdempotencyKeyTTL(200*time.Millisecond))
+	require.NoError(t, err)
+
+	var (
+		ctx   = payment.WithIdempotencyKey(context.Background(), "create-payment-1")
+		npymt = payment.PymtUpsert{
+			Type:  "Payment",
+			OrgID: testutil.NewUUID(t),
+		}
+	)
+	npymt.Attributes = testutil.NewAttrs(t)
+
+	pid, err := svc.Create(ctx, npymt)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, svc.Delete(context.Background(), pid, 0))
+	}()
+
+	t.Run("replay returns the same ID", func(t *testing.T) {
+		var id, err = svc.Create(ctx, npymt)
+		require.NoError(t, err)
+		assert.Equal(t, pid, id)
+	})
+
+	t.Run("same key with another organisation creates a payment", func(t *testing.T) {
+		var op = npymt
+		op.OrgID = testutil.NewUUID(t)
+
+		var id, err = svc.Create(ctx, op)
+		require.NoError(t, err)
+		assert.NotEqual(t, pid, id)
+
+		assert.NoError(t, svc.Delete(context.Background(), id, 0))
+	})
+
+	t.Run("error: same key with a different payment", func(t *testing.T) {
+		var dp = npymt
+		dp.Attributes.Reference = dp.Attributes.Reference + "-changed"
+
+		var _, err = svc.Create(ctx, dp)
+		testutil.AssertError(t, err, payment.ErrInvalidArgIdempotencyKeyReused,
+			payment.ErrMDVar("idempotency_key", "create-payment-1"), payment.ErrMDFact("payment_id", pid.String()),
+		)
+	})
+
+	t.Run("error: invalid key", func(t *testing.T) {
+		var key = strings.Repeat("k", payment.IdempotencyKeyMaxLen+1)
+		var _, err = svc.Create(payment.WithIdempotencyKey(context.Background(), key), npymt)
+		testutil.AssertError(t, err, payment.ErrInvalidArgIdempotencyKey,
+			payment.ErrMDVar("idempotency_key", key), payment.ErrMDFact("max_length", payment.IdempotencyKeyMaxLen),
+		)
+	})
+
+	t.Run("expired key creates a payment", func(t *testing.T) {
+		time.Sleep(250 * time.Millisecond)
+
+		var id, err = svc.Create(ctx, npymt)
+		require.NoError(t, err)
+		assert.NotEqual(t, pid, id)
+
+		assert.NoError(t, svc.Delete(context.Background(), id, 0))
+	})
+}
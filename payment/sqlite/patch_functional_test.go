@@ -0,0 +1,82 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Patch_Transition(t *testing.T) {
+	var svc, err = sqlite.New(testingDB)
+	require.NoError(t, err)
+
+	var (
+		ctx   = context.Background()
+		npymt = payment.PymtUpsert{
+			Type:  "Payment",
+			OrgID: testutil.NewUUID(t),
+		}
+	)
+	npymt.Attributes = testutil.NewAttrs(t)
+
+	pid, err := svc.Create(ctx, npymt)
+	require.NoError(t, err)
+	defer deletePymt(t, pid)
+
+	var newPatch = func(t *testing.T, typ payment.PatchType, doc string) payment.Patch {
+		var p, err = payment.NewPatch(typ, []byte(doc))
+		require.NoError(t, err)
+		return p
+	}
+
+	t.Run("error: version mismatch", func(t *testing.T) {
+		var err = svc.Patch(ctx, pid, 1, newPatch(t, payment.PatchMerge, `{}`))
+		testutil.AssertError(t, err, payment.ErrInvalidArgVersionMismatch,
+			payment.ErrMDArg("version", uint32(1)), payment.ErrMDFact("current_version", uint32(0)),
+		)
+	})
+
+	t.Run("error: invalid result", func(t *testing.T) {
+		var err = svc.Patch(ctx, pid, 0, newPatch(t, payment.PatchMerge, `{"attributes":{"currency":"pounds"}}`))
+		testutil.AssertError(t, err, payment.ErrInvalidPaymentAttrCurrency)
+	})
+
+	t.Run("error: test failed", func(t *testing.T) {
+		var err = svc.Patch(ctx, pid, 0, newPatch(t, payment.PatchJSON,
+			`[{"op":"test","path":"/attributes/reference","value":"other"}]`,
+		))
+		testutil.AssertError(t, err, payment.ErrInvalidArgPatchTestFailed)
+	})
+
+	err = svc.Patch(ctx, pid, 0, newPatch(t, payment.PatchMerge, `{"attributes":{"reference":"merged"}}`))
+	require.NoError(t, err)
+
+	err = svc.Patch(ctx, pid, 1, newPatch(t, payment.PatchJSON, `[
+		{"op":"test","path":"/attributes/reference","value":"merged"},
+		{"op":"replace","path":"/attributes/end_to_end_reference","value":"patched"}
+	]`))
+	require.NoError(t, err)
+
+	var epymt = npymt
+	epymt.Attributes.Reference = "merged"
+	epymt.Attributes.EndToEndReference = "patched"
+
+	p, err := svc.Get(ctx, pid, payment.SelectAll())
+	require.NoError(t, err)
+	assert.Equal(t, payment.Pymt{ID: pid, Version: 2, Status: payment.StatusPending, PymtUpsert: epymt}, p)
+
+	t.Run("error: not editable", func(t *testing.T) {
+		var err = svc.Transition(ctx, pid, 2, payment.StatusSubmitted, "")
+		require.NoError(t, err)
+
+		err = svc.Patch(ctx, pid, 3, newPatch(t, payment.PatchMerge, `{}`))
+		testutil.AssertError(t, err, payment.ErrNotEditable,
+			payment.ErrMDVar("id", pid), payment.ErrMDFact("current_status", payment.StatusSubmitted),
+		)
+	})
+}
@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/ratelimit"
+	"go.fraixed.es/errors"
+)
+
+// NewLimitsStore creates an instance of the SQLite implementation of the
+// ratelimit LimitsStore, which stores the limits with the rest of the settings
+// of the organisations.
+//
+// fname accepts the same values than New and the same error codes can be
+// returned.
+func NewLimitsStore(fname string) (ratelimit.LimitsStore, error) {
+	var svc, err = newService(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	return &limitsStore{svc: svc}, nil
+}
+
+type limitsStore struct {
+	svc *service
+}
+
+// OrgLimits satisfies the ratelimit.LimitsStore interface.
+//
+// The function will return all the errors that ratelimit.LimitsStore documents
+// plus ErrDBCantOpen.
+func (ls *limitsStore) OrgLimits(ctx context.Context, orgID uuid.UUID) (ratelimit.Limits, bool, error) {
+	var conn, pc, err = ls.svc.openConn(ctx)
+	if err != nil {
+		return ratelimit.Limits{}, false, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	stmt, err := prepare(ctx, conn,
+		`SELECT read_rate, read_burst, write_rate, write_burst FROM organisation_settings
+		WHERE organisation_id = ? AND read_rate IS NOT NULL`,
+		orgID.String(),
+	)
+	if err != nil {
+		return ratelimit.Limits{}, false, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	ok, err := stmt.Step()
+	if err != nil {
+		return ratelimit.Limits{}, false, handleSQLiteErr(err)
+	}
+	if !ok {
+		return ratelimit.Limits{}, false, nil
+	}
+
+	var (
+		l      ratelimit.Limits
+		rb, wb int64
+	)
+	if err := stmt.Scan(&l.Read.Rate, &rb, &l.Write.Rate, &wb); err != nil {
+		return ratelimit.Limits{}, false, errors.Wrap(
+			err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.Scan"),
+		)
+	}
+
+	l.Read.Burst = uint32(rb)
+	l.Write.Burst = uint32(wb)
+	return l, true, nil
+}
+
+// SetOrgLimits satisfies the ratelimit.LimitsStore interface.
+//
+// The function will return all the errors that ratelimit.LimitsStore documents
+// plus ErrDBCantOpen.
+func (ls *limitsStore) SetOrgLimits(ctx context.Context, orgID uuid.UUID, l ratelimit.Limits) error {
+	if orgID == uuid.Nil {
+		return errors.New(auth.ErrInvalidOrgID, payment.ErrMDArg("orgID", orgID))
+	}
+
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	var conn, pc, err = ls.svc.openConn(ctx)
+	if err != nil {
+		return wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	err = conn.Exec(
+		`INSERT INTO organisation_settings(organisation_id, read_rate, read_burst, write_rate, write_burst)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(organisation_id) DO UPDATE SET
+			read_rate = excluded.read_rate, read_burst = excluded.read_burst,
+			write_rate = excluded.write_rate, write_burst = excluded.write_burst`,
+		orgID.String(), l.Read.Rate, int64(l.Read.Burst), l.Write.Rate, int64(l.Write.Burst),
+	)
+	if err != nil {
+		return handleSQLiteErr(err)
+	}
+
+	return nil
+}
+
+// DeleteOrgLimits satisfies the ratelimit.LimitsStore interface.
+//
+// The function will return all the errors that ratelimit.LimitsStore documents
+// plus ErrDBCantOpen.
+func (ls *limitsStore) DeleteOrgLimits(ctx context.Context, orgID uuid.UUID) error {
+	var conn, pc, err = ls.svc.openConn(ctx)
+	if err != nil {
+		return wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	err = conn.Exec(
+		`UPDATE organisation_settings SET read_rate = NULL, read_burst = NULL, write_rate = NULL, write_burst = NULL
+		WHERE organisation_id = ?`,
+		orgID.String(),
+	)
+	if err != nil {
+		return handleSQLiteErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment/auth"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/ratelimit"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsStore(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		orgID = testutil.NewUUID(t)
+		l     = ratelimit.Limits{
+			Read:  ratelimit.Limit{Rate: 2.5, Burst: 5},
+			Write: ratelimit.Limit{Rate: 1, Burst: 2},
+		}
+	)
+
+	var ls, err = sqlite.NewLimitsStore(testingDB)
+	require.NoError(t, err)
+
+	_, ok, err := ls.OrgLimits(ctx, orgID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, ls.SetOrgLimits(ctx, orgID, l))
+	gl, ok, err := ls.OrgLimits(ctx, orgID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, l, gl)
+
+	l.Write.Burst = 4
+	require.NoError(t, ls.SetOrgLimits(ctx, orgID, l))
+	gl, _, err = ls.OrgLimits(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, l, gl)
+
+	require.NoError(t, ls.DeleteOrgLimits(ctx, orgID))
+	_, ok, err = ls.OrgLimits(ctx, orgID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, ls.DeleteOrgLimits(ctx, testutil.NewUUID(t)))
+
+	t.Run("error: invalid limits", func(t *testing.T) {
+		var err = ls.SetOrgLimits(ctx, orgID, ratelimit.Limits{Read: l.Read})
+		testutil.AssertError(t, err, ratelimit.ErrInvalidArgLimits)
+	})
+
+	t.Run("error: invalid organisation", func(t *testing.T) {
+		var err = ls.SetOrgLimits(ctx, uuid.Nil, l)
+		testutil.AssertError(t, err, auth.ErrInvalidOrgID)
+	})
+}
@@ -0,0 +1,92 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/ifraixedes/go-payments-api-example/payment/tenancy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_OrgScope(t *testing.T) {
+	var svc, err = sqlite.New(testingDB)
+	require.NoError(t, err)
+
+	var (
+		ctx   = context.Background()
+		npymt = payment.PymtUpsert{
+			Type:  "Payment",
+			OrgID: testutil.NewUUID(t),
+		}
+	)
+	npymt.Attributes = testutil.NewAttrs(t)
+
+	pid, err := svc.Create(ctx, npymt)
+	require.NoError(t, err)
+	defer deletePymt(t, pid)
+
+	patch, err := payment.NewPatch(payment.PatchMerge, []byte(`{"attributes":{"reference":"patched"}}`))
+	require.NoError(t, err)
+
+	t.Run("other organisation", func(t *testing.T) {
+		var (
+			octx = tenancy.WithOrgID(ctx, testutil.NewUUID(t))
+			tsvc = tenancy.New(svc)
+			mdID = payment.ErrMDVar("id", pid)
+		)
+
+		var _, err = tsvc.Get(octx, pid, payment.SelectAll())
+		testutil.AssertError(t, err, payment.ErrNotFound, mdID)
+
+		_, err = tsvc.History(octx, pid)
+		testutil.AssertError(t, err, payment.ErrNotFound, mdID)
+
+		var opymt = npymt
+		opymt.OrgID, _ = tenancy.OrgID(octx)
+		err = tsvc.Update(octx, pid, 0, opymt)
+		testutil.AssertError(t, err, payment.ErrNotFound, mdID)
+
+		err = tsvc.Patch(octx, pid, 0, patch)
+		testutil.AssertError(t, err, payment.ErrNotFound, mdID)
+
+		err = tsvc.Transition(octx, pid, 0, payment.StatusSubmitted, "")
+		testutil.AssertError(t, err, payment.ErrNotFound, mdID)
+
+		err = tsvc.Delete(octx, pid, 0)
+		testutil.AssertError(t, err, payment.ErrNotFound, mdID)
+
+		// The payment hasn't been modified
+		p, err := svc.Get(ctx, pid, payment.SelectAll())
+		require.NoError(t, err)
+		assert.Equal(t, payment.Pymt{ID: pid, Status: payment.StatusPending, PymtUpsert: npymt}, p)
+
+		hist, err := svc.History(ctx, pid)
+		require.NoError(t, err)
+		assert.Len(t, hist, 1)
+	})
+
+	t.Run("own organisation", func(t *testing.T) {
+		var sctx = payment.WithOrgScope(ctx, npymt.OrgID)
+
+		var err = svc.Update(sctx, pid, 0, npymt)
+		require.NoError(t, err)
+
+		err = svc.Patch(sctx, pid, 1, patch)
+		require.NoError(t, err)
+
+		err = svc.Transition(sctx, pid, 2, payment.StatusSubmitted, "")
+		require.NoError(t, err)
+
+		hist, err := svc.History(sctx, pid)
+		require.NoError(t, err)
+		assert.Len(t, hist, 2)
+
+		p, err := svc.Get(sctx, pid, payment.Selection{Version: true, Status: true})
+		require.NoError(t, err)
+		assert.Equal(t, payment.Pymt{ID: pid, Version: 3, Status: payment.StatusSubmitted}, p)
+	})
+}
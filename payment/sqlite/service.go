@@ -11,6 +11,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/ifraixedes/go-payments-api-example/payment"
 	"github.com/ifraixedes/go-payments-api-example/payment/internal/filter"
+	"github.com/ifraixedes/go-payments-api-example/payment/tracing"
 	"go.fraixed.es/errors"
 )
 
@@ -43,18 +44,54 @@ import (
 //
 // * payment.ErrUnexpectedOSError - this error happens if there is an error when
 //   resolving the absolute path of the fname is a path to a file.
-func New(fname string) (payment.Service, error) {
+//
+// The returned payment.Service also satisfies the payment.ChangeFeed and
+// payment.BatchCreator interfaces.
+// The changes of the payments are stored in the same transaction than the
+// operation which applies them.
+//
+// opts allows to change the default values of the optional parameters of the
+// service.
+func New(fname string, opts ...Option) (payment.Service, error) {
+	var svc, err = newService(fname, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc, nil
+}
+
+// Option sets an optional parameter of the service created by New.
+type Option func(*service)
+
+// WithIdempotencyKeyTTL sets the time during which the idempotency keys used
+// for creating payments are kept (see payment.WithIdempotencyKey). By default
+// they are kept 24 hours.
+func WithIdempotencyKeyTTL(ttl time.Duration) Option {
+	return func(s *service) {
+		s.idempotencyKeyTTL = ttl
+	}
+}
+
+// newService creates a service for fname. See New for more information.
+func newService(fname string, opts ...Option) (*service, error) {
 	if fname == "" {
 		return nil, errors.New(ErrInvalidArgDBFname, payment.ErrMDArg("fname", fname))
 	}
 
 	var (
 		svc = service{
-			fname: fname,
+			fname:               fname,
+			changesPollInterval: changesPollInterval,
+			idempotencyKeyTTL:   idempotencyKeyTTL,
 		}
 		isURI bool
 	)
 
+	for _, o := range opts {
+		o(&svc)
+	}
+
 	switch {
 	case fname == ":memory:":
 		// When in-memory, the URI format must be used for being able to enabled the
@@ -89,8 +126,12 @@ func New(fname string) (payment.Service, error) {
 }
 
 type service struct {
-	fname     string
-	openFlags int
+	fname               string
+	openFlags           int
+	changes             notifier
+	changesPollInterval time.Duration
+	idempotencyKeyTTL   time.Duration
+	keys                KeyProvider
 }
 
 // Create stores p in the database.
@@ -104,13 +145,28 @@ type service struct {
 //
 // * ErrDBSchemaChanged
 //
+// * ErrInvalidFormatID
+//
 // * ErrInvalidPayment
 func (s *service) Create(ctx context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
-	if err := p.Validate(); err != nil {
+	if err := validate(ctx, p); err != nil {
+		return uuid.Nil, err
+	}
+
+	var key, withKey, err = payment.IdempotencyKey(ctx)
+	if err != nil {
 		return uuid.Nil, err
 	}
 
-	var id, err = uuid.NewV4()
+	var phash string
+	if withKey {
+		phash, err = pymtHash(p)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	id, err := uuid.NewV4()
 	if err != nil {
 		return uuid.Nil, errors.Wrap(err, payment.ErrUnexpectedStoreError)
 	}
@@ -119,92 +175,275 @@ func (s *service) Create(ctx context.Context, p payment.PymtUpsert) (uuid.UUID,
 	{
 		d := &pymtData{}
 		d.Init(p)
-		pd, err = d.Serialize()
+		pd, err = d.Serialize(s.keys)
 		if err != nil {
 			return uuid.Nil, err
 		}
 	}
 
-	conn, _, err := s.openConn(ctx)
+	conn, pc, err := s.openConn(ctx)
 	if err != nil {
-		return uuid.Nil, errors.Wrap(err, payment.ErrUnexpectedStoreError)
+		return uuid.Nil, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// The transaction is immediate when there is an idempotency key because it's
+	// read before inserting the payment and a deferred one could fail when
+	// upgrading the read lock to a write lock if another connection is writing
+	var withTx = conn.WithTx
+	if withKey {
+		withTx = conn.WithTxImmediate
+	}
+
+	// See the comment in the Update method about why errtx var exists
+	var replayed bool
+	var errtx = withTx(func() error {
+		if withKey {
+			var now = time.Now()
+			var pid uuid.UUID
+			pid, err = idempotentPymtID(conn, p.OrgID, key, phash, now)
+			if err != nil {
+				return err
+			}
+
+			if pid != uuid.Nil {
+				id = pid
+				replayed = true
+				return nil
+			}
+
+			err = insertIdempotencyKey(conn, p.OrgID, key, phash, id, now, now.Add(s.idempotencyKeyTTL))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = insertPymt(conn, id, p, pd, s.keys)
+		return err
+	})
+
+	if err == nil && errtx != nil {
+		return uuid.Nil, errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
+	}
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if !replayed {
+		s.changes.notify()
+	}
+
+	return id, nil
+}
+
+// CreateBatch stores ps in the database in a single transaction.
+//
+// The function will return all the errors that payment.BatchCreator documents
+// plus the ones that Create documents.
+func (s *service) CreateBatch(ctx context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+	var (
+		ids = make([]uuid.UUID, len(ps))
+		pds = make([][]byte, len(ps))
+	)
+
+	for i, p := range ps {
+		if err := validate(ctx, p); err != nil {
+			var c, _ = errors.GetCode(err)
+			return nil, errors.Wrap(err, c, payment.ErrMDVar("index", i))
+		}
+
+		var err error
+		ids[i], err = uuid.NewV4()
+		if err != nil {
+			return nil, errors.Wrap(err, payment.ErrUnexpectedStoreError)
+		}
+
+		d := &pymtData{}
+		d.Init(p)
+		pds[i], err = d.Serialize(s.keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ps) == 0 {
+		return ids, nil
+	}
+
+	conn, pc, err := s.openConn(ctx)
+	if err != nil {
+		return nil, wrapOpenConnErr(err, pc)
 	}
 
 	defer func() {
 		_ = conn.Close()
 	}()
 
-	err = conn.Exec(
+	// See the comment in the Update method about why errtx var exists
+	var errtx = conn.WithTx(func() error {
+		for i, p := range ps {
+			err = insertPymt(conn, ids[i], p, pds[i], s.keys)
+			if err != nil {
+				var c, _ = errors.GetCode(err)
+				err = errors.Wrap(err, c, payment.ErrMDVar("index", i))
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err == nil && errtx != nil {
+		return nil, errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.changes.notify()
+	return ids, nil
+}
+
+// insertPymt inserts, using conn, the payment p, whose serialized data is pd,
+// with the associated id and its creation status change and event, whose
+// sensitive fields are encrypted with kp (see insertEvent).
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidPayment
+//
+// * payment.ErrUnexpectedStoreError
+//
+// * Any of the errors returned by handleSQLiteErrCommon, insertStatusChange
+// and insertEvent
+func insertPymt(conn *sqlite3.Conn, id uuid.UUID, p payment.PymtUpsert, pd []byte, kp KeyProvider) error {
+	var err = conn.Exec(
 		"INSERT INTO payments(id, organisation_id, data) VALUES (?, ?, ?)",
 		id.String(), p.OrgID.String(), pd,
 	)
 	if err != nil {
 		if cerr := handleSQLiteErrCommon(err); cerr != nil {
-			return uuid.Nil, cerr
+			return cerr
 		}
 
 		var pc, _, serr = isSQLiteErr(err)
 		if serr != nil {
 			if pc == sqlite3.CONSTRAINT {
-				return uuid.Nil, errors.Wrap(serr, ErrInvalidPayment)
+				return errors.Wrap(serr, ErrInvalidPayment)
 			}
 		}
 
-		return uuid.Nil, errors.Wrap(err, payment.ErrUnexpectedStoreError)
+		return errors.Wrap(err, payment.ErrUnexpectedStoreError)
 	}
 
-	return id, nil
+	err = insertStatusChange(conn, id, payment.StatusChange{
+		To: payment.StatusPending,
+		At: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return insertEvent(conn, payment.EventTypeCreated, payment.Pymt{
+		ID: id, Status: payment.StatusPending, PymtUpsert: p,
+	}, kp)
 }
 
-func (s *service) Delete(ctx context.Context, id uuid.UUID) error {
+func (s *service) Delete(ctx context.Context, id uuid.UUID, ver uint32) error {
 	if id == uuid.Nil {
 		return errors.New(payment.ErrInvalidPaymentID, payment.ErrMDArg("id", id))
 	}
 
-	conn, _, err := s.openConn(ctx)
+	conn, pc, err := s.openConn(ctx)
 	if err != nil {
-		return errors.Wrap(err, payment.ErrUnexpectedStoreError)
+		return wrapOpenConnErr(err, pc)
 	}
 
 	defer func() {
 		_ = conn.Close()
 	}()
 
-	err = conn.Exec("DELETE FROM payments WHERE id = ?", id.String())
-	if err != nil {
-		return handleSQLiteErr(err)
+	// See the comment in the Update method about why errtx var exists.
+	// The transaction is immediate because it reads the payment, for checking its
+	// version and status, before deleting it and a deferred one could fail when
+	// upgrading the read lock to a write lock if another connection is writing.
+	var errtx = conn.WithTxImmediate(func() error {
+		var p payment.Pymt
+		p, err = getPymt(ctx, conn, id, payment.SelectAll(), s.keys)
+		if err != nil {
+			return err
+		}
+
+		if p.Version != ver {
+			err = errors.New(payment.ErrInvalidArgVersionMismatch,
+				payment.ErrMDArg("version", ver), payment.ErrMDFact("current_version", p.Version),
+			)
+			return err
+		}
+
+		if !p.Status.Editable() {
+			err = errors.New(payment.ErrNotEditable,
+				payment.ErrMDVar("id", id), payment.ErrMDFact("current_status", p.Status),
+			)
+			return err
+		}
+
+		err = conn.Exec("DELETE FROM payments WHERE id = ? AND version = ? AND status = ?",
+			id.String(), int64(ver), payment.StatusPending.String(),
+		)
+		if err != nil {
+			err = handleSQLiteErr(err)
+			return err
+		}
+
+		err = insertEvent(conn, payment.EventTypeDeleted, p, s.keys)
+		return err
+	})
+
+	if err == nil && errtx != nil {
+		return errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
 	}
 
-	if conn.TotalChanges() == 0 {
-		return errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+	if err != nil {
+		return err
 	}
 
+	s.changes.notify()
 	return nil
 }
 
 func (s *service) Find(
 	ctx context.Context, pf payment.Filter, sl payment.Selection, st payment.Sort, pc payment.Chunk,
 ) ([]payment.Pymt, error) {
+	var ordby, err = orderByColumns(st, s.keys != nil)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
-		stmtargs         []interface{}
-		ordby            = orderByColumns(st)
-		sel, scanPymt    = selectPymtColumns(sl)
-		limitargs        = limitOffset(pc)
-		where, whereargs = filter.SQL(pf, leafField)
+		stmtargs      []interface{}
+		sel, scanPymt = selectPymtColumns(sl, s.keys)
+		limitargs     = limitOffset(pc)
 		//nolint:gosec
 		query = fmt.Sprintf("SELECT %s FROM payments", sel)
 	)
 
+	var _, fspan = tracing.Start(ctx, spanFilterSQL)
+	var where, whereargs = filter.SQL(pf, leafField)
+	fspan.End()
+
 	if where != "" {
 		//nolint:gosec
 		query = fmt.Sprintf("%s WHERE %s", query, where)
 		stmtargs = whereargs
 	}
 
-	if ordby != "" {
-		query = fmt.Sprintf("%s ORDER BY %s", query, ordby)
-	}
-
+	query = fmt.Sprintf("%s ORDER BY %s", query, ordby)
 	if len(limitargs) > 0 {
 		query = fmt.Sprintf("%s LIMIT ? OFFSET ?", query)
 		stmtargs = append(stmtargs, limitargs...)
@@ -212,16 +451,16 @@ func (s *service) Find(
 
 	stmtargs = adaptArgsToSQL(stmtargs)
 
-	var conn, _, err = s.openConn(ctx)
+	conn, opc, err := s.openConn(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, payment.ErrUnexpectedStoreError)
+		return nil, wrapOpenConnErr(err, opc)
 	}
 
 	defer func() {
 		_ = conn.Close()
 	}()
 
-	stmt, err := conn.Prepare(query, stmtargs...)
+	stmt, err := prepare(ctx, conn, query, stmtargs...)
 	if err != nil {
 		return nil, handleSQLiteErr(err)
 	}
@@ -230,6 +469,12 @@ func (s *service) Find(
 	}()
 
 	var plist []payment.Pymt
+	var span = startStep(ctx)
+	defer func() {
+		span.SetAttr("db.rows", len(plist))
+		span.End()
+	}()
+
 	for {
 		ok, err := stmt.Step()
 		if err != nil {
@@ -255,18 +500,37 @@ func (s *service) Get(ctx context.Context, id uuid.UUID, sl payment.Selection) (
 		return payment.Pymt{}, errors.New(payment.ErrInvalidPaymentID, payment.ErrMDArg("id", id))
 	}
 
-	var conn, _, err = s.openConn(ctx)
+	var conn, pc, err = s.openConn(ctx)
 	if err != nil {
-		return payment.Pymt{}, errors.Wrap(err, payment.ErrUnexpectedStoreError)
+		return payment.Pymt{}, wrapOpenConnErr(err, pc)
 	}
 
 	defer func() {
 		_ = conn.Close()
 	}()
 
-	var sq, scanPymt = selectPymtColumns(sl)
+	return getPymt(ctx, conn, id, sl, s.keys)
+}
+
+// getPymt gets, using conn, the payment with the associated id and only
+// containing the fields indicated by sl, decrypting the encrypted fields with
+// kp. ctx is used for recording the spans of the operation and for restricting
+// the payment to its organisation scope, see scopeWhere.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrNotFound
+//
+// * Any of the errors returned by handleSQLiteErr
+func getPymt(
+	ctx context.Context, conn *sqlite3.Conn, id uuid.UUID, sl payment.Selection, kp KeyProvider,
+) (payment.Pymt, error) {
+	var (
+		sq, scanPymt = selectPymtColumns(sl, kp)
+		where, args  = scopeWhere(ctx, "id = ?", id.String())
+	)
 	//nolint:gosec
-	stmt, err := conn.Prepare(fmt.Sprintf("SELECT %s FROM payments WHERE id = ?", sq), id.String())
+	stmt, err := prepare(ctx, conn, fmt.Sprintf("SELECT %s FROM payments WHERE %s", sq, where), args...)
 	if err != nil {
 		return payment.Pymt{}, handleSQLiteErr(err)
 	}
@@ -290,7 +554,7 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, ver uint32, p paymen
 		return errors.New(payment.ErrInvalidPaymentID, payment.ErrMDArg("id", id))
 	}
 
-	if err := p.Validate(); err != nil {
+	if err := validate(ctx, p); err != nil {
 		return err
 	}
 
@@ -301,15 +565,15 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, ver uint32, p paymen
 			d   = &pymtData{}
 		)
 		d.Init(p)
-		pd, err = d.Serialize()
+		pd, err = d.Serialize(s.keys)
 		if err != nil {
 			return err
 		}
 	}
 
-	var conn, _, err = s.openConn(ctx)
+	var conn, pc, err = s.openConn(ctx)
 	if err != nil {
-		return errors.Wrap(err, payment.ErrUnexpectedStoreError)
+		return wrapOpenConnErr(err, pc)
 	}
 
 	defer func() {
@@ -322,80 +586,191 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, ver uint32, p paymen
 	// rollback or commit errors
 	// See https://github.com/bvinc/go-sqlite-lite/pull/20
 	var errtx = conn.WithTx(func() error {
-		err = conn.Exec(
-			"UPDATE payments SET version = version + 1, organisation_id = ?, data = ? WHERE id = ? AND version = ?",
-			p.OrgID.String(), pd, id.String(), int64(ver),
-		)
-		if err != nil {
-			if cerr := handleSQLiteErrCommon(err); cerr != nil {
-				err = cerr
-				return cerr
-			}
+		err = updatePymt(ctx, conn, id, ver, p, pd, s.keys)
+		return err
+	})
 
-			var pc, _, serr = isSQLiteErr(err)
-			if serr != nil {
-				if pc == sqlite3.CONSTRAINT {
-					err = errors.Wrap(serr, ErrInvalidPayment)
-					return err
-				}
-			}
+	if err == nil && errtx != nil {
+		return errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.changes.notify()
+	return nil
+}
 
-			err = errors.Wrap(err, payment.ErrUnexpectedStoreError)
+// Patch satisfies the payment.Service interface.
+//
+// The function will return all the errors that payment.Service documents plus
+// ErrDBCantOpen and ErrInvalidPayment.
+func (s *service) Patch(ctx context.Context, id uuid.UUID, ver uint32, pt payment.Patch) error {
+	if id == uuid.Nil {
+		return errors.New(payment.ErrInvalidPaymentID, payment.ErrMDArg("id", id))
+	}
+
+	var conn, pc, err = s.openConn(ctx)
+	if err != nil {
+		return wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// See the comment in the Update method about why errtx var exists.
+	// The transaction is immediate because it reads the payment before updating
+	// it, see the comment in the Delete method.
+	var errtx = conn.WithTxImmediate(func() error {
+		var cp payment.Pymt
+		cp, err = getPymt(ctx, conn, id, payment.SelectAll(), s.keys)
+		if err != nil {
 			return err
 		}
 
-		if conn.TotalChanges() != 1 {
-			var stmt *sqlite3.Stmt
-			stmt, err = conn.Prepare("SELECT version FROM payments WHERE id = ?", id.String())
-			if err != nil {
-				err = handleSQLiteErr(err)
-				return err
-			}
-
-			defer func() {
-				_ = stmt.Close()
-			}()
+		if cp.Version != ver {
+			err = errors.New(payment.ErrInvalidArgVersionMismatch,
+				payment.ErrMDArg("version", ver), payment.ErrMDFact("current_version", cp.Version),
+			)
+			return err
+		}
 
-			var ok bool
-			ok, err = stmt.Step()
-			if err != nil {
-				err = handleSQLiteErr(err)
-				return err
-			}
-			if !ok {
-				err = errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
-				return err
-			}
+		if !cp.Status.Editable() {
+			err = errors.New(payment.ErrNotEditable,
+				payment.ErrMDVar("id", id), payment.ErrMDFact("current_status", cp.Status),
+			)
+			return err
+		}
 
-			var v int64
-			v, _, err = stmt.ColumnInt64(0)
-			if err != nil {
-				err = handleSQLiteErr(err)
-				return err
-			}
+		var p payment.PymtUpsert
+		p, err = pt.Apply(cp)
+		if err != nil {
+			return err
+		}
 
-			if uint32(v) != ver {
-				err = errors.New(payment.ErrInvalidArgVersionMismatch,
-					payment.ErrMDArg("version", ver), payment.ErrMDFact("current_version", v),
-				)
+		if err = validate(ctx, p); err != nil {
+			return err
+		}
 
-				return err
-			}
+		var d = &pymtData{}
+		d.Init(p)
 
-			// This should never happen, but if it happens then return this error,than
-			// returning silently
-			err = errors.New(payment.ErrUnexpectedStoreError)
+		var pd []byte
+		pd, err = d.Serialize(s.keys)
+		if err != nil {
 			return err
 		}
 
-		return nil
+		err = updatePymt(ctx, conn, id, ver, p, pd, s.keys)
+		return err
 	})
 
 	if err == nil && errtx != nil {
 		return errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
 	}
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	s.changes.notify()
+	return nil
+}
+
+// updatePymt updates the payment id, whose version must be ver, its status
+// editable and its organisation the scope carried by ctx (see scopeWhere), with
+// p and its serialized data pd and inserts the event of the update, whose
+// sensitive fields are encrypted with kp (see insertEvent). It must be called
+// inside of a transaction.
+func updatePymt(
+	ctx context.Context, conn *sqlite3.Conn, id uuid.UUID, ver uint32, p payment.PymtUpsert, pd []byte,
+	kp KeyProvider,
+) error {
+	var where, args = scopeWhere(ctx, "id = ? AND version = ? AND status = ?",
+		id.String(), int64(ver), payment.StatusPending.String(),
+	)
+	var err = conn.Exec(
+		//nolint:gosec
+		fmt.Sprintf("UPDATE payments SET version = version + 1, organisation_id = ?, data = ? WHERE %s", where),
+		append([]interface{}{p.OrgID.String(), pd}, args...)...,
+	)
+	if err != nil {
+		if cerr := handleSQLiteErrCommon(err); cerr != nil {
+			return cerr
+		}
+
+		var pc, _, serr = isSQLiteErr(err)
+		if serr != nil {
+			if pc == sqlite3.CONSTRAINT {
+				return errors.Wrap(serr, ErrInvalidPayment)
+			}
+		}
+
+		return errors.Wrap(err, payment.ErrUnexpectedStoreError)
+	}
+
+	if conn.TotalChanges() != 1 {
+		var where, args = scopeWhere(ctx, "id = ?", id.String())
+		//nolint:gosec
+		var stmt, err = conn.Prepare(fmt.Sprintf("SELECT version, status FROM payments WHERE %s", where), args...)
+		if err != nil {
+			return handleSQLiteErr(err)
+		}
+
+		defer func() {
+			_ = stmt.Close()
+		}()
+
+		ok, err := stmt.Step()
+		if err != nil {
+			return handleSQLiteErr(err)
+		}
+		if !ok {
+			return errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+		}
+
+		var (
+			v  int64
+			st string
+		)
+		if err := stmt.Scan(&v, &st); err != nil {
+			return handleSQLiteErr(err)
+		}
+
+		if uint32(v) != ver {
+			return errors.New(payment.ErrInvalidArgVersionMismatch,
+				payment.ErrMDArg("version", ver), payment.ErrMDFact("current_version", v),
+			)
+		}
+
+		if st != payment.StatusPending.String() {
+			return errors.New(payment.ErrNotEditable,
+				payment.ErrMDVar("id", id), payment.ErrMDFact("current_status", st),
+			)
+		}
+
+		// This should never happen, but if it happens then return this error,than
+		// returning silently
+		return errors.New(payment.ErrUnexpectedStoreError)
+	}
+
+	return insertEvent(conn, payment.EventTypeUpdated, payment.Pymt{
+		ID: id, Version: ver + 1, Status: payment.StatusPending, PymtUpsert: p,
+	}, kp)
+}
+
+// scopeWhere returns the SQL condition where, over the payments table, and its
+// arguments args, joined with the AND operator to the condition of the
+// organisation scope carried by ctx, when it carries one (see
+// payment.WithOrgScope).
+func scopeWhere(ctx context.Context, where string, args ...interface{}) (string, []interface{}) {
+	if orgID, ok := payment.OrgScope(ctx); ok {
+		return where + " AND organisation_id = ?", append(args, orgID.String())
+	}
+
+	return where, args
 }
 
 // openConn create a new sqlite3 connection.
@@ -412,6 +787,9 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, ver uint32, p paymen
 //
 // * payment.ErrUnexpectedStoreError
 func (s *service) openConn(ctx context.Context) (*sqlite3.Conn, uint8, error) {
+	var _, span = tracing.Start(ctx, spanOpenConn)
+	defer span.End()
+
 	var (
 		err  error
 		conn *sqlite3.Conn
@@ -457,6 +835,18 @@ func (s *service) openConn(ctx context.Context) (*sqlite3.Conn, uint8, error) {
 	return conn, 0, nil
 }
 
+// wrapOpenConnErr wraps err returned by openConn with pc primary code. The
+// connection errors due to the DB being busy or locked by another connection
+// are wrapped with payment.ErrAbortedOperation for letting know the caller that
+// the operation can be retried; the rest with payment.ErrUnexpectedStoreError.
+func wrapOpenConnErr(err error, pc uint8) error {
+	if pc == sqlite3.BUSY || pc == sqlite3.LOCKED {
+		return errors.Wrap(err, payment.ErrAbortedOperation)
+	}
+
+	return errors.Wrap(err, payment.ErrUnexpectedStoreError)
+}
+
 // isSQLiteErr returns the primary error code, the extended error code and the
 // specific sqlite3 error when it's of such type or 0, 0 and nil when not.
 // See https://www.sqlite.org/rescode.html
@@ -502,7 +892,7 @@ func handleSQLiteErrCommon(err error) error {
 // payment.ErrUnexpectedStoreError error code.
 func handleSQLiteErr(err error) error {
 	if serr := handleSQLiteErrCommon(err); serr != nil {
-		return err
+		return serr
 	}
 
 	return errors.Wrap(err, payment.ErrUnexpectedStoreError)
@@ -5,7 +5,6 @@ import (
 	"math/rand"
 	"testing"
 
-	"github.com/bxcodec/faker"
 	"github.com/gofrs/uuid"
 	"github.com/ifraixedes/go-payments-api-example/payment"
 	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
@@ -25,8 +24,7 @@ func TestService_Create_Get_Delete(t *testing.T) {
 		Type:  "Payment",
 		OrgID: testutil.NewUUID(t),
 	}
-	err = faker.FakeData(&npymt.Attributes)
-	require.NoError(t, err)
+	npymt.Attributes = testutil.NewAttrs(t)
 
 	pid, err := svc.Create(ctx, npymt)
 	require.NoError(t, err)
@@ -35,7 +33,7 @@ func TestService_Create_Get_Delete(t *testing.T) {
 	// Get payment with all the fields
 	pymt, err := svc.Get(ctx, pid, payment.SelectAll())
 	require.NoError(t, err)
-	assert.Equal(t, payment.Pymt{ID: pid, PymtUpsert: npymt}, pymt)
+	assert.Equal(t, payment.Pymt{ID: pid, Status: payment.StatusPending, PymtUpsert: npymt}, pymt)
 
 	// Get payment with only a few fields
 	pymt, err = svc.Get(ctx, pid, payment.Selection{Type: true})
@@ -45,8 +43,29 @@ func TestService_Create_Get_Delete(t *testing.T) {
 		PymtUpsert: payment.PymtUpsert{Type: npymt.Type},
 	}, pymt)
 
+	// Get payment with only a few attributes
+	sl, err := payment.NewSelection(
+		"version", "attributes.amount", "attributes.charges_information.sender_charges", "attributes.fx",
+		"attributes.beneficiary_party.account_type",
+	)
+	require.NoError(t, err)
+
+	pymt, err = svc.Get(ctx, pid, sl)
+	require.NoError(t, err)
+
+	var eattrs payment.Attrs
+	eattrs.Amount = npymt.Attributes.Amount
+	eattrs.ChargesInformation.SenderCharges = npymt.Attributes.ChargesInformation.SenderCharges
+	eattrs.Fx = npymt.Attributes.Fx
+	eattrs.BeneficiaryParty.AccountType = npymt.Attributes.BeneficiaryParty.AccountType
+	assert.Equal(t, payment.Pymt{ID: pid, PymtUpsert: payment.PymtUpsert{Attributes: eattrs}}, pymt)
+
+	pms, err := svc.Find(ctx, payment.Filter{}, sl, payment.Sort{}, payment.Chunk{})
+	require.NoError(t, err)
+	assert.Contains(t, pms, pymt)
+
 	// Delete payment
-	err = svc.Delete(ctx, pid)
+	err = svc.Delete(ctx, pid, 0)
 	require.NoError(t, err)
 
 	_, err = svc.Get(ctx, pid, payment.SelectAll())
@@ -64,8 +83,7 @@ func TestService_Create_Get_Update_Delete(t *testing.T) {
 		Type:  "Payment",
 		OrgID: testutil.NewUUID(t),
 	}
-	err = faker.FakeData(&npymt.Attributes)
-	require.NoError(t, err)
+	npymt.Attributes = testutil.NewAttrs(t)
 
 	pid, err := svc.Create(ctx, npymt)
 	require.NoError(t, err)
@@ -78,8 +96,7 @@ func TestService_Create_Get_Update_Delete(t *testing.T) {
 		Type:  pymt.Type,
 		OrgID: pymt.OrgID,
 	}
-	err = faker.FakeData(&upymt.Attributes)
-	require.NoError(t, err)
+	upymt.Attributes = testutil.NewAttrs(t)
 	upymt.Attributes.Amount = pymt.Attributes.Amount
 
 	// Update with the wrong version number
@@ -97,8 +114,17 @@ func TestService_Create_Get_Update_Delete(t *testing.T) {
 	assert.Equal(t, pymt.Version+1, pymtu.Version)
 	assert.Equal(t, pymtu.PymtUpsert, upymt)
 
+	// Delete payment with the wrong version number
+	err = svc.Delete(ctx, pid, pymt.Version)
+	testutil.AssertError(t, err, payment.ErrInvalidArgVersionMismatch,
+		payment.ErrMDArg("version", pymt.Version), payment.ErrMDFact("current_version", pymtu.Version),
+	)
+
+	_, err = svc.Get(ctx, pid, payment.Selection{})
+	require.NoError(t, err)
+
 	// Delete payment
-	err = svc.Delete(ctx, pid)
+	err = svc.Delete(ctx, pid, pymtu.Version)
 	require.NoError(t, err)
 
 	// Update unexisting payment
@@ -112,7 +138,7 @@ func TestService_Create_Find_Delete(t *testing.T) {
 
 	var (
 		ctx = context.Background()
-		a1  = rand.Float64()
+		a1  = testutil.NewAmount()
 		a2  = a1 + 10
 		a3  = a2 + 10
 	)
@@ -121,8 +147,7 @@ func TestService_Create_Find_Delete(t *testing.T) {
 		Type:  "Payment",
 		OrgID: testutil.NewUUID(t),
 	}
-	err = faker.FakeData(&npymt.Attributes)
-	require.NoError(t, err)
+	npymt.Attributes = testutil.NewAttrs(t)
 	npymt.Attributes.Amount = a1
 
 	id1, err := svc.Create(ctx, npymt)
@@ -134,8 +159,7 @@ func TestService_Create_Find_Delete(t *testing.T) {
 		Type:  "Payment",
 		OrgID: testutil.NewUUID(t),
 	}
-	err = faker.FakeData(&npymt.Attributes)
-	require.NoError(t, err)
+	npymt.Attributes = testutil.NewAttrs(t)
 	npymt.Attributes.Amount = a2
 
 	id2, err := svc.Create(ctx, npymt)
@@ -147,8 +171,7 @@ func TestService_Create_Find_Delete(t *testing.T) {
 		Type:  "Payment",
 		OrgID: testutil.NewUUID(t),
 	}
-	err = faker.FakeData(&npymt.Attributes)
-	require.NoError(t, err)
+	npymt.Attributes = testutil.NewAttrs(t)
 	npymt.Attributes.Amount = a3
 
 	id3, err := svc.Create(ctx, npymt)
@@ -157,19 +180,15 @@ func TestService_Create_Find_Delete(t *testing.T) {
 	require.NoError(t, err)
 
 	defer func() {
-		_ = svc.Delete(ctx, id1)
-		_ = svc.Delete(ctx, id2)
-		_ = svc.Delete(ctx, id3)
+		_ = svc.Delete(ctx, id1, 0)
+		_ = svc.Delete(ctx, id2, 0)
+		_ = svc.Delete(ctx, id3, 0)
 	}()
 
 	t.Run("find all", func(t *testing.T) {
 		var pms, err = svc.Find(
 			ctx, payment.Filter{}, payment.SelectAll(),
-			payment.Sort{
-				Attributes: payment.SortAttributes{
-					Amount: payment.SortAscending,
-				},
-			}, payment.Chunk{},
+			payment.Sort{{Field: payment.SortFieldAmount, Dir: payment.SortAscending}}, payment.Chunk{},
 		)
 		require.NoError(t, err)
 
@@ -183,11 +202,7 @@ func TestService_Create_Find_Delete(t *testing.T) {
 
 		pms, err := svc.Find(
 			ctx, ft, payment.SelectAll(),
-			payment.Sort{
-				Attributes: payment.SortAttributes{
-					Amount: payment.SortDescending,
-				},
-			}, payment.Chunk{},
+			payment.Sort{{Field: payment.SortFieldAmount, Dir: payment.SortDescending}}, payment.Chunk{},
 		)
 		require.NoError(t, err)
 
@@ -195,17 +210,23 @@ func TestService_Create_Find_Delete(t *testing.T) {
 		assert.Equal(t, []payment.Pymt{p3, p2}, pms)
 	})
 
+	t.Run("find by organisation", func(t *testing.T) {
+		var ft, err = payment.NewFilterByOrgID(payment.FilterCmpEqual, p2.OrgID)
+		require.NoError(t, err)
+
+		pms, err := svc.Find(ctx, ft, payment.SelectAll(), payment.Sort{}, payment.Chunk{})
+		require.NoError(t, err)
+
+		assert.Equal(t, []payment.Pymt{p2}, pms)
+	})
+
 	t.Run("find some with some fields", func(t *testing.T) {
 		var ft, err = payment.NewFilterByAmount(payment.FilterCmpGreaterOrEqualThan, a2)
 		require.NoError(t, err)
 
 		pms, err := svc.Find(
 			ctx, ft, payment.Selection{OrgID: true},
-			payment.Sort{
-				Attributes: payment.SortAttributes{
-					Amount: payment.SortDescending,
-				},
-			}, payment.Chunk{},
+			payment.Sort{{Field: payment.SortFieldAmount, Dir: payment.SortDescending}}, payment.Chunk{},
 		)
 		require.NoError(t, err)
 
@@ -240,11 +261,7 @@ func TestService_Create_Find_Delete(t *testing.T) {
 
 		pms, err := svc.Find(
 			ctx, ft, payment.SelectAll(),
-			payment.Sort{
-				Attributes: payment.SortAttributes{
-					Amount: payment.SortAscending,
-				},
-			}, payment.Chunk{
+			payment.Sort{{Field: payment.SortFieldAmount, Dir: payment.SortAscending}}, payment.Chunk{
 				Limit: 1,
 			},
 		)
@@ -255,11 +272,7 @@ func TestService_Create_Find_Delete(t *testing.T) {
 
 		pms, err = svc.Find(
 			ctx, ft, payment.SelectAll(),
-			payment.Sort{
-				Attributes: payment.SortAttributes{
-					Amount: payment.SortAscending,
-				},
-			}, payment.Chunk{
+			payment.Sort{{Field: payment.SortFieldAmount, Dir: payment.SortAscending}}, payment.Chunk{
 				Limit:  1,
 				Offset: 1,
 			},
@@ -276,14 +289,129 @@ func TestService_Create_Find_Delete(t *testing.T) {
 
 		pms, err := svc.Find(
 			ctx, ft, payment.SelectAll(),
-			payment.Sort{
-				Attributes: payment.SortAttributes{
-					Amount: payment.SortDescending,
-				},
-			}, payment.Chunk{},
+			payment.Sort{{Field: payment.SortFieldAmount, Dir: payment.SortDescending}}, payment.Chunk{},
 		)
 		require.NoError(t, err)
 
 		assert.Len(t, pms, 0)
 	})
 }
+
+func TestService_CreateBatch(t *testing.T) {
+	var svc, err = sqlite.New(testingDB)
+	require.NoError(t, err)
+
+	var (
+		ctx   = context.Background()
+		bc    = svc.(payment.BatchCreator)
+		orgID = testutil.NewUUID(t)
+		ps    = make([]payment.PymtUpsert, 3)
+	)
+
+	for i := range ps {
+		ps[i] = payment.PymtUpsert{Type: "Payment", OrgID: orgID, Attributes: testutil.NewAttrs(t)}
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		var ids, err = bc.CreateBatch(ctx, ps)
+		require.NoError(t, err)
+		require.Len(t, ids, len(ps))
+
+		for i, id := range ids {
+			pymt, err := svc.Get(ctx, id, payment.SelectAll())
+			require.NoError(t, err)
+			assert.Equal(t, payment.Pymt{ID: id, Status: payment.StatusPending, PymtUpsert: ps[i]}, pymt)
+
+			require.NoError(t, svc.Delete(ctx, id, 0))
+		}
+	})
+
+	t.Run("error: invalid payment", func(t *testing.T) {
+		var ips = append([]payment.PymtUpsert{}, ps...)
+		ips[1].Type = "Invalid"
+
+		var ids, err = bc.CreateBatch(ctx, ips)
+		testutil.AssertError(t, err, payment.ErrInvalidPaymentType, payment.ErrMDVar("index", 1))
+		assert.Nil(t, ids)
+	})
+}
+
+func TestService_Find_Sort(t *testing.T) {
+	var svc, err = sqlite.New(testingDB)
+	require.NoError(t, err)
+
+	var (
+		ctx   = context.Background()
+		orgID = testutil.NewUUID(t)
+		pms   = make([]payment.Pymt, 4)
+	)
+
+	for i, v := range []struct {
+		currency string
+		amount   float64
+	}{{"USD", 10}, {"GBP", 20}, {"USD", 30}, {"GBP", 20}} {
+		var np = payment.PymtUpsert{Type: "Payment", OrgID: orgID, Attributes: testutil.NewAttrs(t)}
+		np.Attributes.Currency = v.currency
+		np.Attributes.Amount = v.amount
+
+		var id, err = svc.Create(ctx, np)
+		require.NoError(t, err)
+		defer func() {
+			_ = svc.Delete(ctx, id, 0)
+		}()
+
+		pms[i] = payment.Pymt{ID: id, PymtUpsert: payment.PymtUpsert{Attributes: payment.Attrs{Amount: v.amount}}}
+	}
+
+	f, err := payment.NewFilterByOrgID(payment.FilterCmpEqual, orgID)
+	require.NoError(t, err)
+	sl, err := payment.NewSelection("attributes.amount")
+	require.NoError(t, err)
+
+	// The payments with the same currency and amount are sorted by ID
+	var p1, p3 = pms[1], pms[3]
+	if p3.ID.String() < p1.ID.String() {
+		p1, p3 = p3, p1
+	}
+
+	res, err := svc.Find(ctx, f, sl, payment.Sort{
+		{Field: payment.SortFieldCurrency, Dir: payment.SortAscending},
+		{Field: payment.SortFieldAmount, Dir: payment.SortDescending},
+	}, payment.Chunk{})
+	require.NoError(t, err)
+	assert.Equal(t, []payment.Pymt{p1, p3, pms[2], pms[0]}, res)
+
+	res, err = svc.Find(ctx, f, sl, payment.Sort{
+		{Field: payment.SortFieldAmount, Dir: payment.SortDescending},
+		{Field: payment.SortFieldID, Dir: payment.SortDescending},
+	}, payment.Chunk{})
+	require.NoError(t, err)
+	assert.Equal(t, []payment.Pymt{pms[2], p3, p1, pms[0]}, res)
+
+	t.Run("error: invalid sort", func(t *testing.T) {
+		var _, err = svc.Find(ctx, f, sl, payment.Sort{
+			{Field: payment.SortFieldAmount, Dir: payment.SortDescending},
+			{Field: payment.SortFieldAmount, Dir: payment.SortAscending},
+		}, payment.Chunk{})
+		testutil.AssertError(t, err, payment.ErrInvalidArgSort)
+	})
+
+	t.Run("error: encrypted field", func(t *testing.T) {
+		var kr, err = sqlite.NewKeyRing("k", map[string][]byte{"k": make([]byte, sqlite.EncryptionKeyLen)})
+		require.NoError(t, err)
+
+		esvc, err := sqlite.New(testingDB, sqlite.WithFieldEncryption(kr))
+		require.NoError(t, err)
+
+		_, err = esvc.Find(ctx, f, sl, payment.Sort{
+			{Field: payment.SortFieldDebtorName, Dir: payment.SortAscending},
+		}, payment.Chunk{})
+		testutil.AssertError(t, err, payment.ErrInvalidArgSortNotSupported)
+
+		// They can be sorted without encryption
+		_, err = svc.Find(ctx, f, sl, payment.Sort{
+			{Field: payment.SortFieldDebtorName, Dir: payment.SortAscending},
+		}, payment.Chunk{})
+		assert.NoError(t, err)
+	})
+}
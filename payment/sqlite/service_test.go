@@ -147,7 +147,7 @@ func TestService_Delete(t *testing.T) {
 		var s, err = sqlite.New(testingDB)
 		require.NoError(t, err)
 
-		err = s.Delete(context.Background(), uuid.Nil)
+		err = s.Delete(context.Background(), uuid.Nil, 0)
 		testutil.AssertError(t, err, payment.ErrInvalidPaymentID, payment.ErrMDArg("id", uuid.Nil))
 	})
 }
@@ -183,3 +183,41 @@ func TestService_Update(t *testing.T) {
 		testutil.AssertError(t, err, ec)
 	})
 }
+
+func TestService_Patch(t *testing.T) {
+	t.Run("error nil UUID", func(t *testing.T) {
+		var s, err = sqlite.New(testingDB)
+		require.NoError(t, err)
+
+		err = s.Patch(context.Background(), uuid.Nil, 0, payment.Patch{})
+		testutil.AssertError(t, err, payment.ErrInvalidPaymentID, payment.ErrMDArg("id", uuid.Nil))
+	})
+}
+
+func TestService_History(t *testing.T) {
+	t.Run("error nil UUID", func(t *testing.T) {
+		var s, err = sqlite.New(testingDB)
+		require.NoError(t, err)
+
+		_, err = s.History(context.Background(), uuid.Nil)
+		testutil.AssertError(t, err, payment.ErrInvalidPaymentID, payment.ErrMDArg("id", uuid.Nil))
+	})
+}
+
+func TestService_Transition(t *testing.T) {
+	t.Run("error nil UUID", func(t *testing.T) {
+		var s, err = sqlite.New(testingDB)
+		require.NoError(t, err)
+
+		err = s.Transition(context.Background(), uuid.Nil, 0, payment.StatusSubmitted, "")
+		testutil.AssertError(t, err, payment.ErrInvalidPaymentID, payment.ErrMDArg("id", uuid.Nil))
+	})
+
+	t.Run("error invalid status", func(t *testing.T) {
+		var s, err = sqlite.New(testingDB)
+		require.NoError(t, err)
+
+		err = s.Transition(context.Background(), testutil.NewUUID(t), 0, payment.Status(0), "")
+		testutil.AssertError(t, err, payment.ErrInvalidArgStatus, payment.ErrMDArg("to", payment.Status(0)))
+	})
+}
@@ -9,8 +9,10 @@ import (
 	"testing"
 
 	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ifraixedes/go-payments-api-example/payment"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
 )
 
 func TestNew(t *testing.T) {
@@ -101,3 +103,43 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleSQLiteErr(t *testing.T) {
+	var tcases = []struct {
+		desc string
+		err  error
+		code errors.Code
+	}{
+		{
+			desc: "common sqlite error",
+			err:  sqlite3.NewError(sqlite3.BUSY, "database is locked"),
+			code: payment.ErrAbortedOperation,
+		},
+		{
+			desc: "schema changed",
+			err:  sqlite3.NewError(sqlite3.SCHEMA, "database schema has changed"),
+			code: ErrDBSchemaChanged,
+		},
+		{
+			desc: "not common sqlite error",
+			err:  sqlite3.NewError(sqlite3.CONSTRAINT, "constraint failed"),
+			code: payment.ErrUnexpectedStoreError,
+		},
+		{
+			desc: "not sqlite error",
+			err:  fmt.Errorf("other"),
+			code: payment.ErrUnexpectedStoreError,
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var c, ok = errors.GetCode(handleSQLiteErr(tc.err))
+			require.True(t, ok, "the error has a code")
+			assert.Equal(t, tc.code, c)
+		})
+	}
+}
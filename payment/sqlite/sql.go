@@ -1,6 +1,8 @@
 package sqlite
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/bvinc/go-sqlite-lite/sqlite3"
@@ -15,9 +17,12 @@ type dbScanPymt func(*sqlite3.Stmt) (payment.Pymt, error)
 
 // selectPymtColumns is a convenient function which returns a string which the
 // list of columns to be use in a payments select statement and the dbScanPymt
-// function based on s.
-func selectPymtColumns(s payment.Selection) (string, dbScanPymt) {
-	var sf = make([]string, 1, 5)
+// function based on s, which decrypts the encrypted fields with kp.
+//
+// When s selects the paths of the attributes, only them are extracted from the
+// data blob (see attrPathsColumn).
+func selectPymtColumns(s payment.Selection, kp KeyProvider) (string, dbScanPymt) {
+	var sf = make([]string, 1, 6)
 
 	sf[0] = "id"
 
@@ -29,22 +34,29 @@ func selectPymtColumns(s payment.Selection) (string, dbScanPymt) {
 		sf = append(sf, "organisation_id")
 	}
 
+	if s.Status {
+		sf = append(sf, "status")
+	}
+
 	if s.Type {
 		sf = append(sf, "json_extract(data, '$.type') as type")
 	}
 
 	if s.Attributes {
 		sf = append(sf, "data")
+	} else if paths := s.AttrPaths(); len(paths) > 0 {
+		sf = append(sf, attrPathsColumn(paths))
 	}
 
 	return strings.Join(sf, ", "), func(stmt *sqlite3.Stmt) (payment.Pymt, error) {
-		return dbScanPymtFromSelection(s, stmt)
+		return dbScanPymtFromSelection(s, stmt, kp)
 	}
 }
 
 //  dbScanPymtFromSelection scans the columns of a row of payments select
-// statement indicated by sl and using stmt.
-func dbScanPymtFromSelection(sl payment.Selection, stmt *sqlite3.Stmt) (payment.Pymt, error) {
+// statement indicated by sl and using stmt. The encrypted fields are decrypted
+// with kp.
+func dbScanPymtFromSelection(sl payment.Selection, stmt *sqlite3.Stmt, kp KeyProvider) (payment.Pymt, error) {
 	var (
 		p    payment.Pymt
 		cidx = 0
@@ -90,6 +102,22 @@ func dbScanPymtFromSelection(sl payment.Selection, stmt *sqlite3.Stmt) (payment.
 		}
 	}
 
+	if sl.Status {
+		s, _, err := stmt.ColumnText(cidx)
+		if err != nil {
+			return p, errors.Wrap(
+				err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.ColumnText", cidx),
+			)
+		}
+
+		cidx++
+		var ok bool
+		p.Status, ok = payment.ParseStatus(s)
+		if !ok {
+			return p, errors.New(ErrInvalidFormatStatus, payment.ErrMDVar("status", s))
+		}
+	}
+
 	if sl.Type {
 		s, _, err := stmt.ColumnText(cidx)
 		if err != nil {
@@ -111,17 +139,94 @@ func dbScanPymtFromSelection(sl payment.Selection, stmt *sqlite3.Stmt) (payment.
 		}
 
 		var pd pymtData
-		if err = pd.Deserialize(b); err != nil {
-			return p, err
+		if err = pd.Deserialize(b, kp); err != nil {
+			var c, _ = errors.GetCode(err)
+			return p, errors.Wrap(err, c, payment.ErrMDVar("id", p.ID))
 		}
 
 		p.Attributes = pd.Attrs
 		p.Type = pd.Type
+	} else if paths := sl.AttrPaths(); len(paths) > 0 {
+		s, _, err := stmt.ColumnText(cidx)
+		if err != nil {
+			return p, errors.Wrap(
+				err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.ColumnText", cidx),
+			)
+		}
+
+		var pd pymtData
+		if err = deserializeAttrPaths(&pd, s, kp); err != nil {
+			var c, _ = errors.GetCode(err)
+			return p, errors.Wrap(err, c, payment.ErrMDVar("id", p.ID))
+		}
+
+		p.Attributes = pd.Attrs
 	}
 
 	return p, nil
 }
 
+// attrPathsColumn returns the expression of the column which extracts the
+// attribute paths from the data blob, which is a JSON object whose keys are
+// the paths. The envelope of the encrypted fields is also extracted when any of
+// the paths contains a sensitive field (see sensitiveFields).
+//
+// The paths are interpolated because they are names of the attributes, which
+// are validated by payment.NewSelection.
+func attrPathsColumn(paths []string) string {
+	var args = make([]string, 0, len(paths)+1)
+	for _, p := range paths {
+		args = append(args, fmt.Sprintf("'%s', json_extract(data, '$.%s')", p, p))
+	}
+
+	if selectsSensitiveFields(paths) {
+		args = append(args, "'encryption', json_extract(data, '$.encryption')")
+	}
+
+	return fmt.Sprintf("json_object(%s) as attrs", strings.Join(args, ", "))
+}
+
+// deserializeAttrPaths initializes pd from the JSON object s returned by the
+// column of attrPathsColumn. The encrypted fields are decrypted with kp.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidFormatBlob
+//
+// * Any of the errors returned by pymtData.Deserialize.
+func deserializeAttrPaths(pd *pymtData, s string, kp KeyProvider) error {
+	var vals map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &vals); err != nil {
+		return errors.Wrap(err, ErrInvalidFormatBlob)
+	}
+
+	var data = map[string]interface{}{}
+	for p, v := range vals {
+		var (
+			obj   = data
+			names = strings.Split(p, ".")
+		)
+		for _, n := range names[:len(names)-1] {
+			var o, ok = obj[n].(map[string]interface{})
+			if !ok {
+				o = map[string]interface{}{}
+				obj[n] = o
+			}
+
+			obj = o
+		}
+
+		obj[names[len(names)-1]] = v
+	}
+
+	var b, err = json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, ErrInvalidFormatBlob)
+	}
+
+	return pd.Deserialize(b, kp)
+}
+
 func leafField(fl payment.FilterLeaf) string {
 	switch fl.(type) {
 	case payment.FilterLeafAmount:
@@ -130,6 +235,10 @@ func leafField(fl payment.FilterLeaf) string {
 		return "json_extract(data, '$.type')"
 	case payment.FilterLeafID:
 		return "id"
+	case payment.FilterLeafOrgID:
+		return "organisation_id"
+	case payment.FilterLeafStatus:
+		return "status"
 	}
 
 	// This happens is that new filters have been added and this function has not
@@ -137,30 +246,58 @@ func leafField(fl payment.FilterLeaf) string {
 	return ""
 }
 
-func orderByColumns(s payment.Sort) string {
-	var cols []string
-
-	if s.ID.Valid() {
-		cols = append(cols, "id "+orderDir(s.ID))
+// orderByColumns returns the columns of the ORDER BY clause which sorts by the
+// keys of s, always ending with the ID for a stable order. encrypted indicates
+// if the sensitive fields are encrypted, which cannot be used for sorting.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrInvalidArgSort
+//
+// * payment.ErrInvalidArgSortNotSupported - When encrypted is true and s has
+// a sensitive field (see sensitiveFields).
+func orderByColumns(s payment.Sort, encrypted bool) (string, error) {
+	if err := s.Validate(); err != nil {
+		return "", err
 	}
 
-	if s.Type.Valid() {
-		cols = append(cols, "json_extract(data, '$.type') "+orderDir(s.Type))
-	}
-
-	if s.Version.Valid() {
-		cols = append(cols, "version "+orderDir(s.Version))
-	}
+	var (
+		cols  = make([]string, 0, len(s)+1)
+		hasID bool
+	)
+	for _, k := range s {
+		var col string
+		switch k.Field {
+		case payment.SortFieldID:
+			col = "id"
+			hasID = true
+		case payment.SortFieldVersion:
+			col = "version"
+		case payment.SortFieldOrgID:
+			col = "organisation_id"
+		case payment.SortFieldStatus:
+			col = "status"
+		case payment.SortFieldType:
+			col = "json_extract(data, '$.type')"
+		default:
+			var path = strings.TrimPrefix(k.Field.String(), "attributes.")
+			if encrypted && selectsSensitiveFields([]string{path}) {
+				return "", errors.New(payment.ErrInvalidArgSortNotSupported, payment.ErrMDArg("s", k.Field))
+			}
+
+			// The paths are interpolated because they are the names of the attributes
+			// of the valid fields
+			col = fmt.Sprintf("json_extract(data, '$.%s')", path)
+		}
 
-	if s.OrgID.Valid() {
-		cols = append(cols, "organisation_id "+orderDir(s.OrgID))
+		cols = append(cols, col+" "+orderDir(k.Dir))
 	}
 
-	if s.Attributes.Amount.Valid() {
-		cols = append(cols, "json_extract(data, '$.amount') "+orderDir(s.Attributes.Amount))
+	if !hasID {
+		cols = append(cols, "id ASC")
 	}
 
-	return strings.Join(cols, ",")
+	return strings.Join(cols, ","), nil
 }
 
 func orderDir(s payment.SortDir) string {
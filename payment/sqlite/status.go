@@ -0,0 +1,228 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// History satisfies the payment.Service interface.
+//
+// The function will return all the errors that payment.Service documents plus
+// the following ones:
+//
+// * ErrDBCantOpen
+//
+// * ErrInvalidFormatStatus
+func (s *service) History(ctx context.Context, id uuid.UUID) ([]payment.StatusChange, error) {
+	if id == uuid.Nil {
+		return nil, errors.New(payment.ErrInvalidPaymentID, payment.ErrMDArg("id", id))
+	}
+
+	var conn, pc, err = s.openConn(ctx)
+	if err != nil {
+		return nil, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var where, args = scopeWhere(ctx, "id = ?", id.String())
+	stmt, err := prepare(
+		ctx, conn,
+		//nolint:gosec
+		fmt.Sprintf(`SELECT from_status, to_status, reason, payment_version, created_at FROM payment_status_changes
+		WHERE payment_id = ? AND EXISTS (SELECT 1 FROM payments WHERE %s) ORDER BY seq`, where),
+		append([]interface{}{id.String()}, args...)...,
+	)
+	if err != nil {
+		return nil, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	var scs []payment.StatusChange
+	var span = startStep(ctx)
+	defer func() {
+		span.SetAttr("db.rows", len(scs))
+		span.End()
+	}()
+
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			return nil, handleSQLiteErr(err)
+		}
+		if !ok {
+			break
+		}
+
+		sc, err := dbScanStatusChange(stmt)
+		if err != nil {
+			return nil, err
+		}
+
+		scs = append(scs, sc)
+	}
+
+	if len(scs) == 0 {
+		return nil, errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+	}
+
+	return scs, nil
+}
+
+// Transition satisfies the payment.Service interface.
+//
+// The function will return all the errors that payment.Service documents plus
+// the following ones:
+//
+// * ErrDBCantOpen
+//
+// * ErrInvalidFormatBlob
+//
+// * ErrInvalidFormatStatus
+func (s *service) Transition(
+	ctx context.Context, id uuid.UUID, ver uint32, to payment.Status, reason string,
+) error {
+	if id == uuid.Nil {
+		return errors.New(payment.ErrInvalidPaymentID, payment.ErrMDArg("id", id))
+	}
+
+	if !to.Valid() {
+		return errors.New(payment.ErrInvalidArgStatus, payment.ErrMDArg("to", to))
+	}
+
+	var conn, pc, err = s.openConn(ctx)
+	if err != nil {
+		return wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// See the comment in the Update method about why errtx var exists.
+	// The transaction is immediate because it reads the payment before updating
+	// it, see the comment in the Delete method.
+	var errtx = conn.WithTxImmediate(func() error {
+		var p payment.Pymt
+		p, err = getPymt(ctx, conn, id, payment.SelectAll(), s.keys)
+		if err != nil {
+			return err
+		}
+
+		if p.Version != ver {
+			err = errors.New(payment.ErrInvalidArgVersionMismatch,
+				payment.ErrMDArg("version", ver), payment.ErrMDFact("current_version", p.Version),
+			)
+			return err
+		}
+
+		if !p.Status.CanTransitionTo(to) {
+			err = errors.New(payment.ErrInvalidArgStatusTransition,
+				payment.ErrMDArg("to", to), payment.ErrMDFact("current_status", p.Status),
+			)
+			return err
+		}
+
+		err = conn.Exec(
+			"UPDATE payments SET version = version + 1, status = ? WHERE id = ?", to.String(), id.String(),
+		)
+		if err != nil {
+			err = handleSQLiteErr(err)
+			return err
+		}
+
+		var from = p.Status
+		p.Version++
+		p.Status = to
+
+		err = insertStatusChange(conn, id, payment.StatusChange{
+			From:    from,
+			To:      to,
+			Reason:  reason,
+			Version: p.Version,
+			At:      time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = insertEvent(conn, payment.EventTypeUpdated, p, s.keys)
+		return err
+	})
+
+	if err == nil && errtx != nil {
+		return errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.changes.notify()
+	return nil
+}
+
+// insertStatusChange inserts sc as a status change of the payment with ID id.
+//
+// The following error codes can be returned:
+//
+// * Any of the errors returned by handleSQLiteErr
+func insertStatusChange(conn *sqlite3.Conn, id uuid.UUID, sc payment.StatusChange) error {
+	var from interface{}
+	if sc.From.Valid() {
+		from = sc.From.String()
+	}
+
+	var err = conn.Exec(
+		`INSERT INTO payment_status_changes(payment_id, from_status, to_status, reason, payment_version, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		id.String(), from, sc.To.String(), sc.Reason, int64(sc.Version), sc.At.UnixNano(),
+	)
+	if err != nil {
+		return handleSQLiteErr(err)
+	}
+
+	return nil
+}
+
+// dbScanStatusChange scans the columns of a row of payment_status_changes
+// select statement which selects from_status, to_status, reason,
+// payment_version and created_at columns in that order.
+func dbScanStatusChange(stmt *sqlite3.Stmt) (payment.StatusChange, error) {
+	var (
+		sc       payment.StatusChange
+		from, to string
+		ver, at  int64
+	)
+
+	if err := stmt.Scan(&from, &to, &sc.Reason, &ver, &at); err != nil {
+		return sc, handleSQLiteErr(err)
+	}
+
+	if from != "" {
+		var ok bool
+		if sc.From, ok = payment.ParseStatus(from); !ok {
+			return sc, errors.New(ErrInvalidFormatStatus, payment.ErrMDVar("from_status", from))
+		}
+	}
+
+	var ok bool
+	if sc.To, ok = payment.ParseStatus(to); !ok {
+		return sc, errors.New(ErrInvalidFormatStatus, payment.ErrMDVar("to_status", to))
+	}
+
+	sc.Version = uint32(ver)
+	sc.At = time.Unix(0, at)
+
+	return sc, nil
+}
@@ -0,0 +1,124 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Transition_History(t *testing.T) {
+	var svc, err = sqlite.New(testingDB)
+	require.NoError(t, err)
+
+	var (
+		ctx   = context.Background()
+		start = time.Now()
+		npymt = payment.PymtUpsert{
+			Type:  "Payment",
+			OrgID: testutil.NewUUID(t),
+		}
+	)
+	npymt.Attributes = testutil.NewAttrs(t)
+
+	pid, err := svc.Create(ctx, npymt)
+	require.NoError(t, err)
+	defer deletePymt(t, pid)
+
+	t.Run("error: version mismatch", func(t *testing.T) {
+		var err = svc.Transition(ctx, pid, 1, payment.StatusSubmitted, "")
+		testutil.AssertError(t, err, payment.ErrInvalidArgVersionMismatch,
+			payment.ErrMDArg("version", uint32(1)), payment.ErrMDFact("current_version", uint32(0)),
+		)
+	})
+
+	t.Run("error: not allowed transition", func(t *testing.T) {
+		var err = svc.Transition(ctx, pid, 0, payment.StatusSettled, "")
+		testutil.AssertError(t, err, payment.ErrInvalidArgStatusTransition,
+			payment.ErrMDArg("to", payment.StatusSettled), payment.ErrMDFact("current_status", payment.StatusPending),
+		)
+	})
+
+	t.Run("error: not found", func(t *testing.T) {
+		var id = testutil.NewUUID(t)
+		var err = svc.Transition(ctx, id, 0, payment.StatusSubmitted, "")
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", id))
+
+		_, err = svc.History(ctx, id)
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", id))
+	})
+
+	err = svc.Transition(ctx, pid, 0, payment.StatusSubmitted, "sent to the scheme")
+	require.NoError(t, err)
+
+	t.Run("error: update once submitted", func(t *testing.T) {
+		var err = svc.Update(ctx, pid, 1, npymt)
+		testutil.AssertError(t, err, payment.ErrNotEditable,
+			payment.ErrMDVar("id", pid), payment.ErrMDFact("current_status", payment.StatusSubmitted.String()),
+		)
+	})
+
+	t.Run("error: delete once submitted", func(t *testing.T) {
+		var err = svc.Delete(ctx, pid, 1)
+		testutil.AssertError(t, err, payment.ErrNotEditable,
+			payment.ErrMDVar("id", pid), payment.ErrMDFact("current_status", payment.StatusSubmitted),
+		)
+
+		_, err = svc.Get(ctx, pid, payment.Selection{})
+		assert.NoError(t, err, "the payment isn't deleted")
+	})
+
+	err = svc.Transition(ctx, pid, 1, payment.StatusSettled, "")
+	require.NoError(t, err)
+
+	p, err := svc.Get(ctx, pid, payment.SelectAll())
+	require.NoError(t, err)
+	assert.Equal(t, payment.Pymt{ID: pid, Version: 2, Status: payment.StatusSettled, PymtUpsert: npymt}, p)
+
+	hist, err := svc.History(ctx, pid)
+	require.NoError(t, err)
+	require.Len(t, hist, 3)
+
+	for i, sc := range hist {
+		assert.True(t, !sc.At.Before(start.Truncate(time.Second)), "status change %d time", i)
+		hist[i].At = time.Time{}
+	}
+
+	assert.Equal(t, []payment.StatusChange{
+		{To: payment.StatusPending},
+		{From: payment.StatusPending, To: payment.StatusSubmitted, Reason: "sent to the scheme", Version: 1},
+		{From: payment.StatusSubmitted, To: payment.StatusSettled, Version: 2},
+	}, hist)
+
+	t.Run("filter and sort by status", func(t *testing.T) {
+		var op = npymt
+		op.OrgID = testutil.NewUUID(t)
+
+		var opid, err = svc.Create(ctx, op)
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, svc.Delete(ctx, opid, 0))
+		}()
+
+		f, err := payment.NewFilterByStatus(payment.FilterCmpEqual, payment.StatusSettled)
+		require.NoError(t, err)
+
+		pms, err := svc.Find(ctx, f, payment.Selection{Status: true}, payment.Sort{}, payment.Chunk{})
+		require.NoError(t, err)
+		assert.Equal(t, []payment.Pymt{{ID: pid, Status: payment.StatusSettled}}, pms)
+
+		pms, err = svc.Find(
+			ctx, payment.Filter{}, payment.Selection{Status: true},
+			payment.Sort{{Field: payment.SortFieldStatus, Dir: payment.SortAscending}}, payment.Chunk{},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []payment.Pymt{
+			{ID: opid, Status: payment.StatusPending}, {ID: pid, Status: payment.StatusSettled},
+		}, pms)
+	})
+}
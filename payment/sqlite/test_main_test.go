@@ -8,6 +8,9 @@ import (
 	"time"
 
 	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //nolint:gochecknoglobals
@@ -40,9 +43,62 @@ func initDB() {
 		os.Exit(1)
 	}
 
+	err = conn.Exec("DELETE from payment_events; DELETE FROM sqlite_sequence WHERE name='payment_events'")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ABORTED: error when deleting all records of 'payment_events' table: %+v", err)
+		os.Exit(1)
+	}
+
+	err = conn.Exec(
+		"DELETE FROM webhook_deliveries; DELETE FROM webhook_endpoints; UPDATE webhook_cursor SET next_seq = 0",
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ABORTED: error when deleting all records of the webhook tables: %+v", err)
+		os.Exit(1)
+	}
+
+	err = conn.Exec(
+		"DELETE FROM payment_status_changes; DELETE FROM sqlite_sequence WHERE name='payment_status_changes'",
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ABORTED: error when deleting all records of 'payment_status_changes' table: %+v", err)
+		os.Exit(1)
+	}
+
+	err = conn.Exec("DELETE FROM api_keys")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ABORTED: error when deleting all records of 'api_keys' table: %+v", err)
+		os.Exit(1)
+	}
+
+	err = conn.Exec("DELETE FROM idempotency_keys")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ABORTED: error when deleting all records of 'idempotency_keys' table: %+v", err)
+		os.Exit(1)
+	}
+
+	err = conn.Exec("DELETE FROM organisation_settings")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ABORTED: error when deleting all records of 'organisation_settings' table: %+v", err)
+		os.Exit(1)
+	}
+
 	err = conn.Close()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ABORTED: error when closing the connection which init the DB for testing: %+v", err)
 		os.Exit(1)
 	}
 }
+
+// deletePymt deletes the payment id straight from the DB, which allows to clean
+// up the payments which aren't editable, hence the service cannot delete them.
+func deletePymt(t *testing.T, id uuid.UUID) {
+	var conn, err = sqlite3.Open(testingDB, sqlite3.OPEN_READWRITE)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, conn.Close())
+	}()
+
+	conn.BusyTimeout(5 * time.Second)
+	assert.NoError(t, conn.Exec("DELETE FROM payments WHERE id = ?", id.String()))
+}
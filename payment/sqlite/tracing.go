@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/tracing"
+)
+
+// The spans which are started by the service when the context carries a span
+// (see tracing.Start).
+const (
+	spanFilterSQL = "sqlite.filterSQL"
+	spanOpenConn  = "sqlite.openConn"
+	spanPrepare   = "sqlite.prepare"
+	spanStep      = "sqlite.step"
+	spanValidate  = "payment.PymtUpsert.Validate"
+)
+
+// validate validates p recording a span of it.
+func validate(ctx context.Context, p payment.PymtUpsert) error {
+	var _, span = tracing.Start(ctx, spanValidate)
+	defer span.End()
+
+	var err = p.Validate()
+	span.SetError(err)
+	return err
+}
+
+// prepare prepares, using conn, the statement query with args recording a span
+// of it, which has the attribute "db.statement" with query.
+//
+// The errors are the ones returned by sqlite3.Conn.Prepare, hence they must be
+// handled by the caller.
+func prepare(ctx context.Context, conn *sqlite3.Conn, query string, args ...interface{}) (*sqlite3.Stmt, error) {
+	var _, span = tracing.Start(ctx, spanPrepare)
+	defer span.End()
+
+	span.SetAttr("db.statement", query)
+	return conn.Prepare(query, args...)
+}
+
+// startStep starts the span of the loop which steps over the rows of a
+// statement. The caller must set the attribute "db.rows" with the number of
+// rows and end it.
+func startStep(ctx context.Context) *tracing.Span {
+	var _, span = tracing.Start(ctx, spanStep)
+	return span
+}
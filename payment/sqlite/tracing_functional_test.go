@@ -0,0 +1,78 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/ifraixedes/go-payments-api-example/payment/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_tracing(t *testing.T) {
+	var dbfn, rmdb = newSchemaDB(t)
+	defer rmdb()
+
+	var ssvc, err = sqlite.New(dbfn)
+	require.NoError(t, err)
+
+	var (
+		exp = &tracing.InMemoryExporter{}
+		svc = tracing.New(ssvc, tracing.NewTracer(exp))
+		ctx = context.Background()
+		np  = payment.PymtUpsert{Type: "Payment", OrgID: testutil.NewUUID(t), Attributes: testutil.NewAttrs(t)}
+	)
+
+	pid, err := svc.Create(ctx, np)
+	require.NoError(t, err)
+
+	var spans = exp.Spans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "payment.PymtUpsert.Validate", spans[0].Name)
+	assert.Equal(t, "sqlite.openConn", spans[1].Name)
+	assert.Equal(t, "payment.Service.Create", spans[2].Name)
+	for _, s := range spans[:2] {
+		assert.Equal(t, spans[2].Context.TraceID, s.Context.TraceID)
+		assert.Equal(t, spans[2].Context.SpanID, s.ParentID)
+	}
+
+	exp.Reset()
+	f, err := payment.NewFilterByID(payment.FilterCmpEqual, pid)
+	require.NoError(t, err)
+	ps, err := svc.Find(ctx, f, payment.SelectAll(), payment.Sort{}, payment.Chunk{})
+	require.NoError(t, err)
+	require.Len(t, ps, 1)
+
+	spans = exp.Spans()
+	var names = make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name
+	}
+	assert.Equal(t, []string{
+		"sqlite.filterSQL", "sqlite.openConn", "sqlite.prepare", "sqlite.step", "payment.Service.Find",
+	}, names)
+	var rows, _ = spans[3].Attr("db.rows")
+	assert.Equal(t, 1, rows)
+	var stmt, _ = spans[2].Attr("db.statement")
+	assert.Contains(t, stmt, "WHERE id = ?")
+
+	exp.Reset()
+	_, err = svc.Get(ctx, testutil.NewUUID(t), payment.SelectAll())
+	testutil.AssertError(t, err, payment.ErrNotFound)
+
+	spans = exp.Spans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "sqlite.prepare", spans[1].Name)
+	assert.Equal(t, "payment.Service.Get", spans[2].Name)
+	assert.Equal(t, "NotFound", spans[2].ErrCode)
+
+	t.Run("without span in the context", func(t *testing.T) {
+		exp.Reset()
+		_, err := ssvc.Get(ctx, pid, payment.SelectAll())
+		require.NoError(t, err)
+		assert.Empty(t, exp.Spans())
+	})
+}
@@ -0,0 +1,541 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/webhook"
+	"go.fraixed.es/errors"
+)
+
+// NewWebhookStore creates an instance of the SQLite implementation of the
+// webhook Store.
+//
+// The payloads of the deliveries are encrypted when opts contains
+// WithFieldEncryption, which must be the same than the one passed to New,
+// because the payloads contain the payments; the rest of options are ignored.
+//
+// fname accepts the same values than New and the same error codes can be
+// returned.
+func NewWebhookStore(fname string, opts ...Option) (webhook.Store, error) {
+	var svc, err = newService(fname, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhookStore{svc: svc}, nil
+}
+
+type webhookStore struct {
+	svc *service
+}
+
+// CreateEndpoint satisfies the webhook.Store interface.
+//
+// The function will return all the errors that webhook.Store documents plus
+// ErrDBCantOpen.
+func (ws *webhookStore) CreateEndpoint(ctx context.Context, e webhook.EndpointUpsert) (uuid.UUID, error) {
+	if err := e.Validate(); err != nil {
+		return uuid.Nil, err
+	}
+
+	var id, err = uuid.NewV4()
+	if err != nil {
+		return uuid.Nil, errors.Wrap(err, payment.ErrUnexpectedStoreError)
+	}
+
+	var ets = make([]string, len(e.EventTypes))
+	for i, t := range e.EventTypes {
+		ets[i] = t.String()
+	}
+
+	etsb, err := json.Marshal(ets)
+	if err != nil {
+		return uuid.Nil, errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	conn, pc, err := ws.svc.openConn(ctx)
+	if err != nil {
+		return uuid.Nil, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	err = conn.Exec(
+		"INSERT INTO webhook_endpoints(id, organisation_id, url, secret, event_types) VALUES (?, ?, ?, ?, ?)",
+		id.String(), e.OrgID.String(), e.URL, e.Secret, string(etsb),
+	)
+	if err != nil {
+		return uuid.Nil, handleSQLiteErr(err)
+	}
+
+	return id, nil
+}
+
+// DeleteEndpoint satisfies the webhook.Store interface.
+func (ws *webhookStore) DeleteEndpoint(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error {
+	var conn, pc, err = ws.svc.openConn(ctx)
+	if err != nil {
+		return wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// See the comment in the service Update method about why errtx var exists
+	var errtx = conn.WithTx(func() error {
+		err = conn.Exec(
+			"DELETE FROM webhook_endpoints WHERE id = ? AND organisation_id = ?", id.String(), orgID.String(),
+		)
+		if err != nil {
+			err = handleSQLiteErr(err)
+			return err
+		}
+
+		if conn.Changes() == 0 {
+			err = errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+			return err
+		}
+
+		err = conn.Exec("DELETE FROM webhook_deliveries WHERE endpoint_id = ?", id.String())
+		if err != nil {
+			err = handleSQLiteErr(err)
+			return err
+		}
+
+		return nil
+	})
+
+	if err == nil && errtx != nil {
+		return errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
+	}
+
+	return err
+}
+
+// GetEndpoint satisfies the webhook.Store interface.
+func (ws *webhookStore) GetEndpoint(
+	ctx context.Context, orgID uuid.UUID, id uuid.UUID,
+) (webhook.Endpoint, error) {
+	var eps, err = ws.findEndpoints(ctx, "id = ? AND organisation_id = ?", id.String(), orgID.String())
+	if err != nil {
+		return webhook.Endpoint{}, err
+	}
+
+	if len(eps) == 0 {
+		return webhook.Endpoint{}, errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+	}
+
+	return eps[0], nil
+}
+
+// FindEndpoints satisfies the webhook.Store interface.
+func (ws *webhookStore) FindEndpoints(ctx context.Context, orgID uuid.UUID) ([]webhook.Endpoint, error) {
+	return ws.findEndpoints(ctx, "organisation_id = ?", orgID.String())
+}
+
+// findEndpoints returns the endpoints which fulfill the SQL where condition
+// with its args.
+func (ws *webhookStore) findEndpoints(
+	ctx context.Context, where string, args ...interface{},
+) ([]webhook.Endpoint, error) {
+	var conn, pc, err = ws.svc.openConn(ctx)
+	if err != nil {
+		return nil, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	stmt, err := conn.Prepare(
+		"SELECT id, organisation_id, url, secret, event_types FROM webhook_endpoints WHERE "+where+" ORDER BY id",
+		args...,
+	)
+	if err != nil {
+		return nil, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	var eps []webhook.Endpoint
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			return nil, handleSQLiteErr(err)
+		}
+		if !ok {
+			break
+		}
+
+		var (
+			e                webhook.Endpoint
+			id, orgID, etsjs string
+		)
+		if err := stmt.Scan(&id, &orgID, &e.URL, &e.Secret, &etsjs); err != nil {
+			return nil, errors.Wrap(err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.Scan"))
+		}
+
+		if e.ID, err = uuid.FromString(id); err != nil {
+			return nil, errors.Wrap(err, ErrInvalidFormatID, payment.ErrMDVar("id", id))
+		}
+
+		if e.OrgID, err = uuid.FromString(orgID); err != nil {
+			return nil, errors.Wrap(err, ErrInvalidFormatID, payment.ErrMDVar("organisation_id", orgID))
+		}
+
+		var ets []string
+		if err := json.Unmarshal([]byte(etsjs), &ets); err != nil {
+			return nil, errors.Wrap(err, ErrInvalidFormatBlob, payment.ErrMDVar("event_types", etsjs))
+		}
+
+		for _, s := range ets {
+			var t, ok = payment.ParseEventType(s)
+			if !ok {
+				return nil, errors.New(ErrInvalidFormatEventType, payment.ErrMDVar("event_types", etsjs))
+			}
+
+			e.EventTypes = append(e.EventTypes, t)
+		}
+
+		eps = append(eps, e)
+	}
+
+	return eps, nil
+}
+
+// Cursor satisfies the webhook.Store interface.
+func (ws *webhookStore) Cursor(ctx context.Context) (uint64, error) {
+	var conn, pc, err = ws.svc.openConn(ctx)
+	if err != nil {
+		return 0, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	stmt, err := conn.Prepare("SELECT next_seq FROM webhook_cursor WHERE id = 1")
+	if err != nil {
+		return 0, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	ok, err := stmt.Step()
+	if err != nil {
+		return 0, handleSQLiteErr(err)
+	}
+	if !ok {
+		// The row is inserted by the DB migration
+		return 0, errors.New(payment.ErrUnexpectedStoreError, payment.ErrMDFact("webhook_cursor_row", "missing"))
+	}
+
+	seq, _, err := stmt.ColumnInt64(0)
+	if err != nil {
+		return 0, errors.Wrap(
+			err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.ColumnInt64", 0),
+		)
+	}
+
+	return uint64(seq), nil
+}
+
+// Enqueue satisfies the webhook.Store interface.
+func (ws *webhookStore) Enqueue(ctx context.Context, nextSeq uint64, ds []webhook.Delivery) error {
+	var conn, pc, err = ws.svc.openConn(ctx)
+	if err != nil {
+		return wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// See the comment in the service Update method about why errtx var exists
+	var errtx = conn.WithTx(func() error {
+		for _, d := range ds {
+			var pl []byte
+			pl, err = encryptDeliveryPayload(d.Payload, ws.svc.keys)
+			if err != nil {
+				return err
+			}
+
+			err = conn.Exec(
+				"INSERT OR IGNORE INTO webhook_deliveries(id, endpoint_id, organisation_id, event_seq, event_type, "+
+					"payload, status, attempts, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				d.ID.String(), d.EndpointID.String(), d.OrgID.String(), int64(d.EventSeq), d.EventType.String(),
+				string(pl), d.Status.String(), int64(d.Attempts), d.NextAttemptAt.UnixNano(),
+			)
+			if err != nil {
+				err = handleSQLiteErr(err)
+				return err
+			}
+		}
+
+		err = conn.Exec("UPDATE webhook_cursor SET next_seq = ? WHERE id = 1", int64(nextSeq))
+		if err != nil {
+			err = handleSQLiteErr(err)
+			return err
+		}
+
+		return nil
+	})
+
+	if err == nil && errtx != nil {
+		return errors.Wrap(errtx, payment.ErrUnexpectedStoreError)
+	}
+
+	return err
+}
+
+// webhookDeliveryColumns is the list of columns selected for scanning a
+// delivery with dbScanDelivery.
+const webhookDeliveryColumns = "id, endpoint_id, organisation_id, event_seq, event_type, payload, status, " +
+	"attempts, next_attempt_at, last_error, last_status_code"
+
+// DueDeliveries satisfies the webhook.Store interface.
+func (ws *webhookStore) DueDeliveries(ctx context.Context, now time.Time, limit uint32) ([]webhook.Delivery, error) {
+	return ws.findDeliveries(ctx,
+		"status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, event_seq LIMIT ?",
+		webhook.DeliveryStatusPending.String(), now.UnixNano(), int64(limit),
+	)
+}
+
+// DeadLetters satisfies the webhook.Store interface.
+func (ws *webhookStore) DeadLetters(ctx context.Context, orgID uuid.UUID) ([]webhook.Delivery, error) {
+	return ws.findDeliveries(ctx,
+		"organisation_id = ? AND status = ? ORDER BY event_seq",
+		orgID.String(), webhook.DeliveryStatusDead.String(),
+	)
+}
+
+// findDeliveries returns the deliveries which fulfill the SQL where condition
+// with its args.
+func (ws *webhookStore) findDeliveries(
+	ctx context.Context, where string, args ...interface{},
+) ([]webhook.Delivery, error) {
+	var conn, pc, err = ws.svc.openConn(ctx)
+	if err != nil {
+		return nil, wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	stmt, err := conn.Prepare("SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE "+where, args...)
+	if err != nil {
+		return nil, handleSQLiteErr(err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	var ds []webhook.Delivery
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			return nil, handleSQLiteErr(err)
+		}
+		if !ok {
+			break
+		}
+
+		d, err := dbScanDelivery(stmt, ws.svc.keys)
+		if err != nil {
+			return nil, err
+		}
+
+		ds = append(ds, d)
+	}
+
+	return ds, nil
+}
+
+// UpdateDelivery satisfies the webhook.Store interface.
+func (ws *webhookStore) UpdateDelivery(ctx context.Context, d webhook.Delivery) error {
+	var conn, pc, err = ws.svc.openConn(ctx)
+	if err != nil {
+		return wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	err = conn.Exec(
+		"UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, "+
+			"last_status_code = ? WHERE id = ?",
+		d.Status.String(), int64(d.Attempts), d.NextAttemptAt.UnixNano(), d.LastError, int64(d.LastStatusCode),
+		d.ID.String(),
+	)
+	if err != nil {
+		return handleSQLiteErr(err)
+	}
+
+	if conn.Changes() == 0 {
+		return errors.New(payment.ErrNotFound, payment.ErrMDVar("id", d.ID))
+	}
+
+	return nil
+}
+
+// Redeliver satisfies the webhook.Store interface.
+func (ws *webhookStore) Redeliver(ctx context.Context, orgID uuid.UUID, id uuid.UUID, at time.Time) error {
+	var conn, pc, err = ws.svc.openConn(ctx)
+	if err != nil {
+		return wrapOpenConnErr(err, pc)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	err = conn.Exec(
+		"UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ? "+
+			"WHERE id = ? AND organisation_id = ?",
+		webhook.DeliveryStatusPending.String(), at.UnixNano(), id.String(), orgID.String(),
+	)
+	if err != nil {
+		return handleSQLiteErr(err)
+	}
+
+	if conn.Changes() == 0 {
+		return errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+	}
+
+	return nil
+}
+
+// dbScanDelivery scans the columns of a row of webhook_deliveries select
+// statement which selects the webhookDeliveryColumns. The payload is decrypted
+// with kp when it's encrypted.
+func dbScanDelivery(stmt *sqlite3.Stmt, kp KeyProvider) (webhook.Delivery, error) {
+	var (
+		d                           webhook.Delivery
+		id, epID, orgID, et, st, pl string
+		seq, atts, nxt, sc          int64
+	)
+
+	var err = stmt.Scan(&id, &epID, &orgID, &seq, &et, &pl, &st, &atts, &nxt, &d.LastError, &sc)
+	if err != nil {
+		return d, errors.Wrap(err, payment.ErrUnexpectedStoreError, payment.ErrMDFnCall("sqlite3.Stmt.Scan"))
+	}
+
+	if d.ID, err = uuid.FromString(id); err != nil {
+		return d, errors.Wrap(err, ErrInvalidFormatID, payment.ErrMDVar("id", id))
+	}
+
+	if d.EndpointID, err = uuid.FromString(epID); err != nil {
+		return d, errors.Wrap(err, ErrInvalidFormatID, payment.ErrMDVar("endpoint_id", epID))
+	}
+
+	if d.OrgID, err = uuid.FromString(orgID); err != nil {
+		return d, errors.Wrap(err, ErrInvalidFormatID, payment.ErrMDVar("organisation_id", orgID))
+	}
+
+	var ok bool
+	if d.EventType, ok = payment.ParseEventType(et); !ok {
+		return d, errors.New(ErrInvalidFormatEventType, payment.ErrMDVar("event_type", et))
+	}
+
+	if d.Status, ok = webhook.ParseDeliveryStatus(st); !ok {
+		return d, errors.New(ErrInvalidFormatDeliveryStatus, payment.ErrMDVar("status", st))
+	}
+
+	if d.Payload, err = decryptDeliveryPayload([]byte(pl), kp); err != nil {
+		var c, _ = errors.GetCode(err)
+		return d, errors.Wrap(err, c, payment.ErrMDVar("id", id))
+	}
+
+	d.EventSeq = uint64(seq)
+	d.Attempts = uint32(atts)
+	d.NextAttemptAt = time.Unix(0, nxt)
+	d.LastStatusCode = int(sc)
+
+	return d, nil
+}
+
+// deliveryPayloadAD is the additional data authenticated by the encryption of
+// the delivery payloads.
+const deliveryPayloadAD = "webhook_deliveries.payload"
+
+// encryptedPayload is the payload of a delivery stored encrypted in the DB.
+// The payloads stored without encryption are the JSON bodies sent to the
+// endpoints, which don't have the encryption field.
+type encryptedPayload struct {
+	Encryption *envelope `json:"encryption"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// encryptDeliveryPayload returns the delivery payload pl to store in the DB,
+// which is encrypted with a new data key when kp isn't nil.
+//
+// The following error codes can be returned:
+//
+// * payment.ErrUnexpectedSysError
+//
+// * Any of the errors returned by newDataKey.
+func encryptDeliveryPayload(pl []byte, kp KeyProvider) ([]byte, error) {
+	if kp == nil {
+		return pl, nil
+	}
+
+	var dek, env, err = newDataKey(kp)
+	if err != nil {
+		return nil, err
+	}
+
+	ct, err := seal(dek, pl, []byte(deliveryPayloadAD))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(encryptedPayload{Encryption: env, Ciphertext: ct})
+	if err != nil {
+		return nil, errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	return b, nil
+}
+
+// decryptDeliveryPayload returns the delivery payload stored in the DB b,
+// decrypting it with kp when it's encrypted.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidFormatBlob
+//
+// * Any of the errors returned by dataKey.
+func decryptDeliveryPayload(b []byte, kp KeyProvider) ([]byte, error) {
+	var ep encryptedPayload
+	if err := json.Unmarshal(b, &ep); err != nil {
+		return nil, errors.Wrap(err, ErrInvalidFormatBlob)
+	}
+
+	if ep.Encryption == nil {
+		return b, nil
+	}
+
+	var dek, err = dataKey(ep.Encryption, kp)
+	if err != nil {
+		return nil, err
+	}
+
+	pl, err := open(dek, ep.Ciphertext, []byte(deliveryPayloadAD))
+	if err != nil {
+		return nil, errors.Wrap(err, ErrInvalidFormatBlob)
+	}
+
+	return pl, nil
+}
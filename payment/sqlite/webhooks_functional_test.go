@@ -0,0 +1,308 @@
+package sqlite_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/sqlite"
+	"github.com/ifraixedes/go-payments-api-example/payment/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestWebhookStore_Endpoints(t *testing.T) {
+	var ws, err = sqlite.NewWebhookStore(testingDB)
+	require.NoError(t, err)
+
+	var (
+		ctx = context.Background()
+		e   = webhook.EndpointUpsert{
+			OrgID:      testutil.NewUUID(t),
+			URL:        "https://example.com/hooks",
+			Secret:     "a-secret-of-enough-length",
+			EventTypes: []payment.EventType{payment.EventTypeCreated},
+		}
+	)
+
+	t.Run("error invalid endpoint", func(t *testing.T) {
+		var ie = e
+		ie.URL = "/hooks"
+
+		var _, err = ws.CreateEndpoint(ctx, ie)
+		testutil.AssertError(t, err, webhook.ErrInvalidEndpointURL, payment.ErrMDField("URL", ie.URL))
+	})
+
+	id, err := ws.CreateEndpoint(ctx, e)
+	require.NoError(t, err)
+
+	ep, err := ws.GetEndpoint(ctx, e.OrgID, id)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.Endpoint{ID: id, EndpointUpsert: e}, ep)
+
+	eps, err := ws.FindEndpoints(ctx, e.OrgID)
+	require.NoError(t, err)
+	assert.Equal(t, []webhook.Endpoint{ep}, eps)
+
+	t.Run("error: other organisation", func(t *testing.T) {
+		var orgID = testutil.NewUUID(t)
+
+		var _, err = ws.GetEndpoint(ctx, orgID, id)
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", id))
+
+		err = ws.DeleteEndpoint(ctx, orgID, id)
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", id))
+
+		// The endpoint hasn't been deleted
+		_, err = ws.GetEndpoint(ctx, e.OrgID, id)
+		assert.NoError(t, err)
+	})
+
+	err = ws.DeleteEndpoint(ctx, e.OrgID, id)
+	require.NoError(t, err)
+
+	_, err = ws.GetEndpoint(ctx, e.OrgID, id)
+	testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", id))
+
+	err = ws.DeleteEndpoint(ctx, e.OrgID, id)
+	testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", id))
+
+	err = ws.Redeliver(ctx, e.OrgID, id, time.Now())
+	testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", id))
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	var svc, err = sqlite.New(testingDB)
+	require.NoError(t, err)
+
+	ws, err := sqlite.NewWebhookStore(testingDB)
+	require.NoError(t, err)
+
+	var (
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		orgID       = testutil.NewUUID(t)
+		okRcv       = newWebhookReceiver(t, "secret-of-the-ok-endpoint")
+		failRcv     = newWebhookReceiver(t, "secret-of-the-failing-endpoint")
+	)
+	defer cancel()
+	defer okRcv.Close()
+	defer failRcv.Close()
+
+	failRcv.setStatus(http.StatusServiceUnavailable)
+
+	_, err = ws.CreateEndpoint(ctx, webhook.EndpointUpsert{
+		OrgID:      orgID,
+		URL:        okRcv.URL,
+		Secret:     okRcv.secret,
+		EventTypes: []payment.EventType{payment.EventTypeCreated, payment.EventTypeDeleted},
+	})
+	require.NoError(t, err)
+
+	failEpID, err := ws.CreateEndpoint(ctx, webhook.EndpointUpsert{
+		OrgID:  orgID,
+		URL:    failRcv.URL,
+		Secret: failRcv.secret,
+	})
+	require.NoError(t, err)
+
+	// Endpoint of another organisation which must not receive anything
+	otherRcv := newWebhookReceiver(t, "secret-of-the-other-endpoint")
+	defer otherRcv.Close()
+	_, err = ws.CreateEndpoint(ctx, webhook.EndpointUpsert{
+		OrgID:  testutil.NewUUID(t),
+		URL:    otherRcv.URL,
+		Secret: otherRcv.secret,
+	})
+	require.NoError(t, err)
+
+	var d = webhook.NewDispatcher(ws, svc.(payment.ChangeFeed), webhook.Config{
+		MaxAttempts:  2,
+		BackoffBase:  time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+	})
+
+	var (
+		runCtx, runCancel = context.WithCancel(ctx)
+		runErr            = make(chan error, 1)
+	)
+	go func() {
+		runErr <- d.Run(runCtx)
+	}()
+
+	var npymt = payment.PymtUpsert{
+		Type:  "Payment",
+		OrgID: orgID,
+	}
+	npymt.Attributes = testutil.NewAttrs(t)
+
+	// The dispatcher reads concurrently, so the writes may be aborted and have to
+	// be retried
+	var pid uuid.UUID
+	err = retryAborted(func() error {
+		var err error
+		pid, err = svc.Create(ctx, npymt)
+		return err
+	})
+	require.NoError(t, err)
+
+	err = retryAborted(func() error { return svc.Update(ctx, pid, 0, npymt) })
+	require.NoError(t, err)
+
+	err = retryAborted(func() error { return svc.Delete(ctx, pid, 1) })
+	require.NoError(t, err)
+
+	var reqs = okRcv.waitRequests(t, 2)
+	assert.Equal(t, payment.EventTypeCreated.String(), reqs[0].event)
+	assert.Equal(t, pid.String(), reqs[0].body["payment_id"])
+	assert.Equal(t, payment.EventTypeDeleted.String(), reqs[1].event)
+	assert.Equal(t, pid.String(), reqs[1].body["payment_id"])
+
+	var dls []webhook.Delivery
+	waitUntil(t, func() bool {
+		dls, err = ws.DeadLetters(ctx, orgID)
+		require.NoError(t, err)
+		return len(dls) == 3
+	})
+
+	for i, dl := range dls {
+		assert.Equal(t, failEpID, dl.EndpointID)
+		assert.Equal(t, uint32(2), dl.Attempts)
+		assert.Equal(t, http.StatusServiceUnavailable, dl.LastStatusCode)
+		assert.Equal(t, []payment.EventType{
+			payment.EventTypeCreated, payment.EventTypeUpdated, payment.EventTypeDeleted,
+		}[i], dl.EventType)
+	}
+
+	assert.Len(t, failRcv.waitRequests(t, 6), 6)
+
+	// Redeliver a dead delivery once the endpoint is healthy
+	failRcv.setStatus(http.StatusNoContent)
+	// The deliveries of other organisations can't be redelivered
+	err = d.Redeliver(ctx, testutil.NewUUID(t), dls[1].ID)
+	testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", dls[1].ID))
+
+	err = d.Redeliver(ctx, orgID, dls[1].ID)
+	require.NoError(t, err)
+
+	reqs = failRcv.waitRequests(t, 7)
+	assert.Equal(t, dls[1].ID.String(), reqs[6].delivery)
+	assert.Equal(t, payment.EventTypeUpdated.String(), reqs[6].event)
+
+	waitUntil(t, func() bool {
+		dls, err = ws.DeadLetters(ctx, orgID)
+		require.NoError(t, err)
+		return len(dls) == 2
+	})
+
+	runCancel()
+	assert.Equal(t, context.Canceled, <-runErr)
+	assert.Len(t, otherRcv.requests(), 0)
+}
+
+type webhookRequest struct {
+	delivery string
+	event    string
+	body     map[string]interface{}
+}
+
+// webhookReceiver is an HTTP test server which verifies the signature of each
+// request and records them.
+type webhookReceiver struct {
+	*httptest.Server
+	secret string
+	mu     sync.Mutex
+	status int
+	reqs   []webhookRequest
+}
+
+func newWebhookReceiver(t *testing.T, secret string) *webhookReceiver {
+	var wr = &webhookReceiver{secret: secret, status: http.StatusOK}
+
+	wr.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b, err = ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		err = webhook.VerifySignature(wr.secret, r.Header.Get(webhook.HeaderSignature), b, time.Now(), time.Minute)
+		assert.NoError(t, err)
+
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(b, &body))
+
+		wr.mu.Lock()
+		defer wr.mu.Unlock()
+
+		wr.reqs = append(wr.reqs, webhookRequest{
+			delivery: r.Header.Get(webhook.HeaderDelivery),
+			event:    r.Header.Get(webhook.HeaderEvent),
+			body:     body,
+		})
+		w.WriteHeader(wr.status)
+	}))
+
+	return wr
+}
+
+func (wr *webhookReceiver) setStatus(s int) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	wr.status = s
+}
+
+func (wr *webhookReceiver) requests() []webhookRequest {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return append([]webhookRequest(nil), wr.reqs...)
+}
+
+// waitRequests waits until the receiver has received n requests and returns
+// them. It fails the test if they aren't received before 5 seconds.
+func (wr *webhookReceiver) waitRequests(t *testing.T, n int) []webhookRequest {
+	t.Helper()
+
+	var reqs []webhookRequest
+	waitUntil(t, func() bool {
+		reqs = wr.requests()
+		return len(reqs) >= n
+	})
+
+	return reqs
+}
+
+// retryAborted calls fn until it doesn't return payment.ErrAbortedOperation
+// or it has been called 50 times.
+func retryAborted(fn func() error) error {
+	var err error
+	for i := 0; i < 50; i++ {
+		if err = fn(); !errors.Is(err, payment.ErrAbortedOperation) {
+			return err
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return err
+}
+
+// waitUntil waits until cond returns true checking it every 10 milliseconds.
+// It fails the test if cond doesn't return true before 5 seconds.
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	var timeout = time.After(5 * time.Second)
+	for !cond() {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			require.FailNow(t, "timeout waiting for the condition to be true")
+		}
+	}
+}
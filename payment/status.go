@@ -0,0 +1,125 @@
+package payment
+
+import (
+	"time"
+
+	"go.fraixed.es/errors"
+)
+
+// Status is the type which represents the stage of the lifecycle where a
+// payment is.
+//
+// The payments are created with StatusPending and they can only change their
+// status through the following transitions:
+//
+// * StatusPending -> StatusSubmitted, StatusRejected
+//
+// * StatusSubmitted -> StatusSettled, StatusRejected
+//
+// * StatusSettled -> StatusReturned
+//
+// StatusRejected and StatusReturned are final.
+type Status uint8
+
+// The list of valid Status values.
+const (
+	statusNone Status = iota
+	StatusPending
+	StatusSubmitted
+	StatusSettled
+	StatusRejected
+	StatusReturned
+)
+
+// Valid returns true if s is a valid Status value, otherwise false.
+func (s Status) Valid() bool {
+	return s > statusNone && s <= StatusReturned
+}
+
+func (s Status) String() string {
+	switch s {
+	case StatusPending:
+		return "Pending"
+	case StatusSubmitted:
+		return "Submitted"
+	case StatusSettled:
+		return "Settled"
+	case StatusRejected:
+		return "Rejected"
+	case StatusReturned:
+		return "Returned"
+	}
+
+	return ""
+}
+
+// ParseStatus returns the Status whose string representation is s. It returns
+// false if s doesn't match with any valid Status.
+func ParseStatus(s string) (Status, bool) {
+	for st := StatusPending; st <= StatusReturned; st++ {
+		if st.String() == s {
+			return st, true
+		}
+	}
+
+	return statusNone, false
+}
+
+// CanTransitionTo returns true if a payment with status s can change its status
+// to to.
+func (s Status) CanTransitionTo(to Status) bool {
+	switch s {
+	case StatusPending:
+		return to == StatusSubmitted || to == StatusRejected
+	case StatusSubmitted:
+		return to == StatusSettled || to == StatusRejected
+	case StatusSettled:
+		return to == StatusReturned
+	}
+
+	return false
+}
+
+// Editable returns true if the payments with status s can be updated. Only the
+// payments which haven't been submitted can be updated.
+func (s Status) Editable() bool {
+	return s == StatusPending
+}
+
+// MarshalText satisfies the encoding.TextMarshaler interface.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText satisfies the encoding.TextUnmarshaler interface. An empty text
+// is unmarshaled to the zero value.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidArgStatus
+func (s *Status) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = statusNone
+		return nil
+	}
+
+	var st, ok = ParseStatus(string(text))
+	if !ok {
+		return errors.New(ErrInvalidArgStatus, ErrMDArg("text", string(text)))
+	}
+
+	*s = st
+	return nil
+}
+
+// StatusChange contains the information of a status change of a payment.
+type StatusChange struct {
+	// From is the status before the change. It's the zero value for the change
+	// done when the payment was created.
+	From   Status
+	To     Status
+	Reason string
+	// Version is the version of the payment after the change.
+	Version uint32
+	At      time.Time
+}
@@ -0,0 +1,67 @@
+package payment_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatus(t *testing.T) {
+	for _, s := range []payment.Status{
+		payment.StatusPending, payment.StatusSubmitted, payment.StatusSettled,
+		payment.StatusRejected, payment.StatusReturned,
+	} {
+		var ps, ok = payment.ParseStatus(s.String())
+		assert.True(t, ok)
+		assert.Equal(t, s, ps)
+		assert.True(t, ps.Valid())
+	}
+
+	var s, ok = payment.ParseStatus("Unknown")
+	assert.False(t, ok)
+	assert.False(t, s.Valid())
+}
+
+func TestStatus_CanTransitionTo(t *testing.T) {
+	var allowed = map[payment.Status][]payment.Status{
+		payment.StatusPending:   {payment.StatusSubmitted, payment.StatusRejected},
+		payment.StatusSubmitted: {payment.StatusSettled, payment.StatusRejected},
+		payment.StatusSettled:   {payment.StatusReturned},
+	}
+
+	for from := payment.StatusPending; from <= payment.StatusReturned; from++ {
+		for to := payment.StatusPending; to <= payment.StatusReturned; to++ {
+			var exp bool
+			for _, a := range allowed[from] {
+				if a == to {
+					exp = true
+				}
+			}
+
+			assert.Equal(t, exp, from.CanTransitionTo(to), "%s -> %s", from, to)
+		}
+	}
+
+	assert.True(t, payment.StatusPending.Editable())
+	assert.False(t, payment.StatusSubmitted.Editable())
+}
+
+func TestStatus_JSON(t *testing.T) {
+	var p = payment.Pymt{Status: payment.StatusSettled}
+
+	var b, err = json.Marshal(p)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"status":"Settled"`)
+
+	var up payment.Pymt
+	require.NoError(t, json.Unmarshal(b, &up))
+	assert.Equal(t, p, up)
+
+	var s payment.Status
+	err = s.UnmarshalText([]byte("Unknown"))
+	testutil.AssertError(t, err, payment.ErrInvalidArgStatus, payment.ErrMDArg("text", "Unknown"))
+}
@@ -0,0 +1,31 @@
+package tenancy
+
+type code uint8
+
+// The list of specific error codes that the tenancy package can return.
+const (
+	ErrNoOrganisation code = iota + 1
+	ErrOrganisationMismatch
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrNoOrganisation:
+		return "NoOrganisation"
+	case ErrOrganisationMismatch:
+		return "OrganisationMismatch"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrNoOrganisation:
+		return "The context doesn't carry the organisation which the operation is scoped to"
+	case ErrOrganisationMismatch:
+		return "The organisation of the payment isn't the organisation which the operation is scoped to"
+	}
+
+	return ""
+}
@@ -0,0 +1,284 @@
+// Package tenancy scopes the operations of a payment service to the
+// organisation carried by the context, so an organisation cannot access the
+// payments of other organisations.
+package tenancy
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// WithOrgID returns a copy of ctx which carries the organisation ID orgID,
+// which the operations of the services returned by New are scoped to.
+//
+// The organisation is carried as the organisation scope of the payment
+// operations (see payment.WithOrgScope), hence it's the same that the decorated
+// services and any other decorator read.
+func WithOrgID(ctx context.Context, orgID uuid.UUID) context.Context {
+	return payment.WithOrgScope(ctx, orgID)
+}
+
+// OrgID returns the organisation ID carried by ctx.
+//
+// The following error codes can be returned:
+//
+// * ErrNoOrganisation - when ctx doesn't carry any organisation ID or it's
+// uuid.Nil.
+func OrgID(ctx context.Context) (uuid.UUID, error) {
+	var id, _ = payment.OrgScope(ctx)
+	if id == uuid.Nil {
+		return uuid.Nil, errors.New(ErrNoOrganisation)
+	}
+
+	return id, nil
+}
+
+// New returns a payment.Service which scopes the operations of svc to the
+// organisation carried by the context passed to each method (see WithOrgID):
+//
+// * Create and CreateBatch set the organisation to the payments which don't
+// have it.
+//
+// * Aggregate and Find only retrieve the payments of the organisation, adding
+// the filter by the organisation, with the AND operator, to the passed filter.
+//
+// * Delete, Get, History, Patch, Transition and Update return
+// payment.ErrNotFound for the payments of other organisations; the operation of
+// svc is scoped to the organisation by the context (see WithOrgID).
+//
+// * Update doesn't allow to change the organisation of the payment.
+//
+// * Changes only sends the events of the payments of the organisation.
+//
+// The returned payment.Service also satisfies the payment.BatchCreator and
+// payment.ChangeFeed interfaces, delegating them to svc (see
+// payment.CreateBatch and payment.Changes).
+//
+// All the methods return the errors that svc returns plus the following error
+// codes:
+//
+// * ErrNoOrganisation - when the context doesn't carry the organisation.
+//
+// * ErrOrganisationMismatch - when the organisation of the payment passed to
+// Create or Update, or of any of the payments passed to CreateBatch, isn't the
+// organisation of the context.
+//
+// The ownership of the payments is checked by svc in the same operation which
+// reads or modifies them, hence svc must honour the organisation scope, as
+// payment.Service documents, otherwise the payments of other organisations
+// could be modified.
+func New(svc payment.Service) payment.Service {
+	return service{svc: svc}
+}
+
+type service struct {
+	svc payment.Service
+}
+
+func (s service) Aggregate(
+	ctx context.Context, f payment.Filter, a payment.Aggregation,
+) ([]payment.Aggregate, error) {
+	var of, err = orgFilter(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.svc.Aggregate(ctx, of, a)
+}
+
+func (s service) Changes(ctx context.Context, fromSeq uint64) (<-chan payment.Event, <-chan error) {
+	var orgID, err = OrgID(ctx)
+	if err != nil {
+		var (
+			evtc = make(chan payment.Event)
+			errc = make(chan error, 1)
+		)
+
+		close(evtc)
+		errc <- err
+		close(errc)
+		return evtc, errc
+	}
+
+	var (
+		sevtc, serrc = payment.Changes(ctx, s.svc, fromSeq)
+		evtc         = make(chan payment.Event)
+		errc         = make(chan error, 1)
+	)
+
+	go func() {
+		defer close(errc)
+
+		for e := range sevtc {
+			if e.Pymt.OrgID != orgID {
+				continue
+			}
+
+			select {
+			case evtc <- e:
+			case <-ctx.Done():
+				// sevtc is closed because ctx is done
+			}
+		}
+
+		// The errors channel must be closed after the events channel
+		close(evtc)
+		for err := range serrc {
+			errc <- err
+		}
+	}()
+
+	return evtc, errc
+}
+
+func (s service) Create(ctx context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
+	var orgID, err = OrgID(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if p.OrgID == uuid.Nil {
+		p.OrgID = orgID
+	}
+
+	if p.OrgID != orgID {
+		return uuid.Nil, errors.New(ErrOrganisationMismatch, payment.ErrMDField("OrgID", p.OrgID))
+	}
+
+	return s.svc.Create(ctx, p)
+}
+
+func (s service) CreateBatch(ctx context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+	var orgID, err = OrgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// ps is copied for not modifying the payments of the caller
+	var ops = make([]payment.PymtUpsert, len(ps))
+	for i, p := range ps {
+		if p.OrgID == uuid.Nil {
+			p.OrgID = orgID
+		}
+
+		if p.OrgID != orgID {
+			return nil, errors.New(ErrOrganisationMismatch,
+				payment.ErrMDVar("index", i), payment.ErrMDField("OrgID", p.OrgID),
+			)
+		}
+
+		ops[i] = p
+	}
+
+	return payment.CreateBatch(ctx, s.svc, ops)
+}
+
+func (s service) Delete(ctx context.Context, id uuid.UUID, version uint32) error {
+	if _, err := OrgID(ctx); err != nil {
+		return err
+	}
+
+	return s.svc.Delete(ctx, id, version)
+}
+
+func (s service) Find(
+	ctx context.Context, f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk,
+) ([]payment.Pymt, error) {
+	var of, err = orgFilter(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.svc.Find(ctx, of, sl, st, c)
+}
+
+// orgFilter returns the filter by the organisation carried by ctx joined with
+// the AND operator to f, when it isn't empty.
+func orgFilter(ctx context.Context, f payment.Filter) (payment.Filter, error) {
+	var orgID, err = OrgID(ctx)
+	if err != nil {
+		return payment.Filter{}, err
+	}
+
+	of, err := payment.NewFilterByOrgID(payment.FilterCmpEqual, orgID)
+	if err != nil {
+		return payment.Filter{}, errors.Wrap(err, payment.ErrUnexpectedSysError)
+	}
+
+	if f.NodeType() != payment.FilterNodeTypeEmpty {
+		of, err = payment.NewFilter(payment.FilterLogicalAnd, of, f)
+		if err != nil {
+			return payment.Filter{}, errors.Wrap(err, payment.ErrUnexpectedSysError)
+		}
+	}
+
+	return of, nil
+}
+
+func (s service) Get(ctx context.Context, id uuid.UUID, sl payment.Selection) (payment.Pymt, error) {
+	var orgID, err = OrgID(ctx)
+	if err != nil {
+		return payment.Pymt{}, err
+	}
+
+	var osl = sl
+	osl.OrgID = true
+	p, err := s.svc.Get(ctx, id, osl)
+	if err != nil {
+		return payment.Pymt{}, err
+	}
+
+	// The organisation is also checked because the payment could be got from a
+	// cache (see cache.New)
+	if p.OrgID != orgID {
+		return payment.Pymt{}, errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+	}
+
+	if !sl.OrgID {
+		p.OrgID = uuid.Nil
+	}
+
+	return p, nil
+}
+
+func (s service) History(ctx context.Context, id uuid.UUID) ([]payment.StatusChange, error) {
+	if _, err := OrgID(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.svc.History(ctx, id)
+}
+
+func (s service) Patch(ctx context.Context, id uuid.UUID, version uint32, p payment.Patch) error {
+	if _, err := OrgID(ctx); err != nil {
+		return err
+	}
+
+	return s.svc.Patch(ctx, id, version, p)
+}
+
+func (s service) Transition(
+	ctx context.Context, id uuid.UUID, version uint32, to payment.Status, reason string,
+) error {
+	if _, err := OrgID(ctx); err != nil {
+		return err
+	}
+
+	return s.svc.Transition(ctx, id, version, to, reason)
+}
+
+func (s service) Update(ctx context.Context, id uuid.UUID, version uint32, p payment.PymtUpsert) error {
+	var orgID, err = OrgID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if p.OrgID != orgID {
+		return errors.New(ErrOrganisationMismatch, payment.ErrMDVar("id", id), payment.ErrMDField("OrgID", p.OrgID))
+	}
+
+	return s.svc.Update(ctx, id, version, p)
+}
@@ -0,0 +1,311 @@
+package tenancy_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/tenancy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestOrgID(t *testing.T) {
+	var orgID = testutil.NewUUID(t)
+
+	var id, err = tenancy.OrgID(tenancy.WithOrgID(context.Background(), orgID))
+	require.NoError(t, err)
+	assert.Equal(t, orgID, id)
+
+	_, err = tenancy.OrgID(context.Background())
+	testutil.AssertError(t, err, tenancy.ErrNoOrganisation)
+
+	_, err = tenancy.OrgID(tenancy.WithOrgID(context.Background(), uuid.Nil))
+	testutil.AssertError(t, err, tenancy.ErrNoOrganisation)
+}
+
+func TestService_Create(t *testing.T) {
+	var (
+		orgID   = testutil.NewUUID(t)
+		ctx     = tenancy.WithOrgID(context.Background(), orgID)
+		created []payment.PymtUpsert
+		svc     = tenancy.New(&testutil.SvcStub{
+			CreateFn: func(_ context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
+				created = append(created, p)
+				return testutil.NewUUID(t), nil
+			},
+		})
+	)
+
+	var _, err = svc.Create(ctx, payment.PymtUpsert{Type: "Payment"})
+	require.NoError(t, err)
+	_, err = svc.Create(ctx, payment.PymtUpsert{Type: "Payment", OrgID: orgID})
+	require.NoError(t, err)
+	assert.Equal(t, []payment.PymtUpsert{
+		{Type: "Payment", OrgID: orgID}, {Type: "Payment", OrgID: orgID},
+	}, created)
+
+	var oorgID = testutil.NewUUID(t)
+	_, err = svc.Create(ctx, payment.PymtUpsert{Type: "Payment", OrgID: oorgID})
+	testutil.AssertError(t, err, tenancy.ErrOrganisationMismatch, payment.ErrMDField("OrgID", oorgID))
+
+	_, err = svc.Create(context.Background(), payment.PymtUpsert{Type: "Payment"})
+	testutil.AssertError(t, err, tenancy.ErrNoOrganisation)
+	assert.Len(t, created, 2)
+}
+
+func TestService_CreateBatch(t *testing.T) {
+	var (
+		orgID   = testutil.NewUUID(t)
+		ctx     = tenancy.WithOrgID(context.Background(), orgID)
+		created []payment.PymtUpsert
+		bc      = tenancy.New(&testutil.SvcStub{
+			CreateBatchFn: func(_ context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+				created = append(created, ps...)
+				return make([]uuid.UUID, len(ps)), nil
+			},
+		}).(payment.BatchCreator)
+	)
+
+	var ps = []payment.PymtUpsert{{Type: "Payment"}, {Type: "Payment", OrgID: orgID}}
+	var _, err = bc.CreateBatch(ctx, ps)
+	require.NoError(t, err)
+	assert.Equal(t, []payment.PymtUpsert{
+		{Type: "Payment", OrgID: orgID}, {Type: "Payment", OrgID: orgID},
+	}, created)
+	assert.Equal(t, uuid.Nil, ps[0].OrgID, "the passed payments aren't modified")
+
+	var oorgID = testutil.NewUUID(t)
+	_, err = bc.CreateBatch(ctx, []payment.PymtUpsert{{Type: "Payment"}, {Type: "Payment", OrgID: oorgID}})
+	testutil.AssertError(t, err, tenancy.ErrOrganisationMismatch,
+		payment.ErrMDVar("index", 1), payment.ErrMDField("OrgID", oorgID),
+	)
+
+	_, err = bc.CreateBatch(context.Background(), ps)
+	testutil.AssertError(t, err, tenancy.ErrNoOrganisation)
+	assert.Len(t, created, 2)
+}
+
+func TestService_Changes(t *testing.T) {
+	var (
+		orgID  = testutil.NewUUID(t)
+		ctx    = tenancy.WithOrgID(context.Background(), orgID)
+		errSvc = errors.New(payment.ErrUnexpectedStoreError)
+		evts   = []payment.Event{
+			{Seq: 1, Pymt: payment.Pymt{PymtUpsert: payment.PymtUpsert{OrgID: orgID}}},
+			{Seq: 2, Pymt: payment.Pymt{PymtUpsert: payment.PymtUpsert{OrgID: testutil.NewUUID(t)}}},
+			{Seq: 3, Pymt: payment.Pymt{PymtUpsert: payment.PymtUpsert{OrgID: orgID}}},
+		}
+		cf = tenancy.New(&testutil.SvcStub{
+			ChangesFn: func(_ context.Context, fromSeq uint64) (<-chan payment.Event, <-chan error) {
+				assert.Equal(t, uint64(1), fromSeq)
+
+				var (
+					evtc = make(chan payment.Event, len(evts))
+					errc = make(chan error, 1)
+				)
+
+				for _, e := range evts {
+					evtc <- e
+				}
+
+				close(evtc)
+				errc <- errSvc
+				close(errc)
+				return evtc, errc
+			},
+		}).(payment.ChangeFeed)
+	)
+
+	var evtc, errc = cf.Changes(ctx, 1)
+	var revts []payment.Event
+	for e := range evtc {
+		revts = append(revts, e)
+	}
+
+	assert.Equal(t, []payment.Event{evts[0], evts[2]}, revts, "only the events of the organisation are sent")
+	assert.Equal(t, errSvc, <-errc)
+	var _, ok = <-errc
+	assert.False(t, ok)
+
+	evtc, errc = cf.Changes(context.Background(), 1)
+	_, ok = <-evtc
+	assert.False(t, ok)
+	testutil.AssertError(t, <-errc, tenancy.ErrNoOrganisation)
+}
+
+func TestService_Find(t *testing.T) {
+	var (
+		orgID = testutil.NewUUID(t)
+		ctx   = tenancy.WithOrgID(context.Background(), orgID)
+		found payment.Filter
+		svc   = tenancy.New(&testutil.SvcStub{
+			FindFn: func(
+				_ context.Context, f payment.Filter, _ payment.Selection, _ payment.Sort, _ payment.Chunk,
+			) ([]payment.Pymt, error) {
+				found = f
+				return nil, nil
+			},
+		})
+	)
+
+	var of, err = payment.NewFilterByOrgID(payment.FilterCmpEqual, orgID)
+	require.NoError(t, err)
+
+	t.Run("without filter", func(t *testing.T) {
+		var _, err = svc.Find(ctx, payment.Filter{}, payment.SelectAll(), payment.Sort{}, payment.Chunk{})
+		require.NoError(t, err)
+		assert.Equal(t, of, found)
+	})
+
+	t.Run("with filter", func(t *testing.T) {
+		var af, err = payment.NewFilterByAmount(payment.FilterCmpGreaterThan, 10)
+		require.NoError(t, err)
+
+		_, err = svc.Find(ctx, af, payment.SelectAll(), payment.Sort{}, payment.Chunk{})
+		require.NoError(t, err)
+
+		var op, l, r = found.Nodes()
+		assert.Equal(t, payment.FilterLogicalAnd, op)
+		assert.Equal(t, of, l)
+		assert.Equal(t, af, r)
+	})
+
+	t.Run("without organisation", func(t *testing.T) {
+		var _, err = svc.Find(context.Background(), payment.Filter{}, payment.SelectAll(), payment.Sort{}, payment.Chunk{})
+		testutil.AssertError(t, err, tenancy.ErrNoOrganisation)
+	})
+}
+
+func TestService_Aggregate(t *testing.T) {
+	var (
+		orgID      = testutil.NewUUID(t)
+		ctx        = tenancy.WithOrgID(context.Background(), orgID)
+		aggregated payment.Filter
+		agg        = payment.Aggregation{Fns: []payment.AggregateFn{payment.AggregateFnCount}}
+		svc        = tenancy.New(&testutil.SvcStub{
+			AggregateFn: func(_ context.Context, f payment.Filter, a payment.Aggregation) ([]payment.Aggregate, error) {
+				aggregated = f
+				assert.Equal(t, agg, a)
+				return nil, nil
+			},
+		})
+	)
+
+	var of, err = payment.NewFilterByOrgID(payment.FilterCmpEqual, orgID)
+	require.NoError(t, err)
+
+	af, err := payment.NewFilterByAmount(payment.FilterCmpGreaterThan, 10)
+	require.NoError(t, err)
+
+	_, err = svc.Aggregate(ctx, af, agg)
+	require.NoError(t, err)
+
+	var op, l, r = aggregated.Nodes()
+	assert.Equal(t, payment.FilterLogicalAnd, op)
+	assert.Equal(t, of, l)
+	assert.Equal(t, af, r)
+
+	_, err = svc.Aggregate(context.Background(), af, agg)
+	testutil.AssertError(t, err, tenancy.ErrNoOrganisation)
+}
+
+func TestService_cross_organisation(t *testing.T) {
+	var (
+		orgID  = testutil.NewUUID(t)
+		ctx    = tenancy.WithOrgID(context.Background(), orgID)
+		own    = payment.Pymt{ID: testutil.NewUUID(t), PymtUpsert: payment.PymtUpsert{Type: "Payment", OrgID: orgID}}
+		other  = payment.Pymt{ID: testutil.NewUUID(t), PymtUpsert: payment.PymtUpsert{OrgID: testutil.NewUUID(t)}}
+		calls  []string
+		stored = map[uuid.UUID]payment.Pymt{own.ID: own, other.ID: other}
+		// call records the call of the method m, as a store does, it only
+		// operates on the payments of the organisation scope carried by ctx.
+		call = func(ctx context.Context, m string, id uuid.UUID) error {
+			var orgID, ok = payment.OrgScope(ctx)
+			require.True(t, ok, "organisation scope of %s", m)
+			if stored[id].OrgID != orgID {
+				return errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+			}
+
+			calls = append(calls, m)
+			return nil
+		}
+		svc = tenancy.New(&testutil.SvcStub{
+			GetFn: func(ctx context.Context, id uuid.UUID, sl payment.Selection) (payment.Pymt, error) {
+				assert.True(t, sl.OrgID)
+				if err := call(ctx, "Get", id); err != nil {
+					return payment.Pymt{}, err
+				}
+
+				return stored[id], nil
+			},
+			DeleteFn: func(ctx context.Context, id uuid.UUID, _ uint32) error {
+				return call(ctx, "Delete", id)
+			},
+			HistoryFn: func(ctx context.Context, id uuid.UUID) ([]payment.StatusChange, error) {
+				return nil, call(ctx, "History", id)
+			},
+			PatchFn: func(ctx context.Context, id uuid.UUID, _ uint32, _ payment.Patch) error {
+				return call(ctx, "Patch", id)
+			},
+			TransitionFn: func(ctx context.Context, id uuid.UUID, _ uint32, _ payment.Status, _ string) error {
+				return call(ctx, "Transition", id)
+			},
+			UpdateFn: func(ctx context.Context, id uuid.UUID, _ uint32, _ payment.PymtUpsert) error {
+				return call(ctx, "Update", id)
+			},
+		})
+	)
+
+	t.Run("own payment", func(t *testing.T) {
+		calls = nil
+
+		var p, err = svc.Get(ctx, own.ID, payment.Selection{Type: true})
+		require.NoError(t, err)
+		assert.Equal(t, payment.Pymt{ID: own.ID, PymtUpsert: payment.PymtUpsert{Type: "Payment"}}, p)
+
+		require.NoError(t, svc.Delete(ctx, own.ID, 0))
+		_, err = svc.History(ctx, own.ID)
+		require.NoError(t, err)
+		require.NoError(t, svc.Patch(ctx, own.ID, 0, payment.Patch{}))
+		require.NoError(t, svc.Transition(ctx, own.ID, 0, payment.StatusSubmitted, ""))
+		require.NoError(t, svc.Update(ctx, own.ID, 0, own.PymtUpsert))
+		assert.Equal(t, []string{"Get", "Delete", "History", "Patch", "Transition", "Update"}, calls)
+	})
+
+	t.Run("other organisation payment", func(t *testing.T) {
+		calls = nil
+
+		var _, err = svc.Get(ctx, other.ID, payment.SelectAll())
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", other.ID))
+
+		err = svc.Delete(ctx, other.ID, 0)
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", other.ID))
+
+		_, err = svc.History(ctx, other.ID)
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", other.ID))
+
+		err = svc.Patch(ctx, other.ID, 0, payment.Patch{})
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", other.ID))
+
+		err = svc.Transition(ctx, other.ID, 0, payment.StatusSubmitted, "")
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", other.ID))
+
+		err = svc.Update(ctx, other.ID, 0, payment.PymtUpsert{OrgID: orgID})
+		testutil.AssertError(t, err, payment.ErrNotFound, payment.ErrMDVar("id", other.ID))
+		assert.Empty(t, calls)
+	})
+
+	t.Run("change organisation", func(t *testing.T) {
+		calls = nil
+
+		var err = svc.Update(ctx, own.ID, 0, other.PymtUpsert)
+		testutil.AssertError(t, err, tenancy.ErrOrganisationMismatch,
+			payment.ErrMDVar("id", own.ID), payment.ErrMDField("OrgID", other.OrgID),
+		)
+		assert.Empty(t, calls)
+	})
+}
@@ -0,0 +1,26 @@
+package tracing
+
+type code uint8
+
+// The list of specific error codes that the tracing package can return.
+const (
+	ErrInvalidTraceparent code = iota + 1
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrInvalidTraceparent:
+		return "InvalidTraceparent"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrInvalidTraceparent:
+		return "The traceparent isn't a valid W3C trace context traceparent"
+	}
+
+	return ""
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import "net/http"
+
+// WrapHandler returns an http.Handler which starts a span with t for each
+// request, which is carried by the context of the request passed to h.
+//
+// The span is named name followed by the request method (e.g. "rest POST"),
+// it's a child of the span context propagated by the request headers, if any
+// (see Extract), and it has the attributes "http.method", "http.target" (the
+// request path) and "http.status_code".
+func WrapHandler(h http.Handler, t *Tracer, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx, span = t.Start(Extract(r.Context(), r.Header), name+" "+r.Method)
+		defer span.End()
+
+		span.SetAttr("http.method", r.Method)
+		span.SetAttr("http.target", r.URL.Path)
+
+		var sw = &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r.WithContext(ctx))
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		span.SetAttr("http.status_code", sw.status)
+	})
+}
+
+// statusWriter is an http.ResponseWriter which keeps the status code of the
+// response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,64 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestWrapHandler(t *testing.T) {
+	var (
+		exp    = &tracing.InMemoryExporter{}
+		tracer = tracing.NewTracer(exp)
+		pid    = testutil.NewUUID(t)
+		svc    = tracing.New(&testutil.SvcStub{
+			GetFn: func(ctx context.Context, id uuid.UUID, _ payment.Selection) (payment.Pymt, error) {
+				var _, span = tracing.Start(ctx, "store")
+				span.End()
+				return payment.Pymt{}, errors.New(payment.ErrNotFound)
+			},
+		}, tracer)
+		h = tracing.WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var _, err = svc.Get(r.Context(), pid, payment.SelectAll())
+			if err != nil {
+				http.NotFound(w, r)
+			}
+		}), tracer, "rest")
+	)
+
+	var req = httptest.NewRequest(http.MethodGet, "/payments/"+pid.String(), nil)
+	req.Header.Set(tracing.HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var spans = exp.Spans()
+	require.Len(t, spans, 3)
+	var store, get, server = spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "rest GET", server.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.Context.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", server.ParentID.String())
+	assert.Equal(t, []tracing.Attr{
+		{Key: "http.method", Value: http.MethodGet},
+		{Key: "http.target", Value: "/payments/" + pid.String()},
+		{Key: "http.status_code", Value: http.StatusNotFound},
+	}, server.Attrs)
+
+	assert.Equal(t, "payment.Service.Get", get.Name)
+	assert.Equal(t, server.Context.SpanID, get.ParentID)
+	assert.Equal(t, "NotFound", get.ErrCode)
+	var id, _ = get.Attr("payment.id")
+	assert.Equal(t, pid.String(), id)
+
+	assert.Equal(t, "store", store.Name)
+	assert.Equal(t, get.Context.SpanID, store.ParentID)
+	assert.Equal(t, server.Context.TraceID, store.Context.TraceID)
+}
@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// The names of the W3C trace context HTTP headers.
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+// flagSampled is the sampled bit of the trace flags of a traceparent.
+const flagSampled = 0x01
+
+// ParseTraceparent parses the value of a traceparent header (see
+// https://www.w3.org/TR/trace-context/#traceparent-header), returning its span
+// context, whose TraceState is empty.
+//
+// The values of versions higher than 00 are parsed as version 00, ignoring the
+// fields after the trace flags, as the specification requires.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidTraceparent
+func ParseTraceparent(v string) (SpanContext, error) {
+	var errInvalid = func() (SpanContext, error) {
+		return SpanContext{}, errors.New(ErrInvalidTraceparent, payment.ErrMDArg("v", v))
+	}
+
+	// version(2) - trace-id(32) - parent-id(16) - trace-flags(2)
+	if len(v) < 55 || v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return errInvalid()
+	}
+
+	var ver, err = hex.DecodeString(v[:2])
+	if err != nil || ver[0] == 0xff || strings.ToLower(v[:55]) != v[:55] {
+		return errInvalid()
+	}
+
+	if (ver[0] == 0 && len(v) != 55) || (len(v) > 55 && v[55] != '-') {
+		return errInvalid()
+	}
+
+	var sc SpanContext
+	if _, err := hex.Decode(sc.TraceID[:], []byte(v[3:35])); err != nil {
+		return errInvalid()
+	}
+
+	if _, err := hex.Decode(sc.SpanID[:], []byte(v[36:52])); err != nil {
+		return errInvalid()
+	}
+
+	flags, err := hex.DecodeString(v[53:55])
+	if err != nil || !sc.Valid() {
+		return errInvalid()
+	}
+
+	sc.Sampled = flags[0]&flagSampled != 0
+	return sc, nil
+}
+
+// Traceparent returns the value of the traceparent header, with version 00,
+// which propagates sc.
+func (sc SpanContext) Traceparent() string {
+	var flags = "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// Extract returns a copy of ctx which carries the remote span context
+// propagated by the traceparent and tracestate headers of h, so the spans
+// started with it are its children (see Tracer.Start).
+//
+// ctx is returned when h doesn't have a valid traceparent header, so the
+// spans started with it belong to a new trace, as the specification requires.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	var tps = h[http.CanonicalHeaderKey(HeaderTraceparent)]
+	if len(tps) != 1 {
+		return ctx
+	}
+
+	var sc, err = ParseTraceparent(strings.TrimSpace(tps[0]))
+	if err != nil {
+		return ctx
+	}
+
+	sc.TraceState = strings.Join(h[http.CanonicalHeaderKey(HeaderTracestate)], ",")
+	return context.WithValue(ctx, remoteCtxKey{}, sc)
+}
+
+// Inject sets the traceparent and tracestate headers to h for propagating the
+// span context of the span carried by ctx. It doesn't do anything if ctx
+// doesn't carry any span.
+func Inject(ctx context.Context, h http.Header) {
+	var s = SpanFromContext(ctx)
+	if s == nil {
+		return
+	}
+
+	var sc = s.SpanContext()
+	h.Set(HeaderTraceparent, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(HeaderTracestate, sc.TraceState)
+	} else {
+		h.Del(HeaderTracestate)
+	}
+}
@@ -0,0 +1,129 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	var tcases = []struct {
+		desc    string
+		v       string
+		sampled bool
+		err     bool
+	}{
+		{
+			desc:    "sampled",
+			v:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			sampled: true,
+		},
+		{
+			desc: "not sampled",
+			v:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		},
+		{
+			desc:    "higher version with more fields",
+			v:       "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-09-what-the-future-will-be-like",
+			sampled: true,
+		},
+		{
+			desc: "error: version 00 with more fields",
+			v:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			err:  true,
+		},
+		{
+			desc: "error: version ff",
+			v:    "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			err:  true,
+		},
+		{
+			desc: "error: uppercase",
+			v:    "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			err:  true,
+		},
+		{
+			desc: "error: zero trace ID",
+			v:    "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			err:  true,
+		},
+		{
+			desc: "error: zero parent ID",
+			v:    "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			err:  true,
+		},
+		{
+			desc: "error: not hexadecimal",
+			v:    "00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+			err:  true,
+		},
+		{
+			desc: "error: short",
+			v:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			err:  true,
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var sc, err = tracing.ParseTraceparent(tc.v)
+			if tc.err {
+				testutil.AssertError(t, err, tracing.ErrInvalidTraceparent, payment.ErrMDArg("v", tc.v))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+			assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+			assert.Equal(t, tc.sampled, sc.Sampled)
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	var (
+		exp     = &tracing.InMemoryExporter{}
+		tracer  = tracing.NewTracer(exp)
+		inbound = http.Header{}
+	)
+
+	inbound.Set(tracing.HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	inbound.Set(tracing.HeaderTracestate, "congo=t61rcWkgMzE")
+
+	var ctx, span = tracer.Start(tracing.Extract(context.Background(), inbound), "client")
+
+	var outbound = http.Header{}
+	tracing.Inject(ctx, outbound)
+	assert.Equal(t,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-"+span.SpanContext().SpanID.String()+"-01",
+		outbound.Get(tracing.HeaderTraceparent),
+	)
+	assert.Equal(t, "congo=t61rcWkgMzE", outbound.Get(tracing.HeaderTracestate))
+
+	var sc, err = tracing.ParseTraceparent(outbound.Get(tracing.HeaderTraceparent))
+	require.NoError(t, err)
+	assert.Equal(t, span.SpanContext().SpanID, sc.SpanID)
+
+	var empty = http.Header{}
+	tracing.Inject(context.Background(), empty)
+	assert.Empty(t, empty)
+
+	t.Run("invalid traceparent starts a new trace", func(t *testing.T) {
+		var h = http.Header{}
+		h.Set(tracing.HeaderTraceparent, "00-xyz")
+		h.Set(tracing.HeaderTracestate, "congo=t61rcWkgMzE")
+
+		var _, span = tracer.Start(tracing.Extract(context.Background(), h), "server")
+		assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID.String())
+		assert.Equal(t, "", span.SpanContext().TraceState)
+	})
+}
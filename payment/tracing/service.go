@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+)
+
+// New returns a payment.Service which delegates the operations to svc starting
+// a span with t for each call, which is carried by the context passed to svc,
+// so the spans started by svc with Start are its children.
+//
+// The spans are named "payment.Service." followed by the method name and they
+// are children of the span carried by the context passed to the method, if
+// any. They have the attribute "payment.id" when the method receives or
+// returns a payment ID, the ones of CreateBatch have the attribute
+// "payment.count" with the number of passed payments, and they record the
+// returned error (see Span.SetError).
+//
+// The returned payment.Service also satisfies the payment.BatchCreator and
+// payment.ChangeFeed interfaces, delegating them to svc (see
+// payment.CreateBatch and payment.Changes). The calls to Changes aren't traced
+// because they last until its context is done.
+//
+// The methods return the same values than svc.
+func New(svc payment.Service, t *Tracer) payment.Service {
+	return service{svc: svc, tracer: t}
+}
+
+type service struct {
+	svc    payment.Service
+	tracer *Tracer
+}
+
+func (s service) Aggregate(
+	ctx context.Context, f payment.Filter, a payment.Aggregation,
+) ([]payment.Aggregate, error) {
+	var ctx2, span = s.start(ctx, "Aggregate", uuid.Nil)
+	defer span.End()
+
+	var aggs, err = s.svc.Aggregate(ctx2, f, a)
+	span.SetError(err)
+	return aggs, err
+}
+
+func (s service) Changes(ctx context.Context, fromSeq uint64) (<-chan payment.Event, <-chan error) {
+	return payment.Changes(ctx, s.svc, fromSeq)
+}
+
+func (s service) Create(ctx context.Context, p payment.PymtUpsert) (uuid.UUID, error) {
+	var ctx2, span = s.start(ctx, "Create", uuid.Nil)
+	defer span.End()
+
+	var id, err = s.svc.Create(ctx2, p)
+	if id != uuid.Nil {
+		span.SetAttr("payment.id", id.String())
+	}
+
+	span.SetError(err)
+	return id, err
+}
+
+func (s service) CreateBatch(ctx context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+	var ctx2, span = s.start(ctx, "CreateBatch", uuid.Nil)
+	defer span.End()
+
+	var ids, err = payment.CreateBatch(ctx2, s.svc, ps)
+	span.SetAttr("payment.count", len(ps))
+	span.SetError(err)
+	return ids, err
+}
+
+func (s service) Delete(ctx context.Context, id uuid.UUID, version uint32) error {
+	var ctx2, span = s.start(ctx, "Delete", id)
+	defer span.End()
+
+	var err = s.svc.Delete(ctx2, id, version)
+	span.SetError(err)
+	return err
+}
+
+func (s service) Find(
+	ctx context.Context, f payment.Filter, sl payment.Selection, st payment.Sort, c payment.Chunk,
+) ([]payment.Pymt, error) {
+	var ctx2, span = s.start(ctx, "Find", uuid.Nil)
+	defer span.End()
+
+	var ps, err = s.svc.Find(ctx2, f, sl, st, c)
+	span.SetError(err)
+	return ps, err
+}
+
+func (s service) Get(ctx context.Context, id uuid.UUID, sl payment.Selection) (payment.Pymt, error) {
+	var ctx2, span = s.start(ctx, "Get", id)
+	defer span.End()
+
+	var p, err = s.svc.Get(ctx2, id, sl)
+	span.SetError(err)
+	return p, err
+}
+
+func (s service) History(ctx context.Context, id uuid.UUID) ([]payment.StatusChange, error) {
+	var ctx2, span = s.start(ctx, "History", id)
+	defer span.End()
+
+	var scs, err = s.svc.History(ctx2, id)
+	span.SetError(err)
+	return scs, err
+}
+
+func (s service) Patch(ctx context.Context, id uuid.UUID, version uint32, p payment.Patch) error {
+	var ctx2, span = s.start(ctx, "Patch", id)
+	defer span.End()
+
+	var err = s.svc.Patch(ctx2, id, version, p)
+	span.SetError(err)
+	return err
+}
+
+func (s service) Transition(
+	ctx context.Context, id uuid.UUID, version uint32, to payment.Status, reason string,
+) error {
+	var ctx2, span = s.start(ctx, "Transition", id)
+	defer span.End()
+
+	var err = s.svc.Transition(ctx2, id, version, to, reason)
+	span.SetError(err)
+	return err
+}
+
+func (s service) Update(ctx context.Context, id uuid.UUID, version uint32, p payment.PymtUpsert) error {
+	var ctx2, span = s.start(ctx, "Update", id)
+	defer span.End()
+
+	var err = s.svc.Update(ctx2, id, version, p)
+	span.SetError(err)
+	return err
+}
+
+// start starts the span of a call to the method m, setting the "payment.id"
+// attribute when id isn't uuid.Nil.
+func (s service) start(ctx context.Context, m string, id uuid.UUID) (context.Context, *Span) {
+	var ctx2, span = s.tracer.Start(ctx, "payment.Service."+m)
+	if id != uuid.Nil {
+		span.SetAttr("payment.id", id.String())
+	}
+
+	return ctx2, span
+}
@@ -0,0 +1,45 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_CreateBatch(t *testing.T) {
+	var (
+		exp    = &tracing.InMemoryExporter{}
+		tracer = tracing.NewTracer(exp)
+		stub   = &testutil.SvcStub{
+			CreateBatchFn: func(_ context.Context, ps []payment.PymtUpsert) ([]uuid.UUID, error) {
+				return make([]uuid.UUID, len(ps)), nil
+			},
+		}
+	)
+
+	var bc, ok = tracing.New(stub, tracer).(payment.BatchCreator)
+	require.True(t, ok)
+
+	var ids, err = bc.CreateBatch(context.Background(), make([]payment.PymtUpsert, 2))
+	require.NoError(t, err)
+	assert.Len(t, ids, 2)
+
+	// The decorated service doesn't satisfy payment.BatchCreator
+	bc = tracing.New(struct{ payment.Service }{stub}, tracer).(payment.BatchCreator)
+	_, err = bc.CreateBatch(context.Background(), nil)
+	testutil.AssertError(t, err, payment.ErrNotSupported)
+
+	var spans = exp.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "payment.Service.CreateBatch", spans[0].Name)
+	assert.Equal(t, "", spans[0].ErrCode)
+	var n, _ = spans[0].Attr("payment.count")
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "NotSupported", spans[1].ErrCode)
+}
@@ -0,0 +1,311 @@
+// Package tracing records spans of the operations performed for serving a
+// request, propagates their trace context through the W3C trace context HTTP
+// headers (traceparent and tracestate) and exports them through an Exporter.
+//
+// The root spans are started by a Tracer; the rest of the packages start child
+// spans with Start, which is a no-op when the context doesn't carry any span,
+// hence they don't require any configuration.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"go.fraixed.es/errors"
+)
+
+// TraceID is the ID of a trace.
+type TraceID [16]byte
+
+// String returns the lowercase hexadecimal representation of id.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero returns true if all the bytes of id are 0, which isn't a valid ID.
+func (id TraceID) IsZero() bool {
+	return id == TraceID{}
+}
+
+// SpanID is the ID of a span.
+type SpanID [8]byte
+
+// String returns the lowercase hexadecimal representation of id.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero returns true if all the bytes of id are 0, which isn't a valid ID.
+func (id SpanID) IsZero() bool {
+	return id == SpanID{}
+}
+
+// SpanContext is the part of a span which is propagated to other processes.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Sampled indicates that the span is recorded, so it's exported.
+	Sampled bool
+	// TraceState is the value of the tracestate header, which is propagated
+	// without modifications.
+	TraceState string
+}
+
+// Valid returns true if the trace ID and span ID aren't zero.
+func (sc SpanContext) Valid() bool {
+	return !sc.TraceID.IsZero() && !sc.SpanID.IsZero()
+}
+
+// Attr is a span attribute.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanData is the data of a span which has ended, which is passed to the
+// exporters.
+type SpanData struct {
+	Name     string
+	Context  SpanContext
+	ParentID SpanID
+	Start    time.Time
+	End      time.Time
+	Attrs    []Attr
+	// ErrCode is the string representation of the code of the error which the
+	// operation of the span returned, "Unknown" if the error doesn't have a code
+	// or empty if it didn't return an error (see Span.SetError).
+	ErrCode string
+}
+
+// Duration returns the duration of the span.
+func (sd SpanData) Duration() time.Duration {
+	return sd.End.Sub(sd.Start)
+}
+
+// Attr returns the value of the attribute key and true, or nil and false if sd
+// doesn't have it.
+func (sd SpanData) Attr(key string) (interface{}, bool) {
+	for _, a := range sd.Attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Exporter is the interface which any exporter of spans must satisfy.
+type Exporter interface {
+	// ExportSpan exports the span sd. It's called when a sampled span ends, so
+	// it must not block and it must be safe for concurrent use.
+	ExportSpan(sd SpanData)
+}
+
+// InMemoryExporter is an Exporter which keeps the exported spans in memory.
+// It's meant to be used by tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// ExportSpan satisfies the Exporter interface.
+func (e *InMemoryExporter) ExportSpan(sd SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, sd)
+}
+
+// Spans returns the exported spans in the order that they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset removes all the exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = nil
+}
+
+// Tracer starts spans and exports them with its exporter when they end.
+type Tracer struct {
+	exp Exporter
+}
+
+// NewTracer creates a Tracer which exports the spans with exp.
+func NewTracer(exp Exporter) *Tracer {
+	return &Tracer{exp: exp}
+}
+
+type spanCtxKey struct{}
+
+type remoteCtxKey struct{}
+
+// Start starts a span with name, returning it and a copy of ctx which carries
+// it.
+//
+// The span is a child of the span carried by ctx or, if it doesn't carry any,
+// of the remote span context carried by ctx (see Extract). When ctx doesn't
+// carry any of them, the span is the root of a new sampled trace.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	var psc SpanContext
+	if ps := SpanFromContext(ctx); ps != nil {
+		psc = ps.data.Context
+	} else if rsc, ok := ctx.Value(remoteCtxKey{}).(SpanContext); ok {
+		psc = rsc
+	}
+
+	var s = &Span{tracer: t}
+	s.data.Name = name
+	s.data.Start = time.Now()
+	s.data.Context.SpanID = newSpanID()
+	if psc.Valid() {
+		s.data.Context.TraceID = psc.TraceID
+		s.data.Context.Sampled = psc.Sampled
+		s.data.Context.TraceState = psc.TraceState
+		s.data.ParentID = psc.SpanID
+	} else {
+		s.data.Context.TraceID = newTraceID()
+		s.data.Context.Sampled = true
+	}
+
+	return context.WithValue(ctx, spanCtxKey{}, s), s
+}
+
+// Start starts a child span with name of the span carried by ctx, using its
+// tracer, returning it and a copy of ctx which carries it.
+//
+// When ctx doesn't carry any span, ctx and a nil span are returned; all the
+// methods of a nil span are no-ops, so the callers don't have to check it.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	var ps = SpanFromContext(ctx)
+	if ps == nil {
+		return ctx, nil
+	}
+
+	return ps.tracer.Start(ctx, name)
+}
+
+// SpanFromContext returns the span carried by ctx or nil if it doesn't carry
+// any.
+func SpanFromContext(ctx context.Context) *Span {
+	var s, _ = ctx.Value(spanCtxKey{}).(*Span)
+	return s
+}
+
+// Span is a span which has been started and it's recorded until it ends. It's
+// safe for concurrent use.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SpanContext returns the span context of s. It returns the zero value if s is
+// nil.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.data.Context
+}
+
+// SetAttr sets the attribute key with the value val, replacing its previous
+// value if it was already set. The attributes set after the span ends are
+// ignored.
+func (s *Span) SetAttr(key string, val interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	for i := range s.data.Attrs {
+		if s.data.Attrs[i].Key == key {
+			s.data.Attrs[i].Value = val
+			return
+		}
+	}
+
+	s.data.Attrs = append(s.data.Attrs, Attr{Key: key, Value: val})
+}
+
+// SetError records that the operation of the span returned err. It doesn't do
+// anything if err is nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	var ec = "Unknown"
+	if c, ok := errors.GetCode(err); ok && c.String() != "" {
+		ec = c.String()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ended {
+		s.data.ErrCode = ec
+	}
+}
+
+// End ends the span and exports it if it's sampled. Calling it more than once
+// doesn't do anything.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+
+	s.ended = true
+	s.data.End = time.Now()
+	var sd = s.data
+	s.mu.Unlock()
+
+	if sd.Context.Sampled && s.tracer.exp != nil {
+		s.tracer.exp.ExportSpan(sd)
+	}
+}
+
+// newTraceID returns a random trace ID. The zero IDs, which aren't valid, are
+// discarded.
+func newTraceID() TraceID {
+	var id TraceID
+	for id.IsZero() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
+
+// newSpanID returns a random span ID. The zero IDs, which aren't valid, are
+// discarded.
+func newSpanID() SpanID {
+	var id SpanID
+	for id.IsZero() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
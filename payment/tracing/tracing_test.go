@@ -0,0 +1,108 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestTracer_Start(t *testing.T) {
+	var (
+		exp    = &tracing.InMemoryExporter{}
+		tracer = tracing.NewTracer(exp)
+	)
+
+	var ctx, root = tracer.Start(context.Background(), "root")
+	assert.True(t, root == tracing.SpanFromContext(ctx))
+	assert.True(t, root.SpanContext().Valid())
+	assert.True(t, root.SpanContext().Sampled)
+
+	var cctx, child = tracing.Start(ctx, "child")
+	assert.True(t, child == tracing.SpanFromContext(cctx))
+	child.SetAttr("k", 1)
+	child.SetAttr("k", 2)
+	child.SetError(errors.New(payment.ErrNotFound))
+	child.End()
+	child.End()
+	child.SetAttr("after", true)
+
+	root.SetError(nil)
+	root.End()
+
+	var spans = exp.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, root.SpanContext().TraceID, spans[0].Context.TraceID)
+	assert.Equal(t, root.SpanContext().SpanID, spans[0].ParentID)
+	assert.NotEqual(t, root.SpanContext().SpanID, spans[0].Context.SpanID)
+	assert.Equal(t, []tracing.Attr{{Key: "k", Value: 2}}, spans[0].Attrs)
+	assert.Equal(t, "NotFound", spans[0].ErrCode)
+	assert.True(t, spans[0].Duration() >= 0)
+
+	assert.Equal(t, "root", spans[1].Name)
+	assert.True(t, spans[1].ParentID.IsZero())
+	assert.Equal(t, "", spans[1].ErrCode)
+
+	var _, other = tracer.Start(context.Background(), "other")
+	assert.NotEqual(t, root.SpanContext().TraceID, other.SpanContext().TraceID)
+
+	exp.Reset()
+	assert.Empty(t, exp.Spans())
+}
+
+func TestStart_withoutSpan(t *testing.T) {
+	var ctx = context.Background()
+	var sctx, span = tracing.Start(ctx, "noop")
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, sctx)
+
+	// The methods of a nil span are no-ops
+	span.SetAttr("k", "v")
+	span.SetError(errors.New(payment.ErrNotFound))
+	span.End()
+	assert.False(t, span.SpanContext().Valid())
+}
+
+func TestTracer_Start_remote(t *testing.T) {
+	var (
+		exp    = &tracing.InMemoryExporter{}
+		tracer = tracing.NewTracer(exp)
+	)
+
+	t.Run("sampled", func(t *testing.T) {
+		var sc, err = tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		require.NoError(t, err)
+
+		var h = map[string][]string{
+			"Traceparent": {sc.Traceparent()},
+			"Tracestate":  {"congo=t61rcWkgMzE", "rojo=00f067aa0ba902b7"},
+		}
+
+		var _, span = tracer.Start(tracing.Extract(context.Background(), h), "server")
+		assert.Equal(t, sc.TraceID, span.SpanContext().TraceID)
+		assert.Equal(t, "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", span.SpanContext().TraceState)
+		span.End()
+
+		var spans = exp.Spans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, sc.SpanID, spans[0].ParentID)
+	})
+
+	t.Run("not sampled", func(t *testing.T) {
+		var h = map[string][]string{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"}}
+
+		var ctx, span = tracer.Start(tracing.Extract(context.Background(), h), "server")
+		assert.False(t, span.SpanContext().Sampled)
+		var _, child = tracing.Start(ctx, "child")
+		assert.False(t, child.SpanContext().Sampled)
+		child.End()
+		span.End()
+
+		assert.Len(t, exp.Spans(), 1)
+	})
+}
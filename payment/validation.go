@@ -0,0 +1,209 @@
+package payment
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.fraixed.es/errors"
+)
+
+// Violation is a field whose value doesn't respect the requirements of the
+// business domain.
+type Violation struct {
+	// Field is the path of the field from the validated value. The path parts are
+	// the names of the struct fields and the indexes of the slices separated by
+	// '.' (e.g. "Attributes.ChargesInformation.SenderCharges.0.Currency").
+	Field string
+	Code  errors.Code
+	Value interface{}
+}
+
+// String returns the code and the value of the violation, redacted (see
+// Violation.Redacted).
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %+v", v.Code.String(), v.Redacted().(Violation).Value)
+}
+
+// Violations is a list of Violation.
+type Violations []Violation
+
+// Err returns nil if vs is empty, otherwise an error whose code is the code of
+// the first violation and has an ErrMDField metadata for each violation, whose
+// name is the violation field and its value is the violation.
+func (vs Violations) Err() error {
+	if len(vs) == 0 {
+		return nil
+	}
+
+	var mds = make([]errors.MD, len(vs))
+	for i, v := range vs {
+		mds[i] = ErrMDField(v.Field, v)
+	}
+
+	return errors.New(vs[0].Code, mds...)
+}
+
+// add appends a violation of field with code c and value val.
+func (vs *Violations) add(field string, c errors.Code, val interface{}) {
+	*vs = append(*vs, Violation{Field: field, Code: c, Value: val})
+}
+
+// merge appends ovs prefixing their fields with prefix.
+func (vs *Violations) merge(prefix string, ovs Violations) {
+	for _, v := range ovs {
+		v.Field = prefix + "." + v.Field
+		*vs = append(*vs, v)
+	}
+}
+
+// The list of the known codes of the attributes which accept a closed set of
+// values.
+//
+//nolint:gochecknoglobals
+var (
+	knownPaymentSchemes        = []string{"BACS", "CHAPS", "FPS", "SEPA", "SWIFT"}
+	knownPaymentTypes          = []string{"Credit", "Debit"}
+	knownSchemePaymentTypes    = []string{"ImmediatePayment", "ForwardDatedPayment", "StandingOrder"}
+	knownSchemePaymentSubTypes = []string{
+		"BranchInstruction", "InternetBanking", "MobileBanking", "TelephoneBanking", "Other",
+	}
+	knownBearerCodes         = []string{"CRED", "DEBT", "SHAR", "SLEV"}
+	knownAccountNumberCodes  = []string{"BBAN", "IBAN"}
+	knownBankIDCodes         = []string{"GBDSC", "SWBIC"}
+	decimalRegexp            = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+	numericReferenceRegexp   = regexp.MustCompile(`^[0-9]+$`)
+	processingDateTimeLayout = "2006-01-02"
+)
+
+func isOneOf(v string, vals []string) bool {
+	for _, val := range vals {
+		if v == val {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validAmount returns true if a is positive and it doesn't have more decimal
+// places than the minor units of the currency c. c must be a valid currency.
+func validAmount(a float64, c string) bool {
+	if a <= 0 || math.IsInf(a, 0) || math.IsNaN(a) {
+		return false
+	}
+
+	var mu, _ = CurrencyMinorUnits(c)
+	var r = a * math.Pow10(int(mu))
+
+	// The tolerance absorbs the error of the floating point representation
+	return math.Abs(r-math.Round(r)) <= 1e-9*math.Max(1, math.Abs(r))
+}
+
+// validDecimal returns true if s is the string representation of a positive
+// decimal number whose decimal places aren't more than maxDecimals. A negative
+// maxDecimals doesn't limit the decimal places.
+func validDecimal(s string, maxDecimals int) bool {
+	if !decimalRegexp.MatchString(s) {
+		return false
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err != nil || f <= 0 {
+		return false
+	}
+
+	if maxDecimals < 0 {
+		return true
+	}
+
+	var i = strings.IndexByte(s, '.')
+	return i < 0 || len(s)-i-1 <= maxDecimals
+}
+
+// amountViolations returns the violations of the amount a of currency c, being
+// af and cf the field names of each one.
+func amountViolations(af string, a float64, cf string, c string) Violations {
+	var vs Violations
+	if _, ok := CurrencyMinorUnits(c); !ok {
+		vs.add(cf, ErrInvalidPaymentAttrCurrency, c)
+		if a <= 0 {
+			vs.add(af, ErrInvalidPaymentAttrAmount, a)
+		}
+
+		return vs
+	}
+
+	if !validAmount(a, c) {
+		vs.add(af, ErrInvalidPaymentAttrAmount, a)
+	}
+
+	return vs
+}
+
+// chargesCurrencyViolations returns the violations of the consistency of the
+// currencies of the charges with the payment: the sender charges must share
+// the same currency and the receiver charges must be in the currency of the
+// payment or in the original currency of the foreign exchange.
+//
+// The currencies which aren't valid aren't reported because amountViolations
+// reports them.
+func (a Attrs) chargesCurrencyViolations() Violations {
+	var (
+		vs Violations
+		ci = a.ChargesInformation
+		sc string
+	)
+
+	for i, c := range ci.SenderCharges {
+		if _, ok := CurrencyMinorUnits(c.Currency); !ok {
+			continue
+		}
+
+		if sc == "" {
+			sc = c.Currency
+			continue
+		}
+
+		if c.Currency != sc {
+			vs.add(fmt.Sprintf("SenderCharges.%d.Currency", i), ErrInvalidPaymentAttrCurrency, c.Currency)
+		}
+	}
+
+	var rc = ci.ReceiverChargesCurrency
+	if _, ok := CurrencyMinorUnits(rc); ok && rc != a.Currency && rc != a.Fx.OriginalCurrency {
+		vs.add("ReceiverChargesCurrency", ErrInvalidPaymentAttrCurrency, rc)
+	}
+
+	return vs
+}
+
+// fxViolations returns the violations of the foreign exchange attributes, which
+// are all required when any of them is set.
+func (a Attrs) fxViolations() Violations {
+	var (
+		vs Violations
+		fx = a.Fx
+	)
+
+	if fx.ContractReference == "" {
+		vs.add("ContractReference", ErrInvalidPaymentAttrRequired, fx.ContractReference)
+	}
+
+	if !validDecimal(fx.ExchangeRate, -1) {
+		vs.add("ExchangeRate", ErrInvalidPaymentAttrAmount, fx.ExchangeRate)
+	}
+
+	var mu, ok = CurrencyMinorUnits(fx.OriginalCurrency)
+	if !ok {
+		vs.add("OriginalCurrency", ErrInvalidPaymentAttrCurrency, fx.OriginalCurrency)
+		mu = math.MaxUint8
+	}
+
+	if !validDecimal(fx.OriginalAmount, int(mu)) {
+		vs.add("OriginalAmount", ErrInvalidPaymentAttrAmount, fx.OriginalAmount)
+	}
+
+	return vs
+}
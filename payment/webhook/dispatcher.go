@@ -0,0 +1,412 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// Config contains the parameters of a Dispatcher. The zero value of each
+// field means to use its default value.
+type Config struct {
+	// MaxAttempts is the number of attempts after which a delivery is considered
+	// dead. Default 10.
+	MaxAttempts uint32
+	// BackoffBase is the time to wait after the first failed attempt, which is
+	// doubled on each following failed attempt. Default 30 seconds.
+	BackoffBase time.Duration
+	// BackoffMax is the maximum time to wait between attempts. Default 1 hour.
+	BackoffMax time.Duration
+	// PollInterval is the maximum time to wait for checking if there are
+	// deliveries to attempt. Default 1 second.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of deliveries attempted in each poll.
+	// Default 50.
+	BatchSize uint32
+	// Client is the HTTP client used to send the deliveries. Default a client
+	// with 10 seconds of timeout.
+	Client *http.Client
+	// Now returns the current time. Default time.Now.
+	Now func() time.Time
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = 10
+	}
+
+	if c.BackoffBase == 0 {
+		c.BackoffBase = 30 * time.Second
+	}
+
+	if c.BackoffMax == 0 {
+		c.BackoffMax = time.Hour
+	}
+
+	if c.PollInterval == 0 {
+		c.PollInterval = time.Second
+	}
+
+	if c.BatchSize == 0 {
+		c.BatchSize = 50
+	}
+
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	return c
+}
+
+// Dispatcher sends the payment events to the endpoints registered by the
+// organisation of each payment.
+//
+// The events are read from a payment.ChangeFeed and each one is stored as a
+// delivery for each matching endpoint before advancing the cursor; hence the
+// events are delivered at-least-once and only one Dispatcher must run for the
+// same Store.
+type Dispatcher struct {
+	store Store
+	feed  payment.ChangeFeed
+	cfg   Config
+	wake  chan struct{}
+}
+
+// NewDispatcher creates a Dispatcher which delivers the events of f to the
+// endpoints stored in s.
+func NewDispatcher(s Store, f payment.ChangeFeed, cfg Config) *Dispatcher {
+	return &Dispatcher{
+		store: s,
+		feed:  f,
+		cfg:   cfg.withDefaults(),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Run consumes the change feed and attempts the due deliveries until ctx is
+// done or an error happens. It returns ctx.Err when ctx is done, otherwise the
+// first error which has happened; HTTP errors aren't returned, they are
+// recorded in the deliveries, and the Store operations which return
+// payment.ErrAbortedOperation are retried after PollInterval.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, 2)
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errs <- d.consume(ctx)
+		cancel()
+	}()
+
+	go func() {
+		defer wg.Done()
+		errs <- d.deliverDue(ctx)
+		cancel()
+	}()
+
+	wg.Wait()
+	close(errs)
+
+	var err = <-errs
+	if cerr := <-errs; err == nil || err == context.Canceled {
+		err = cerr
+	}
+
+	return err
+}
+
+// Redeliver schedules the delivery of the organisation orgID which has
+// associated the passed ID for being attempted again as soon as possible. It's
+// mostly used for the deliveries of the dead letter list (see
+// Store.DeadLetters).
+//
+// The following error codes can be returned:
+//
+// * payment.ErrNotFound - when there isn't any delivery with the passed ID or
+// it belongs to another organisation.
+func (d *Dispatcher) Redeliver(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error {
+	if err := d.store.Redeliver(ctx, orgID, id, d.cfg.Now()); err != nil {
+		return err
+	}
+
+	d.notify()
+	return nil
+}
+
+func (d *Dispatcher) consume(ctx context.Context) error {
+	var seq uint64
+	var err = d.retry(ctx, func() error {
+		var err error
+		seq, err = d.store.Cursor(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var evtc, errc = d.feed.Changes(ctx, seq)
+	for e := range evtc {
+		var e = e
+		if err := d.retry(ctx, func() error { return d.enqueue(ctx, e) }); err != nil {
+			return err
+		}
+	}
+
+	if err := <-errc; err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// eventPayload is the body sent to the endpoints.
+type eventPayload struct {
+	Seq       uint64       `json:"seq"`
+	Type      string       `json:"type"`
+	PaymentID uuid.UUID    `json:"payment_id"`
+	Version   uint32       `json:"version"`
+	Data      payment.Pymt `json:"data"`
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, e payment.Event) error {
+	var eps, err = d.store.FindEndpoints(ctx, e.Pymt.OrgID)
+	if err != nil {
+		return err
+	}
+
+	var ds []Delivery
+	for _, ep := range eps {
+		if !ep.Accepts(e.Type) {
+			continue
+		}
+
+		if ds == nil {
+			ds = make([]Delivery, 0, len(eps))
+		}
+
+		id, err := uuid.NewV4()
+		if err != nil {
+			return errors.Wrap(err, payment.ErrUnexpectedSysError)
+		}
+
+		pl, err := json.Marshal(eventPayload{
+			Seq:       e.Seq,
+			Type:      e.Type.String(),
+			PaymentID: e.PymtID,
+			Version:   e.Version,
+			Data:      e.Pymt,
+		})
+		if err != nil {
+			return errors.Wrap(err, payment.ErrUnexpectedSysError)
+		}
+
+		ds = append(ds, Delivery{
+			ID:            id,
+			EndpointID:    ep.ID,
+			OrgID:         ep.OrgID,
+			EventSeq:      e.Seq,
+			EventType:     e.Type,
+			Payload:       pl,
+			Status:        DeliveryStatusPending,
+			NextAttemptAt: d.cfg.Now(),
+		})
+	}
+
+	if err := d.store.Enqueue(ctx, e.Seq+1, ds); err != nil {
+		return err
+	}
+
+	if len(ds) > 0 {
+		d.notify()
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) error {
+	for {
+		var ds []Delivery
+		var err = d.retry(ctx, func() error {
+			var err error
+			ds, err = d.store.DueDeliveries(ctx, d.cfg.Now(), d.cfg.BatchSize)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, dl := range ds {
+			if err := d.attempt(ctx, dl); err != nil {
+				return err
+			}
+		}
+
+		if len(ds) == int(d.cfg.BatchSize) {
+			continue
+		}
+
+		var t = time.NewTimer(d.cfg.PollInterval)
+		select {
+		case <-d.wake:
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+
+		t.Stop()
+	}
+}
+
+// attempt sends dl to its endpoint and records the result.
+func (d *Dispatcher) attempt(ctx context.Context, dl Delivery) error {
+	var ep Endpoint
+	var err = d.retry(ctx, func() error {
+		var err error
+		ep, err = d.store.GetEndpoint(ctx, dl.OrgID, dl.EndpointID)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, payment.ErrNotFound) {
+			return err
+		}
+
+		dl.Attempts++
+		dl.Status = DeliveryStatusDead
+		dl.LastError = "endpoint not found"
+		dl.LastStatusCode = 0
+		return d.updateDelivery(ctx, dl)
+	}
+
+	dl.Attempts++
+	dl.LastStatusCode, err = d.send(ctx, ep, dl)
+	if err == nil {
+		dl.Status = DeliveryStatusSucceeded
+		dl.LastError = ""
+		return d.updateDelivery(ctx, dl)
+	}
+
+	if ctx.Err() != nil {
+		// The attempt is aborted, so it isn't recorded
+		return ctx.Err()
+	}
+
+	dl.LastError = err.Error()
+	if dl.Attempts >= d.cfg.MaxAttempts {
+		dl.Status = DeliveryStatusDead
+	} else {
+		dl.NextAttemptAt = d.cfg.Now().Add(d.backoff(dl.Attempts))
+	}
+
+	return d.updateDelivery(ctx, dl)
+}
+
+func (d *Dispatcher) updateDelivery(ctx context.Context, dl Delivery) error {
+	return d.retry(ctx, func() error {
+		return d.store.UpdateDelivery(ctx, dl)
+	})
+}
+
+// send sends the HTTP request of dl to ep and returns the HTTP status code of
+// the response. It returns an error if the request fails or the status code
+// isn't 2XX.
+func (d *Dispatcher) send(ctx context.Context, ep Endpoint, dl Delivery) (int, error) {
+	var req, err = http.NewRequest(http.MethodPost, ep.URL, bytes.NewReader(dl.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDelivery, dl.ID.String())
+	req.Header.Set(HeaderEvent, dl.EventType.String())
+	req.Header.Set(HeaderSignature, Sign(ep.Secret, d.cfg.Now(), dl.Payload))
+
+	res, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	// Read part of the body for allowing the connection to be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected response status code: %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// backoff returns the time to wait after the attempt number attempts has
+// failed.
+func (d *Dispatcher) backoff(attempts uint32) time.Duration {
+	var b = d.cfg.BackoffBase
+	for i := uint32(1); i < attempts; i++ {
+		b *= 2
+		if b >= d.cfg.BackoffMax {
+			return d.cfg.BackoffMax
+		}
+	}
+
+	if b > d.cfg.BackoffMax {
+		return d.cfg.BackoffMax
+	}
+
+	return b
+}
+
+// retry calls fn until it returns nil or an error which isn't
+// payment.ErrAbortedOperation, waiting PollInterval between calls. It returns
+// ctx.Err if ctx is done.
+func (d *Dispatcher) retry(ctx context.Context, fn func() error) error {
+	for {
+		var err = fn()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err == nil || !errors.Is(err, payment.ErrAbortedOperation) {
+			return err
+		}
+
+		var t = time.NewTimer(d.cfg.PollInterval)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// notify wakes up the loop which attempts the due deliveries.
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
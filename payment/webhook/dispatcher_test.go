@@ -0,0 +1,353 @@
+package webhook
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.fraixed.es/errors"
+)
+
+func TestDispatcher_backoff(t *testing.T) {
+	var d = NewDispatcher(nil, nil, Config{
+		BackoffBase: time.Second,
+		BackoffMax:  10 * time.Second,
+	})
+
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, 2*time.Second, d.backoff(2))
+	assert.Equal(t, 8*time.Second, d.backoff(4))
+	assert.Equal(t, 10*time.Second, d.backoff(5))
+	assert.Equal(t, 10*time.Second, d.backoff(100))
+}
+
+func TestDispatcher_send(t *testing.T) {
+	var (
+		now    = time.Date(2019, 3, 4, 10, 30, 0, 0, time.UTC)
+		status = int32(http.StatusNoContent)
+		reqc   = make(chan *http.Request, 1)
+		bodyc  = make(chan []byte, 1)
+	)
+
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b, err = ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		reqc <- r
+		bodyc <- b
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer srv.Close()
+
+	var (
+		d  = NewDispatcher(nil, nil, Config{Now: func() time.Time { return now }})
+		ep = newEndpoint(t, srv.URL)
+		dl = newDelivery(t, ep, now)
+	)
+
+	var sc, err = d.send(context.Background(), ep, dl)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, sc)
+
+	var r, b = <-reqc, <-bodyc
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, dl.Payload, b)
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	assert.Equal(t, dl.ID.String(), r.Header.Get(HeaderDelivery))
+	assert.Equal(t, payment.EventTypeCreated.String(), r.Header.Get(HeaderEvent))
+	assert.Equal(t, Sign(ep.Secret, now, dl.Payload), r.Header.Get(HeaderSignature))
+	assert.NoError(t, VerifySignature(ep.Secret, r.Header.Get(HeaderSignature), b, now, time.Minute))
+
+	t.Run("error: non 2XX status code", func(t *testing.T) {
+		atomic.StoreInt32(&status, http.StatusInternalServerError)
+
+		var sc, err = d.send(context.Background(), ep, dl)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusInternalServerError, sc)
+		<-reqc
+		<-bodyc
+	})
+}
+
+func TestDispatcher_attempt(t *testing.T) {
+	var (
+		now    = time.Date(2019, 3, 4, 10, 30, 0, 0, time.UTC)
+		status int32
+		nreqs  int32
+	)
+
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&nreqs, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer srv.Close()
+
+	var (
+		ctx = context.Background()
+		ep  = newEndpoint(t, srv.URL)
+		s   = newFakeStore(ep)
+		d   = NewDispatcher(s, nil, Config{
+			MaxAttempts:  2,
+			BackoffBase:  time.Second,
+			PollInterval: time.Millisecond,
+			Now:          func() time.Time { return now },
+		})
+	)
+
+	t.Run("retry then dead letter", func(t *testing.T) {
+		atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+
+		var dl = newDelivery(t, ep, now)
+		// The aborted store operations are retried
+		s.getEndpointErrs = []error{errors.New(payment.ErrAbortedOperation)}
+
+		require.NoError(t, d.attempt(ctx, dl))
+		var ul = s.lastUpdate(t)
+		assert.Equal(t, DeliveryStatusPending, ul.Status)
+		assert.Equal(t, uint32(1), ul.Attempts)
+		assert.Equal(t, now.Add(time.Second), ul.NextAttemptAt)
+		assert.Equal(t, http.StatusServiceUnavailable, ul.LastStatusCode)
+		assert.NotEmpty(t, ul.LastError)
+
+		require.NoError(t, d.attempt(ctx, ul))
+		ul = s.lastUpdate(t)
+		assert.Equal(t, DeliveryStatusDead, ul.Status)
+		assert.Equal(t, uint32(2), ul.Attempts)
+		assert.Equal(t, http.StatusServiceUnavailable, ul.LastStatusCode)
+		assert.NotEmpty(t, ul.LastError)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&nreqs))
+	})
+
+	t.Run("succeeded", func(t *testing.T) {
+		atomic.StoreInt32(&status, http.StatusOK)
+
+		var dl = newDelivery(t, ep, now)
+		dl.Attempts = 1
+		dl.LastError = "unexpected response status code: 503"
+
+		require.NoError(t, d.attempt(ctx, dl))
+		var ul = s.lastUpdate(t)
+		assert.Equal(t, DeliveryStatusSucceeded, ul.Status)
+		assert.Equal(t, uint32(2), ul.Attempts)
+		assert.Equal(t, http.StatusOK, ul.LastStatusCode)
+		assert.Empty(t, ul.LastError)
+	})
+
+	t.Run("dead letter: endpoint not found", func(t *testing.T) {
+		var dl = newDelivery(t, ep, now)
+		dl.EndpointID = testutil.NewUUID(t)
+
+		require.NoError(t, d.attempt(ctx, dl))
+		var ul = s.lastUpdate(t)
+		assert.Equal(t, DeliveryStatusDead, ul.Status)
+		assert.Equal(t, uint32(1), ul.Attempts)
+		assert.Equal(t, "endpoint not found", ul.LastError)
+	})
+
+	t.Run("error: store", func(t *testing.T) {
+		var dl = newDelivery(t, ep, now)
+		s.getEndpointErrs = []error{errors.New(payment.ErrUnexpectedStoreError)}
+
+		var err = d.attempt(ctx, dl)
+		testutil.AssertError(t, err, payment.ErrUnexpectedStoreError)
+	})
+}
+
+func TestDispatcher_deliverDue(t *testing.T) {
+	var (
+		now      = time.Date(2019, 3, 4, 10, 30, 0, 0, time.UTC)
+		inflight int32
+		mu       sync.Mutex
+		received []string
+	)
+
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := atomic.AddInt32(&inflight, 1); n > 1 {
+			t.Errorf("deliveries sent concurrently: %d requests in flight", n)
+		}
+		defer atomic.AddInt32(&inflight, -1)
+
+		// Give time to any concurrent delivery to be sent
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		received = append(received, r.Header.Get(HeaderDelivery))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var (
+		ep = newEndpoint(t, srv.URL)
+		s  = newFakeStore(ep)
+		d  = NewDispatcher(s, nil, Config{
+			BatchSize:    2,
+			PollInterval: time.Millisecond,
+			Now:          func() time.Time { return now },
+		})
+		ids []string
+	)
+
+	// A delivery which isn't due yet must not be sent
+	var notDue = newDelivery(t, ep, now.Add(time.Minute))
+	s.dls = append(s.dls, notDue)
+	for i := 0; i < 5; i++ {
+		var dl = newDelivery(t, ep, now)
+		ids = append(ids, dl.ID.String())
+		s.dls = append(s.dls, dl)
+	}
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		errc        = make(chan error, 1)
+	)
+	defer cancel()
+
+	go func() {
+		errc <- d.deliverDue(ctx)
+	}()
+
+	var deadline = time.Now().Add(5 * time.Second)
+	for s.countStatus(DeliveryStatusSucceeded) < len(ids) {
+		require.True(t, time.Now().Before(deadline), "deliveries not succeeded before 5 seconds")
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-errc)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, ids, received)
+	assert.Equal(t, 1, s.countStatus(DeliveryStatusPending))
+}
+
+func newEndpoint(t *testing.T, url string) Endpoint {
+	return Endpoint{
+		ID: testutil.NewUUID(t),
+		EndpointUpsert: EndpointUpsert{
+			OrgID:  testutil.NewUUID(t),
+			URL:    url,
+			Secret: "secret-of-the-test-endpoint",
+		},
+	}
+}
+
+func newDelivery(t *testing.T, ep Endpoint, at time.Time) Delivery {
+	return Delivery{
+		ID:            testutil.NewUUID(t),
+		EndpointID:    ep.ID,
+		OrgID:         ep.OrgID,
+		EventSeq:      1,
+		EventType:     payment.EventTypeCreated,
+		Payload:       []byte(`{"seq":1,"type":"Created"}`),
+		Status:        DeliveryStatusPending,
+		NextAttemptAt: at,
+	}
+}
+
+// fakeStore is an in-memory Store which only implements the methods used for
+// attempting the deliveries; the rest of them panic.
+type fakeStore struct {
+	Store
+	mu  sync.Mutex
+	eps map[uuid.UUID]Endpoint
+	dls []Delivery
+	ups []Delivery
+	// getEndpointErrs are the errors returned, in order, by the first calls to
+	// GetEndpoint.
+	getEndpointErrs []error
+}
+
+func newFakeStore(eps ...Endpoint) *fakeStore {
+	var s = &fakeStore{eps: map[uuid.UUID]Endpoint{}}
+	for _, ep := range eps {
+		s.eps[ep.ID] = ep
+	}
+
+	return s
+}
+
+func (s *fakeStore) GetEndpoint(_ context.Context, orgID uuid.UUID, id uuid.UUID) (Endpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.getEndpointErrs) > 0 {
+		var err = s.getEndpointErrs[0]
+		s.getEndpointErrs = s.getEndpointErrs[1:]
+		return Endpoint{}, err
+	}
+
+	var ep, ok = s.eps[id]
+	if !ok || ep.OrgID != orgID {
+		return Endpoint{}, errors.New(payment.ErrNotFound, payment.ErrMDVar("id", id))
+	}
+
+	return ep, nil
+}
+
+func (s *fakeStore) DueDeliveries(_ context.Context, now time.Time, limit uint32) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ds []Delivery
+	for _, d := range s.dls {
+		if len(ds) == int(limit) {
+			break
+		}
+
+		if d.Status == DeliveryStatusPending && !d.NextAttemptAt.After(now) {
+			ds = append(ds, d)
+		}
+	}
+
+	return ds, nil
+}
+
+func (s *fakeStore) UpdateDelivery(_ context.Context, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ups = append(s.ups, d)
+	for i := range s.dls {
+		if s.dls[i].ID == d.ID {
+			s.dls[i] = d
+		}
+	}
+
+	return nil
+}
+
+// lastUpdate returns the delivery passed to the last call of UpdateDelivery.
+func (s *fakeStore) lastUpdate(t *testing.T) Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	require.NotEmpty(t, s.ups)
+	return s.ups[len(s.ups)-1]
+}
+
+// countStatus returns the number of stored deliveries whose status is st.
+func (s *fakeStore) countStatus(st DeliveryStatus) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for _, d := range s.dls {
+		if d.Status == st {
+			n++
+		}
+	}
+
+	return n
+}
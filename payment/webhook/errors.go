@@ -0,0 +1,52 @@
+package webhook
+
+type code uint8
+
+// The list of specific error codes that the webhook package can return.
+const (
+	ErrInvalidEndpointEventType code = iota + 1
+	ErrInvalidEndpointOrgID
+	ErrInvalidEndpointSecret
+	ErrInvalidEndpointURL
+
+	ErrInvalidSignature
+	ErrInvalidSignatureTimestamp
+)
+
+func (c code) String() string {
+	switch c {
+	case ErrInvalidEndpointEventType:
+		return "InvalidEndpointEventType"
+	case ErrInvalidEndpointOrgID:
+		return "InvalidEndpointOrgID"
+	case ErrInvalidEndpointSecret:
+		return "InvalidEndpointSecret"
+	case ErrInvalidEndpointURL:
+		return "InvalidEndpointURL"
+	case ErrInvalidSignature:
+		return "InvalidSignature"
+	case ErrInvalidSignatureTimestamp:
+		return "InvalidSignatureTimestamp"
+	}
+
+	return ""
+}
+
+func (c code) Message() string {
+	switch c {
+	case ErrInvalidEndpointEventType:
+		return "Invalid endpoint because some of its event types isn't valid"
+	case ErrInvalidEndpointOrgID:
+		return "Invalid endpoint because its organisation ID is not valid"
+	case ErrInvalidEndpointSecret:
+		return "Invalid endpoint because its secret is too short"
+	case ErrInvalidEndpointURL:
+		return "Invalid endpoint because its URL isn't an absolute HTTP or HTTPS URL"
+	case ErrInvalidSignature:
+		return "The signature is malformed or it doesn't match with the payload"
+	case ErrInvalidSignatureTimestamp:
+		return "The timestamp of the signature is out of the tolerated time window"
+	}
+
+	return ""
+}
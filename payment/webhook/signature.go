@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// The list of HTTP headers sent with each delivery.
+const (
+	// HeaderSignature contains the signature of the payload with the format
+	// "t=<unix timestamp>,v1=<hex HMAC-SHA256>".
+	HeaderSignature = "X-Payments-Signature"
+	// HeaderDelivery contains the ID of the delivery, which is the same when a
+	// delivery is retried, so receivers can use it to discard duplicates.
+	HeaderDelivery = "X-Payments-Delivery"
+	// HeaderEvent contains the type of the event.
+	HeaderEvent = "X-Payments-Event"
+)
+
+// Sign returns the value of the HeaderSignature header for payload signed with
+// secret at t.
+//
+// The signature is the HMAC-SHA256 of the Unix timestamp of t, the character
+// '.' and the payload.
+func Sign(secret string, t time.Time, payload []byte) string {
+	var ts = strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, hex.EncodeToString(mac(secret, ts, payload)))
+}
+
+// VerifySignature verifies that sig, which is a value of the HeaderSignature
+// header, is the signature of payload with secret and that its timestamp isn't
+// older or newer than tolerance with respect to now. A zero tolerance doesn't
+// check the timestamp.
+//
+// The following error codes can be returned:
+//
+// * ErrInvalidSignature
+//
+// * ErrInvalidSignatureTimestamp
+func VerifySignature(secret string, sig string, payload []byte, now time.Time, tolerance time.Duration) error {
+	var ts, v1 string
+	for _, p := range strings.Split(sig, ",") {
+		var kv = strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return errors.New(ErrInvalidSignature, payment.ErrMDArg("sig", sig))
+		}
+
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			v1 = kv[1]
+		}
+	}
+
+	var unix, err = strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.Wrap(err, ErrInvalidSignature, payment.ErrMDArg("sig", sig))
+	}
+
+	expmac, err := hex.DecodeString(v1)
+	if err != nil {
+		return errors.Wrap(err, ErrInvalidSignature, payment.ErrMDArg("sig", sig))
+	}
+
+	if !hmac.Equal(expmac, mac(secret, ts, payload)) {
+		return errors.New(ErrInvalidSignature, payment.ErrMDArg("sig", sig))
+	}
+
+	if tolerance > 0 {
+		var d = now.Sub(time.Unix(unix, 0))
+		if d > tolerance || d < -tolerance {
+			return errors.New(ErrInvalidSignatureTimestamp,
+				payment.ErrMDArg("tolerance", tolerance), payment.ErrMDFact("timestamp", unix),
+			)
+		}
+	}
+
+	return nil
+}
+
+func mac(secret string, ts string, payload []byte) []byte {
+	var h = hmac.New(sha256.New, []byte(secret))
+	_, _ = h.Write([]byte(ts))
+	_, _ = h.Write([]byte{'.'})
+	_, _ = h.Write(payload)
+
+	return h.Sum(nil)
+}
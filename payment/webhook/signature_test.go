@@ -0,0 +1,97 @@
+package webhook_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySignature(t *testing.T) {
+	var (
+		secret  = "a-secret-of-enough-length"
+		payload = []byte(`{"seq":1}`)
+		now     = time.Now()
+		sig     = webhook.Sign(secret, now, payload)
+	)
+
+	var tcases = []struct {
+		desc    string
+		secret  string
+		sig     string
+		payload []byte
+		now     time.Time
+		assert  func(*testing.T, error)
+	}{
+		{
+			desc:    "valid",
+			secret:  secret,
+			sig:     sig,
+			payload: payload,
+			now:     now.Add(30 * time.Second),
+			assert: func(t *testing.T, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			desc:    "error: tampered payload",
+			secret:  secret,
+			sig:     sig,
+			payload: []byte(`{"seq":2}`),
+			now:     now,
+			assert: func(t *testing.T, err error) {
+				testutil.AssertError(t, err, webhook.ErrInvalidSignature, payment.ErrMDArg("sig", sig))
+			},
+		},
+		{
+			desc:    "error: different secret",
+			secret:  "another-secret-of-enough-length",
+			sig:     sig,
+			payload: payload,
+			now:     now,
+			assert: func(t *testing.T, err error) {
+				testutil.AssertError(t, err, webhook.ErrInvalidSignature, payment.ErrMDArg("sig", sig))
+			},
+		},
+		{
+			desc:    "error: malformed",
+			secret:  secret,
+			sig:     "v1",
+			payload: payload,
+			now:     now,
+			assert: func(t *testing.T, err error) {
+				testutil.AssertError(t, err, webhook.ErrInvalidSignature, payment.ErrMDArg("sig", "v1"))
+			},
+		},
+		{
+			desc:    "error: timestamp out of tolerance",
+			secret:  secret,
+			sig:     sig,
+			payload: payload,
+			now:     now.Add(2 * time.Minute),
+			assert: func(t *testing.T, err error) {
+				testutil.AssertError(t, err, webhook.ErrInvalidSignatureTimestamp,
+					payment.ErrMDArg("tolerance", time.Minute), payment.ErrMDFact("timestamp", now.Unix()),
+				)
+			},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			var err = webhook.VerifySignature(tc.secret, tc.sig, tc.payload, tc.now, time.Minute)
+			tc.assert(t, err)
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	var ts = time.Unix(1500000000, 0)
+	var sig = webhook.Sign("secret", ts, []byte("payload"))
+	assert.Regexp(t, fmt.Sprintf("^t=%d,v1=[0-9a-f]{64}$", ts.Unix()), sig)
+}
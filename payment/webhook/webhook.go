@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"go.fraixed.es/errors"
+)
+
+// minSecretLen is the minimum length that the secret of an endpoint must have.
+const minSecretLen = 16
+
+// Endpoint contains all the information of a registered webhook endpoint.
+type Endpoint struct {
+	EndpointUpsert
+	ID uuid.UUID
+}
+
+// EndpointUpsert contains the information required to register an endpoint.
+type EndpointUpsert struct {
+	OrgID uuid.UUID
+	// URL is the absolute HTTP(S) URL where the events are sent.
+	URL string
+	// Secret is the key used for signing the payloads sent to the endpoint.
+	Secret string
+	// EventTypes is the list of the types of the events which are sent to the
+	// endpoint. When it's empty, all the types are sent.
+	EventTypes []payment.EventType
+}
+
+// Validate validates that the endpoint contains all the required values and
+// their values are valid.
+func (e EndpointUpsert) Validate() error {
+	if e.OrgID == uuid.Nil {
+		return errors.New(ErrInvalidEndpointOrgID, payment.ErrMDField("OrgID", e.OrgID))
+	}
+
+	var u, err = url.Parse(e.URL)
+	if err != nil {
+		return errors.Wrap(err, ErrInvalidEndpointURL, payment.ErrMDField("URL", e.URL))
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New(ErrInvalidEndpointURL, payment.ErrMDField("URL", e.URL))
+	}
+
+	if len(e.Secret) < minSecretLen {
+		return errors.New(ErrInvalidEndpointSecret, payment.ErrMDFact("min_length", minSecretLen))
+	}
+
+	for _, t := range e.EventTypes {
+		if !t.Valid() {
+			return errors.New(ErrInvalidEndpointEventType, payment.ErrMDField("EventTypes", e.EventTypes))
+		}
+	}
+
+	return nil
+}
+
+// Accepts returns true if the endpoint must receive the events of type t.
+func (e EndpointUpsert) Accepts(t payment.EventType) bool {
+	if len(e.EventTypes) == 0 {
+		return true
+	}
+
+	for _, et := range e.EventTypes {
+		if et == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeliveryStatus represents the status of a delivery.
+type DeliveryStatus uint8
+
+// The list of valid DeliveryStatus values.
+const (
+	deliveryStatusNone DeliveryStatus = iota
+	// DeliveryStatusPending is the status of the deliveries which haven't been
+	// successfully delivered yet and they will be retried.
+	DeliveryStatusPending
+	// DeliveryStatusSucceeded is the status of the deliveries which have been
+	// delivered.
+	DeliveryStatusSucceeded
+	// DeliveryStatusDead is the status of the deliveries which have reached the
+	// maximum number of attempts without being delivered. They are only retried
+	// if they are explicitly redelivered.
+	DeliveryStatusDead
+)
+
+func (s DeliveryStatus) String() string {
+	switch s {
+	case DeliveryStatusPending:
+		return "Pending"
+	case DeliveryStatusSucceeded:
+		return "Succeeded"
+	case DeliveryStatusDead:
+		return "Dead"
+	}
+
+	return ""
+}
+
+// ParseDeliveryStatus returns the DeliveryStatus whose string representation
+// is s. It returns false if s doesn't match with any valid DeliveryStatus.
+func ParseDeliveryStatus(s string) (DeliveryStatus, bool) {
+	for ds := DeliveryStatusPending; ds <= DeliveryStatusDead; ds++ {
+		if ds.String() == s {
+			return ds, true
+		}
+	}
+
+	return deliveryStatusNone, false
+}
+
+// Delivery contains the information of the delivery of an event to an
+// endpoint.
+type Delivery struct {
+	ID         uuid.UUID
+	EndpointID uuid.UUID
+	OrgID      uuid.UUID
+	EventSeq   uint64
+	EventType  payment.EventType
+	// Payload is the body of the HTTP request sent to the endpoint.
+	Payload        []byte
+	Status         DeliveryStatus
+	Attempts       uint32
+	NextAttemptAt  time.Time
+	LastError      string
+	LastStatusCode int
+}
+
+// Store is the interface which any specific implementation for persisting the
+// webhook endpoints and deliveries must satisfy.
+//
+// All the methods can return, a part of their specific ones which are
+// documented on them, the general error codes documented in payment.Service.
+type Store interface {
+	// CreateEndpoint registers a new endpoint returning its ID.
+	//
+	// This method can return any of the errors returned by e.Validate.
+	CreateEndpoint(ctx context.Context, e EndpointUpsert) (uuid.UUID, error)
+
+	// DeleteEndpoint deletes the endpoint of the organisation orgID which has
+	// associated the passed ID and all its deliveries.
+	//
+	// The following error codes can be returned:
+	//
+	// * payment.ErrNotFound - when there isn't any endpoint with the passed ID
+	// or it belongs to another organisation.
+	DeleteEndpoint(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error
+
+	// GetEndpoint retrieves the endpoint of the organisation orgID which has
+	// associated the passed ID.
+	//
+	// The following error codes can be returned:
+	//
+	// * payment.ErrNotFound - when there isn't any endpoint with the passed ID
+	// or it belongs to another organisation.
+	GetEndpoint(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (Endpoint, error)
+
+	// FindEndpoints retrieves all the endpoints registered by the organisation
+	// orgID.
+	FindEndpoints(ctx context.Context, orgID uuid.UUID) ([]Endpoint, error)
+
+	// Cursor returns the sequence number of the next payment event to process.
+	Cursor(ctx context.Context) (uint64, error)
+
+	// Enqueue stores ds and sets the cursor to nextSeq atomically.
+	// Deliveries whose endpoint and event sequence number already exist are
+	// ignored, so enqueuing the same event more than once is harmless.
+	Enqueue(ctx context.Context, nextSeq uint64, ds []Delivery) error
+
+	// DueDeliveries retrieves at most limit pending deliveries whose next attempt
+	// is at or before now, sorted by their next attempt.
+	DueDeliveries(ctx context.Context, now time.Time, limit uint32) ([]Delivery, error)
+
+	// UpdateDelivery updates the status, attempts, next attempt, last error and
+	// last status code of the delivery with the same ID of d.
+	//
+	// The following error codes can be returned:
+	//
+	// * payment.ErrNotFound
+	UpdateDelivery(ctx context.Context, d Delivery) error
+
+	// DeadLetters retrieves the deliveries of the organisation orgID whose
+	// status is DeliveryStatusDead.
+	DeadLetters(ctx context.Context, orgID uuid.UUID) ([]Delivery, error)
+
+	// Redeliver sets the delivery of the organisation orgID which has
+	// associated the passed ID as pending with zero attempts and its next
+	// attempt at.
+	//
+	// The following error codes can be returned:
+	//
+	// * payment.ErrNotFound - when there isn't any delivery with the passed ID
+	// or it belongs to another organisation.
+	Redeliver(ctx context.Context, orgID uuid.UUID, id uuid.UUID, at time.Time) error
+}
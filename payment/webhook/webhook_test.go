@@ -0,0 +1,96 @@
+package webhook_test
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ifraixedes/go-payments-api-example/payment"
+	"github.com/ifraixedes/go-payments-api-example/payment/internal/testutil"
+	"github.com/ifraixedes/go-payments-api-example/payment/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointUpsert_Validate(t *testing.T) {
+	var tcases = []struct {
+		desc   string
+		e      func(*webhook.EndpointUpsert)
+		assert func(*testing.T, webhook.EndpointUpsert, error)
+	}{
+		{
+			desc: "Valid",
+			e:    func(*webhook.EndpointUpsert) {},
+			assert: func(t *testing.T, _ webhook.EndpointUpsert, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			desc: "Invalid: OrgID",
+			e: func(e *webhook.EndpointUpsert) {
+				e.OrgID = uuid.Nil
+			},
+			assert: func(t *testing.T, e webhook.EndpointUpsert, err error) {
+				testutil.AssertError(t, err, webhook.ErrInvalidEndpointOrgID, payment.ErrMDField("OrgID", e.OrgID))
+			},
+		},
+		{
+			desc: "Invalid: URL not absolute",
+			e: func(e *webhook.EndpointUpsert) {
+				e.URL = "/hooks"
+			},
+			assert: func(t *testing.T, e webhook.EndpointUpsert, err error) {
+				testutil.AssertError(t, err, webhook.ErrInvalidEndpointURL, payment.ErrMDField("URL", e.URL))
+			},
+		},
+		{
+			desc: "Invalid: URL scheme",
+			e: func(e *webhook.EndpointUpsert) {
+				e.URL = "ftp://example.com/hooks"
+			},
+			assert: func(t *testing.T, e webhook.EndpointUpsert, err error) {
+				testutil.AssertError(t, err, webhook.ErrInvalidEndpointURL, payment.ErrMDField("URL", e.URL))
+			},
+		},
+		{
+			desc: "Invalid: Secret",
+			e: func(e *webhook.EndpointUpsert) {
+				e.Secret = "short"
+			},
+			assert: func(t *testing.T, _ webhook.EndpointUpsert, err error) {
+				testutil.AssertError(t, err, webhook.ErrInvalidEndpointSecret, payment.ErrMDFact("min_length", 16))
+			},
+		},
+		{
+			desc: "Invalid: EventTypes",
+			e: func(e *webhook.EndpointUpsert) {
+				e.EventTypes = []payment.EventType{payment.EventTypeCreated, payment.EventType(0)}
+			},
+			assert: func(t *testing.T, e webhook.EndpointUpsert, err error) {
+				testutil.AssertError(t, err, webhook.ErrInvalidEndpointEventType, payment.ErrMDField("EventTypes", e.EventTypes))
+			},
+		},
+	}
+
+	for i := range tcases {
+		var tc = tcases[i]
+		t.Run(tc.desc, func(t *testing.T) {
+			var e = webhook.EndpointUpsert{
+				OrgID:  testutil.NewUUID(t),
+				URL:    "https://example.com/hooks",
+				Secret: "a-secret-of-enough-length",
+			}
+			tc.e(&e)
+
+			tc.assert(t, e, e.Validate())
+		})
+	}
+}
+
+func TestEndpointUpsert_Accepts(t *testing.T) {
+	var e webhook.EndpointUpsert
+	assert.True(t, e.Accepts(payment.EventTypeCreated))
+	assert.True(t, e.Accepts(payment.EventTypeDeleted))
+
+	e.EventTypes = []payment.EventType{payment.EventTypeDeleted}
+	assert.False(t, e.Accepts(payment.EventTypeCreated))
+	assert.True(t, e.Accepts(payment.EventTypeDeleted))
+}